@@ -0,0 +1,18 @@
+// kubectl-gitoperator is a kubectl plugin for viewing and managing the repositories of the git operator via
+// `kubectl gitoperator <command>`
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/cli"
+)
+
+func main() {
+	err := cli.Run(&cli.Options{Name: "kubectl gitoperator", Out: os.Stdout, In: os.Stdin}, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		os.Exit(cli.ExitCode(err))
+	}
+}
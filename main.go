@@ -2,28 +2,80 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/sethvargo/go-envconfig/pkg/envconfig"
 
-	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/cli"
+	"github.com/jenkins-x/jx-git-operator/pkg/operator"
 )
 
 func main() {
+	var err error
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		err = cli.Run(&cli.Options{Name: "jx-git-operator", Out: os.Stdout, In: os.Stdin}, os.Args[1:])
+	} else {
+		err = runOperator(os.Args[1:])
+	}
+	if err != nil {
+		_, printErr := fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		if printErr != nil {
+			os.Exit(2)
+		}
+		os.Exit(cli.ExitCode(err))
+	}
+}
+
+// runOperator runs the operator configured via environment variables. The `--feature-gates` flag overrides
+// `$FEATURE_GATES` in the same way as the Kubernetes components and the `--repo-group` flags override `$REPO_GROUPS`
+func runOperator(args []string) error {
 	ctx := context.Background()
 
-	o := &poller.Options{}
-	if err := envconfig.Process(ctx, o); err != nil {
+	o := operator.Options{}
+	if err := envconfig.Process(ctx, &o); err != nil {
 		log.Fatal(err)
 	}
 
-	if err := o.Run(); err != nil {
-		_, err = fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
-		if err != nil {
-			os.Exit(2)
+	flags := flag.NewFlagSet("jx-git-operator", flag.ContinueOnError)
+	flags.StringVar(&o.FeatureGates, "feature-gates", o.FeatureGates, "comma separated feature gates such as Rollback=true,Pruning=false")
+	groups := &repoGroups{}
+	flags.Var(groups, "repo-group", "the group of the repositories to process such as prod. Can be specified multiple times or comma separated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if len(groups.values) > 0 {
+		o.RepoGroups = groups.values
+	}
+
+	op, err := operator.New(o)
+	if err != nil {
+		return err
+	}
+	return op.Run(ctx)
+}
+
+// repoGroups the values of the repeatable `--repo-group` flag
+type repoGroups struct {
+	values []string
+}
+
+// String returns the comma separated groups
+func (g *repoGroups) String() string {
+	return strings.Join(g.values, ",")
+}
+
+// Set adds the comma separated groups
+func (g *repoGroups) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			g.values = append(g.values, v)
 		}
-		os.Exit(1)
 	}
+	return nil
 }
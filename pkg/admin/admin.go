@@ -0,0 +1,263 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// Path the path prefix of the admin API
+const Path = "/api/v1/"
+
+// repositoriesPath the path prefix of the repository resources of the admin API
+const repositoriesPath = Path + "repositories"
+
+// Repository a repository along with the status of its last poll
+type Repository struct {
+	// Name the name of the repository
+	Name string `json:"name"`
+
+	// Namespace the namespace of the repository
+	Namespace string `json:"namespace"`
+
+	// Tenant the optional tenant of the repository
+	Tenant string `json:"tenant,omitempty"`
+
+	// Priority the priority of the repository
+	Priority int `json:"priority,omitempty"`
+
+	// Paused true if launching the repository has been paused
+	Paused bool `json:"paused,omitempty"`
+
+	// Status the optional status of the last poll of the repository
+	Status *status.Repository `json:"status,omitempty"`
+}
+
+// Server the admin REST API which lets portals list repositories, trigger launches, pause and resume repositories
+// and view the launch history and logs of a repository without kubectl access. It also lets the log level be
+// changed at runtime.
+//
+// Every request must specify the token via an `Authorization: Bearer <token>` header
+type Server struct {
+	token     string
+	poller    *poller.Options
+	logLevels *logLevels
+}
+
+// NewServer creates a new admin API for the given poller which requires the given token
+func NewServer(token string, p *poller.Options) *Server {
+	return &Server{
+		token:     token,
+		poller:    p,
+		logLevels: &logLevels{},
+	}
+}
+
+// Handler returns the HTTP handler of the admin API
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="jx-git-operator"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == logLevelPath {
+			s.logLevel(w, r)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, repositoriesPath) {
+			http.NotFound(w, r)
+			return
+		}
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, repositoriesPath), "/")
+		if path == "" {
+			if !allowMethod(w, r, http.MethodGet) {
+				return
+			}
+			s.listRepositories(w)
+			return
+		}
+
+		parts := strings.Split(path, "/")
+		if len(parts) < 2 {
+			http.NotFound(w, r)
+			return
+		}
+		rp, found, err := s.findRepository(parts[0], parts[1])
+		if err != nil {
+			log.Logger().Warnf("failed to find repository %s in namespace %s: %s", parts[1], parts[0], err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("repository %s not found in namespace %s", parts[1], parts[0]), http.StatusNotFound)
+			return
+		}
+
+		action := strings.Join(parts[2:], "/")
+		switch {
+		case action == "":
+			if allowMethod(w, r, http.MethodGet) {
+				writeJSON(w, s.toRepository(rp))
+			}
+		case action == "trigger":
+			if allowMethod(w, r, http.MethodPost) {
+				s.trigger(w, rp)
+			}
+		case action == "pause" || action == "resume":
+			if allowMethod(w, r, http.MethodPost) {
+				s.setPaused(w, rp, action == "pause")
+			}
+		case action == "launches":
+			if allowMethod(w, r, http.MethodGet) {
+				s.history(w, rp)
+			}
+		case len(parts) == 5 && parts[2] == "launches" && parts[4] == "logs":
+			if allowMethod(w, r, http.MethodGet) {
+				s.logs(w, rp, parts[3])
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// authorized returns true if the request has the bearer token
+func (s *Server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if s.token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) listRepositories(w http.ResponseWriter) {
+	repos, err := s.poller.RepoClient.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	answer := make([]Repository, 0, len(repos))
+	for _, r := range repos {
+		answer = append(answer, s.toRepository(r))
+	}
+	writeJSON(w, answer)
+}
+
+func (s *Server) trigger(w http.ResponseWriter, r repo.Repository) {
+	if r.Paused {
+		http.Error(w, fmt.Sprintf("repository %s in namespace %s is paused", r.Name, r.Namespace), http.StatusConflict)
+		return
+	}
+	log.Logger().Infof("triggering repository %s in namespace %s via the admin API", r.Name, r.Namespace)
+	s.poller.Trigger(r.Namespace, r.Name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) setPaused(w http.ResponseWriter, r repo.Repository, paused bool) {
+	pauser, ok := s.poller.RepoClient.(repo.Pauser)
+	if !ok {
+		http.Error(w, "the repository client does not support pausing repositories", http.StatusNotImplemented)
+		return
+	}
+	err := pauser.SetPaused(r, paused)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Logger().Infof("set paused to %t on repository %s in namespace %s via the admin API", paused, r.Name, r.Namespace)
+	r.Paused = paused
+	writeJSON(w, s.toRepository(r))
+}
+
+func (s *Server) history(w http.ResponseWriter, r repo.Repository) {
+	provider, ok := s.poller.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		http.Error(w, "the launcher does not support the launch history", http.StatusNotImplemented)
+		return
+	}
+	records, err := provider.History(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []launcher.LaunchRecord{}
+	}
+	writeJSON(w, records)
+}
+
+func (s *Server) logs(w http.ResponseWriter, r repo.Repository, name string) {
+	provider, ok := s.poller.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		http.Error(w, "the launcher does not support the launch history", http.StatusNotImplemented)
+		return
+	}
+	text, err := provider.Logs(r, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, err = w.Write([]byte(text))
+	if err != nil {
+		log.Logger().Warnf("failed to write the logs of %s: %s", name, err.Error())
+	}
+}
+
+// findRepository finds the repository with the given namespace and name
+func (s *Server) findRepository(ns string, name string) (repo.Repository, bool, error) {
+	repos, err := s.poller.RepoClient.List()
+	if err != nil {
+		return repo.Repository{}, false, err
+	}
+	for _, r := range repos {
+		if r.Namespace == ns && r.Name == name {
+			return r, true, nil
+		}
+	}
+	return repo.Repository{}, false, nil
+}
+
+func (s *Server) toRepository(r repo.Repository) Repository {
+	answer := Repository{
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Tenant:    r.Tenant,
+		Priority:  r.Priority,
+		Paused:    r.Paused,
+	}
+	if s.poller.Status != nil {
+		if st, found := s.poller.Status.Get(r.Namespace, r.Name); found {
+			answer.Status = &st
+		}
+	}
+	return answer
+}
+
+// allowMethod returns true if the request uses the given method otherwise responds with method not allowed
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Logger().Warnf("failed to write JSON response: %s", err.Error())
+	}
+}
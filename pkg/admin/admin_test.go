@@ -0,0 +1,132 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/admin"
+	"github.com/jenkins-x/jx-git-operator/pkg/harness"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const token = "mytoken"
+
+func TestAdminAPI(t *testing.T) {
+	ns := "jx"
+	h := harness.NewHarness(t, ns, nil)
+	h.AddRepository(t, "myrepo", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.Poll(t)
+
+	handler := admin.NewServer(token, h.Poller).Handler()
+
+	w := request(t, handler, http.MethodGet, "/api/v1/repositories", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject an invalid token")
+
+	w = request(t, handler, http.MethodGet, "/api/v1/repositories", token)
+	require.Equal(t, http.StatusOK, w.Code, "list repositories: %s", w.Body.String())
+	var repos []admin.Repository
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &repos), "failed to parse %s", w.Body.String())
+	require.Len(t, repos, 1)
+	assert.Equal(t, "myrepo", repos[0].Name, "repository name")
+	require.NotNil(t, repos[0].Status, "repository status")
+	assert.Equal(t, status.ResultLaunched, repos[0].Status.Result, "repository status result")
+
+	w = request(t, handler, http.MethodGet, "/api/v1/repositories/jx/missing", token)
+	assert.Equal(t, http.StatusNotFound, w.Code, "missing repository")
+
+	w = request(t, handler, http.MethodGet, "/api/v1/repositories/jx/myrepo/launches", token)
+	require.Equal(t, http.StatusOK, w.Code, "launches: %s", w.Body.String())
+	var launches []launcher.LaunchRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &launches), "failed to parse %s", w.Body.String())
+	require.Len(t, launches, 1)
+	assert.Equal(t, "sha1", launches[0].GitSHA, "launched sha")
+	assert.Equal(t, launcher.ResultActive, launches[0].Result, "launch result")
+
+	// lets pause the repository
+	w = request(t, handler, http.MethodPost, "/api/v1/repositories/jx/myrepo/pause", token)
+	require.Equal(t, http.StatusOK, w.Code, "pause: %s", w.Body.String())
+	secret, err := h.KubeClient.CoreV1().Secrets(ns).Get("myrepo", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get repository Secret")
+	assert.Equal(t, "true", secret.Annotations[repo.PausedAnnotation], "paused annotation")
+
+	h.SetJobSucceeded(t, "myrepo", "sha1")
+	h.SetGitSHA("myrepo", "sha2")
+	h.Poll(t)
+	h.AssertJobCount(t, "myrepo", "sha2", 0)
+
+	w = request(t, handler, http.MethodPost, "/api/v1/repositories/jx/myrepo/trigger", token)
+	assert.Equal(t, http.StatusConflict, w.Code, "should not trigger a paused repository")
+
+	w = request(t, handler, http.MethodPost, "/api/v1/repositories/jx/myrepo/resume", token)
+	require.Equal(t, http.StatusOK, w.Code, "resume: %s", w.Body.String())
+	h.Poll(t)
+	h.AssertJobCount(t, "myrepo", "sha2", 1)
+
+	// lets trigger a relaunch of the current commit once it has completed
+	h.SetJobSucceeded(t, "myrepo", "sha2")
+	w = request(t, handler, http.MethodPost, "/api/v1/repositories/jx/myrepo/trigger", token)
+	require.Equal(t, http.StatusAccepted, w.Code, "trigger: %s", w.Body.String())
+	h.Poll(t)
+	jobs := h.JobsForRepositoryAndSha(t, "myrepo", "sha2")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, int32(0), jobs[0].Status.Succeeded, "should have relaunched the job")
+
+	w = request(t, handler, http.MethodGet, "/api/v1/repositories/jx/myrepo/trigger", token)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code, "trigger requires a POST")
+}
+
+func request(t *testing.T, handler http.Handler, method string, path string, bearer string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", "Bearer "+bearer)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	t.Logf("%s %s returned %d", method, path, w.Code)
+	return w
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	h := harness.NewHarness(t, "jx", nil)
+	handler := admin.NewServer(token, h.Poller).Handler()
+
+	original := log.GetLevel()
+	defer log.SetLevel(original)
+
+	w := requestBody(t, handler, http.MethodPut, "/api/v1/loglevel", `{"level": "debug", "duration": "100ms"}`)
+	require.Equal(t, http.StatusOK, w.Code, "set log level: %s", w.Body.String())
+	got := admin.LogLevel{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got), "failed to parse %s", w.Body.String())
+	assert.Equal(t, "debug", got.Level, "log level")
+	assert.NotNil(t, got.RevertAt, "should revert the log level")
+	assert.Equal(t, "debug", log.GetLevel(), "current log level")
+
+	assert.Eventually(t, func() bool {
+		return log.GetLevel() == original
+	}, 5*time.Second, 50*time.Millisecond, "should restore the log level %s", original)
+
+	w = requestBody(t, handler, http.MethodPut, "/api/v1/loglevel", `{"level": "chatty"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "should reject an invalid log level")
+
+	w = request(t, handler, http.MethodGet, "/api/v1/loglevel", token)
+	require.Equal(t, http.StatusOK, w.Code, "get log level: %s", w.Body.String())
+	assert.Contains(t, w.Body.String(), original, "log level")
+}
+
+func requestBody(t *testing.T, handler http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	t.Logf("%s %s returned %d", method, path, w.Code)
+	return w
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// logLevelPath the path of the log level resource of the admin API
+const logLevelPath = Path + "loglevel"
+
+// LogLevel the log level of the operator
+type LogLevel struct {
+	// Level the log level such as `info`, `debug` or `trace`
+	Level string `json:"level"`
+
+	// Duration the optional duration after which the previous log level is restored such as `15m`
+	Duration string `json:"duration,omitempty"`
+
+	// RevertAt the optional time when the previous log level is restored
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// logLevels changes the log level at runtime, optionally restoring the previous level after a duration
+type logLevels struct {
+	lock     sync.Mutex
+	timer    *time.Timer
+	previous string
+	revertAt *time.Time
+}
+
+func (l *logLevels) get() LogLevel {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return LogLevel{
+		Level:    log.GetLevel(),
+		RevertAt: l.revertAt,
+	}
+}
+
+// set sets the log level, restoring the level which was in use before any temporary change after the duration
+func (l *logLevels) set(level string, duration time.Duration) (LogLevel, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	current := log.GetLevel()
+	err := log.SetLevel(level)
+	if err != nil {
+		return LogLevel{}, err
+	}
+	log.Logger().Infof("changed the log level from %s to %s", current, level)
+
+	if l.timer != nil {
+		l.timer.Stop()
+		current = l.previous
+		l.timer = nil
+		l.revertAt = nil
+	}
+	if duration > 0 {
+		l.previous = current
+		revertAt := time.Now().Add(duration)
+		l.revertAt = &revertAt
+		l.timer = time.AfterFunc(duration, l.revert)
+	}
+	return LogLevel{
+		Level:    log.GetLevel(),
+		RevertAt: l.revertAt,
+	}, nil
+}
+
+// revert restores the previous log level once a temporary change has expired
+func (l *logLevels) revert() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	err := log.SetLevel(l.previous)
+	if err != nil {
+		log.Logger().Warnf("failed to restore the log level %s: %s", l.previous, err.Error())
+	} else {
+		log.Logger().Infof("restored the log level to %s", l.previous)
+	}
+	l.timer = nil
+	l.revertAt = nil
+}
+
+// logLevel gets or changes the log level
+func (s *Server) logLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.logLevels.get())
+	case http.MethodPut, http.MethodPost:
+		request := LogLevel{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse the log level: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		var duration time.Duration
+		if request.Duration != "" {
+			duration, err = time.ParseDuration(request.Duration)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid duration %s: %s", request.Duration, err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		answer, err := s.logLevels.set(request.Level, duration)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s. Supported levels: %s", err.Error(), strings.Join(log.GetLevels(), ", ")), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, answer)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,209 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	admissionv1 "k8s.io/api/admission/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Path the path of the validating admission webhook endpoint for Jobs
+	Path = "/admission/jobs"
+
+	// maxPayloadSize the maximum size of an admission review
+	maxPayloadSize = 10 * 1024 * 1024
+)
+
+// ProtectedLabelPrefix the prefix of the labels, such as those via which the operator selects the Jobs it has
+// launched and their commit sha, which only trusted users may set. A Job carrying any of them could make the operator
+// believe a commit has already been launched
+const ProtectedLabelPrefix = "git-operator.jenkins.io/"
+
+// ProtectedLabels the labels via which the operator selects and classifies the Jobs it has launched. Any other label
+// with the ProtectedLabelPrefix is protected too
+var ProtectedLabels = []string{
+	constants.DefaultSelectorKey,
+	launcher.RepositoryLabelKey,
+	launcher.CommitShaLabelKey,
+	launcher.ClusterLabelKey,
+	launcher.RollbackLabelKey,
+	launcher.PostJobLabelKey,
+	launcher.ParentRepositoryLabelKey,
+	launcher.TriggerLabelKey,
+}
+
+// ProtectedAnnotations the annotations via which the operator tracks the attempts, relaunches and rollbacks of the
+// Jobs it has launched. Other annotations, such as the approval of a suspended Job, may be set by any user
+var ProtectedAnnotations = []string{
+	launcher.AttemptAnnotation,
+	launcher.RelaunchRequestAnnotation,
+	launcher.RollbackOfAnnotation,
+	launcher.RolledBackToAnnotation,
+	launcher.GitSecretAnnotation,
+	launcher.FolderAnnotation,
+}
+
+// Handler the validating admission webhook which rejects Jobs carrying the protected labels or annotations of the
+// operator unless they are created by one of the allowed users, such as the service account of the operator, so that users cannot
+// spoof the Jobs of a commit to suppress its launch.
+//
+// Updates by other users are allowed as long as they do not add, change or remove any of the protected labels or
+// annotations so that the Kubernetes controllers can still update the Jobs
+type Handler struct {
+	allowedUsers map[string]bool
+}
+
+// NewHandler creates a new admission webhook allowing the given user names such as
+// `system:serviceaccount:jx:jx-git-operator` to create the Jobs of the operator
+func NewHandler(allowedUsers []string) *Handler {
+	h := &Handler{allowedUsers: map[string]bool{}}
+	for _, u := range allowedUsers {
+		u = strings.TrimSpace(u)
+		if u != "" {
+			h.allowedUsers[u] = true
+		}
+	}
+	return h
+}
+
+// ServeHTTP handles an `AdmissionReview` of the `admission.k8s.io/v1` or `admission.k8s.io/v1beta1` API versions
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read the request body", http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1.AdmissionReview{}
+	err = json.Unmarshal(payload, review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse the admission review: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "the admission review has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = h.Review(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	data, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to marshal the admission review: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(data)
+	if err != nil {
+		log.Logger().Warnf("failed to write response: %s", err.Error())
+	}
+}
+
+// Review returns the response to the admission request of a Job
+func (h *Handler) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Kind.Group != batchv1.GroupName || req.Kind.Kind != "Job" {
+		return allowed()
+	}
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed()
+	}
+	if h.allowedUsers[req.UserInfo.Username] {
+		return allowed()
+	}
+
+	job := &batchv1.Job{}
+	err := json.Unmarshal(req.Object.Raw, job)
+	if err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, fmt.Sprintf("failed to parse the Job: %s", err.Error()))
+	}
+	old := &batchv1.Job{}
+	if req.Operation == admissionv1.Update {
+		err = json.Unmarshal(req.OldObject.Raw, old)
+		if err != nil {
+			return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, fmt.Sprintf("failed to parse the old Job: %s", err.Error()))
+		}
+	}
+
+	kind, key := "label", changedKey(old.Labels, job.Labels, protectedLabel)
+	if key == "" {
+		kind, key = "annotation", changedKey(old.Annotations, job.Annotations, protectedAnnotation)
+	}
+	if key == "" {
+		return allowed()
+	}
+	name := job.Name
+	if name == "" {
+		name = req.Name
+	}
+	message := fmt.Sprintf("user %s is not allowed to set the %s %s of Job %s as it is reserved for the git operator", req.UserInfo.Username, kind, key, name)
+	log.Logger().Warnf("audit: denied %s of Job %s in namespace %s: %s", strings.ToLower(string(req.Operation)), name, req.Namespace, message)
+	return denied(http.StatusForbidden, metav1.StatusReasonForbidden, message)
+}
+
+// changedKey returns the first protected key, in sorted order, which was added, changed or removed between the old
+// and new values or an empty string if none were
+func changedKey(oldValues map[string]string, values map[string]string, protected func(key string) bool) string {
+	var keys []string
+	for k := range oldValues {
+		keys = append(keys, k)
+	}
+	for k := range values {
+		if _, ok := oldValues[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !protected(k) {
+			continue
+		}
+		oldValue, oldOK := oldValues[k]
+		value, ok := values[k]
+		if ok != oldOK || value != oldValue {
+			return k
+		}
+	}
+	return ""
+}
+
+// protectedLabel returns true if the label key is reserved for the operator
+func protectedLabel(key string) bool {
+	return strings.HasPrefix(key, ProtectedLabelPrefix) || stringhelpers.StringArrayIndex(ProtectedLabels, key) >= 0
+}
+
+// protectedAnnotation returns true if the annotation key is reserved for the operator
+func protectedAnnotation(key string) bool {
+	return stringhelpers.StringArrayIndex(ProtectedAnnotations, key) >= 0
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func denied(code int32, reason metav1.StatusReason, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Reason:  reason,
+			Message: message,
+		},
+	}
+}
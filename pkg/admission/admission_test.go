@@ -0,0 +1,100 @@
+package admission_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/admission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+const operatorUser = "system:serviceaccount:jx:jx-git-operator"
+
+const operatorJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myrepo-1","labels":{"git-operator.jenkins.io/kind":"git-operator","git-operator.jenkins.io/repository":"myrepo","git-operator.jenkins.io/commit-sha":"sha2"}}}`
+
+const relabelledJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myrepo-1","labels":{"git-operator.jenkins.io/kind":"git-operator","git-operator.jenkins.io/repository":"myrepo","git-operator.jenkins.io/commit-sha":"sha3"}}}`
+
+const extraLabelJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myrepo-1","labels":{"app":"myapp","git-operator.jenkins.io/kind":"git-operator","git-operator.jenkins.io/repository":"myrepo","git-operator.jenkins.io/commit-sha":"sha2"}}}`
+
+const attemptJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myrepo-1","annotations":{"git-operator.jenkins.io/attempt":"2"},"labels":{"git-operator.jenkins.io/kind":"git-operator","git-operator.jenkins.io/repository":"myrepo","git-operator.jenkins.io/commit-sha":"sha2"}}}`
+
+const approvedJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myrepo-1","annotations":{"git-operator.jenkins.io/approval":"approved"},"labels":{"git-operator.jenkins.io/kind":"git-operator","git-operator.jenkins.io/repository":"myrepo","git-operator.jenkins.io/commit-sha":"sha2"}}}`
+
+const unknownLabelJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myjob","labels":{"app":"myapp","git-operator.jenkins.io/something":"else"}}}`
+
+const userJob = `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"myjob","labels":{"app":"myapp"}}}`
+
+func TestAdmissionHandler(t *testing.T) {
+	handler := admission.NewHandler([]string{operatorUser, " system:serviceaccount:jx:jx-boot "})
+
+	testCases := []struct {
+		name       string
+		apiVersion string
+		operation  string
+		user       string
+		object     string
+		oldObject  string
+		allowed    bool
+	}{
+		{name: "operator creates its Job", apiVersion: "admission.k8s.io/v1", operation: "CREATE", user: operatorUser, object: operatorJob, allowed: true},
+		{name: "other allowed user", apiVersion: "admission.k8s.io/v1", operation: "CREATE", user: "system:serviceaccount:jx:jx-boot", object: operatorJob, allowed: true},
+		{name: "user spoofs a Job", apiVersion: "admission.k8s.io/v1", operation: "CREATE", user: "alice", object: operatorJob},
+		{name: "user spoofs a Job via v1beta1", apiVersion: "admission.k8s.io/v1beta1", operation: "CREATE", user: "alice", object: operatorJob},
+		{name: "user creates an unlabelled Job", apiVersion: "admission.k8s.io/v1", operation: "CREATE", user: "alice", object: userJob, allowed: true},
+		{name: "user labels an existing Job", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: operatorJob, oldObject: userJob},
+		{name: "user removes the labels of a Job", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: userJob, oldObject: operatorJob},
+		{name: "user relabels the commit sha of a Job", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: relabelledJob, oldObject: operatorJob},
+		{name: "user adds an attempt annotation", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: attemptJob, oldObject: operatorJob},
+		{name: "user adds any label with the reserved prefix", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: unknownLabelJob, oldObject: userJob},
+		{name: "user adds an unrelated label", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: extraLabelJob, oldObject: operatorJob, allowed: true},
+		{name: "approver approves a Job", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "alice", object: approvedJob, oldObject: operatorJob, allowed: true},
+		{name: "controller updates a Job", apiVersion: "admission.k8s.io/v1", operation: "UPDATE", user: "system:serviceaccount:kube-system:job-controller", object: operatorJob, oldObject: operatorJob, allowed: true},
+		{name: "user deletes a Job", apiVersion: "admission.k8s.io/v1", operation: "DELETE", user: "alice", oldObject: operatorJob, allowed: true},
+	}
+	for _, tc := range testCases {
+		req := map[string]interface{}{
+			"uid":       "myuid",
+			"kind":      map[string]string{"group": "batch", "version": "v1", "kind": "Job"},
+			"namespace": "jx",
+			"operation": tc.operation,
+			"userInfo":  map[string]string{"username": tc.user},
+		}
+		if tc.object != "" {
+			req["object"] = json.RawMessage(tc.object)
+		}
+		if tc.oldObject != "" {
+			req["oldObject"] = json.RawMessage(tc.oldObject)
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"apiVersion": tc.apiVersion,
+			"kind":       "AdmissionReview",
+			"request":    req,
+		})
+		require.NoError(t, err, "failed to marshal review for %s", tc.name)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, admission.Path, strings.NewReader(string(payload))))
+		require.Equal(t, http.StatusOK, w.Code, "%s: %s", tc.name, w.Body.String())
+
+		review := &admissionv1.AdmissionReview{}
+		err = json.Unmarshal(w.Body.Bytes(), review)
+		require.NoError(t, err, "failed to parse response for %s", tc.name)
+		assert.Equal(t, tc.apiVersion, review.APIVersion, "apiVersion for %s", tc.name)
+		require.NotNil(t, review.Response, "response for %s", tc.name)
+		assert.Equal(t, "myuid", string(review.Response.UID), "uid for %s", tc.name)
+		assert.Equal(t, tc.allowed, review.Response.Allowed, "allowed for %s", tc.name)
+		if !tc.allowed {
+			require.NotNil(t, review.Response.Result, "result for %s", tc.name)
+			assert.Contains(t, review.Response.Result.Message, "reserved for the git operator", "message for %s", tc.name)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, admission.Path, strings.NewReader(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "should reject a review without a request")
+}
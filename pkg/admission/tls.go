@@ -0,0 +1,78 @@
+package admission
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewTLSConfig returns the TLS configuration of the webhook server for the given PEM encoded certificate and key
+// files. The files are loaded again whenever they are modified so that certificates rotated by cert-manager are used
+// without restarting the operator
+func NewTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	c := &certificateLoader{certFile: certFile, keyFile: keyFile}
+	_, err := c.GetCertificate(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}, nil
+}
+
+// certificateLoader loads the certificate when its files are modified
+type certificateLoader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+// GetCertificate returns the latest certificate
+func (c *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	modTime, err := latestModTime(c.certFile, c.keyFile)
+	if err != nil {
+		if c.certificate != nil {
+			return c.certificate, nil
+		}
+		return nil, err
+	}
+	if c.certificate != nil && modTime.Equal(c.modTime) {
+		return c.certificate, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.certificate != nil {
+			// lets keep using the previous certificate while the files are being rotated
+			return c.certificate, nil
+		}
+		return nil, errors.Wrapf(err, "failed to load the webhook certificate %s and key %s", c.certFile, c.keyFile)
+	}
+	c.certificate = &certificate
+	c.modTime = modTime
+	return c.certificate, nil
+}
+
+// latestModTime returns the latest modification time of the given files
+func latestModTime(fileNames ...string) (time.Time, error) {
+	answer := time.Time{}
+	for _, fileName := range fileNames {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return answer, errors.Wrapf(err, "failed to stat file %s", fileName)
+		}
+		if info.ModTime().After(answer) {
+			answer = info.ModTime()
+		}
+	}
+	return answer, nil
+}
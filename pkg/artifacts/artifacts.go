@@ -0,0 +1,109 @@
+package artifacts
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SchemeS3 the scheme of an AWS S3 bucket URL such as `s3://mybucket/prefix`
+	SchemeS3 = "s3"
+
+	// SchemeGCS the scheme of a Google Cloud Storage bucket URL such as `gs://mybucket/prefix`
+	SchemeGCS = "gs"
+
+	// SchemeAzure the scheme of an Azure Blob Storage container URL such as `az://myaccount/mycontainer/prefix`
+	SchemeAzure = "az"
+)
+
+// Bucket the object storage bucket the artifacts are uploaded to. The CLI of the cloud provider is used so that
+// the credentials are resolved via workload identity in the same way as any other client in the pod
+type Bucket struct {
+	// Scheme the scheme of the bucket URL: `s3`, `gs` or `az`
+	Scheme string
+
+	// Account the storage account of an Azure container
+	Account string
+
+	// Name the name of the bucket or Azure container
+	Name string
+
+	// Prefix the optional path prefix within the bucket
+	Prefix string
+
+	runner cmdrunner.CommandRunner
+}
+
+// NewBucket parses the bucket URL such as `s3://mybucket/prefix`, `gs://mybucket/prefix` or
+// `az://myaccount/mycontainer/prefix`
+func NewBucket(bucketURL string, runner cmdrunner.CommandRunner) (*Bucket, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse bucket URL %s", bucketURL)
+	}
+	if runner == nil {
+		runner = cmdrunner.DefaultCommandRunner
+	}
+	b := &Bucket{
+		Scheme: u.Scheme,
+		Name:   u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+		runner: runner,
+	}
+	switch b.Scheme {
+	case SchemeS3, SchemeGCS:
+	case SchemeAzure:
+		parts := strings.SplitN(b.Prefix, "/", 2)
+		b.Account = u.Host
+		b.Name = parts[0]
+		b.Prefix = ""
+		if len(parts) > 1 {
+			b.Prefix = parts[1]
+		}
+	default:
+		return nil, errors.Errorf("unsupported scheme of bucket URL %s. Supported schemes are s3://, gs:// and az://", bucketURL)
+	}
+	if b.Name == "" || (b.Scheme == SchemeAzure && b.Account == "") {
+		return nil, errors.Errorf("missing bucket name in URL %s", bucketURL)
+	}
+	return b, nil
+}
+
+// Upload uploads the files in the given directory to the given path within the bucket returning the URL of the
+// uploaded directory
+func (b *Bucket) Upload(dir string, p string) (string, error) {
+	key := strings.Trim(path.Join(b.Prefix, p), "/")
+	var cmd *cmdrunner.Command
+	answer := ""
+	switch b.Scheme {
+	case SchemeS3:
+		answer = "s3://" + b.Name + "/" + key + "/"
+		cmd = &cmdrunner.Command{
+			Name: "aws",
+			Args: []string{"s3", "cp", dir, answer, "--recursive", "--only-show-errors"},
+		}
+	case SchemeGCS:
+		answer = "gs://" + b.Name + "/" + key + "/"
+		cmd = &cmdrunner.Command{
+			Name: "gsutil",
+			Args: []string{"-m", "-q", "rsync", "-r", dir, answer},
+		}
+	default:
+		answer = "https://" + b.Account + ".blob.core.windows.net/" + b.Name + "/" + key + "/"
+		cmd = &cmdrunner.Command{
+			Name: "az",
+			Args: []string{"storage", "blob", "upload-batch", "--auth-mode", "login", "--account-name", b.Account, "--destination", b.Name, "--destination-path", key, "--source", dir, "--only-show-errors"},
+		}
+	}
+	log.Logger().Infof("running command: %s", cmd.CLI())
+	_, err := b.runner(cmd)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to upload %s to %s", dir, answer)
+	}
+	return answer, nil
+}
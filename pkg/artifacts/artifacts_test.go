@@ -0,0 +1,49 @@
+package artifacts_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/artifacts"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketUpload(t *testing.T) {
+	testCases := []struct {
+		bucketURL string
+		url       string
+		cli       string
+	}{
+		{
+			bucketURL: "s3://mybucket/boot",
+			url:       "s3://mybucket/boot/jx/myrepo/myjob/",
+			cli:       "aws s3 cp /tmp/myjob s3://mybucket/boot/jx/myrepo/myjob/ --recursive --only-show-errors",
+		},
+		{
+			bucketURL: "gs://mybucket",
+			url:       "gs://mybucket/jx/myrepo/myjob/",
+			cli:       "gsutil -m -q rsync -r /tmp/myjob gs://mybucket/jx/myrepo/myjob/",
+		},
+		{
+			bucketURL: "az://myaccount/mycontainer/boot/",
+			url:       "https://myaccount.blob.core.windows.net/mycontainer/boot/jx/myrepo/myjob/",
+			cli:       "az storage blob upload-batch --auth-mode login --account-name myaccount --destination mycontainer --destination-path boot/jx/myrepo/myjob --source /tmp/myjob --only-show-errors",
+		},
+	}
+	for _, tc := range testCases {
+		runner := &fakerunner.FakeRunner{}
+		b, err := artifacts.NewBucket(tc.bucketURL, runner.Run)
+		require.NoError(t, err, "failed to parse bucket URL %s", tc.bucketURL)
+
+		u, err := b.Upload("/tmp/myjob", "jx/myrepo/myjob")
+		require.NoError(t, err, "failed to upload to %s", tc.bucketURL)
+		assert.Equal(t, tc.url, u, "uploaded URL for %s", tc.bucketURL)
+		runner.ExpectResults(t, fakerunner.FakeResult{CLI: tc.cli})
+	}
+
+	for _, bucketURL := range []string{"ftp://mybucket", "s3://", "az://myaccount"} {
+		_, err := artifacts.NewBucket(bucketURL, nil)
+		assert.Error(t, err, "should fail to parse bucket URL %s", bucketURL)
+	}
+}
@@ -0,0 +1,257 @@
+package chatops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+const (
+	// Path the path of the endpoint which receives the comment events of the git provider
+	Path = "/hooks/chatops"
+
+	// Prefix the prefix of the commands in a comment
+	Prefix = "/boot"
+
+	// maxPayloadSize the maximum size of an event payload
+	maxPayloadSize = 10 * 1024 * 1024
+)
+
+// DefaultRoles the author associations which are allowed to run commands by default
+var DefaultRoles = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+// commentEvents the comment events which can contain commands
+var commentEvents = map[string]bool{
+	"issue_comment":               true,
+	"commit_comment":              true,
+	"pull_request_review_comment": true,
+}
+
+// CommentEvent a comment event of GitHub or GitHub Enterprise
+type CommentEvent struct {
+	// Action the action of the comment such as `created`
+	Action string `json:"action"`
+
+	// Comment the comment
+	Comment Comment `json:"comment"`
+
+	// Repository the repository which was commented on
+	Repository EventRepository `json:"repository"`
+}
+
+// Comment a comment on a commit, issue or pull request
+type Comment struct {
+	// Body the text of the comment
+	Body string `json:"body"`
+
+	// AuthorAssociation the role of the author of the comment in the repository such as `COLLABORATOR`
+	AuthorAssociation string `json:"author_association"`
+
+	// User the author of the comment
+	User User `json:"user"`
+}
+
+// User the author of a comment
+type User struct {
+	// Login the login of the user
+	Login string `json:"login"`
+}
+
+// EventRepository the URLs of the repository of a comment event
+type EventRepository struct {
+	// CloneURL the http clone URL
+	CloneURL string `json:"clone_url"`
+
+	// SSHURL the ssh clone URL
+	SSHURL string `json:"ssh_url"`
+
+	// HTMLURL the web URL
+	HTMLURL string `json:"html_url"`
+}
+
+// Commands returns the commands in the comment such as `rerun` for a `/boot rerun` line
+func (c *Comment) Commands() []string {
+	var answer []string
+	for _, line := range strings.Split(c.Body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == Prefix {
+			answer = append(answer, strings.ToLower(fields[1]))
+		}
+	}
+	return answer
+}
+
+// Handler receives the comment events of the git provider so that teams can operate their environment without
+// kubectl access by commenting on a commit, issue or pull request of a repository:
+//
+// * `/boot rerun` launches the latest commit of the repository again
+// * `/boot pause` pauses launching the repository
+// * `/boot resume` resumes launching the repository
+//
+// Each request must be signed with the HMAC token via the `X-Hub-Signature-256` or `X-Hub-Signature` header. Only
+// authors whose association with the repository is one of the allowed roles can run commands
+type Handler struct {
+	hmacToken []byte
+	roles     map[string]bool
+	poller    *poller.Options
+}
+
+// NewHandler creates a new handler of comment events which verifies the signature with the given HMAC token and
+// allows the given author associations to run commands. If no roles are specified the DefaultRoles are used
+func NewHandler(hmacToken string, roles []string, p *poller.Options) *Handler {
+	if len(roles) == 0 {
+		roles = DefaultRoles
+	}
+	h := &Handler{
+		hmacToken: []byte(hmacToken),
+		roles:     map[string]bool{},
+		poller:    p,
+	}
+	for _, role := range roles {
+		role = strings.ToUpper(strings.TrimSpace(role))
+		if role != "" {
+			h.roles[role] = true
+		}
+	}
+	return h
+}
+
+// ServeHTTP handles a comment event
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read the payload", http.StatusBadRequest)
+		return
+	}
+	if !signature.Valid(h.hmacToken, payload, r.Header) {
+		log.Logger().Warnf("rejected event from %s with an invalid signature", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	eventType := r.Header.Get("X-GitHub-Event")
+	if !commentEvents[eventType] {
+		writeText(w, http.StatusOK, fmt.Sprintf("ignored %s event", eventType))
+		return
+	}
+
+	event := &CommentEvent{}
+	err = json.Unmarshal(payload, event)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse the comment event: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if event.Action != "" && event.Action != "created" {
+		writeText(w, http.StatusOK, fmt.Sprintf("ignored %s comment", event.Action))
+		return
+	}
+	commands := event.Comment.Commands()
+	if len(commands) == 0 {
+		writeText(w, http.StatusOK, "no commands in the comment")
+		return
+	}
+	user := event.Comment.User.Login
+	if !h.roles[strings.ToUpper(event.Comment.AuthorAssociation)] {
+		log.Logger().Warnf("audit: rejected commands %s from user %s with association %s", strings.Join(commands, ", "), user, event.Comment.AuthorAssociation)
+		http.Error(w, fmt.Sprintf("user %s is not allowed to run commands", user), http.StatusForbidden)
+		return
+	}
+
+	repos, err := h.matchRepositories(&event.Repository)
+	if err != nil {
+		log.Logger().Warnf("failed to match the comment event to repositories: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(repos) == 0 {
+		writeText(w, http.StatusOK, "no repositories match the comment event")
+		return
+	}
+
+	var results []string
+	for _, command := range commands {
+		for _, rp := range repos {
+			results = append(results, h.run(command, rp, user))
+		}
+	}
+	writeText(w, http.StatusAccepted, strings.Join(results, "\n"))
+}
+
+// run runs the command on the repository returning a description of the result
+func (h *Handler) run(command string, r repo.Repository, user string) string {
+	key := r.Namespace + "/" + r.Name
+	switch command {
+	case "rerun":
+		if r.Paused {
+			return fmt.Sprintf("cannot rerun repository %s as it is paused", key)
+		}
+		message := fmt.Sprintf("triggered by %s via %s %s", user, Prefix, command)
+		log.Logger().Infof("audit: repository %s %s", key, message)
+		h.poller.Trigger(r.Namespace, r.Name)
+		h.poller.Events.Publish(stream.NewEvent(stream.EventTriggered, r, "", message))
+		return fmt.Sprintf("triggered repository %s", key)
+
+	case "pause", "resume":
+		pauser, ok := h.poller.RepoClient.(repo.Pauser)
+		if !ok {
+			return "the repository client does not support pausing repositories"
+		}
+		paused := command == "pause"
+		err := pauser.SetPaused(r, paused)
+		if err != nil {
+			log.Logger().Warnf("failed to %s repository %s: %s", command, key, err.Error())
+			return fmt.Sprintf("failed to %s repository %s", command, key)
+		}
+		log.Logger().Infof("audit: set paused to %t on repository %s by %s via %s %s", paused, key, user, Prefix, command)
+		return fmt.Sprintf("%sd repository %s", command, key)
+
+	default:
+		return fmt.Sprintf("unknown command %s %s. Supported commands are rerun, pause and resume", Prefix, command)
+	}
+}
+
+// matchRepositories returns the discovered repositories whose git URL matches the repository of the comment event
+func (h *Handler) matchRepositories(er *EventRepository) ([]repo.Repository, error) {
+	keys := map[string]bool{}
+	for _, u := range []string{er.CloneURL, er.SSHURL, er.HTMLURL} {
+		key := repo.GitURLKey(u)
+		if key != "" {
+			keys[key] = true
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	repos, err := h.poller.RepoClient.List()
+	if err != nil {
+		return nil, err
+	}
+	var answer []repo.Repository
+	for _, r := range repos {
+		if keys[repo.GitURLKey(r.GitURL)] {
+			answer = append(answer, r)
+		}
+	}
+	return answer, nil
+}
+
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(text + "\n"))
+	if err != nil {
+		log.Logger().Warnf("failed to write response: %s", err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package chatops_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/chatops"
+	"github.com/jenkins-x/jx-git-operator/pkg/harness"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const hmacToken = "myhmac"
+
+func TestChatOpsHandler(t *testing.T) {
+	h := harness.NewHarness(t, "jx", nil)
+	h.AddRepository(t, "myrepo", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.Poll(t)
+	h.SetJobSucceeded(t, "myrepo", "sha1")
+
+	handler := chatops.NewHandler(hmacToken, nil, h.Poller)
+
+	w := post(handler, comment("/boot rerun", "NONE"), "issue_comment", hmacToken)
+	assert.Equal(t, http.StatusForbidden, w.Code, "should reject a user who is not a collaborator")
+
+	w = post(handler, comment("/boot rerun", "COLLABORATOR"), "issue_comment", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject an invalid signature")
+
+	w = post(handler, comment("/boot rerun", "COLLABORATOR"), "push", hmacToken)
+	assert.Equal(t, http.StatusOK, w.Code, "push event")
+	assert.Contains(t, w.Body.String(), "ignored", "push event")
+
+	w = post(handler, comment("looks good to me", "COLLABORATOR"), "issue_comment", hmacToken)
+	assert.Equal(t, http.StatusOK, w.Code, "comment without commands")
+
+	w = post(handler, comment("/boot rerun", "COLLABORATOR"), "issue_comment", hmacToken)
+	require.Equal(t, http.StatusAccepted, w.Code, "rerun: %s", w.Body.String())
+	assert.Contains(t, w.Body.String(), "triggered repository jx/myrepo")
+	h.Poll(t)
+	jobs := h.JobsForRepositoryAndSha(t, "myrepo", "sha1")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, int32(0), jobs[0].Status.Succeeded, "should have relaunched the job")
+
+	w = post(handler, comment("/boot pause", "OWNER"), "commit_comment", hmacToken)
+	require.Equal(t, http.StatusAccepted, w.Code, "pause: %s", w.Body.String())
+	secret, err := h.KubeClient.CoreV1().Secrets("jx").Get("myrepo", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get repository Secret")
+	assert.Equal(t, "true", secret.Annotations[repo.PausedAnnotation], "paused annotation")
+
+	w = post(handler, comment("/boot resume\n/boot dance", "MEMBER"), "issue_comment", hmacToken)
+	require.Equal(t, http.StatusAccepted, w.Code, "resume: %s", w.Body.String())
+	assert.Contains(t, w.Body.String(), "unknown command /boot dance")
+	secret, err = h.KubeClient.CoreV1().Secrets("jx").Get("myrepo", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get repository Secret")
+	assert.NotEqual(t, "true", secret.Annotations[repo.PausedAnnotation], "paused annotation")
+}
+
+func comment(body string, association string) string {
+	return fmt.Sprintf(`{"action": "created", "comment": {"body": %q, "author_association": %q, "user": {"login": "someone"}}, "repository": {"clone_url": "https://github.com/jenkins-x/fake-repository.git"}}`, body, association)
+}
+
+func post(handler http.Handler, payload string, event string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, chatops.Path, strings.NewReader(payload))
+	req.Header.Set("X-Hub-Signature-256", signature.Sign([]byte(token), []byte(payload)))
+	req.Header.Set("X-GitHub-Event", event)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
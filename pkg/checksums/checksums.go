@@ -0,0 +1,241 @@
+package checksums
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FileName the name of the file in the git operator folder containing the sha256 checksums of the other files
+	// of the folder in the format of `sha256sum`
+	FileName = "checksums"
+
+	// SignatureFileName the name of the file in the git operator folder containing the detached signature of the
+	// checksums file, either binary or base64 encoded
+	SignatureFileName = "checksums.sig"
+)
+
+// Generate returns the contents of the checksums file of the files in the given git operator folder
+func Generate(folder string) ([]byte, error) {
+	sums, err := folderChecksums(folder)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s  %s\n", sums[name], name)
+	}
+	return buf.Bytes(), nil
+}
+
+// Verify verifies that the checksums file of the given git operator folder is signed by one of the given public
+// keys and that it contains the checksum of every file in the folder so that the folder cannot be tampered with
+func Verify(folder string, keys []crypto.PublicKey) error {
+	if len(keys) == 0 {
+		return errors.Errorf("no public keys to verify the signature of %s", folder)
+	}
+	checksumsFile := filepath.Join(folder, FileName)
+	data, err := ioutil.ReadFile(checksumsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("the git operator folder %s does not have a %s file", folder, FileName)
+		}
+		return errors.Wrapf(err, "failed to read file %s", checksumsFile)
+	}
+	signatureFile := filepath.Join(folder, SignatureFileName)
+	signature, err := ioutil.ReadFile(signatureFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("the git operator folder %s does not have a %s file", folder, SignatureFileName)
+		}
+		return errors.Wrapf(err, "failed to read file %s", signatureFile)
+	}
+	err = VerifySignature(data, signature, keys)
+	if err != nil {
+		return errors.Wrapf(err, "invalid signature of %s", checksumsFile)
+	}
+
+	expected, err := parse(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", checksumsFile)
+	}
+	actual, err := folderChecksums(folder)
+	if err != nil {
+		return err
+	}
+	for name, sum := range actual {
+		expectedSum, ok := expected[name]
+		if !ok {
+			return errors.Errorf("file %s in %s is not in the signed checksums", name, folder)
+		}
+		if expectedSum != sum {
+			return errors.Errorf("file %s in %s does not match its signed checksum", name, folder)
+		}
+	}
+	for name := range expected {
+		if _, ok := actual[name]; !ok {
+			return errors.Errorf("file %s of the signed checksums is missing from %s", name, folder)
+		}
+	}
+	return nil
+}
+
+// VerifySignature verifies the binary or base64 encoded signature of the data by one of the given ed25519, ECDSA or
+// RSA public keys. ECDSA and RSA signatures are of the sha256 digest of the data as created by
+// `openssl dgst -sha256 -sign` or `cosign sign-blob`
+func VerifySignature(data []byte, signature []byte, keys []crypto.PublicKey) error {
+	text := strings.TrimSpace(string(signature))
+	if decoded, err := base64.StdEncoding.DecodeString(text); err == nil && text != "" {
+		signature = decoded
+	}
+	digest := sha256.Sum256(data)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, data, signature) {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			sig := struct {
+				R, S *big.Int
+			}{}
+			if _, err := asn1.Unmarshal(signature, &sig); err == nil && sig.R != nil && sig.S != nil && ecdsa.Verify(k, digest[:], sig.R, sig.S) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		}
+	}
+	return errors.Errorf("the signature is not valid for any of the %d public keys", len(keys))
+}
+
+// ParsePublicKeys parses the PEM encoded PKIX ed25519, ECDSA or RSA public keys such as those created by
+// `openssl pkey -pubout` so that keys can be rotated by trusting both the old and new keys
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var answer []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse public key")
+		}
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+			answer = append(answer, key)
+		default:
+			return nil, errors.Errorf("unsupported public key type %T", key)
+		}
+	}
+	if len(answer) == 0 {
+		return nil, errors.Errorf("no PEM encoded public keys found")
+	}
+	return answer, nil
+}
+
+// LoadPublicKeys loads the PEM encoded public keys in the given file
+func LoadPublicKeys(fileName string) ([]crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read public keys file %s", fileName)
+	}
+	keys, err := ParsePublicKeys(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid public keys file %s", fileName)
+	}
+	return keys, nil
+}
+
+// parse parses the checksums file returning the checksums indexed by their slash separated path
+func parse(data []byte) (map[string]string, error) {
+	answer := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("invalid line %q. Expected `<sha256>  <path>`", line)
+		}
+		// lets support the binary mode marker of sha256sum
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./")
+		answer[name] = strings.ToLower(fields[0])
+	}
+	return answer, scanner.Err()
+}
+
+// folderChecksums returns the sha256 checksums of the files in the folder, other than the checksums and signature
+// files, indexed by their slash separated path relative to the folder
+func folderChecksums(folder string) (map[string]string, error) {
+	answer := map[string]string{}
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == FileName || name == SignatureFileName {
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return errors.Errorf("file %s in %s is not a regular file", name, folder)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		if err != nil {
+			return err
+		}
+		answer[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to checksum the files in %s", folder)
+	}
+	return answer, nil
+}
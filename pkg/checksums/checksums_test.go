@@ -0,0 +1,102 @@
+package checksums_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/checksums"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, "failed to generate ed25519 key")
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "failed to generate ECDSA key")
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "failed to generate RSA key")
+
+	keys, err := checksums.ParsePublicKeys(append(encodePublicKey(t, edPublic), encodePublicKey(t, &ecPrivate.PublicKey)...))
+	require.NoError(t, err, "failed to parse public keys")
+	require.Len(t, keys, 2, "public keys")
+
+	tmpDir, err := ioutil.TempDir("", "checksums-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(tmpDir)
+	folder := filepath.Join(tmpDir, ".jx", "git-operator")
+	writeFile(t, filepath.Join(folder, "job.yaml"), "apiVersion: batch/v1\nkind: Job\n")
+	writeFile(t, filepath.Join(folder, "resources", "configmap.yaml"), "apiVersion: v1\nkind: ConfigMap\n")
+
+	data, err := checksums.Generate(folder)
+	require.NoError(t, err, "failed to generate checksums")
+	assert.Contains(t, string(data), "  job.yaml\n", "checksums")
+	assert.Contains(t, string(data), "  resources/configmap.yaml\n", "checksums")
+	writeFile(t, filepath.Join(folder, checksums.FileName), string(data))
+
+	err = checksums.Verify(folder, keys)
+	require.Error(t, err, "should fail without a signature")
+
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, ecPrivate, digest[:])
+	require.NoError(t, err, "failed to sign with ECDSA")
+	ecSignature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	require.NoError(t, err, "failed to marshal the ECDSA signature")
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaPrivate, crypto.SHA256, digest[:])
+	require.NoError(t, err, "failed to sign with RSA")
+
+	testCases := map[string][]byte{
+		"ed25519":        ed25519.Sign(edPrivate, data),
+		"base64 ed25519": []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(edPrivate, data)) + "\n"),
+		"ecdsa":          []byte(base64.StdEncoding.EncodeToString(ecSignature)),
+	}
+	for name, signature := range testCases {
+		writeFile(t, filepath.Join(folder, checksums.SignatureFileName), string(signature))
+		err = checksums.Verify(folder, keys)
+		assert.NoError(t, err, "should verify the %s signature", name)
+	}
+
+	writeFile(t, filepath.Join(folder, checksums.SignatureFileName), string(rsaSignature))
+	err = checksums.Verify(folder, keys)
+	require.Error(t, err, "should fail for a signature of an untrusted key")
+	err = checksums.Verify(folder, []crypto.PublicKey{&rsaPrivate.PublicKey})
+	require.NoError(t, err, "should verify the RSA signature")
+
+	writeFile(t, filepath.Join(folder, "job.yaml"), "apiVersion: batch/v1\nkind: Job\nspec: {}\n")
+	err = checksums.Verify(folder, []crypto.PublicKey{&rsaPrivate.PublicKey})
+	require.Error(t, err, "should fail for a modified file")
+	assert.Contains(t, err.Error(), "does not match its signed checksum", "error")
+
+	writeFile(t, filepath.Join(folder, "job.yaml"), "apiVersion: batch/v1\nkind: Job\n")
+	writeFile(t, filepath.Join(folder, "preflight.sh"), "#!/bin/sh\n")
+	err = checksums.Verify(folder, []crypto.PublicKey{&rsaPrivate.PublicKey})
+	require.Error(t, err, "should fail for an unsigned file")
+	assert.Contains(t, err.Error(), "preflight.sh in", "error")
+}
+
+func encodePublicKey(t *testing.T, key crypto.PublicKey) []byte {
+	data, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err, "failed to marshal public key")
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: data})
+}
+
+func writeFile(t *testing.T, fileName string, text string) {
+	err := os.MkdirAll(filepath.Dir(fileName), 0700)
+	require.NoError(t, err, "failed to create dir for %s", fileName)
+	err = ioutil.WriteFile(fileName, []byte(text), 0600)
+	require.NoError(t, err, "failed to save %s", fileName)
+}
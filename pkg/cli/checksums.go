@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/checksums"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/pkg/errors"
+)
+
+func runChecksums(o *Options, args []string) error {
+	fs := o.flags("checksums", "checksums [flags] [dir]")
+	keyFile := ""
+	fs.StringVar(&keyFile, "verify", "", "verifies the signed checksums via the PEM encoded public keys in the given file rather than generating them")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	folders, err := job.GitOperatorFolders(dir)
+	if err != nil {
+		return err
+	}
+	if keyFile != "" {
+		keys, err := checksums.LoadPublicKeys(keyFile)
+		if err != nil {
+			return err
+		}
+		for _, folder := range folders {
+			err = checksums.Verify(folder, keys)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(o.Out, "the signed checksums of %s are valid\n", folder)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, folder := range folders {
+		data, err := checksums.Generate(folder)
+		if err != nil {
+			return err
+		}
+		fileName := filepath.Join(folder, checksums.FileName)
+		err = ioutil.WriteFile(fileName, data, 0600)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save file %s", fileName)
+		}
+		_, err = fmt.Fprintf(o.Out, "saved %s which should be signed to %s\n", fileName, checksums.SignatureFileName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo/secret"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/gitclient"
+	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Command a sub command of the CLI
+type Command struct {
+	// Name the name of the command
+	Name string
+
+	// Usage the one line description of the command
+	Usage string
+
+	// Run runs the command with the given options and arguments
+	Run func(o *Options, args []string) error
+}
+
+// Options the options of the CLI commands. Any clients which are not specified are lazily created
+type Options struct {
+	// Name the name of the binary used in the usage such as `jx-git-operator` or `kubectl gitoperator`
+	Name string
+
+	// Out the output of the commands
+	Out io.Writer
+
+	// In the optional input of the commands used to prompt for values
+	In io.Reader
+
+	// KubeClient the kubernetes client
+	KubeClient kubernetes.Interface
+
+	// RepoClient the client of the repositories
+	RepoClient repo.Interface
+
+	// Launcher the launcher used to find the launched resources
+	Launcher launcher.Interface
+
+	// CommandRunner the runner of the `kubectl` commands used to delete resources. Defaults to running the commands
+	CommandRunner cmdrunner.CommandRunner
+
+	// GitClient the git client used to check the connectivity of the repositories
+	GitClient gitclient.Interface
+
+	// PodLogs streams the logs of a container of a pod. Defaults to streaming the logs via the KubeClient
+	PodLogs func(ns string, pod string, options *corev1.PodLogOptions) (io.ReadCloser, error)
+
+	// Namespace the namespace of the repositories. Defaults to the current namespace
+	Namespace string
+
+	// Output the output format of the commands: `json`, `yaml` or empty for a human readable format
+	Output string
+
+	// OperatorURL the optional URL of the operator whose status endpoint reports the pending and blocked launches
+	// of the repositories displayed by the `status` command
+	OperatorURL string
+
+	// OperatorID the optional identifier of the operator instance whose applied resources are garbage collected
+	OperatorID string
+}
+
+const (
+	// OutputJSON the output format of the commands as JSON
+	OutputJSON = "json"
+
+	// OutputYAML the output format of the commands as YAML
+	OutputYAML = "yaml"
+)
+
+// Commands the sub commands of the CLI
+var Commands = []Command{
+	{
+		Name:  "status",
+		Usage: "displays the repositories with their latest launched commit and its result",
+		Run:   runStatus,
+	},
+	{
+		Name:  "trigger",
+		Usage: "launches the latest commit of the repository again",
+		Run:   runTrigger,
+	},
+	{
+		Name:  "pause",
+		Usage: "pauses launching new commits of the repository",
+		Run:   runPause,
+	},
+	{
+		Name:  "resume",
+		Usage: "resumes launching new commits of the repository",
+		Run:   runResume,
+	},
+	{
+		Name:  "logs",
+		Usage: "displays or follows the logs of the latest Job of the repository",
+		Run:   runLogs,
+	},
+	{
+		Name:  "wait",
+		Usage: "waits for the launch of a commit of the repository to complete, exiting non zero if it fails or times out",
+		Run:   runWait,
+	},
+	{
+		Name:  "correlate",
+		Usage: "lists the cluster events and resource changes within the window of a Job of the repository",
+		Run:   runCorrelate,
+	},
+	{
+		Name:  "import",
+		Usage: "registers a git repository by creating its labelled Secret",
+		Run:   runImport,
+	},
+	{
+		Name:  "gc",
+		Usage: "deletes completed Jobs older than a retention period and the resources of removed repositories",
+		Run:   runGarbageCollect,
+	},
+	{
+		Name:  "preflight",
+		Usage: "checks the binaries, RBAC permissions and git connectivity required by the operator",
+		Run:   runPreflight,
+	},
+	{
+		Name:  "validate",
+		Usage: "validates the job.yaml of a git clone reporting the line and path of any unknown fields",
+		Run:   runValidate,
+	},
+	{
+		Name:  "checksums",
+		Usage: "generates or verifies the signed checksums of the git operator folder of a git clone",
+		Run:   runChecksums,
+	},
+	{
+		Name:  "loadtest",
+		Usage: "simulates many repositories with generated commits to measure the throughput, latency and resource usage",
+		Run:   runLoadTest,
+	},
+	{
+		Name:  "leases",
+		Usage: "displays the boot leases of the repositories and optionally deletes the expired leases",
+		Run:   runLeases,
+	},
+}
+
+// IsCommand returns true if the given argument is the name of a sub command
+func IsCommand(name string) bool {
+	return findCommand(name) != nil || name == "help"
+}
+
+// Run runs the sub command of the given arguments
+func Run(o *Options, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		o.usage()
+		return nil
+	}
+	c := findCommand(args[0])
+	if c == nil {
+		o.usage()
+		return errors.Errorf("unknown command %s", args[0])
+	}
+	return c.Run(o, args[1:])
+}
+
+// flags returns the flag set of a sub command with the common flags
+func (o *Options) flags(name string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(o.Out)
+	fs.StringVar(&o.Namespace, "namespace", o.Namespace, "the namespace of the repositories. Defaults to the current namespace")
+	fs.StringVar(&o.Namespace, "n", o.Namespace, "the namespace of the repositories (shorthand)")
+	fs.StringVar(&o.Output, "output", o.Output, "the output format: json or yaml. Defaults to a human readable format")
+	fs.StringVar(&o.Output, "o", o.Output, "the output format (shorthand)")
+	fs.Usage = func() {
+		fmt.Fprintf(o.Out, "Usage: %s %s\n\n", o.Name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// Validate validates the output format and lazily creates any clients which have not been specified
+func (o *Options) Validate() error {
+	var err error
+	if o.Output != "" && o.Output != OutputJSON && o.Output != OutputYAML {
+		return errors.Errorf("unsupported output format %s. Supported formats are %s and %s", o.Output, OutputJSON, OutputYAML)
+	}
+	if o.KubeClient == nil {
+		f := kubeclient.NewFactory()
+		cfg, err := f.CreateKubeConfig()
+		if err != nil {
+			return errors.Wrapf(err, "failed to create kube config")
+		}
+		o.KubeClient, err = kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the kube client")
+		}
+	}
+	if o.Namespace == "" {
+		o.Namespace, err = kubeclient.CurrentNamespace()
+		if err != nil {
+			return errors.Wrapf(err, "failed to find the current namespace")
+		}
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.DefaultCommandRunner
+	}
+	if o.RepoClient == nil {
+		o.RepoClient, err = secret.NewClient(o.KubeClient, o.Namespace, constants.DefaultSelector)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create repo client")
+		}
+	}
+	if o.Launcher == nil {
+		o.Launcher, err = launcher.New(job.LauncherName, launcher.FactoryOptions{
+			KubeClient:    o.KubeClient,
+			Namespace:     o.Namespace,
+			Selector:      constants.DefaultSelector,
+			CommandRunner: o.CommandRunner,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create launcher")
+		}
+	}
+	return nil
+}
+
+// findRepository finds the repository of the given name
+func (o *Options) findRepository(name string) (repo.Repository, error) {
+	repos, err := o.RepoClient.List()
+	if err != nil {
+		return repo.Repository{}, errors.Wrapf(err, "failed to list repositories")
+	}
+	var names []string
+	for _, r := range repos {
+		if r.Name == name {
+			return r, nil
+		}
+		names = append(names, r.Name)
+	}
+	sort.Strings(names)
+	return repo.Repository{}, errors.Errorf("repository %s not found in namespace %s. Available repositories: %s", name, o.Namespace, strings.Join(names, ", "))
+}
+
+// write writes the value in the output format or invokes the text function for the human readable format
+func (o *Options) write(value interface{}, text func() error) error {
+	var data []byte
+	var err error
+	switch o.Output {
+	case OutputJSON:
+		data, err = json.MarshalIndent(value, "", "  ")
+		data = append(data, '\n')
+	case OutputYAML:
+		data, err = yaml.Marshal(value)
+	default:
+		return text()
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal the output as %s", o.Output)
+	}
+	_, err = o.Out.Write(data)
+	return err
+}
+
+func (o *Options) usage() {
+	fmt.Fprintf(o.Out, "Usage: %s <command> [flags]\n\nCommands:\n", o.Name)
+	for _, c := range Commands {
+		fmt.Fprintf(o.Out, "  %-10s %s\n", c.Name, c.Usage)
+	}
+}
+
+func findCommand(name string) *Command {
+	for i := range Commands {
+		if Commands[i].Name == name {
+			return &Commands[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultCorrelateAfter the default duration after a Job completed during which cluster events are still correlated
+// with it, as breakages caused by a boot often only show up once the rolled out pods start failing
+const defaultCorrelateAfter = 5 * time.Minute
+
+// Correlation the result of the `correlate` command: the cluster events and resource changes within the window of
+// a Job of a repository
+type Correlation struct {
+	// Repository the name of the repository
+	Repository string `json:"repository"`
+
+	// Job the name of the Job
+	Job string `json:"job"`
+
+	// Namespace the namespace of the Job
+	Namespace string `json:"namespace"`
+
+	// GitSHA the commit sha launched by the Job
+	GitSHA string `json:"gitSha"`
+
+	// Result the result of the Job: `active`, `succeeded` or `failed`
+	Result string `json:"result"`
+
+	// Started when the Job started
+	Started time.Time `json:"started"`
+
+	// Completed the optional time the Job completed
+	Completed *time.Time `json:"completed,omitempty"`
+
+	// Until the end of the window of the correlated events
+	Until time.Time `json:"until"`
+
+	// Events the cluster events within the window, with the oldest first
+	Events []ClusterEvent `json:"events,omitempty"`
+
+	// AppliedResources the resources applied by the operator for the commit sha such as `Deployment foo in namespace jx`
+	AppliedResources []string `json:"appliedResources,omitempty"`
+
+	// ChangedResources the resources the Job reported it changed in its boot inventory
+	ChangedResources []string `json:"changedResources,omitempty"`
+}
+
+// ClusterEvent a Kubernetes Event correlated with a Job
+type ClusterEvent struct {
+	// Time when the event was last seen
+	Time time.Time `json:"time"`
+
+	// Type the type of the event: `Normal` or `Warning`
+	Type string `json:"type"`
+
+	// Reason the reason of the event such as `BackOff`
+	Reason string `json:"reason"`
+
+	// Object the object the event is about such as `Pod/foo`
+	Object string `json:"object"`
+
+	// Namespace the namespace of the object
+	Namespace string `json:"namespace,omitempty"`
+
+	// Message the message of the event
+	Message string `json:"message"`
+
+	// Count how many times the event occurred
+	Count int32 `json:"count,omitempty"`
+}
+
+func runCorrelate(o *Options, args []string) error {
+	fs := o.flags("correlate", "correlate [flags]")
+	name := fs.String("repo", "", "the name of the repository. Defaults to the only repository in the namespace")
+	sha := fs.String("sha", "", "the full or abbreviated commit sha of the Job. Defaults to the latest launch of the repository")
+	after := fs.Duration("after", defaultCorrelateAfter, "the duration after the Job completed during which events are still included")
+	eventNamespace := fs.String("event-namespace", "", "the namespace of the events to include. Defaults to all namespaces")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	r, err := o.defaultRepository(*name)
+	if err != nil {
+		return err
+	}
+	provider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return errors.Errorf("the launcher does not support finding the launched resources")
+	}
+	records, err := provider.History(r)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the launches of repository %s", r.Name)
+	}
+	var record *launcher.LaunchRecord
+	for i := range records {
+		if *sha == "" || strings.HasPrefix(records[i].GitSHA, *sha) {
+			record = &records[i]
+			break
+		}
+	}
+	if record == nil {
+		if *sha == "" {
+			return errors.Errorf("repository %s has not been launched yet", r.Name)
+		}
+		return errors.Errorf("no Job of repository %s found for sha %s", r.Name, *sha)
+	}
+	ns := record.Namespace
+	if ns == "" {
+		ns = o.Namespace
+	}
+
+	c := Correlation{
+		Repository: r.Name,
+		Job:        record.JobName(),
+		Namespace:  ns,
+		GitSHA:     record.GitSHA,
+		Result:     record.Result,
+		Started:    record.Created,
+		Completed:  record.Completed,
+		Until:      time.Now(),
+	}
+	if record.Started != nil {
+		c.Started = *record.Started
+	}
+	if c.Completed != nil && c.Completed.Add(*after).Before(c.Until) {
+		c.Until = c.Completed.Add(*after)
+	}
+
+	c.Events, err = o.eventsBetween(*eventNamespace, c.Started, c.Until)
+	if err != nil {
+		return err
+	}
+	c.AppliedResources, err = o.appliedResources(ns, naming.ToValidValue(r.Name), record.GitSHA)
+	if err != nil {
+		return err
+	}
+	for _, jobName := range []string{record.Name, record.PostJob} {
+		if jobName == "" {
+			continue
+		}
+		inv, err := inventory.LoadBoot(o.KubeClient, ns, jobName)
+		if err != nil {
+			return err
+		}
+		if inv != nil {
+			for _, e := range inv.Entries {
+				c.ChangedResources = append(c.ChangedResources, e.String())
+			}
+		}
+	}
+
+	return o.write(c, func() error {
+		completed := "<active>"
+		if c.Completed != nil {
+			completed = c.Completed.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(o.Out, "Job %s in namespace %s of repository %s sha %s: %s\n", c.Job, c.Namespace, c.Repository, c.GitSHA, c.Result)
+		fmt.Fprintf(o.Out, "started %s, completed %s, events until %s\n\n", c.Started.UTC().Format(time.RFC3339), completed, c.Until.UTC().Format(time.RFC3339))
+
+		w := tabwriter.NewWriter(o.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tTYPE\tREASON\tNAMESPACE\tOBJECT\tMESSAGE")
+		for _, e := range c.Events {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.UTC().Format(time.RFC3339), e.Type, e.Reason, orNone(e.Namespace), e.Object, e.Message)
+		}
+		err := w.Flush()
+		if err != nil {
+			return err
+		}
+		for _, section := range []struct {
+			title     string
+			resources []string
+		}{
+			{"applied resources", c.AppliedResources},
+			{"changed resources", c.ChangedResources},
+		} {
+			fmt.Fprintf(o.Out, "\n%d %s\n", len(section.resources), section.title)
+			for _, text := range section.resources {
+				fmt.Fprintf(o.Out, "  %s\n", text)
+			}
+		}
+		return nil
+	})
+}
+
+// eventsBetween returns the events in the namespace, or all namespaces if it is blank, which occurred between the
+// given times with the oldest first
+func (o *Options) eventsBetween(ns string, start time.Time, end time.Time) ([]ClusterEvent, error) {
+	events, err := o.KubeClient.CoreV1().Events(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the events in namespace %s", orNone(ns))
+	}
+	var answer []ClusterEvent
+	for _, e := range events.Items {
+		first, last := eventTimes(e)
+		if first.After(end) || last.Before(start) {
+			continue
+		}
+		answer = append(answer, ClusterEvent{
+			Time:      last,
+			Type:      e.Type,
+			Reason:    e.Reason,
+			Object:    e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+			Namespace: e.InvolvedObject.Namespace,
+			Message:   e.Message,
+			Count:     e.Count,
+		})
+	}
+	sort.SliceStable(answer, func(i, j int) bool {
+		return answer[i].Time.Before(answer[j].Time)
+	})
+	return answer, nil
+}
+
+// eventTimes returns when the event was first and last seen
+func eventTimes(e corev1.Event) (time.Time, time.Time) {
+	first := e.FirstTimestamp.Time
+	last := e.LastTimestamp.Time
+	if !e.EventTime.IsZero() {
+		if first.IsZero() {
+			first = e.EventTime.Time
+		}
+		if last.IsZero() {
+			last = e.EventTime.Time
+		}
+	}
+	if e.Series != nil && e.Series.LastObservedTime.After(last) {
+		last = e.Series.LastObservedTime.Time
+	}
+	if first.IsZero() {
+		first = last
+	}
+	if last.IsZero() {
+		last = first
+	}
+	return first, last
+}
+
+// appliedResources returns the descriptions of the resources applied by the operator for the repository if they were
+// last applied for the commit sha
+func (o *Options) appliedResources(ns string, safeName string, gitSHA string) ([]string, error) {
+	inventories, err := inventory.List(o.KubeClient, ns)
+	if err != nil {
+		return nil, err
+	}
+	var answer []string
+	for _, inv := range inventories {
+		if inv.Repository != safeName || inv.GitSHA != gitSHA {
+			continue
+		}
+		for _, e := range inv.Entries {
+			answer = append(answer, e.String())
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// defaultRetention the default period completed Jobs are kept for
+const defaultRetention = 7 * 24 * time.Hour
+
+// GarbageCollectResult the result of the `gc` command
+type GarbageCollectResult struct {
+	// DryRun true if the resources would be deleted but were not
+	DryRun bool `json:"dryRun"`
+
+	// Resources the deleted resources
+	Resources []Garbage `json:"resources"`
+}
+
+// Garbage a resource deleted by the `gc` command
+type Garbage struct {
+	// Kind the kind of the resource
+	Kind string `json:"kind"`
+
+	// Name the name of the resource
+	Name string `json:"name"`
+
+	// Namespace the namespace of the resource
+	Namespace string `json:"namespace,omitempty"`
+
+	// Repository the safe name of the repository the resource was launched or applied for
+	Repository string `json:"repository"`
+
+	// Reason why the resource is deleted
+	Reason string `json:"reason"`
+}
+
+func runGarbageCollect(o *Options, args []string) error {
+	fs := o.flags("gc", "gc [flags]")
+	retention := fs.Duration("retention", defaultRetention, "the period completed Jobs are kept for. The latest Job of each repository is always kept")
+	dryRun := fs.Bool("dry-run", false, "displays the resources which would be deleted without deleting them")
+	fs.StringVar(&o.OperatorID, "operator-id", o.OperatorID, "the optional identifier of the operator instance whose applied resources are garbage collected")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	garbage, err := o.GarbageCollect(*retention, *dryRun)
+	if garbage == nil {
+		garbage = []Garbage{}
+	}
+
+	writeErr := o.write(GarbageCollectResult{DryRun: *dryRun, Resources: garbage}, func() error {
+		verb := "deleted"
+		if *dryRun {
+			verb = "would delete"
+		}
+		for _, g := range garbage {
+			fmt.Fprintf(o.Out, "%s %s %s in namespace %s of repository %s: %s\n", verb, g.Kind, g.Name, g.Namespace, g.Repository, g.Reason)
+		}
+		_, err := fmt.Fprintf(o.Out, "%s %d resources\n", verb, len(garbage))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// GarbageCollect deletes the completed Jobs which completed longer ago than the retention period along with the
+// completed Jobs and applied resources of repositories which have been removed.
+//
+// The latest Job of each repository is always kept as it records the commit which was last launched. Only the
+// applied resources recorded by the operator instance of the OperatorID are deleted. An error is returned if no
+// repositories are found as every resource would otherwise be deleted. If dry run is enabled the resources which
+// would be deleted are returned without deleting them
+func (o *Options) GarbageCollect(retention time.Duration, dryRun bool) ([]Garbage, error) {
+	repos, err := o.RepoClient.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list repositories")
+	}
+	if len(repos) == 0 {
+		return nil, errors.Errorf("refusing to garbage collect as no repositories were found")
+	}
+	names := map[string]bool{}
+	for _, r := range repos {
+		ns := r.Namespace
+		if ns == "" {
+			ns = o.Namespace
+		}
+		names[ns+"/"+naming.ToValidValue(r.Name)] = true
+	}
+
+	answer, err := o.garbageCollectJobs(names, retention, dryRun)
+	if err != nil {
+		return answer, err
+	}
+
+	inventories, err := inventory.List(o.KubeClient, o.Namespace)
+	if err != nil {
+		return answer, err
+	}
+	for _, inv := range inventories {
+		if names[inv.Key()] || !inv.OwnedBy(o.OperatorID, constants.DefaultSelector) {
+			continue
+		}
+		entries := inv.Entries
+		if !dryRun {
+			entries, err = inventory.Remove(o.KubeClient, o.CommandRunner, o.Namespace, inv.Owner, inv.Repository, "")
+		}
+		for _, e := range entries {
+			answer = append(answer, Garbage{
+				Kind:       e.Kind,
+				Name:       e.Name,
+				Namespace:  e.Namespace,
+				Repository: inv.Repository,
+				Reason:     "the repository has been removed",
+			})
+		}
+		if err != nil {
+			return answer, errors.Wrapf(err, "failed to delete the applied resources of removed repository %s", inv.Repository)
+		}
+	}
+	return answer, nil
+}
+
+// garbageCollectJobs deletes the completed Jobs which are older than the retention period other than the latest Job
+// of each repository along with the completed Jobs of removed repositories
+func (o *Options) garbageCollectJobs(names map[string]bool, retention time.Duration, dryRun bool) ([]Garbage, error) {
+	jobInterface := o.KubeClient.BatchV1().Jobs(o.Namespace)
+	list, err := jobInterface.List(metav1.ListOptions{
+		LabelSelector: constants.DefaultSelectorKey,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to find Jobs in namespace %s", o.Namespace)
+	}
+
+	// lets sort the Jobs with the most recent first
+	jobs := list.Items
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[j].CreationTimestamp.Before(&jobs[i].CreationTimestamp)
+	})
+
+	var answer []Garbage
+	latest := map[string]bool{}
+	propagation := metav1.DeletePropagationBackground
+	for _, j := range jobs {
+		repository := j.Labels[launcher.RepositoryLabelKey]
+		if repository == "" {
+			continue
+		}
+		isLatest := !latest[repository]
+		latest[repository] = true
+		if job.IsJobActive(j) {
+			continue
+		}
+
+		reason := "the repository has been removed"
+		if names[o.Namespace+"/"+repository] {
+			age := time.Since(completionTime(j))
+			if isLatest || age < retention {
+				continue
+			}
+			reason = fmt.Sprintf("completed %s ago", duration.HumanDuration(age))
+		}
+		if !dryRun {
+			err = jobInterface.Delete(j.Name, &metav1.DeleteOptions{
+				PropagationPolicy: &propagation,
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return answer, errors.Wrapf(err, "failed to delete Job %s in namespace %s", j.Name, o.Namespace)
+			}
+		}
+		answer = append(answer, Garbage{
+			Kind:       "Job",
+			Name:       j.Name,
+			Namespace:  o.Namespace,
+			Repository: repository,
+			Reason:     reason,
+		})
+	}
+	return answer, nil
+}
+
+// completionTime returns when the Job completed defaulting to when it was created if it is not known
+func completionTime(j v1.Job) time.Time {
+	answer := job.JobCompletionTime(j)
+	if answer.IsZero() {
+		answer = j.CreationTimestamp.Time
+	}
+	return answer
+}
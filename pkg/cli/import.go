@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImportOptions the options of a repository registered via the `import` command
+type ImportOptions struct {
+	// Name the name of the repository Secret. Defaults to the name of the git repository
+	Name string
+
+	// URL the git URL of the repository without any credentials
+	URL string
+
+	// Branch the optional branch of the repository to pull
+	Branch string
+
+	// Group the optional group of the repository such as `prod`
+	Group string
+
+	// Username the optional git user name
+	Username string
+
+	// Password the optional git password or token
+	Password string
+}
+
+func runImport(o *Options, args []string) error {
+	imp := &ImportOptions{
+		Username: os.Getenv("GIT_USERNAME"),
+		Password: os.Getenv("GIT_TOKEN"),
+	}
+	if imp.Password == "" {
+		imp.Password = os.Getenv("GIT_PASSWORD")
+	}
+	fs := o.flags("import", "import [flags] --url <git URL>")
+	fs.StringVar(&imp.URL, "url", "", "the git URL of the repository")
+	fs.StringVar(&imp.Name, "name", "", "the name of the repository Secret. Defaults to the name of the git repository")
+	fs.StringVar(&imp.Branch, "branch", "", "the branch of the repository to pull. Defaults to "+repo.DefaultBranch)
+	fs.StringVar(&imp.Group, "group", "", "the optional group of the repository such as prod so that only the operators processing the group launch it")
+	fs.StringVar(&imp.Username, "username", imp.Username, "the git user name. Defaults to $GIT_USERNAME")
+	noPrompt := fs.Bool("no-prompt", false, "disables prompting for the git credentials if they are not specified via $GIT_USERNAME and $GIT_TOKEN or $GIT_PASSWORD")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if imp.URL == "" {
+		fs.Usage()
+		return errors.Errorf("missing --url")
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	if !*noPrompt && o.In != nil && imp.Password == "" && needsCredentials(imp.URL) {
+		err = imp.promptCredentials(o.In, o.Out)
+		if err != nil {
+			return err
+		}
+	}
+	s, err := o.Import(imp)
+	if err != nil {
+		return err
+	}
+	return o.writeUpdate(RepositoryUpdate{Name: s.Name, Namespace: s.Namespace, Action: "imported"})
+}
+
+// Import creates the labelled repository Secret so that the operator polls the repository
+func (o *Options) Import(imp *ImportOptions) (*corev1.Secret, error) {
+	if !strings.Contains(imp.URL, "://") {
+		// lets allow scp style ssh URLs such as git@github.com:myorg/myrepo.git
+		if !strings.Contains(imp.URL, "@") || !strings.Contains(imp.URL, ":") {
+			return nil, errors.Errorf("invalid git URL %s", imp.URL)
+		}
+	} else if u, err := url.Parse(imp.URL); err != nil {
+		return nil, errors.Wrapf(err, "invalid git URL %s", imp.URL)
+	} else if u.User != nil && (u.Scheme == "http" || u.Scheme == "https") {
+		// lets store any credentials in the URL separately
+		if imp.Username == "" {
+			imp.Username = u.User.Username()
+		}
+		if password, ok := u.User.Password(); ok && imp.Password == "" {
+			imp.Password = password
+		}
+		imp.URL = repo.RedactGitURL(imp.URL)
+	}
+	if imp.Name == "" {
+		imp.Name = strings.TrimSuffix(path.Base(strings.TrimSuffix(imp.URL, "/")), ".git")
+	}
+	name := naming.ToValidName(imp.Name)
+	if name == "" {
+		return nil, errors.Errorf("could not find a valid name for the repository %s. Please specify one via --name", imp.URL)
+	}
+
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: o.Namespace,
+			Labels: map[string]string{
+				constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+			},
+		},
+		Data: map[string][]byte{
+			"url": []byte(imp.URL),
+		},
+	}
+	if imp.Group != "" {
+		s.Labels[repo.GroupLabel] = naming.ToValidValue(imp.Group)
+	}
+	if imp.Branch != "" {
+		s.Annotations = map[string]string{
+			repo.BranchAnnotation: imp.Branch,
+		}
+	}
+	if imp.Username != "" {
+		s.Data["username"] = []byte(imp.Username)
+	}
+	if imp.Password != "" {
+		s.Data["password"] = []byte(imp.Password)
+	}
+	s, err := o.KubeClient.CoreV1().Secrets(o.Namespace).Create(s)
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil, errors.Errorf("the Secret %s already exists in namespace %s. Please specify a different name via --name", name, o.Namespace)
+		}
+		return nil, errors.Wrapf(err, "failed to create Secret %s in namespace %s", name, o.Namespace)
+	}
+	return s, nil
+}
+
+// promptCredentials prompts for any missing git user name and password. The password is not echoed if the input
+// is a terminal
+func (imp *ImportOptions) promptCredentials(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	if imp.Username == "" {
+		fmt.Fprint(out, "git user name (leave empty for a public repository): ")
+		text, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return errors.Wrapf(err, "failed to read the git user name")
+		}
+		imp.Username = strings.TrimSpace(text)
+		if imp.Username == "" {
+			return nil
+		}
+	}
+	fmt.Fprint(out, "git password or token: ")
+	if f, ok := in.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		data, err := terminal.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the git password")
+		}
+		imp.Password = strings.TrimSpace(string(data))
+		return nil
+	}
+	text, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return errors.Wrapf(err, "failed to read the git password")
+	}
+	imp.Password = strings.TrimSpace(text)
+	return nil
+}
+
+// needsCredentials returns true if the git URL uses http or https without any credentials and so may need a user
+// name and password
+func needsCredentials(gitURL string) bool {
+	u, err := url.Parse(gitURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.User == nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/lease"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// LeasesResult the result of the `leases` command
+type LeasesResult struct {
+	// Leases the boot leases of the repositories
+	Leases []lease.Boot `json:"leases"`
+
+	// Cleared the names of the expired leases which were deleted
+	Cleared []string `json:"cleared,omitempty"`
+}
+
+func runLeases(o *Options, args []string) error {
+	fs := o.flags("leases", "leases [flags]")
+	clearExpired := fs.Bool("clear-expired", false, "deletes the expired leases whose holder has stopped renewing them")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	boots, err := lease.List(o.KubeClient, o.Namespace, now)
+	if err != nil {
+		return err
+	}
+	result := LeasesResult{Leases: boots}
+	if result.Leases == nil {
+		result.Leases = []lease.Boot{}
+	}
+	if *clearExpired {
+		leases := o.KubeClient.CoordinationV1().Leases(o.Namespace)
+		for _, b := range boots {
+			if !b.Expired {
+				continue
+			}
+			err = leases.Delete(b.Name, nil)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete Lease %s in namespace %s", b.Name, o.Namespace)
+			}
+			result.Cleared = append(result.Cleared, b.Name)
+		}
+	}
+	return o.write(result, func() error {
+		w := tabwriter.NewWriter(o.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tREPOSITORY\tHOLDER\tSHA\tJOB\tAGE\tRENEWED\tEXPIRED")
+		for _, b := range boots {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n", b.Name, b.Repository, b.Holder, orNone(b.GitSHA), orNone(b.Job), since(now, b.Acquired), since(now, b.Renewed), b.Expired)
+		}
+		err := w.Flush()
+		if err != nil {
+			return err
+		}
+		for _, name := range result.Cleared {
+			fmt.Fprintf(o.Out, "deleted expired Lease %s\n", name)
+		}
+		return nil
+	})
+}
+
+// since returns the human readable duration since the given time or `<none>` if it is not known
+func since(now time.Time, t time.Time) string {
+	if t.IsZero() {
+		return "<none>"
+	}
+	return duration.HumanDuration(now.Sub(t))
+}
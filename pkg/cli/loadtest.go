@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/loadtest"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func runLoadTest(o *Options, args []string) error {
+	lo := &loadtest.Options{}
+	fs := o.flags("loadtest", "loadtest [flags]")
+	fs.IntVar(&lo.Repositories, "repos", 10, "the number of simulated repositories")
+	fs.IntVar(&lo.Commits, "commits", 3, "the number of commits launched for each repository")
+	fs.StringVar(&lo.Dir, "dir", "", "the directory of the simulated git repositories. Defaults to a temporary directory which is removed afterwards")
+	fakeCluster := fs.Bool("fake", false, "uses a fake in memory cluster rather than creating the Secrets and Jobs in the current cluster")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *fakeCluster {
+		lo.KubeClient = fake.NewSimpleClientset()
+		lo.Namespace = o.Namespace
+		if lo.Namespace == "" {
+			lo.Namespace = "default"
+		}
+	} else {
+		err = o.Validate()
+		if err != nil {
+			return err
+		}
+		lo.KubeClient = o.KubeClient
+		lo.Namespace = o.Namespace
+	}
+	lo.GitClient = o.GitClient
+
+	report, err := lo.Run()
+	if err != nil {
+		return err
+	}
+	return o.write(report, func() error {
+		fmt.Fprintf(o.Out, "repositories:  %d\n", report.Repositories)
+		fmt.Fprintf(o.Out, "commits:       %d pushed, %d launched\n", report.Commits, report.Launched)
+		fmt.Fprintf(o.Out, "duration:      %.3fs\n", report.DurationSeconds)
+		fmt.Fprintf(o.Out, "throughput:    %.2f launches/s\n", report.Throughput)
+		fmt.Fprintf(o.Out, "latency:       %s\n", report.Latency.String())
+		if report.DroppedEvents > 0 {
+			fmt.Fprintf(o.Out, "dropped:       %d events so the latency of some launches is not included\n", report.DroppedEvents)
+		}
+		fmt.Fprintf(o.Out, "poll duration: %s\n", report.PollDuration.String())
+		_, err := fmt.Fprintf(o.Out, "memory:        %d MiB max heap, %d MiB sys, %d max goroutines\n", report.MaxHeapBytes>>20, report.SysBytes>>20, report.MaxGoroutines)
+		return err
+	})
+}
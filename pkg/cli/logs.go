@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// logsPollInterval the interval between checks for new pods and restarted containers when following the logs
+const logsPollInterval = 2 * time.Second
+
+func runLogs(o *Options, args []string) error {
+	fs := o.flags("logs", "logs [flags]")
+	name := fs.String("repo", "", "the name of the repository. Defaults to the only repository in the namespace")
+	follow := fs.Bool("follow", false, "streams the logs until the Job completes")
+	fs.BoolVar(follow, "f", false, "streams the logs until the Job completes (shorthand)")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	r, err := o.defaultRepository(*name)
+	if err != nil {
+		return err
+	}
+	provider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return errors.Errorf("the launcher does not support finding the launched resources")
+	}
+	records, err := provider.History(r)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the launches of repository %s", r.Name)
+	}
+	if len(records) == 0 {
+		return errors.Errorf("repository %s has not been launched yet", r.Name)
+	}
+	latest := records[0]
+	ns := latest.Namespace
+	if ns == "" {
+		ns = o.Namespace
+	}
+	if !*follow {
+		text, err := provider.Logs(r, latest.JobName())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(o.Out, text)
+		return err
+	}
+	return o.followJobLogs(ns, latest.JobName())
+}
+
+// defaultRepository finds the repository of the given name or, if no name is given, the only repository
+func (o *Options) defaultRepository(name string) (repo.Repository, error) {
+	if name != "" {
+		return o.findRepository(name)
+	}
+	repos, err := o.RepoClient.List()
+	if err != nil {
+		return repo.Repository{}, errors.Wrapf(err, "failed to list repositories")
+	}
+	if len(repos) == 1 {
+		return repos[0], nil
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name)
+	}
+	sort.Strings(names)
+	return repo.Repository{}, errors.Errorf("there are %d repositories in namespace %s so specify one via --repo: %s", len(repos), o.Namespace, strings.Join(names, ", "))
+}
+
+// followJobLogs streams the logs of each container of each pod of the Job, including the pods created to retry failed
+// pods and restarted containers, until the Job has completed
+func (o *Options) followJobLogs(ns string, name string) error {
+	streamed := map[string]bool{}
+	for {
+		j, err := o.KubeClient.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get Job %s in namespace %s", name, ns)
+		}
+		active := job.IsJobActive(*j)
+
+		selector := "job-name=" + name
+		pods, err := o.KubeClient.CoreV1().Pods(ns).List(metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to find pods in namespace %s with selector %s", ns, selector)
+		}
+		sort.SliceStable(pods.Items, func(i, j int) bool {
+			return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+		})
+		for _, pod := range pods.Items {
+			statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+			for _, s := range statuses {
+				if s.State.Running == nil && s.State.Terminated == nil {
+					// the container has not started yet
+					continue
+				}
+				key := fmt.Sprintf("%s/%s/%d", pod.Name, s.Name, s.RestartCount)
+				if streamed[key] {
+					continue
+				}
+				streamed[key] = true
+
+				fmt.Fprintf(o.Out, "==> pod %s container %s <==\n", pod.Name, s.Name)
+				if s.RestartCount > 0 {
+					fmt.Fprintf(o.Out, "==> restart %d <==\n", s.RestartCount)
+				}
+				err = o.streamLogs(ns, pod.Name, s.Name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if !active {
+			return nil
+		}
+		time.Sleep(logsPollInterval)
+	}
+}
+
+// streamLogs copies the logs of the container to the output until the container terminates
+func (o *Options) streamLogs(ns string, pod string, container string) error {
+	options := &corev1.PodLogOptions{
+		Container: container,
+		Follow:    true,
+	}
+	var reader io.ReadCloser
+	var err error
+	if o.PodLogs != nil {
+		reader, err = o.PodLogs(ns, pod, options)
+	} else {
+		reader, err = o.KubeClient.CoreV1().Pods(ns).GetLogs(pod, options).Stream()
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the logs of container %s of pod %s in namespace %s", container, pod, ns)
+	}
+	defer reader.Close()
+	_, err = io.Copy(o.Out, reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stream the logs of container %s of pod %s in namespace %s", container, pod, ns)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/preflight"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	gitcli "github.com/jenkins-x/jx-helpers/pkg/gitclient/cli"
+)
+
+// PreflightResult the result of the `preflight` command
+type PreflightResult struct {
+	// Ready true if all the checks passed
+	Ready bool `json:"ready"`
+
+	// Checks the result of each check
+	Checks []preflight.Check `json:"checks"`
+}
+
+func runPreflight(o *Options, args []string) error {
+	po := &preflight.Options{}
+	fs := o.flags("preflight", "preflight [flags]")
+	fs.StringVar(&po.GitBinary, "git-binary", "", "the name of the git binary. Defaults to git")
+	fs.BoolVar(&po.NoResourceApply, "no-resource-apply", false, "if the operator does not apply the resources of repositories so does not need kubectl or permission to create any resource")
+	fs.BoolVar(&po.BootLeases, "boot-leases", false, "if the operator holds a boot Lease for each repository so needs permission to create Leases")
+	rewrites := fs.String("git-url-rewrites", "", "the comma separated prefix=replacement rules which rewrite the git URLs of repositories such as https://github.com/=https://git.example.com/mirrors/github/")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	po.URLRewrites, err = repo.ParseURLRewrites(strings.Split(*rewrites, ","))
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	if o.GitClient == nil {
+		o.GitClient = gitcli.NewCLIClient(po.GitBinary, nil)
+	}
+	po.KubeClient = o.KubeClient
+	po.RepoClient = o.RepoClient
+	po.GitClient = o.GitClient
+	po.Namespace = o.Namespace
+
+	checks := po.Run()
+	failed := preflight.Error(checks)
+	err = o.write(PreflightResult{Ready: failed == nil, Checks: checks}, func() error {
+		_, err := fmt.Fprint(o.Out, preflight.Summary(checks))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return failed
+}
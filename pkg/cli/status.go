@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// RepositoryStatus the status of a repository displayed by the `status` command
+type RepositoryStatus struct {
+	// Name the name of the repository
+	Name string `json:"name"`
+
+	// URL the git URL of the repository without any credentials
+	URL string `json:"url"`
+
+	// Branch the branch which is launched
+	Branch string `json:"branch"`
+
+	// GitSHA the commit sha of the latest launch
+	GitSHA string `json:"gitSha,omitempty"`
+
+	// Result the result of the latest launch
+	Result string `json:"result,omitempty"`
+
+	// Paused true if the repository has been paused
+	Paused bool `json:"paused"`
+
+	// Created when the latest launch was created
+	Created *time.Time `json:"created,omitempty"`
+
+	// ActiveJob the optional name of the Job which is still running
+	ActiveJob string `json:"activeJob,omitempty"`
+
+	// Pending the commit shas queued to be launched once the active Job completes, with the oldest first, as reported
+	// by the operator
+	Pending []string `json:"pending,omitempty"`
+
+	// Blocked the optional launch which is blocked by the active Job of a previous commit as reported by the operator
+	Blocked *status.Blocked `json:"blocked,omitempty"`
+}
+
+func runStatus(o *Options, args []string) error {
+	fs := o.flags("status", "status [flags]")
+	fs.StringVar(&o.OperatorURL, "operator-url", o.OperatorURL, "the optional URL of the operator, such as a port-forward to its service, whose status includes the pending and blocked launches")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	statuses, err := o.Statuses()
+	if err != nil {
+		return err
+	}
+
+	if statuses == nil {
+		statuses = []RepositoryStatus{}
+	}
+	return o.write(statuses, func() error {
+		w := tabwriter.NewWriter(o.Out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tURL\tBRANCH\tLAST SHA\tLAST RESULT\tPAUSED\tAGE\tACTIVE JOB\tPENDING\tBLOCKED")
+		for _, s := range statuses {
+			age := "<none>"
+			if s.Created != nil {
+				age = duration.HumanDuration(time.Since(*s.Created))
+			}
+			blocked := "<none>"
+			if s.Blocked != nil {
+				blocked = duration.HumanDuration(time.Since(s.Blocked.Since))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%s\t%s\t%d\t%s\n", s.Name, s.URL, s.Branch, orNone(s.GitSHA), orNone(s.Result), s.Paused, age, orNone(s.ActiveJob), len(s.Pending), blocked)
+		}
+		return w.Flush()
+	})
+}
+
+// Statuses returns the status of each repository along with its latest launch
+func (o *Options) Statuses() ([]RepositoryStatus, error) {
+	repos, err := o.RepoClient.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list repositories")
+	}
+	provider, _ := o.Launcher.(launcher.HistoryProvider)
+	operatorStatuses, err := o.operatorStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	var answer []RepositoryStatus
+	for _, r := range repos {
+		if r.Branch == "" {
+			r.Branch = repo.DefaultBranch
+		}
+		s := RepositoryStatus{
+			Name:   r.Name,
+			URL:    repo.RedactGitURL(r.GitURL),
+			Branch: r.Branch,
+			Paused: r.Paused,
+		}
+		if provider != nil {
+			records, err := provider.History(r)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find the launches of repository %s", r.Name)
+			}
+			if len(records) > 0 {
+				latest := records[0]
+				s.GitSHA = latest.GitSHA
+				s.Result = latest.Result
+				s.Created = &latest.Created
+			}
+			for _, record := range records {
+				if record.Result == launcher.ResultActive {
+					s.ActiveJob = record.JobName()
+					break
+				}
+			}
+		}
+		if reported, ok := operatorStatuses[r.Namespace+"/"+r.Name]; ok {
+			if s.ActiveJob == "" {
+				s.ActiveJob = reported.ActiveJob
+			}
+			s.Pending = reported.Pending
+			s.Blocked = reported.Blocked
+		}
+		answer = append(answer, s)
+	}
+	return answer, nil
+}
+
+// operatorStatuses returns the statuses of the repositories reported by the status endpoint of the operator, indexed
+// by their namespace and name, as the pending and blocked launches are only known to the operator
+func (o *Options) operatorStatuses() (map[string]status.Repository, error) {
+	answer := map[string]status.Repository{}
+	if o.OperatorURL == "" {
+		return answer, nil
+	}
+	u := strings.TrimSuffix(o.OperatorURL, "/") + strings.TrimSuffix(status.StatusPath, "/")
+	resp, err := http.Get(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the status of the operator from %s", u)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to get the status of the operator from %s: status code %d", u, resp.StatusCode)
+	}
+	var statuses []status.Repository
+	err = json.NewDecoder(resp.Body).Decode(&statuses)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the status of the operator from %s", u)
+	}
+	for _, s := range statuses {
+		answer[s.Namespace+"/"+s.Name] = s
+	}
+	return answer, nil
+}
+
+func orNone(text string) string {
+	if text == "" {
+		return "<none>"
+	}
+	return text
+}
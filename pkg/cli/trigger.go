@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+// RepositoryUpdate the result of a command which updated a repository
+type RepositoryUpdate struct {
+	// Name the name of the repository
+	Name string `json:"name"`
+
+	// Namespace the namespace of the repository
+	Namespace string `json:"namespace"`
+
+	// Action the action performed such as `triggered`, `paused`, `resumed` or `imported`
+	Action string `json:"action"`
+}
+
+func runTrigger(o *Options, args []string) error {
+	return o.updateRepository("trigger", args, func(r repo.Repository) (string, error) {
+		triggerer, ok := o.RepoClient.(repo.Triggerer)
+		if !ok {
+			return "", errors.Errorf("the repository client does not support triggering repositories")
+		}
+		return "triggered", triggerer.SetTriggered(r, true)
+	})
+}
+
+func runPause(o *Options, args []string) error {
+	return o.updateRepository("pause", args, func(r repo.Repository) (string, error) {
+		return "paused", o.setPaused(r, true)
+	})
+}
+
+func runResume(o *Options, args []string) error {
+	return o.updateRepository("resume", args, func(r repo.Repository) (string, error) {
+		return "resumed", o.setPaused(r, false)
+	})
+}
+
+// updateRepository parses the repository name argument of the command and invokes the function on the repository
+func (o *Options) updateRepository(name string, args []string, fn func(r repo.Repository) (string, error)) error {
+	fs := o.flags(name, name+" [flags] <repository>")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.Errorf("the %s command requires the name of a repository", name)
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	r, err := o.findRepository(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	action, err := fn(r)
+	if err != nil {
+		return errors.Wrapf(err, "failed to %s repository %s", name, r.Name)
+	}
+	return o.writeUpdate(RepositoryUpdate{Name: r.Name, Namespace: r.Namespace, Action: action})
+}
+
+// writeUpdate writes the result of a command which updated a repository
+func (o *Options) writeUpdate(u RepositoryUpdate) error {
+	return o.write(u, func() error {
+		_, err := fmt.Fprintf(o.Out, "repository %s %s\n", u.Name, u.Action)
+		return err
+	})
+}
+
+func (o *Options) setPaused(r repo.Repository, paused bool) error {
+	pauser, ok := o.RepoClient.(repo.Pauser)
+	if !ok {
+		return errors.Errorf("the repository client does not support pausing repositories")
+	}
+	return pauser.SetPaused(r, paused)
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/pkg/errors"
+)
+
+// ValidateResult the result of the `validate` command
+type ValidateResult struct {
+	// File the job file which was validated
+	File string `json:"file"`
+
+	// Valid true if the job file has no problems
+	Valid bool `json:"valid"`
+
+	// Problems the problems found in the job file
+	Problems []job.SchemaProblem `json:"problems,omitempty"`
+}
+
+func runValidate(o *Options, args []string) error {
+	fs := o.flags("validate", "validate [flags] [dir]")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	folder, err := job.FindGitOperatorFolder(dir)
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(folder, "job.yaml")
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file %s exists", fileName)
+	}
+	if !exists {
+		return errors.Errorf("no job file found at %s", fileName)
+	}
+
+	result := ValidateResult{File: fileName, Valid: true}
+	validateErr := job.ValidateJobFile(fileName)
+	if schemaErr, ok := validateErr.(*job.SchemaError); ok {
+		result.Valid = false
+		result.Problems = schemaErr.Problems
+	} else if validateErr != nil {
+		return validateErr
+	}
+	err = o.write(result, func() error {
+		if result.Valid {
+			_, err := fmt.Fprintf(o.Out, "%s is valid\n", fileName)
+			return err
+		}
+		for _, p := range result.Problems {
+			_, err := fmt.Fprintf(o.Out, "%s: %s\n", fileName, p.String())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Valid {
+		return errors.Errorf("%s has %d problems", fileName, len(result.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ExitFailed the exit code of the `wait` command when the Job failed
+	ExitFailed = 1
+
+	// ExitTimeout the exit code of the `wait` command when the timeout expired, the same as the `timeout` command
+	ExitTimeout = 124
+
+	// defaultWaitTimeout the default timeout of the `wait` command
+	defaultWaitTimeout = 30 * time.Minute
+
+	// waitPollInterval the interval between checks of the launches of the repository by the `wait` command
+	waitPollInterval = 2 * time.Second
+)
+
+// ExitError an error which exits the CLI with a specific exit code
+type ExitError struct {
+	// Code the exit code
+	Code int
+
+	// Message the error message
+	Message string
+}
+
+// Error returns the error message
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+// ExitCode returns the exit code of the CLI for the given error: the code of an ExitError, 0 if there is no error
+// or 1 otherwise
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := errors.Cause(err).(*ExitError); ok {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// WaitResult the result of the `wait` command
+type WaitResult struct {
+	// Repository the name of the repository
+	Repository string `json:"repository"`
+
+	// GitSHA the commit sha of the launch
+	GitSHA string `json:"gitSha,omitempty"`
+
+	// Job the name of the Job which determined the result of the launch
+	Job string `json:"job,omitempty"`
+
+	// Result the result of the launch: `succeeded`, `failed` or `active` if the timeout expired
+	Result string `json:"result"`
+}
+
+func runWait(o *Options, args []string) error {
+	fs := o.flags("wait", "wait [flags]")
+	name := fs.String("repo", "", "the name of the repository. Defaults to the only repository in the namespace")
+	sha := fs.String("sha", "", "the full or abbreviated commit sha to wait for. Defaults to the latest launch of the repository")
+	timeout := fs.Duration("timeout", defaultWaitTimeout, "the maximum duration to wait for the launch to complete")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = o.Validate()
+	if err != nil {
+		return err
+	}
+	r, err := o.defaultRepository(*name)
+	if err != nil {
+		return err
+	}
+	provider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return errors.Errorf("the launcher does not support finding the launched resources")
+	}
+
+	result := WaitResult{Repository: r.Name, GitSHA: *sha, Result: launcher.ResultActive}
+	deadline := time.Now().Add(*timeout)
+	for {
+		records, err := provider.History(r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find the launches of repository %s", r.Name)
+		}
+		for _, record := range records {
+			if *sha == "" || strings.HasPrefix(record.GitSHA, *sha) {
+				result.GitSHA = record.GitSHA
+				result.Job = record.JobName()
+				result.Result = record.Result
+				break
+			}
+		}
+		remaining := time.Until(deadline)
+		if result.Result != launcher.ResultActive || remaining <= 0 {
+			break
+		}
+		if remaining > waitPollInterval {
+			remaining = waitPollInterval
+		}
+		time.Sleep(remaining)
+	}
+
+	err = o.write(result, func() error {
+		_, err := fmt.Fprintf(o.Out, "repository %s sha %s: %s\n", r.Name, orNone(result.GitSHA), result.Result)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	switch result.Result {
+	case launcher.ResultSucceeded:
+		return nil
+	case launcher.ResultFailed:
+		return &ExitError{Code: ExitFailed, Message: fmt.Sprintf("the Job %s of repository %s failed", result.Job, r.Name)}
+	default:
+		return &ExitError{Code: ExitTimeout, Message: fmt.Sprintf("timed out after %s waiting for repository %s sha %s", timeout.String(), r.Name, orNone(result.GitSHA))}
+	}
+}
@@ -0,0 +1,180 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIVersion the API version of the credential requests and responses of exec credential helpers
+	APIVersion = "git-operator.jenkins.io/v1alpha1"
+
+	// RequestKind the kind of the credential requests passed to exec credential helpers
+	RequestKind = "CredentialRequest"
+
+	// RequestEnvVar the environment variable containing the JSON credential request passed to exec credential helpers
+	RequestEnvVar = "GIT_OPERATOR_CREDENTIAL_REQUEST"
+
+	// DefaultTimeout the default timeout of exec credential helpers
+	DefaultTimeout = 30 * time.Second
+
+	// RefreshMargin the duration before the expiry of cached credentials after which the helper is invoked again
+	RefreshMargin = time.Minute
+)
+
+// Credentials the git credentials of a repository
+type Credentials struct {
+	// Username the user name of the git URL
+	Username string `json:"username,omitempty"`
+
+	// Password the password or token of the git URL
+	Password string `json:"password,omitempty"`
+
+	// ExpirationTimestamp the optional time the credentials expire until which they are cached. Credentials without
+	// an expiration are requested every poll
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// Provider supplies the git credentials of repositories at clone time so that credentials stored in bespoke secret
+// systems do not need to be copied into the repository Secrets
+type Provider interface {
+	// Credentials returns the credentials of the repository or nil if the provider has none for it
+	Credentials(r repo.Repository) (*Credentials, error)
+}
+
+// Request the credential request passed to exec credential helpers as JSON via the RequestEnvVar environment variable
+type Request struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Repository RequestRepository `json:"repository"`
+}
+
+// RequestRepository the repository whose credentials are requested
+type RequestRepository struct {
+	// Name the name of the repository
+	Name string `json:"name"`
+
+	// Namespace the namespace of the repository
+	Namespace string `json:"namespace"`
+
+	// GitURL the git URL of the repository without any credentials
+	GitURL string `json:"gitURL"`
+
+	// Branch the optional branch of the repository
+	Branch string `json:"branch,omitempty"`
+
+	// Parameters the optional parameters of the repository
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// Response the credential response written as JSON to the standard output by exec credential helpers
+type Response struct {
+	APIVersion string       `json:"apiVersion,omitempty"`
+	Kind       string       `json:"kind,omitempty"`
+	Status     *Credentials `json:"status,omitempty"`
+}
+
+// ExecProvider a Provider which invokes an exec credential helper, like the exec plugins of kubeconfig files, for
+// each repository and caches the returned credentials until shortly before they expire
+type ExecProvider struct {
+	// Command the command of the helper
+	Command string
+
+	// Args the arguments of the helper
+	Args []string
+
+	// Timeout the timeout of the helper. Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// Now returns the current time. Defaults to time.Now
+	Now func() time.Time
+
+	runner cmdrunner.CommandRunner
+	lock   sync.Mutex
+	cache  map[string]Credentials
+}
+
+// NewExecProvider creates a provider which invokes the helper with the given command line such as
+// `/usr/local/bin/vault-git-credentials --role git-operator`. The command runner is not wrapped by the logging and
+// recording of the operator so that the credentials are never logged
+func NewExecProvider(commandLine string, commandRunner cmdrunner.CommandRunner) (*ExecProvider, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, errors.Errorf("missing credential helper command")
+	}
+	if commandRunner == nil {
+		commandRunner = runner.Exec
+	}
+	return &ExecProvider{
+		Command: fields[0],
+		Args:    fields[1:],
+		Timeout: DefaultTimeout,
+		Now:     time.Now,
+		runner:  commandRunner,
+		cache:   map[string]Credentials{},
+	}, nil
+}
+
+// Credentials returns the cached credentials of the repository or invokes the helper
+func (p *ExecProvider) Credentials(r repo.Repository) (*Credentials, error) {
+	request := Request{
+		APIVersion: APIVersion,
+		Kind:       RequestKind,
+		Repository: RequestRepository{
+			Name:       r.Name,
+			Namespace:  r.Namespace,
+			GitURL:     repo.RedactGitURL(r.GitURL),
+			Branch:     r.Branch,
+			Parameters: r.Parameters,
+		},
+	}
+	data, err := json.Marshal(&request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal the credential request")
+	}
+	key := string(data)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := p.Now()
+	if c, ok := p.cache[key]; ok && now.Before(c.ExpirationTimestamp.Add(-RefreshMargin)) {
+		return &c, nil
+	}
+	delete(p.cache, key)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	c := &cmdrunner.Command{
+		Name:    p.Command,
+		Args:    p.Args,
+		Env:     map[string]string{RequestEnvVar: key},
+		Timeout: p.Timeout,
+		Out:     stdout,
+		Err:     stderr,
+	}
+	_, err = p.runner(c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "the credential helper %s failed for repository %s: %s", p.Command, r.Name, strings.TrimSpace(stderr.String()))
+	}
+	response := &Response{}
+	err = json.Unmarshal(stdout.Bytes(), response)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the response of the credential helper %s for repository %s", p.Command, r.Name)
+	}
+	answer := response.Status
+	if answer == nil || (answer.Username == "" && answer.Password == "") {
+		return nil, nil
+	}
+	if answer.ExpirationTimestamp != nil {
+		p.cache[key] = *answer
+	}
+	return answer, nil
+}
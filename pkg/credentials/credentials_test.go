@@ -0,0 +1,79 @@
+package credentials_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/credentials"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const helperScript = `#!/bin/sh
+echo "$GIT_OPERATOR_CREDENTIAL_REQUEST" >> "$(dirname "$0")/requests.log"
+case "$GIT_OPERATOR_CREDENTIAL_REQUEST" in
+  *'"name":"broken"'*)
+    echo "vault is sealed" >&2
+    exit 1;;
+  *'"name":"public"'*)
+    echo '{"status":{}}';;
+  *)
+    echo "looking up $1" >&2
+    echo '{"apiVersion":"git-operator.jenkins.io/v1alpha1","kind":"CredentialResponse","status":{"username":"bot","password":"'"$1"'","expirationTimestamp":"2020-01-01T01:00:00Z"}}';;
+esac
+`
+
+func TestExecProvider(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "credentials-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(tmpDir)
+	helper := filepath.Join(tmpDir, "helper.sh")
+	err = ioutil.WriteFile(helper, []byte(helperScript), 0700)
+	require.NoError(t, err, "failed to save helper")
+
+	p, err := credentials.NewExecProvider(helper+" mytoken", nil)
+	require.NoError(t, err, "failed to create provider")
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.Now = func() time.Time {
+		return now
+	}
+
+	r := repo.Repository{
+		Name:       "myrepo",
+		Namespace:  "jx",
+		GitURL:     "https://github.com/myorg/myrepo.git",
+		Parameters: map[string]string{"vault-path": "secret/git/myrepo"},
+	}
+	c, err := p.Credentials(r)
+	require.NoError(t, err, "failed to get credentials")
+	require.NotNil(t, c, "credentials")
+	assert.Equal(t, "bot", c.Username, "username")
+	assert.Equal(t, "mytoken", c.Password, "password")
+
+	// the credentials are cached until shortly before they expire
+	now = now.Add(30 * time.Minute)
+	_, err = p.Credentials(r)
+	require.NoError(t, err, "failed to get cached credentials")
+	now = now.Add(30 * time.Minute)
+	_, err = p.Credentials(r)
+	require.NoError(t, err, "failed to refresh credentials")
+
+	c, err = p.Credentials(repo.Repository{Name: "public", Namespace: "jx", GitURL: "https://github.com/myorg/public.git"})
+	require.NoError(t, err, "failed to get credentials")
+	assert.Nil(t, c, "should have no credentials")
+
+	_, err = p.Credentials(repo.Repository{Name: "broken", Namespace: "jx", GitURL: "https://github.com/myorg/broken.git"})
+	require.Error(t, err, "should fail when the helper fails")
+	assert.Contains(t, err.Error(), "vault is sealed", "error")
+
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, "requests.log"))
+	require.NoError(t, err, "failed to load requests")
+	requests := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, requests, 4, "requests")
+	assert.Equal(t, `{"apiVersion":"git-operator.jenkins.io/v1alpha1","kind":"CredentialRequest","repository":{"name":"myrepo","namespace":"jx","gitURL":"https://github.com/myorg/myrepo.git","parameters":{"vault-path":"secret/git/myrepo"}}}`, requests[0], "request")
+}
@@ -0,0 +1,166 @@
+package features
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Feature the name of a feature gate such as `Rollback`
+type Feature string
+
+// Maturity the maturity of a feature which determines its default
+type Maturity string
+
+const (
+	// Alpha a new feature which is disabled by default and may change or be removed
+	Alpha Maturity = "ALPHA"
+
+	// Beta a well tested feature which is enabled by default
+	Beta Maturity = "BETA"
+
+	// GA a stable feature which is always enabled and can no longer be disabled
+	GA Maturity = "GA"
+)
+
+const (
+	// Pruning garbage collects the resources which are no longer part of the latest launch of a repository
+	Pruning Feature = "Pruning"
+
+	// DriftRelaunch relaunches the latest commit of a repository when its resources drift from the cluster
+	DriftRelaunch Feature = "DriftRelaunch"
+
+	// BootInventory records the inventory of the resources changed by each boot Job
+	BootInventory Feature = "BootInventory"
+
+	// Rollback launches the last successful commit again when the Job of a newer commit fails
+	Rollback Feature = "Rollback"
+
+	// PreflightScripts runs the optional `preflight.sh` script of a repository before each new commit is launched
+	PreflightScripts Feature = "PreflightScripts"
+
+	// RequireApproval creates the Jobs of every repository suspended until they are approved
+	RequireApproval Feature = "RequireApproval"
+
+	// ValidateResources validates the resources of each new commit via a server side dry run before applying them
+	ValidateResources Feature = "ValidateResources"
+)
+
+// Spec the default and maturity of a feature gate
+type Spec struct {
+	// Default whether the feature is enabled when the gate is not specified
+	Default bool
+
+	// Maturity the maturity of the feature
+	Maturity Maturity
+}
+
+// DefaultSpecs the known feature gates of the operator
+var DefaultSpecs = map[Feature]Spec{
+	Pruning:           {Default: false, Maturity: Alpha},
+	DriftRelaunch:     {Default: false, Maturity: Alpha},
+	BootInventory:     {Default: false, Maturity: Alpha},
+	Rollback:          {Default: false, Maturity: Alpha},
+	PreflightScripts:  {Default: false, Maturity: Alpha},
+	RequireApproval:   {Default: false, Maturity: Alpha},
+	ValidateResources: {Default: false, Maturity: Alpha},
+}
+
+// Gates the registry of the known feature gates along with any gates which have been explicitly specified
+type Gates struct {
+	specs    map[Feature]Spec
+	explicit map[Feature]bool
+}
+
+// NewGates creates the feature gates for the given specs which defaults to DefaultSpecs
+func NewGates(specs map[Feature]Spec) *Gates {
+	if specs == nil {
+		specs = DefaultSpecs
+	}
+	return &Gates{
+		specs:    specs,
+		explicit: map[Feature]bool{},
+	}
+}
+
+// Parse parses the comma separated feature gates such as `Rollback=true,Pruning=false` in the same format as the
+// `--feature-gates` flag of the Kubernetes components
+func Parse(text string, specs map[Feature]Spec) (*Gates, error) {
+	g := NewGates(specs)
+	err := g.Set(text)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Set sets the comma separated feature gates such as `Rollback=true,Pruning=false`. Unknown gates, invalid values
+// and attempts to disable a GA feature fail
+func (g *Gates) Set(text string) error {
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return errors.Errorf("missing bool value for feature gate %s", entry)
+		}
+		f := Feature(strings.TrimSpace(parts[0]))
+		spec, ok := g.specs[f]
+		if !ok {
+			return errors.Errorf("unknown feature gate %s. Known feature gates are: %s", f, strings.Join(g.Known(), ", "))
+		}
+		value, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return errors.Errorf("invalid value %s for feature gate %s", parts[1], f)
+		}
+		if spec.Maturity == GA && !value {
+			return errors.Errorf("cannot disable feature gate %s as it is GA", f)
+		}
+		g.explicit[f] = value
+	}
+	return nil
+}
+
+// Enabled returns true if the feature has been enabled or is enabled by default
+func (g *Gates) Enabled(f Feature) bool {
+	if value, ok := g.explicit[f]; ok {
+		return value
+	}
+	return g.specs[f].Default
+}
+
+// Apply applies the feature gate to the given option of the feature. An explicitly specified gate overrides the
+// option otherwise the option is enabled if the feature is enabled by default
+func (g *Gates) Apply(f Feature, option *bool) {
+	if value, ok := g.explicit[f]; ok {
+		*option = value
+		return
+	}
+	if g.specs[f].Default {
+		*option = true
+	}
+}
+
+// Known returns the sorted descriptions of the known feature gates such as `Rollback=true|false (ALPHA - default=false)`
+func (g *Gates) Known() []string {
+	var answer []string
+	for f, spec := range g.specs {
+		answer = append(answer, string(f)+"=true|false ("+string(spec.Maturity)+" - default="+strconv.FormatBool(spec.Default)+")")
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// String returns the sorted comma separated state of every known feature gate such as `Pruning=false,Rollback=true`
+func (g *Gates) String() string {
+	var answer []string
+	for f := range g.specs {
+		answer = append(answer, string(f)+"="+strconv.FormatBool(g.Enabled(f)))
+	}
+	sort.Strings(answer)
+	return strings.Join(answer, ",")
+}
@@ -0,0 +1,47 @@
+package features_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/features"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureGates(t *testing.T) {
+	specs := map[features.Feature]features.Spec{
+		"AlphaFeature": {Default: false, Maturity: features.Alpha},
+		"BetaFeature":  {Default: true, Maturity: features.Beta},
+		"GAFeature":    {Default: true, Maturity: features.GA},
+	}
+
+	g, err := features.Parse("", specs)
+	require.NoError(t, err, "failed to parse empty feature gates")
+	assert.False(t, g.Enabled("AlphaFeature"), "alpha feature should be disabled by default")
+	assert.True(t, g.Enabled("BetaFeature"), "beta feature should be enabled by default")
+	assert.True(t, g.Enabled("GAFeature"), "GA feature should be enabled by default")
+	assert.Equal(t, "AlphaFeature=false,BetaFeature=true,GAFeature=true", g.String())
+
+	g, err = features.Parse(" AlphaFeature=true, BetaFeature=false,GAFeature=true", specs)
+	require.NoError(t, err, "failed to parse feature gates")
+	assert.True(t, g.Enabled("AlphaFeature"), "alpha feature should be enabled")
+	assert.False(t, g.Enabled("BetaFeature"), "beta feature should be disabled")
+	assert.Equal(t, "AlphaFeature=true,BetaFeature=false,GAFeature=true", g.String())
+
+	option := true
+	g.Apply("BetaFeature", &option)
+	assert.False(t, option, "explicit gate should override the option")
+	option = false
+	g.Apply("GAFeature", &option)
+	assert.True(t, option, "default gate should enable the option")
+
+	for _, text := range []string{"UnknownFeature=true", "AlphaFeature", "AlphaFeature=maybe", "GAFeature=false"} {
+		_, err = features.Parse(text, specs)
+		assert.Error(t, err, "should fail to parse feature gates %s", text)
+	}
+
+	g, err = features.Parse("Rollback=true", nil)
+	require.NoError(t, err, "failed to parse default feature gates")
+	assert.True(t, g.Enabled(features.Rollback), "rollback should be enabled")
+	assert.False(t, g.Enabled(features.Pruning), "pruning should be disabled by default")
+}
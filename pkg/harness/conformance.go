@@ -0,0 +1,183 @@
+package harness
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Conformance runs the conformance tests of the operator against the cluster of the given options so that forks and
+// downstream distributions can verify they still launch, relaunch, retry, clean up and garbage collect repositories in the same way:
+//
+//	func TestConformance(t *testing.T) {
+//		harness.Conformance(t, harness.OptionsFromEnv(t))
+//	}
+func Conformance(t *testing.T, o Options) {
+	e := NewEnvironment(t, o)
+	defer e.Close(t)
+
+	t.Run("launch", func(t *testing.T) {
+		testLaunch(t, e)
+	})
+	t.Run("webhook", func(t *testing.T) {
+		testWebhook(t, e)
+	})
+	t.Run("retry", func(t *testing.T) {
+		testRetry(t, e)
+	})
+	t.Run("cleanup", func(t *testing.T) {
+		testCleanup(t, e)
+	})
+	t.Run("gc", func(t *testing.T) {
+		testGarbageCollect(t, e)
+	})
+}
+
+// testLaunch verifies a new repository is discovered, cloned via the git server and its commit launched
+func testLaunch(t *testing.T, e *Environment) {
+	name := "launch"
+	sha := e.AddRepository(t, name)
+	e.PollNow()
+
+	job := e.WaitForJob(t, name, sha, 1)
+	e.CompleteJob(t, job, true)
+
+	s := e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.LastSucceeded != nil && s.GitSHA == sha
+	}, "should have recorded the successful launch")
+	assert.Equal(t, sha, s.LaunchedSHA, "launched sha")
+}
+
+// testWebhook verifies a push event relayed via the Lighthouse endpoint launches the pushed commit straight away and
+// the trigger endpoint relaunches the latest commit
+func testWebhook(t *testing.T, e *Environment) {
+	name := "webhook"
+	sha := e.AddRepository(t, name)
+	e.PollNow()
+	job := e.WaitForJob(t, name, sha, 1)
+	e.CompleteJob(t, job, true)
+	e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.LastSucceeded != nil
+	}, "should have completed the initial launch")
+
+	// the operator polls rarely so only the push event launches the commit
+	sha2 := e.Push(t, name, map[string]string{"README.md": "pushed\n"})
+	e.NotifyPush(t, name, sha2)
+	job = e.WaitForJob(t, name, sha2, 1)
+	e.CompleteJob(t, job, true)
+	e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.ActiveJob == "" && s.LaunchedSHA == sha2
+	}, "should have completed the pushed commit")
+
+	e.Trigger(t, name)
+	relaunched := e.WaitForJob(t, name, sha2, 2)
+	assert.NotEqual(t, job.Name, relaunched.Name, "the relaunch should create a new Job")
+	e.CompleteJob(t, relaunched, true)
+	assert.Len(t, e.Jobs(t, name, sha2), 1, "the relaunch should replace the Job of the previous attempt")
+}
+
+// testRetry verifies a failed launch is reported as failing until a fixed commit succeeds
+func testRetry(t *testing.T, e *Environment) {
+	name := "retry"
+	sha := e.AddRepository(t, name)
+	e.PollNow()
+	job := e.WaitForJob(t, name, sha, 1)
+	e.CompleteJob(t, job, true)
+
+	failing := e.Push(t, name, map[string]string{JobFilePath: e.JobFile(false)})
+	e.NotifyPush(t, name, failing)
+	job = e.WaitForJob(t, name, failing, 1)
+	e.CompleteJob(t, job, false)
+	s := e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.Retry != nil && s.Retry.State == status.RetryFailing
+	}, "should be failing")
+	assert.Equal(t, 1, s.Retry.ConsecutiveFailures, "consecutive failures")
+
+	// the failed commit is not relaunched until a new commit is pushed
+	e.PollNow()
+	e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.LastPolled.After(job.CreationTimestamp.Time)
+	}, "should have polled again")
+	assert.Len(t, e.Jobs(t, name, failing), 1, "should not relaunch the failed commit")
+
+	fixed := e.Push(t, name, map[string]string{JobFilePath: e.JobFile(true)})
+	e.NotifyPush(t, name, fixed)
+	job = e.WaitForJob(t, name, fixed, 1)
+	e.CompleteJob(t, job, true)
+	s = e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.Retry != nil && s.Retry.State == status.RetryHealthy
+	}, "should be healthy once the fixed commit succeeded")
+	assert.Equal(t, 0, s.Retry.ConsecutiveFailures, "consecutive failures")
+}
+
+// testCleanup verifies the Jobs of a deleted repository are garbage collected before its finalizer is removed
+func testCleanup(t *testing.T, e *Environment) {
+	name := "cleanup"
+	sha := e.AddRepository(t, name)
+	e.PollNow()
+	job := e.WaitForJob(t, name, sha, 1)
+	e.CompleteJob(t, job, true)
+	e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.LastSucceeded != nil
+	}, "should have completed the launch")
+	require.True(t, e.HasFinalizer(t, name, repo.CleanupFinalizer), "should have added the cleanup finalizer")
+
+	e.DeleteRepository(t, name)
+	e.WaitForRepositoryRemoved(t, name)
+	assert.Empty(t, e.Jobs(t, name, ""), "should have deleted the Jobs of the repository")
+	_, ok := e.Operator.Options().Status.Get(e.Namespace, name)
+	assert.False(t, ok, "should have removed the status of the repository")
+}
+
+// testGarbageCollect verifies the resources applied from a repository which is removed without its cleanup finalizer
+// are garbage collected along with their inventory
+func testGarbageCollect(t *testing.T, e *Environment) {
+	name := "gc"
+	configMapName := "gc-applied"
+
+	// garbage collection is skipped if no repositories are found so lets keep another repository
+	e.AddRepository(t, "gc-kept")
+	e.AddRepository(t, name)
+	sha := e.Push(t, name, map[string]string{
+		filepath.Join(ResourcesPath, "namespaces", e.Namespace, "configmap.yaml"): fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+data:
+  applied: "true"
+`, configMapName),
+	})
+	e.PollNow()
+	job := e.WaitForJob(t, name, sha, 1)
+	e.CompleteJob(t, job, true)
+	e.WaitForStatus(t, name, func(s status.Repository) bool {
+		return s.LastSucceeded != nil
+	}, "should have completed the launch")
+
+	configMaps := e.KubeClient.CoreV1().ConfigMaps(e.Namespace)
+	_, err := configMaps.Get(e.InventoryName(name), metav1.GetOptions{})
+	require.NoError(t, err, "should have saved the inventory of the applied resources")
+
+	e.RemoveRepository(t, name)
+	e.WaitForGarbageCollected(t, name)
+	if e.Options.KubeConfig == "" {
+		deleted := false
+		for _, c := range e.KubectlCommands() {
+			if strings.HasPrefix(c, "kubectl delete ConfigMap "+configMapName+" ") {
+				deleted = true
+			}
+		}
+		assert.True(t, deleted, "should have deleted ConfigMap %s via kubectl in %v", configMapName, e.KubectlCommands())
+		return
+	}
+	_, err = configMaps.Get(configMapName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "should have deleted ConfigMap %s but got %v", configMapName, err)
+}
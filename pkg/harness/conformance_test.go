@@ -0,0 +1,29 @@
+package harness_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/harness"
+)
+
+// TestConformance runs the conformance tests against the in memory cluster or the cluster of the
+// `$INTEGRATION_KUBECONFIG` such as via `make test-integration`
+func TestConformance(t *testing.T) {
+	o := harness.OptionsFromEnv(t)
+	if o.GitBinary == "" {
+		o.GitBinary = "git"
+	}
+	execPath, err := exec.Command(o.GitBinary, "--exec-path").Output()
+	if err != nil {
+		t.Skipf("skipping as git is not available: %s", err.Error())
+	}
+	_, err = os.Stat(filepath.Join(strings.TrimSpace(string(execPath)), "git-http-backend"))
+	if err != nil {
+		t.Skipf("skipping as git http-backend is not available: %s", err.Error())
+	}
+	harness.Conformance(t, o)
+}
@@ -0,0 +1,588 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/lighthouse"
+	"github.com/jenkins-x/jx-git-operator/pkg/operator"
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-git-operator/pkg/trigger"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/pkg/errors"
+	"github.com/sethvargo/go-envconfig/pkg/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// DefaultImage the default image of the containers of the Jobs of the repositories
+	DefaultImage = "busybox"
+
+	// DefaultTimeout the default time to wait for the operator against an in memory cluster
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultClusterTimeout the default time to wait for the operator against a real cluster which has to pull the
+	// image and run the pods of each Job
+	DefaultClusterTimeout = 3 * time.Minute
+
+	// JobFilePath the path of the Job file of the repositories
+	JobFilePath = ".jx/git-operator/job.yaml"
+
+	// ResourcesPath the path of the directory of the resources applied from the repositories
+	ResourcesPath = ".jx/git-operator/resources"
+
+	// fakeNamespace the namespace of the repositories in an in memory cluster
+	fakeNamespace = "jx"
+)
+
+// Options the options of an integration test environment. They can be populated from environment variables via
+// OptionsFromEnv()
+type Options struct {
+	// KubeConfig the kubeconfig file of the cluster such as a kind or k3d cluster. If not specified an in memory fake
+	// cluster is used
+	KubeConfig string `env:"INTEGRATION_KUBECONFIG"`
+
+	// SimulateJobs if enabled the environment completes the Jobs by updating their status rather than waiting for
+	// the pods to run. This is required for clusters without a Job controller and is always enabled for the in
+	// memory cluster
+	SimulateJobs bool `env:"INTEGRATION_SIMULATE_JOBS"`
+
+	// Image the image of the containers of the Jobs. Defaults to `busybox`
+	Image string `env:"INTEGRATION_IMAGE"`
+
+	// Timeout how long to wait for the operator to launch or clean up the Jobs of a repository. Defaults to 10
+	// seconds for the in memory cluster or 3 minutes for a real cluster
+	Timeout time.Duration `env:"INTEGRATION_TIMEOUT"`
+
+	// GitBinary the git binary used by the git server and the operator. Defaults to `git`
+	GitBinary string `env:"INTEGRATION_GIT_BINARY"`
+
+	// Configure the optional function to customize the options of the operator before it is started, such as to
+	// enable the features of a fork
+	Configure func(o *operator.Options)
+}
+
+// OptionsFromEnv returns the options populated from the `$INTEGRATION_*` environment variables
+func OptionsFromEnv(t testing.TB) Options {
+	o := Options{}
+	err := envconfig.Process(context.Background(), &o)
+	require.NoError(t, err, "failed to process the integration test environment variables")
+	return o
+}
+
+// Environment runs the operator in process against a real or in memory cluster with a local git server. The
+// operator polls rarely so that launches are driven by the webhooks and triggers of the tests, via PollNow() or
+// by the helper functions which wait for a result.
+//
+// Each environment uses its own namespace which is removed from a real cluster when the environment is closed
+type Environment struct {
+	// Options the options of the environment
+	Options Options
+
+	// KubeClient the client of the cluster
+	KubeClient kubernetes.Interface
+
+	// Namespace the namespace of the repository Secrets and Jobs
+	Namespace string
+
+	// Dir the directory of the git repositories and the work directory of the operator
+	Dir string
+
+	// Git the git server of the repositories
+	Git *GitServer
+
+	// Operator the operator under test
+	Operator *operator.Operator
+
+	// URL the base URL of the HTTP endpoints of the operator
+	URL string
+
+	// TriggerToken the bearer token of the trigger endpoint
+	TriggerToken string
+
+	// HMACToken the HMAC token of the push events relayed to the Lighthouse endpoint
+	HMACToken string
+
+	server           *httptest.Server
+	cancel           context.CancelFunc
+	done             chan error
+	createdNamespace bool
+	lock             sync.Mutex
+	kubectlCommands  []string
+}
+
+// NewEnvironment creates a namespace, starts the git server and runs the operator in the background. The environment
+// must be closed via Close() once the test completes
+func NewEnvironment(t testing.TB, o Options) *Environment {
+	if o.Image == "" {
+		o.Image = DefaultImage
+	}
+	if o.KubeConfig == "" {
+		o.SimulateJobs = true
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+		if o.KubeConfig != "" {
+			o.Timeout = DefaultClusterTimeout
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "jx-git-operator-integration-")
+	require.NoError(t, err, "failed to create temp dir")
+
+	e := &Environment{
+		Options:      o,
+		Dir:          dir,
+		TriggerToken: "integration-trigger-token",
+		HMACToken:    "integration-hmac-token",
+	}
+	if o.KubeConfig == "" {
+		e.KubeClient = fake.NewSimpleClientset()
+		e.Namespace = fakeNamespace
+	} else {
+		cfg, err := clientcmd.BuildConfigFromFlags("", o.KubeConfig)
+		require.NoError(t, err, "failed to load kubeconfig %s", o.KubeConfig)
+		e.KubeClient, err = kubernetes.NewForConfig(cfg)
+		require.NoError(t, err, "failed to create the kube client for kubeconfig %s", o.KubeConfig)
+
+		ns, err := e.KubeClient.CoreV1().Namespaces().Create(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "jx-git-operator-it-",
+			},
+		})
+		require.NoError(t, err, "failed to create the namespace of the integration test")
+		e.Namespace = ns.Name
+		e.createdNamespace = true
+	}
+
+	e.Git, err = NewGitServer(filepath.Join(dir, "git"), o.GitBinary)
+	require.NoError(t, err, "failed to start the git server")
+
+	oo := operator.Options{
+		Options: poller.Options{
+			KubeClient:      e.KubeClient,
+			Namespace:       e.Namespace,
+			Dir:             filepath.Join(dir, "operator"),
+			GitBinary:       o.GitBinary,
+			PollDuration:    time.Hour,
+			CleanupOnDelete: true,
+			GarbageCollect:  true,
+		},
+		TriggerToken:        e.TriggerToken,
+		TriggerMinInterval:  time.Millisecond,
+		LighthouseHMACToken: e.HMACToken,
+	}
+	if o.KubeConfig == "" {
+		// there is no API server for kubectl so lets record its commands instead
+		oo.CommandRunner = e.runCommand
+	} else {
+		oo.Commands.Env = map[string]string{"KUBECONFIG": o.KubeConfig}
+
+		// lets only garbage collect the inventories of this environment as the cluster may be shared
+		oo.OperatorID = e.Namespace
+	}
+	if o.Configure != nil {
+		o.Configure(&oo)
+	}
+	e.Operator, err = operator.New(oo)
+	require.NoError(t, err, "failed to create the operator")
+
+	e.server = httptest.NewServer(e.Operator.Handler())
+	e.URL = e.server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan error, 1)
+	go func() {
+		e.done <- e.Operator.Run(ctx)
+	}()
+	return e
+}
+
+// Close stops the operator and the servers and removes the namespace and directory of the environment, failing the
+// test if the operator failed
+func (e *Environment) Close(t testing.TB) {
+	e.cancel()
+	select {
+	case err := <-e.done:
+		assert.NoError(t, err, "the operator failed")
+	case <-time.After(e.Options.Timeout):
+		assert.Fail(t, "the operator did not stop", "waited %s", e.Options.Timeout.String())
+	}
+	e.server.Close()
+	e.Git.Close()
+
+	if e.createdNamespace {
+		err := e.KubeClient.CoreV1().Namespaces().Delete(e.Namespace, nil)
+		if err != nil && !apierrors.IsNotFound(err) {
+			t.Logf("failed to delete namespace %s: %s", e.Namespace, err.Error())
+		}
+	}
+	err := os.RemoveAll(e.Dir)
+	if err != nil {
+		t.Logf("failed to remove dir %s: %s", e.Dir, err.Error())
+	}
+}
+
+// runCommand records the kubectl commands of the operator against the in memory cluster and runs any other command
+func (e *Environment) runCommand(c *cmdrunner.Command) (string, error) {
+	if c.Name != "kubectl" {
+		return runner.Exec(c)
+	}
+	e.lock.Lock()
+	e.kubectlCommands = append(e.kubectlCommands, c.CLI())
+	e.lock.Unlock()
+	return "", nil
+}
+
+// KubectlCommands returns the kubectl commands recorded against the in memory cluster
+func (e *Environment) KubectlCommands() []string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]string{}, e.kubectlCommands...)
+}
+
+// JobFile returns the Job file of a repository whose container succeeds or fails
+func (e *Environment) JobFile(succeed bool) string {
+	command := "true"
+	if !succeed {
+		command = "false"
+	}
+	return fmt.Sprintf(`apiVersion: batch/v1
+kind: Job
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: boot
+        image: %s
+        command: ["%s"]
+`, e.Options.Image, command)
+}
+
+// AddRepository creates the git repository with a Job file which succeeds and registers it via a labelled Secret
+// returning the sha of its initial commit
+func (e *Environment) AddRepository(t testing.TB, name string) string {
+	sha, err := e.Git.CreateRepository(name, map[string]string{JobFilePath: e.JobFile(true)})
+	require.NoError(t, err, "failed to create git repository %s", name)
+
+	_, err = e.KubeClient.CoreV1().Secrets(e.Namespace).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: e.Namespace,
+			Labels: map[string]string{
+				constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+			},
+		},
+		Data: map[string][]byte{
+			"url": []byte(e.Git.GitURL(name)),
+		},
+	})
+	require.NoError(t, err, "failed to create the Secret of repository %s", name)
+	return sha
+}
+
+// Push pushes a commit of the given files to the repository returning its sha. The operator is not notified
+func (e *Environment) Push(t testing.TB, name string, fileContents map[string]string) string {
+	sha, err := e.Git.Push(name, fileContents)
+	require.NoError(t, err, "failed to push to git repository %s", name)
+	return sha
+}
+
+// NotifyPush sends the signed push event of the commit of the repository to the Lighthouse webhook endpoint
+func (e *Environment) NotifyPush(t testing.TB, name string, gitSHA string) {
+	payload, err := json.Marshal(&lighthouse.PushEvent{
+		Ref:   "refs/heads/" + Branch,
+		After: gitSHA,
+		Repository: lighthouse.EventRepository{
+			CloneURL: e.Git.GitURL(name),
+		},
+	})
+	require.NoError(t, err, "failed to marshal the push event of repository %s", name)
+
+	req, err := http.NewRequest(http.MethodPost, e.URL+lighthouse.Path, bytes.NewReader(payload))
+	require.NoError(t, err, "failed to create the push event request")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Hub-Signature-256", signature.Sign([]byte(e.HMACToken), payload))
+	e.send(t, req, http.StatusAccepted)
+}
+
+// Trigger requests the relaunch of the latest commit of the repository via the trigger endpoint
+func (e *Environment) Trigger(t testing.TB, name string) {
+	payload, err := json.Marshal(&trigger.Request{
+		Source: "integration-test",
+		Reason: "conformance",
+	})
+	require.NoError(t, err, "failed to marshal the trigger request")
+
+	req, err := http.NewRequest(http.MethodPost, e.URL+trigger.Path+e.Namespace+"/"+name, bytes.NewReader(payload))
+	require.NoError(t, err, "failed to create the trigger request")
+	req.Header.Set("Authorization", "Bearer "+e.TriggerToken)
+	e.send(t, req, http.StatusAccepted)
+}
+
+// send sends the request to the operator requiring the given status code
+func (e *Environment) send(t testing.TB, req *http.Request, expectedStatus int) {
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "failed to send %s %s", req.Method, req.URL.String())
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(t, expectedStatus, resp.StatusCode, "status of %s %s: %s", req.Method, req.URL.String(), strings.TrimSpace(string(body)))
+}
+
+// PollNow wakes up the operator so that the repositories are polled straight away
+func (e *Environment) PollNow() {
+	e.Operator.Options().PollNow()
+}
+
+// Jobs returns the Jobs of the repository and the optional commit sha with the oldest first
+func (e *Environment) Jobs(t testing.TB, name string, gitSHA string) []v1.Job {
+	jobs, err := e.listJobs(name, gitSHA)
+	require.NoError(t, err, "failed to list the Jobs of repository %s", name)
+	return jobs
+}
+
+// listJobs returns the Jobs of the repository and the optional commit sha with the oldest first
+func (e *Environment) listJobs(name string, gitSHA string) ([]v1.Job, error) {
+	selector := launcher.RepositoryLabelKey + "=" + name
+	if gitSHA != "" {
+		selector += "," + launcher.CommitShaLabelKey + "=" + gitSHA
+	}
+	list, err := e.KubeClient.BatchV1().Jobs(e.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the Jobs in namespace %s with selector %s", e.Namespace, selector)
+	}
+	jobs := list.Items
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreationTimestamp.Before(&jobs[j].CreationTimestamp)
+	})
+	return jobs, nil
+}
+
+// WaitForJob waits for the operator to launch the given attempt of the commit sha of the repository. Relaunching
+// a commit replaces the Jobs of its previous attempt
+func (e *Environment) WaitForJob(t testing.TB, name string, gitSHA string, attempt int) v1.Job {
+	var answer v1.Job
+	require.Eventually(t, func() bool {
+		jobs, err := e.listJobs(name, gitSHA)
+		if err != nil {
+			t.Logf("%s", err.Error())
+			return false
+		}
+		for _, j := range jobs {
+			a, err := strconv.Atoi(j.Annotations[launcher.AttemptAnnotation])
+			if err != nil || a < 1 {
+				a = 1
+			}
+			if a == attempt {
+				answer = j
+				return true
+			}
+		}
+		return false
+	}, e.Options.Timeout, e.interval(), "should have launched attempt %d of repository %s sha %s", attempt, name, gitSHA)
+	return answer
+}
+
+// CompleteJob completes the Job with the given result. If Jobs are simulated its status is updated, otherwise it
+// waits for the pods of the Job to complete requiring the expected result
+func (e *Environment) CompleteJob(t testing.TB, job v1.Job, succeeded bool) {
+	jobs := e.KubeClient.BatchV1().Jobs(e.Namespace)
+	if !e.Options.SimulateJobs {
+		require.Eventually(t, func() bool {
+			j, err := jobs.Get(job.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Logf("failed to get Job %s: %s", job.Name, err.Error())
+				return false
+			}
+			job = *j
+			return j.Status.Succeeded > 0 || j.Status.Failed > 0
+		}, e.Options.Timeout, e.interval(), "Job %s should have completed", job.Name)
+		assert.Equal(t, succeeded, job.Status.Succeeded > 0, "whether Job %s succeeded", job.Name)
+		return
+	}
+
+	// lets wait for the operator to record the Job as launched as simulated Jobs complete sooner than any real Job
+	require.Eventually(t, func() bool {
+		for _, s := range e.Operator.Options().Status.List() {
+			for _, w := range s.Jobs {
+				if w.Name == job.Name && w.Namespace == job.Namespace {
+					return true
+				}
+			}
+		}
+		return false
+	}, e.Options.Timeout, e.interval(), "the operator should have recorded the launch of Job %s", job.Name)
+
+	j, err := jobs.Get(job.Name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get Job %s", job.Name)
+
+	now := metav1.Now()
+	if j.Status.StartTime == nil {
+		j.Status.StartTime = &now
+	}
+	condition := v1.JobFailed
+	if succeeded {
+		condition = v1.JobComplete
+		j.Status.Succeeded = 1
+		j.Status.CompletionTime = &now
+	} else {
+		j.Status.Failed = 1
+	}
+	j.Status.Active = 0
+	j.Status.Conditions = append(j.Status.Conditions, v1.JobCondition{
+		Type:               condition,
+		Status:             corev1.ConditionTrue,
+		LastProbeTime:      now,
+		LastTransitionTime: now,
+	})
+	_, err = jobs.UpdateStatus(j)
+	require.NoError(t, err, "failed to update the status of Job %s", job.Name)
+}
+
+// WaitForStatus polls the repository until its status matches the given function
+func (e *Environment) WaitForStatus(t testing.TB, name string, fn func(s status.Repository) bool, message string) status.Repository {
+	var answer status.Repository
+	require.Eventually(t, func() bool {
+		s, ok := e.Operator.Options().Status.Get(e.Namespace, name)
+		if ok && fn(s) {
+			answer = s
+			return true
+		}
+		e.PollNow()
+		return false
+	}, e.Options.Timeout, e.interval(), "status of repository %s: %s", name, message)
+	return answer
+}
+
+// DeleteRepository deletes the Secret of the repository. The in memory cluster does not support finalizers so the
+// deletion is simulated by setting the deletion timestamp until the finalizers are removed
+func (e *Environment) DeleteRepository(t testing.TB, name string) {
+	secrets := e.KubeClient.CoreV1().Secrets(e.Namespace)
+	if e.Options.KubeConfig != "" {
+		err := secrets.Delete(name, nil)
+		require.NoError(t, err, "failed to delete the Secret of repository %s", name)
+		return
+	}
+	secret, err := secrets.Get(name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the Secret of repository %s", name)
+
+	now := metav1.Now()
+	secret.DeletionTimestamp = &now
+	_, err = secrets.Update(secret)
+	require.NoError(t, err, "failed to mark the Secret of repository %s as deleted", name)
+}
+
+// WaitForRepositoryRemoved polls until the Secret of a deleted repository has been removed once the operator has
+// cleaned up its resources and removed its finalizer
+func (e *Environment) WaitForRepositoryRemoved(t testing.TB, name string) {
+	secrets := e.KubeClient.CoreV1().Secrets(e.Namespace)
+	require.Eventually(t, func() bool {
+		secret, err := secrets.Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true
+		}
+		if err != nil {
+			t.Logf("failed to get the Secret of repository %s: %s", name, err.Error())
+			return false
+		}
+		if secret.DeletionTimestamp != nil && len(secret.Finalizers) == 0 {
+			// lets complete the simulated deletion now the finalizers are removed
+			err = secrets.Delete(name, nil)
+			if err != nil && !apierrors.IsNotFound(err) {
+				t.Logf("failed to delete the Secret of repository %s: %s", name, err.Error())
+				return false
+			}
+			return true
+		}
+		e.PollNow()
+		return false
+	}, e.Options.Timeout, e.interval(), "repository %s should have been removed", name)
+}
+
+// RemoveRepository removes the Secret of the repository without letting the operator clean up its resources, as if
+// the finalizer had been removed by hand, so that its applied resources are left to be garbage collected
+func (e *Environment) RemoveRepository(t testing.TB, name string) {
+	secrets := e.KubeClient.CoreV1().Secrets(e.Namespace)
+	secret, err := secrets.Get(name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the Secret of repository %s", name)
+	if len(secret.Finalizers) > 0 {
+		secret.Finalizers = nil
+		_, err = secrets.Update(secret)
+		require.NoError(t, err, "failed to remove the finalizers of the Secret of repository %s", name)
+	}
+	err = secrets.Delete(name, nil)
+	require.NoError(t, err, "failed to delete the Secret of repository %s", name)
+}
+
+// InventoryName returns the name of the ConfigMap of the inventory of the resources applied from the repository
+func (e *Environment) InventoryName(name string) string {
+	return inventory.ConfigMapName(e.Operator.Options().OperatorID, name)
+}
+
+// WaitForGarbageCollected polls until the inventory of the resources applied from a removed repository has been
+// deleted once the operator has garbage collected its resources
+func (e *Environment) WaitForGarbageCollected(t testing.TB, name string) {
+	configMaps := e.KubeClient.CoreV1().ConfigMaps(e.Namespace)
+	inventoryName := e.InventoryName(name)
+	require.Eventually(t, func() bool {
+		_, err := configMaps.Get(inventoryName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true
+		}
+		if err != nil {
+			t.Logf("failed to get the inventory ConfigMap %s: %s", inventoryName, err.Error())
+		}
+		e.PollNow()
+		return false
+	}, e.Options.Timeout, e.interval(), "the resources of repository %s should have been garbage collected", name)
+}
+
+// HasFinalizer returns true if the Secret of the repository has the given finalizer
+func (e *Environment) HasFinalizer(t testing.TB, name string, finalizer string) bool {
+	secret, err := e.KubeClient.CoreV1().Secrets(e.Namespace).Get(name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the Secret of repository %s", name)
+	return stringhelpers.StringArrayIndex(secret.Finalizers, finalizer) >= 0
+}
+
+// interval the interval between the checks of the conditions waited for
+func (e *Environment) interval() time.Duration {
+	if e.Options.KubeConfig == "" {
+		return 20 * time.Millisecond
+	}
+	return time.Second
+}
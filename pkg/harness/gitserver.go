@@ -0,0 +1,160 @@
+package harness
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/pkg/errors"
+)
+
+// Branch the branch of the repositories served by the git server
+const Branch = "master"
+
+// GitServer a local git server which serves bare git repositories over the smart HTTP protocol via
+// `git http-backend` so that the operator clones and fetches them in the same way as a real git provider
+type GitServer struct {
+	// Dir the directory of the bare repositories and their working copies
+	Dir string
+
+	// URL the base URL of the server. Repositories are served at `<URL>/<name>.git`
+	URL string
+
+	gitBinary string
+	server    *httptest.Server
+	lock      sync.Mutex
+	commits   map[string]int
+}
+
+// NewGitServer starts a git server serving the repositories created in the given directory using the git binary,
+// which defaults to `git`
+func NewGitServer(dir string, gitBinary string) (*GitServer, error) {
+	if gitBinary == "" {
+		gitBinary = "git"
+	}
+	path, err := exec.LookPath(gitBinary)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the git binary %s", gitBinary)
+	}
+	remotesDir := filepath.Join(dir, "remotes")
+	err = os.MkdirAll(remotesDir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create dir %s", remotesDir)
+	}
+	s := &GitServer{
+		Dir:       dir,
+		gitBinary: path,
+		commits:   map[string]int{},
+	}
+	s.server = httptest.NewServer(&cgi.Handler{
+		Path: path,
+		Args: []string{"http-backend"},
+		Env: []string{
+			"GIT_PROJECT_ROOT=" + remotesDir,
+			"GIT_HTTP_EXPORT_ALL=1",
+		},
+	})
+	s.URL = s.server.URL
+	return s, nil
+}
+
+// Close stops the server
+func (s *GitServer) Close() {
+	s.server.Close()
+}
+
+// GitURL returns the clone URL of the repository
+func (s *GitServer) GitURL(name string) string {
+	return s.URL + "/" + name + ".git"
+}
+
+// CreateRepository creates the bare repository and pushes an initial commit of the given files returning its sha
+func (s *GitServer) CreateRepository(name string, fileContents map[string]string) (string, error) {
+	bareDir := filepath.Join(s.Dir, "remotes", name+".git")
+	workDir := filepath.Join(s.Dir, "work", name)
+	err := os.MkdirAll(workDir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create dir %s", workDir)
+	}
+	for _, args := range [][]string{
+		{"init", "--bare", bareDir},
+		{"-C", bareDir, "symbolic-ref", "HEAD", "refs/heads/" + Branch},
+		{"init", workDir},
+		{"-C", workDir, "remote", "add", "origin", bareDir},
+	} {
+		_, err = s.git(s.Dir, args...)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to create repository %s", name)
+		}
+	}
+	return s.Push(name, fileContents)
+}
+
+// Push commits the given files, keyed by their path relative to the root of the repository, and pushes the commit
+// to the branch of the repository returning its sha. A file with empty contents is removed
+func (s *GitServer) Push(name string, fileContents map[string]string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	workDir := filepath.Join(s.Dir, "work", name)
+	for path, contents := range fileContents {
+		fileName := filepath.Join(workDir, filepath.FromSlash(path))
+		if contents == "" {
+			err := os.RemoveAll(fileName)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to remove file %s", fileName)
+			}
+			continue
+		}
+		err := os.MkdirAll(filepath.Dir(fileName), files.DefaultDirWritePermissions)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to create dir for %s", fileName)
+		}
+		err = ioutil.WriteFile(fileName, []byte(contents), files.DefaultFileWritePermissions)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to write file %s", fileName)
+		}
+	}
+
+	// lets make sure every push creates a new commit even if the files did not change
+	s.commits[name]++
+	commit := s.commits[name]
+	err := ioutil.WriteFile(filepath.Join(workDir, "commit.txt"), []byte(fmt.Sprintf("%d\n", commit)), files.DefaultFileWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to write a change to repository %s", name)
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=integration", "-c", "user.email=integration@example.com", "commit", "-m", fmt.Sprintf("commit %d of %s", commit, name)},
+		{"push", "origin", "HEAD:refs/heads/" + Branch},
+	} {
+		_, err = s.git(workDir, args...)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to push commit %d of repository %s", commit, name)
+		}
+	}
+	sha, err := s.git(workDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find the commit sha of repository %s", name)
+	}
+	return sha, nil
+}
+
+// git runs the git binary in the given directory returning its trimmed output
+func (s *GitServer) git(dir string, args ...string) (string, error) {
+	cmd := exec.Command(s.gitBinary, args...)
+	cmd.Dir = dir
+	data, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(data))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run git %s: %s", strings.Join(args, " "), output)
+	}
+	return output, nil
+}
@@ -0,0 +1,221 @@
+package harness
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Harness a test harness for running the poll and launch pipeline against fake kubernetes clients and git commands
+type Harness struct {
+	// Namespace the namespace of the repository Secrets and Jobs
+	Namespace string
+
+	// Dir the work directory containing the fake git clones
+	Dir string
+
+	// KubeClient the fake kubernetes client
+	KubeClient *fake.Clientset
+
+	// Runner the fake command runner used for git and kubectl commands
+	Runner *fakerunner.FakeRunner
+
+	// Poller the poller options. Any fields can be modified before calling Poll()
+	Poller *poller.Options
+
+	lock         sync.Mutex
+	gitSHAs      map[string]string
+	changedFiles map[string][]string
+	launcher     launcher.Interface
+}
+
+// NewHarness creates a new test harness in the given namespace with the optional kubernetes resources.
+//
+// If the launcher is nil the default `Job` launcher is used with the fake kubernetes client
+func NewHarness(t testing.TB, ns string, l launcher.Interface, objects ...runtime.Object) *Harness {
+	dir, err := ioutil.TempDir("", "test-jx-git-operator-")
+	require.NoError(t, err, "failed to create temp dir")
+
+	h := &Harness{
+		Namespace:    ns,
+		Dir:          dir,
+		KubeClient:   fake.NewSimpleClientset(objects...),
+		gitSHAs:      map[string]string{},
+		changedFiles: map[string][]string{},
+	}
+	h.Runner = &fakerunner.FakeRunner{
+		CommandRunner: h.runCommand,
+	}
+	h.launcher = l
+	h.Restart()
+	return h
+}
+
+// Restart simulates restarting the operator by replacing the poller with a new one which has no in memory state
+func (h *Harness) Restart() {
+	h.Poller = &poller.Options{
+		CommandRunner: h.Runner.Run,
+		KubeClient:    h.KubeClient,
+		Launcher:      h.launcher,
+		Dir:           h.Dir,
+		Namespace:     h.Namespace,
+		NoLoop:        true,
+	}
+}
+
+// AddRepository creates the repository Secret and a fake git clone by copying the given source directory
+func (h *Harness) AddRepository(t testing.TB, name string, gitURL string, sourceDir string, gitSHA string) {
+	err := files.CopyDirOverwrite(sourceDir, h.CloneDir(name))
+	require.NoError(t, err, "failed to copy git clone data from %s to temp dir", sourceDir)
+
+	_, err = h.KubeClient.CoreV1().Secrets(h.Namespace).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: h.Namespace,
+			Labels: map[string]string{
+				constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+			},
+		},
+		Data: map[string][]byte{
+			"url": []byte(gitURL),
+		},
+	})
+	require.NoError(t, err, "failed to create Secret for repository %s", name)
+
+	h.SetGitSHA(name, gitSHA)
+}
+
+// CloneDir returns the directory of the fake git clone of the given repository using the work directory layout of
+// the poller
+func (h *Harness) CloneDir(name string) string {
+	if h.Poller.WorkDirLayout == poller.LayoutNamespaced {
+		return filepath.Join(h.Dir, h.Namespace, name, ".clone")
+	}
+	return filepath.Join(h.Dir, name)
+}
+
+// LabelRepository adds the given label to the repository Secret
+func (h *Harness) LabelRepository(t testing.TB, name string, key string, value string) {
+	secretInterface := h.KubeClient.CoreV1().Secrets(h.Namespace)
+	secret, err := secretInterface.Get(name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get Secret for repository %s", name)
+
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[key] = value
+	_, err = secretInterface.Update(secret)
+	require.NoError(t, err, "failed to update Secret for repository %s", name)
+}
+
+// AnnotateRepository adds the given annotation to the repository Secret
+func (h *Harness) AnnotateRepository(t testing.TB, name string, key string, value string) {
+	secretInterface := h.KubeClient.CoreV1().Secrets(h.Namespace)
+	secret, err := secretInterface.Get(name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get Secret for repository %s", name)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[key] = value
+	_, err = secretInterface.Update(secret)
+	require.NoError(t, err, "failed to update Secret for repository %s", name)
+}
+
+// SetGitSHA simulates a new git commit in the given repository
+func (h *Harness) SetGitSHA(name string, gitSHA string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.gitSHAs[name] = gitSHA
+}
+
+// SetChangedFiles simulates the paths changed by the latest git commits of the given repository which are returned
+// by `git diff --name-only`
+func (h *Harness) SetChangedFiles(name string, paths ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.changedFiles[name] = paths
+}
+
+// Poll performs a single poll of all the repositories
+func (h *Harness) Poll(t testing.TB) {
+	err := h.Poller.Run()
+	require.NoError(t, err, "failed to run poller")
+}
+
+// SetJobSucceeded marks the Jobs for the given repository and sha as succeeded
+func (h *Harness) SetJobSucceeded(t testing.TB, name string, gitSHA string) {
+	for _, j := range h.JobsForRepositoryAndSha(t, name, gitSHA) {
+		j.Status.Succeeded = 1
+		j.Status.Conditions = append(j.Status.Conditions, v1.JobCondition{Type: v1.JobComplete, Status: corev1.ConditionTrue})
+		_, err := h.KubeClient.BatchV1().Jobs(h.Namespace).Update(&j)
+		require.NoError(t, err, "failed to update the job %s in namespace %s to succeeded", j.Name, h.Namespace)
+	}
+}
+
+// JobsForRepositoryAndSha returns the Jobs for the given repository and git sha
+func (h *Harness) JobsForRepositoryAndSha(t testing.TB, name string, gitSHA string) []v1.Job {
+	selector := constants.DefaultSelectorKey
+	jobs, err := h.KubeClient.BatchV1().Jobs(h.Namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	require.NoError(t, err, "failed to list jobs in namespace %s with selector %s", h.Namespace, selector)
+
+	var answer []v1.Job
+	for _, j := range jobs.Items {
+		labels := j.Labels
+		if labels != nil && labels[launcher.RepositoryLabelKey] == name && labels[launcher.CommitShaLabelKey] == gitSHA {
+			answer = append(answer, j)
+		}
+	}
+	return answer
+}
+
+// AssertJobCount asserts the number of Jobs for the given repository and git sha
+func (h *Harness) AssertJobCount(t testing.TB, name string, gitSHA string, expectedCount int) {
+	jobs := h.JobsForRepositoryAndSha(t, name, gitSHA)
+	assert.Len(t, jobs, expectedCount, "number of Jobs in namespace %s for repository %s and git sha %s", h.Namespace, name, gitSHA)
+}
+
+// runCommand fakes the git commands so that `rev-parse` returns the current sha of the repository, `diff` returns
+// its changed paths and `worktree add` copies the fake git clone
+func (h *Harness) runCommand(c *cmdrunner.Command) (string, error) {
+	if c.Name != "git" || len(c.Args) == 0 {
+		return "", nil
+	}
+	name := filepath.Base(c.Dir)
+	if name == ".clone" {
+		name = filepath.Base(filepath.Dir(c.Dir))
+	}
+	switch c.Args[0] {
+	case "rev-parse":
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		return h.gitSHAs[name], nil
+	case "diff":
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		return strings.Join(h.changedFiles[name], "\n"), nil
+	case "worktree":
+		if len(c.Args) == 5 && c.Args[1] == "add" {
+			return "", files.CopyDirOverwrite(c.Dir, c.Args[3])
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,48 @@
+package harness_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/harness"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/fake"
+)
+
+func TestHarness(t *testing.T) {
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	sourceDir := filepath.Join("..", "poller", "test_data", repoName)
+
+	h := harness.NewHarness(t, "jx", nil)
+	h.AddRepository(t, repoName, gitURL, sourceDir, "sha1")
+
+	h.Poll(t)
+	h.AssertJobCount(t, repoName, "sha1", 1)
+
+	h.SetGitSHA(repoName, "sha2")
+	h.Poll(t)
+	h.AssertJobCount(t, repoName, "sha2", 0)
+
+	h.SetJobSucceeded(t, repoName, "sha1")
+	h.Poll(t)
+	h.AssertJobCount(t, repoName, "sha2", 1)
+}
+
+func TestHarnessWithFakeLauncher(t *testing.T) {
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	sourceDir := filepath.Join("..", "poller", "test_data", repoName)
+
+	f := &fake.Launcher{}
+	h := harness.NewHarness(t, "jx", f)
+	h.AddRepository(t, repoName, gitURL, sourceDir, "sha1")
+
+	h.Poll(t)
+	h.SetGitSHA(repoName, "sha2")
+	h.Poll(t)
+
+	f.ExpectLaunches(t,
+		fake.ExpectedLaunch{Name: repoName, GitSHA: "sha1"},
+		fake.ExpectedLaunch{Name: repoName, GitSHA: "sha2"},
+	)
+}
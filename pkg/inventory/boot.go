@@ -0,0 +1,59 @@
+package inventory
+
+import (
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// BootEnvVar the environment variable injected into the containers of each boot Job containing the name of the
+	// ConfigMap the Job can write the inventory of the resources it changed to
+	BootEnvVar = "GIT_OPERATOR_INVENTORY"
+
+	// BootAnnotation the annotation on a boot Job which the Job can set to the YAML inventory of the resources it
+	// changed as an alternative to writing the inventory ConfigMap
+	BootAnnotation = "git-operator.jenkins.io/inventory"
+
+	// bootConfigMapPrefix the prefix of the names of the inventory ConfigMaps written by boot Jobs
+	bootConfigMapPrefix = "jx-git-operator-boot-"
+)
+
+// BootConfigMapName returns the name of the ConfigMap the given boot Job can write its inventory to
+func BootConfigMapName(jobName string) string {
+	return bootConfigMapPrefix + jobName
+}
+
+// LoadBoot loads the inventory of the resources changed by the given boot Job from either the ConfigMap named by
+// BootConfigMapName or the BootAnnotation on the Job. Either form contains an Inventory in YAML in which only the
+// entries are required. Returns nil if the Job did not write an inventory
+func LoadBoot(kubeClient kubernetes.Interface, ns string, jobName string) (*Inventory, error) {
+	text := ""
+	name := BootConfigMapName(jobName)
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get boot inventory ConfigMap %s in namespace %s", name, ns)
+	}
+	if err == nil {
+		text = cm.Data[DataKey]
+	} else {
+		j, err := kubeClient.BatchV1().Jobs(ns).Get(jobName, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Job %s in namespace %s", jobName, ns)
+		}
+		if err == nil {
+			text = j.Annotations[BootAnnotation]
+		}
+	}
+	if text == "" {
+		return nil, nil
+	}
+	inv := &Inventory{}
+	err = yaml.Unmarshal([]byte(text), inv)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the inventory of boot Job %s in namespace %s", jobName, ns)
+	}
+	return inv, nil
+}
@@ -0,0 +1,299 @@
+package inventory
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KindLabelValue the value of the `git-operator.jenkins.io/kind` label of inventory ConfigMaps
+	KindLabelValue = "inventory"
+
+	// Selector the label selector of the inventory ConfigMaps
+	Selector = constants.DefaultSelectorKey + "=" + KindLabelValue
+
+	// DataKey the key in the inventory ConfigMap containing the inventory
+	DataKey = "inventory.yaml"
+
+	// configMapPrefix the prefix of the names of inventory ConfigMaps
+	configMapPrefix = "jx-git-operator-inventory-"
+)
+
+// Inventory the resources applied for a repository
+type Inventory struct {
+	// Repository the safe name of the repository the resources were applied for
+	Repository string `json:"repository"`
+
+	// Namespace the namespace of the repository the resources were applied for. Inventories recorded before the
+	// namespace was recorded default to the namespace of their ConfigMap
+	Namespace string `json:"namespace,omitempty"`
+
+	// Owner the optional identifier of the operator instance which applied the resources
+	Owner string `json:"owner,omitempty"`
+
+	// Selector the label selector of the resources of the operator which applied the resources. Inventories recorded
+	// before the selector was recorded default to the default selector of the operator
+	Selector string `json:"selector,omitempty"`
+
+	// GitSHA the commit sha the resources were last applied for
+	GitSHA string `json:"gitSha,omitempty"`
+
+	// Entries the applied resources
+	Entries []Entry `json:"entries,omitempty"`
+}
+
+// Entry a resource applied by the operator
+type Entry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// String returns a description of the entry
+func (e Entry) String() string {
+	if e.Namespace == "" {
+		return fmt.Sprintf("%s %s", e.Kind, e.Name)
+	}
+	return fmt.Sprintf("%s %s in namespace %s", e.Kind, e.Name, e.Namespace)
+}
+
+// NewInventory creates the inventory of the given resources applied for a repository
+func NewInventory(safeName string, gitSHA string, resources []*unstructured.Unstructured) *Inventory {
+	answer := &Inventory{
+		Repository: safeName,
+		GitSHA:     gitSHA,
+	}
+	for _, r := range resources {
+		answer.Entries = append(answer.Entries, Entry{
+			APIVersion: r.GetAPIVersion(),
+			Kind:       r.GetKind(),
+			Namespace:  r.GetNamespace(),
+			Name:       r.GetName(),
+		})
+	}
+	return answer
+}
+
+// Key returns the `<namespace>/<safe name>` key of the repository of the inventory
+func (i *Inventory) Key() string {
+	return i.Namespace + "/" + i.Repository
+}
+
+// OwnedBy returns true if the inventory was recorded by the operator instance with the given optional identifier and
+// label selector so that operators sharing a namespace never garbage collect each others resources
+func (i *Inventory) OwnedBy(owner string, selector string) bool {
+	invSelector := i.Selector
+	if invSelector == "" {
+		invSelector = constants.DefaultSelector
+	}
+	if selector == "" {
+		selector = constants.DefaultSelector
+	}
+	return i.Owner == owner && invSelector == selector
+}
+
+// ConfigMapName returns the name of the inventory ConfigMap of the given repository applied by the operator instance
+// with the given optional identifier
+func ConfigMapName(owner string, safeName string) string {
+	if owner == "" {
+		return configMapPrefix + safeName
+	}
+	return configMapPrefix + naming.ToValidName(owner) + "-" + safeName
+}
+
+// Save creates or updates the inventory ConfigMap in the given namespace
+func Save(kubeClient kubernetes.Interface, ns string, inv *Inventory) error {
+	var data []byte
+	var err error
+	if inv.Namespace == "" {
+		inv.Namespace = ns
+	}
+	data, err = yaml.Marshal(inv)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal inventory of repository %s", inv.Repository)
+	}
+	name := ConfigMapName(inv.Owner, inv.Repository)
+	cmInterface := kubeClient.CoreV1().ConfigMaps(ns)
+	cm, err := cmInterface.Get(name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get inventory ConfigMap %s in namespace %s", name, ns)
+	}
+	if err != nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				Labels: map[string]string{
+					constants.DefaultSelectorKey: KindLabelValue,
+					launcher.RepositoryLabelKey:  inv.Repository,
+				},
+			},
+			Data: map[string]string{
+				DataKey: string(data),
+			},
+		}
+		_, err = cmInterface.Create(cm)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create inventory ConfigMap %s in namespace %s", name, ns)
+		}
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[DataKey] = string(data)
+	_, err = cmInterface.Update(cm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update inventory ConfigMap %s in namespace %s", name, ns)
+	}
+	return nil
+}
+
+// List loads the inventories in the given namespace or all namespaces if it is blank
+func List(kubeClient kubernetes.Interface, ns string) ([]*Inventory, error) {
+	list, err := kubeClient.CoreV1().ConfigMaps(ns).List(metav1.ListOptions{
+		LabelSelector: Selector,
+	})
+	if err != nil && apierrors.IsNotFound(err) {
+		err = nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find inventory ConfigMaps in namespace %s", ns)
+	}
+	var answer []*Inventory
+	for _, cm := range list.Items {
+		inv := &Inventory{}
+		err = yaml.Unmarshal([]byte(cm.Data[DataKey]), inv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse inventory ConfigMap %s in namespace %s", cm.Name, ns)
+		}
+		if inv.Repository == "" {
+			inv.Repository = cm.Labels[launcher.RepositoryLabelKey]
+		}
+		if inv.Namespace == "" {
+			inv.Namespace = cm.Namespace
+		}
+		answer = append(answer, inv)
+	}
+	return answer, nil
+}
+
+// GarbageCollect deletes the resources of every inventory in the given namespace, or all namespaces if it is blank,
+// which was recorded by the operator instance with the given optional identifier and label selector and whose
+// repository is not in the given set of `<namespace>/<safe name>` keys, along with the inventory itself.
+//
+// Nothing is deleted if the set of repositories is empty as a transient failure to discover the repositories would
+// otherwise delete every applied resource. The deleted entries are returned
+func GarbageCollect(kubeClient kubernetes.Interface, runner cmdrunner.CommandRunner, ns string, owner string, selector string, repositories map[string]bool) ([]Entry, error) {
+	if len(repositories) == 0 {
+		log.Logger().Warnf("not garbage collecting the applied resources as no repositories were found")
+		return nil, nil
+	}
+	inventories, err := List(kubeClient, ns)
+	if err != nil {
+		return nil, err
+	}
+	var answer []Entry
+	for _, inv := range inventories {
+		if repositories[inv.Key()] || !inv.OwnedBy(owner, selector) {
+			continue
+		}
+		log.Logger().Infof("garbage collecting %d resources of deleted repository %s in namespace %s", len(inv.Entries), inv.Repository, inv.Namespace)
+
+		entries, err := deleteInventory(kubeClient, runner, inv.Namespace, inv, "")
+		answer = append(answer, entries...)
+		if err != nil {
+			return answer, errors.Wrapf(err, "failed to garbage collect repository %s in namespace %s", inv.Repository, inv.Namespace)
+		}
+	}
+	return answer, nil
+}
+
+// Remove deletes the resources in the inventory of the given repository along with the inventory itself.
+//
+// The optional kubeconfig file is used to delete the resources in a remote cluster. The deleted entries are returned
+func Remove(kubeClient kubernetes.Interface, runner cmdrunner.CommandRunner, ns string, owner string, safeName string, kubeConfigFile string) ([]Entry, error) {
+	name := ConfigMapName(owner, safeName)
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get inventory ConfigMap %s in namespace %s", name, ns)
+	}
+	inv := &Inventory{}
+	err = yaml.Unmarshal([]byte(cm.Data[DataKey]), inv)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse inventory ConfigMap %s in namespace %s", name, ns)
+	}
+	inv.Repository = safeName
+	inv.Owner = owner
+	return deleteInventory(kubeClient, runner, ns, inv, kubeConfigFile)
+}
+
+// deleteInventory deletes the resources of the inventory via `kubectl delete` in the reverse order they were
+// applied, so that namespaces are removed last, then deletes the inventory ConfigMap
+func deleteInventory(kubeClient kubernetes.Interface, runner cmdrunner.CommandRunner, ns string, inv *Inventory, kubeConfigFile string) ([]Entry, error) {
+	var answer []Entry
+	for i := len(inv.Entries) - 1; i >= 0; i-- {
+		e := inv.Entries[i]
+		err := deleteEntry(runner, e, kubeConfigFile)
+		if err != nil {
+			return answer, err
+		}
+		answer = append(answer, e)
+	}
+
+	name := ConfigMapName(inv.Owner, inv.Repository)
+	err := kubeClient.CoreV1().ConfigMaps(ns).Delete(name, nil)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return answer, errors.Wrapf(err, "failed to delete inventory ConfigMap %s in namespace %s", name, ns)
+	}
+	return answer, nil
+}
+
+// deleteEntry deletes the resource via kubectl so that the resource type and scope are resolved the same way as
+// when the resource was applied
+func deleteEntry(runner cmdrunner.CommandRunner, e Entry, kubeConfigFile string) error {
+	gv, err := schema.ParseGroupVersion(e.APIVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse apiVersion of %s", e.String())
+	}
+
+	// lets fully qualify the kind with its version and group to avoid ambiguity
+	resourceType := e.Kind
+	if gv.Group != "" {
+		resourceType = fmt.Sprintf("%s.%s.%s", e.Kind, gv.Version, gv.Group)
+	}
+	args := []string{"delete", resourceType, e.Name, "--ignore-not-found"}
+	if e.Namespace != "" {
+		args = append(args, "--namespace", e.Namespace)
+	}
+	if kubeConfigFile != "" {
+		args = append(args, "--kubeconfig", kubeConfigFile)
+	}
+	cmd := &cmdrunner.Command{
+		Name: "kubectl",
+		Args: args,
+	}
+	log.Logger().Infof("running command: %s", cmd.CLI())
+	_, err = runner(cmd)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete %s", e.String())
+	}
+	return nil
+}
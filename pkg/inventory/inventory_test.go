@@ -0,0 +1,101 @@
+package inventory_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestInventoryGarbageCollect(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+
+	ns1 := &unstructured.Unstructured{}
+	ns1.SetAPIVersion("v1")
+	ns1.SetKind("Namespace")
+	ns1.SetName("boot")
+
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("example.jenkins.io/v1")
+	widget.SetKind("Widget")
+	widget.SetNamespace("boot")
+	widget.SetName("my-widget")
+
+	err := inventory.Save(kubeClient, ns, inventory.NewInventory("removed-repo", "sha1", []*unstructured.Unstructured{ns1, widget}))
+	require.NoError(t, err, "failed to save inventory")
+	err = inventory.Save(kubeClient, ns, inventory.NewInventory("removed-repo", "sha2", []*unstructured.Unstructured{ns1, widget}))
+	require.NoError(t, err, "failed to update inventory")
+	err = inventory.Save(kubeClient, ns, inventory.NewInventory("my-repo", "sha3", []*unstructured.Unstructured{widget}))
+	require.NoError(t, err, "failed to save inventory")
+
+	inventories, err := inventory.List(kubeClient, ns)
+	require.NoError(t, err, "failed to list inventories")
+	require.Len(t, inventories, 2, "inventories")
+
+	runner := &fakerunner.FakeRunner{}
+	entries, err := inventory.GarbageCollect(kubeClient, runner.Run, ns, "", "", map[string]bool{"jx/my-repo": true})
+	require.NoError(t, err, "failed to garbage collect")
+	assert.Len(t, entries, 2, "deleted entries")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{
+			CLI: "kubectl delete Widget.v1.example.jenkins.io my-widget --ignore-not-found --namespace boot",
+		},
+		fakerunner.FakeResult{
+			CLI: "kubectl delete Namespace boot --ignore-not-found",
+		},
+	)
+
+	inventories, err = inventory.List(kubeClient, ns)
+	require.NoError(t, err, "failed to list inventories")
+	require.Len(t, inventories, 1, "inventories after garbage collection")
+	assert.Equal(t, "my-repo", inventories[0].Repository, "remaining inventory")
+	assert.Equal(t, "sha3", inventories[0].GitSHA, "remaining inventory sha")
+}
+
+func TestInventoryGarbageCollectScope(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("example.jenkins.io/v1")
+	widget.SetKind("Widget")
+	widget.SetNamespace("boot")
+	widget.SetName("my-widget")
+	resources := []*unstructured.Unstructured{widget}
+
+	// the same repository name in two namespaces along with a removed repository of another operator instance
+	err := inventory.Save(kubeClient, "jx", inventory.NewInventory("my-repo", "sha1", resources))
+	require.NoError(t, err, "failed to save inventory")
+	err = inventory.Save(kubeClient, "team", inventory.NewInventory("my-repo", "sha2", resources))
+	require.NoError(t, err, "failed to save inventory")
+	other := inventory.NewInventory("other-repo", "sha3", resources)
+	other.Owner = "other-operator"
+	err = inventory.Save(kubeClient, "jx", other)
+	require.NoError(t, err, "failed to save inventory")
+
+	inventories, err := inventory.List(kubeClient, "")
+	require.NoError(t, err, "failed to list inventories")
+	require.Len(t, inventories, 3, "inventories")
+
+	runner := &fakerunner.FakeRunner{}
+	entries, err := inventory.GarbageCollect(kubeClient, runner.Run, "", "", "", map[string]bool{})
+	require.NoError(t, err, "failed to garbage collect")
+	assert.Empty(t, entries, "should not garbage collect when no repositories are found")
+
+	entries, err = inventory.GarbageCollect(kubeClient, runner.Run, "", "", "", map[string]bool{"jx/my-repo": true})
+	require.NoError(t, err, "failed to garbage collect")
+	assert.Len(t, entries, 1, "should only delete the repository removed from the team namespace")
+
+	inventories, err = inventory.List(kubeClient, "")
+	require.NoError(t, err, "failed to list inventories")
+	var keys []string
+	for _, inv := range inventories {
+		keys = append(keys, inv.Key())
+	}
+	assert.ElementsMatch(t, []string{"jx/my-repo", "jx/other-repo"}, keys, "remaining inventories")
+}
@@ -0,0 +1,118 @@
+package kube
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// minRateFraction the fraction of the maximum rate the adaptive rate limiter never goes below
+	minRateFraction = 0.05
+
+	// recoverRateFraction the fraction of the maximum rate restored by each successful request
+	recoverRateFraction = 0.05
+)
+
+// AdaptiveLimiter limits the rate of requests to the kubernetes API using a token bucket whose rate is halved each
+// time the API server throttles a request and is gradually restored as requests succeed.
+//
+// A nil limiter does not limit any requests
+type AdaptiveLimiter struct {
+	lock    sync.Mutex
+	maxQPS  float64
+	qps     float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+}
+
+// NewAdaptiveLimiter creates a new adaptive rate limiter with the given maximum queries per second and burst
+func NewAdaptiveLimiter(qps float32, burst int) *AdaptiveLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	metrics.AdaptiveRateLimit.Set(float64(qps))
+	return &AdaptiveLimiter{
+		maxQPS:  float64(qps),
+		qps:     float64(qps),
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		updated: time.Now(),
+	}
+}
+
+// Wait blocks until the next request is allowed by the current rate
+func (l *AdaptiveLimiter) Wait() {
+	delay := l.reserve()
+	if delay <= 0 {
+		return
+	}
+	observeThrottled(SourceAdaptive, delay)
+	time.Sleep(delay)
+}
+
+// reserve takes a token returning how long to wait until it is available
+func (l *AdaptiveLimiter) reserve() time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.updated).Seconds()*l.qps)
+	l.updated = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.qps * float64(time.Second))
+}
+
+// Observe adapts the rate to the result of a request. The rate is halved if the request was throttled by the API
+// server and is otherwise gradually restored up to the maximum if the request succeeded
+func (l *AdaptiveLimiter) Observe(err error) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	switch {
+	case IsThrottled(err):
+		l.qps = math.Max(l.qps/2, l.maxQPS*minRateFraction)
+	case err == nil:
+		l.qps = math.Min(l.qps+l.maxQPS*recoverRateFraction, l.maxQPS)
+	default:
+		return
+	}
+	metrics.AdaptiveRateLimit.Set(l.qps)
+}
+
+// QPS returns the current queries per second allowed by the limiter
+func (l *AdaptiveLimiter) QPS() float64 {
+	if l == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.qps
+}
+
+// IsThrottled returns true if the error was caused by the API server rejecting a request as there were too many
+// requests, such as a 429 status or the equivalent `kubectl` output
+func IsThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsTooManyRequests(errors.Cause(err)) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "too many requests")
+}
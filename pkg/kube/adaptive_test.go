@@ -0,0 +1,60 @@
+package kube_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/kube"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := kube.NewAdaptiveLimiter(20, 5)
+	assert.Equal(t, float64(20), l.QPS(), "initial rate")
+
+	l.Observe(apierrors.NewTooManyRequests("slow down", 1))
+	assert.Equal(t, float64(10), l.QPS(), "should halve the rate when throttled")
+
+	l.Observe(errors.New("error: the server has received too many requests and has asked us to try again later"))
+	assert.Equal(t, float64(5), l.QPS(), "should halve the rate when kubectl is throttled")
+
+	l.Observe(errors.New("connection refused"))
+	assert.Equal(t, float64(5), l.QPS(), "should ignore other errors")
+
+	for i := 0; i < 100; i++ {
+		l.Observe(apierrors.NewTooManyRequests("slow down", 1))
+	}
+	assert.Equal(t, float64(1), l.QPS(), "should not go below the minimum rate")
+
+	l.Observe(nil)
+	assert.Equal(t, float64(2), l.QPS(), "should gradually restore the rate")
+	for i := 0; i < 100; i++ {
+		l.Observe(nil)
+	}
+	assert.Equal(t, float64(20), l.QPS(), "should not exceed the maximum rate")
+
+	// lets check the burst does not block and a nil limiter is a no-op
+	for i := 0; i < 5; i++ {
+		l.Wait()
+	}
+	var nilLimiter *kube.AdaptiveLimiter
+	nilLimiter.Wait()
+	nilLimiter.Observe(nil)
+}
+
+func TestConfigure(t *testing.T) {
+	cfg := &rest.Config{}
+	kube.ClientOptions{QPS: 50, Burst: 100}.Configure(cfg)
+	assert.Equal(t, float32(50), cfg.QPS, "QPS")
+	assert.Equal(t, 100, cfg.Burst, "Burst")
+	require.NotNil(t, cfg.RateLimiter, "should have a rate limiter")
+	assert.Equal(t, float32(50), cfg.RateLimiter.QPS(), "rate limiter QPS")
+	require.NotNil(t, cfg.WrapTransport, "should wrap the transport")
+
+	cfg = &rest.Config{}
+	kube.ClientOptions{}.Configure(cfg)
+	assert.Equal(t, rest.DefaultQPS, cfg.RateLimiter.QPS(), "should default the QPS")
+}
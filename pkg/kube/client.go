@@ -0,0 +1,119 @@
+package kube
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// SourceClient the throttling source of requests delayed by the client side rate limiter
+	SourceClient = "client"
+
+	// SourceAdaptive the throttling source of requests delayed by the adaptive rate limiter of the launcher
+	SourceAdaptive = "adaptive"
+
+	// SourceServer the throttling source of requests rejected by the API server as there were too many requests
+	SourceServer = "server"
+)
+
+// ClientOptions the options of the clients of the kubernetes API
+type ClientOptions struct {
+	// QPS the maximum queries per second to the kubernetes API. If not specified the client-go default of 5 is used
+	// and the adaptive rate limiting of the launcher is disabled
+	QPS float32 `env:"KUBE_API_QPS"`
+
+	// Burst the maximum burst of queries to the kubernetes API. If not specified the client-go default of 10 is used
+	Burst int `env:"KUBE_API_BURST"`
+}
+
+// CreateConfig creates the REST config of the kubernetes API using the QPS and Burst which records metrics of the
+// requests which are throttled by the client or rejected by the API server
+func (o ClientOptions) CreateConfig() (*rest.Config, error) {
+	f := kubeclient.NewFactory()
+	cfg, err := f.CreateKubeConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create kube config")
+	}
+	o.Configure(cfg)
+	return cfg, nil
+}
+
+// Configure configures the QPS, Burst and throttling metrics of the given REST config
+func (o ClientOptions) Configure(cfg *rest.Config) {
+	if o.QPS > 0 {
+		cfg.QPS = o.QPS
+	}
+	if o.Burst > 0 {
+		cfg.Burst = o.Burst
+	}
+	qps := cfg.QPS
+	if qps <= 0 {
+		qps = rest.DefaultQPS
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = rest.DefaultBurst
+	}
+	cfg.RateLimiter = &meteredRateLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst)}
+
+	wrap := cfg.WrapTransport
+	cfg.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &throttleRoundTripper{next: rt}
+	}
+}
+
+// meteredRateLimiter records the requests which are delayed by the client side rate limiter
+type meteredRateLimiter struct {
+	flowcontrol.RateLimiter
+}
+
+// Accept returns once a token becomes available
+func (l *meteredRateLimiter) Accept() {
+	if l.TryAccept() {
+		return
+	}
+	start := time.Now()
+	l.RateLimiter.Accept()
+	observeThrottled(SourceClient, time.Since(start))
+}
+
+// Wait returns nil if a token is taken before the context is done
+func (l *meteredRateLimiter) Wait(ctx context.Context) error {
+	if l.TryAccept() {
+		return nil
+	}
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	observeThrottled(SourceClient, time.Since(start))
+	return err
+}
+
+// throttleRoundTripper records the requests which are rejected by the API server as there were too many requests
+type throttleRoundTripper struct {
+	next http.RoundTripper
+}
+
+// RoundTrip performs the request
+func (t *throttleRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		metrics.ThrottledRequests.WithLabelValues(SourceServer).Inc()
+	}
+	return resp, err
+}
+
+// observeThrottled records a request which was delayed by rate limiting
+func observeThrottled(source string, delay time.Duration) {
+	metrics.ThrottledRequests.WithLabelValues(source).Inc()
+	metrics.ThrottleDelay.WithLabelValues(source).Observe(delay.Seconds())
+}
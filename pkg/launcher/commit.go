@@ -0,0 +1,48 @@
+package launcher
+
+import "strings"
+
+// Commit the metadata of the git commit being launched
+type Commit struct {
+	// Author the name and email of the author of the commit
+	Author string
+
+	// Committer the name and email of the committer of the commit
+	Committer string
+
+	// Timestamp the RFC 3339 timestamp of the commit
+	Timestamp string
+
+	// Subject the subject line of the commit message
+	Subject string
+
+	// Branch the branch the commit was pulled from
+	Branch string
+
+	// Tags the optional tags pointing at the commit
+	Tags []string
+}
+
+// Annotations adds the non empty commit metadata to the given annotations returning the annotations
+func (c *Commit) Annotations(annotations map[string]string) map[string]string {
+	if c == nil {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	values := map[string]string{
+		CommitAuthorAnnotation:    c.Author,
+		CommitCommitterAnnotation: c.Committer,
+		CommitTimestampAnnotation: c.Timestamp,
+		CommitSubjectAnnotation:   c.Subject,
+		CommitBranchAnnotation:    c.Branch,
+		CommitTagsAnnotation:      strings.Join(c.Tags, ","),
+	}
+	for k, v := range values {
+		if v != "" {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,143 @@
+package launcher
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Completion the completion of a resource launched for a commit of a repository
+type Completion struct {
+	// Kind the kind of the launched resource
+	Kind string
+
+	// Name the name of the launched resource which can be passed to HistoryProvider.Logs to get its logs
+	Name string
+
+	// Namespace the namespace of the launched resource
+	Namespace string
+
+	// Cluster the name of the kubeconfig Secret of the remote cluster the resource was launched in or blank for the
+	// local cluster
+	Cluster string
+
+	// GitSHA the commit sha which was launched
+	GitSHA string
+
+	// Result the result of the resource: `succeeded`, `failed` or `deleted`
+	Result string
+
+	// Started when the resource started running
+	Started time.Time
+
+	// Completed when the resource succeeded, failed or was deleted
+	Completed time.Time
+
+	// LogSelector the label selector of the pods of the resource whose logs can be viewed via
+	// `kubectl logs --selector`
+	LogSelector string
+}
+
+// Succeeded returns true if the launched resource completed successfully
+func (c *Completion) Succeeded() bool {
+	return c.Result == ResultSucceeded
+}
+
+// Duration returns how long the launched resource ran for
+func (c *Completion) Duration() time.Duration {
+	if c.Started.IsZero() || c.Completed.Before(c.Started) {
+		return 0
+	}
+	return c.Completed.Sub(c.Started)
+}
+
+// LaunchResult the resources created by a launch whose callbacks fire as each watched resource completes so that
+// notifications, status reporting and retries can react to completions without polling the resources themselves
+type LaunchResult struct {
+	// Objects the resources created by the launch
+	Objects []runtime.Object
+
+	lock        sync.Mutex
+	pending     int
+	completions []Completion
+	callbacks   []func(Completion)
+	done        chan struct{}
+}
+
+// NewLaunchResult creates a result of the given launched objects which is done once the given number of watched
+// resources have completed
+func NewLaunchResult(objects []runtime.Object, pending int) *LaunchResult {
+	r := &LaunchResult{
+		Objects: objects,
+		pending: pending,
+		done:    make(chan struct{}),
+	}
+	if pending <= 0 {
+		close(r.done)
+	}
+	return r
+}
+
+// OnComplete registers a callback which is invoked with the completion of each watched resource, including any
+// which have already completed
+func (r *LaunchResult) OnComplete(fn func(Completion)) {
+	r.lock.Lock()
+	r.callbacks = append(r.callbacks, fn)
+	completions := append([]Completion{}, r.completions...)
+	r.lock.Unlock()
+
+	for _, c := range completions {
+		fn(c)
+	}
+}
+
+// Complete records the completion of a watched resource, invoking the callbacks, and closes the done channel once
+// every watched resource has completed. Completions of a resource which has already completed are ignored
+func (r *LaunchResult) Complete(c Completion) {
+	r.lock.Lock()
+	for _, existing := range r.completions {
+		if existing.Kind == c.Kind && existing.Name == c.Name && existing.Namespace == c.Namespace && existing.Cluster == c.Cluster {
+			r.lock.Unlock()
+			return
+		}
+	}
+	r.completions = append(r.completions, c)
+	callbacks := append([]func(Completion){}, r.callbacks...)
+	done := len(r.completions) == r.pending
+	r.lock.Unlock()
+
+	for _, fn := range callbacks {
+		fn(c)
+	}
+	if done {
+		close(r.done)
+	}
+}
+
+// Done returns a channel which is closed once every watched resource has completed
+func (r *LaunchResult) Done() <-chan struct{} {
+	return r.done
+}
+
+// Completions returns the completions of the watched resources which have completed so far
+func (r *LaunchResult) Completions() []Completion {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Completion{}, r.completions...)
+}
+
+// Succeeded returns true if every watched resource has completed successfully
+func (r *LaunchResult) Succeeded() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.completions) < r.pending {
+		return false
+	}
+	for _, c := range r.completions {
+		if !c.Succeeded() {
+			return false
+		}
+	}
+	return true
+}
@@ -6,4 +6,99 @@ const (
 
 	// CommitShaLabelKey the label key for associating the commit sha
 	CommitShaLabelKey = "git-operator.jenkins.io/commit-sha"
+
+	// ParentRepositoryLabelKey the label key for associating the resources launched for a folder of a monorepo to
+	// the repository containing the folder
+	ParentRepositoryLabelKey = "git-operator.jenkins.io/parent-repository"
+
+	// FolderAnnotation the annotation on resources launched for a folder of a monorepo which records the folder
+	FolderAnnotation = "git-operator.jenkins.io/folder"
+
+	// ClusterLabelKey the label key for associating resources to the kubeconfig Secret of the remote cluster they were launched in
+	ClusterLabelKey = "git-operator.jenkins.io/cluster"
+
+	// DiffAnnotation the annotation on launched resources which records the diff of the resources which were applied
+	DiffAnnotation = "git-operator.jenkins.io/diff"
+
+	// ResultActive the result of a launched resource which has not completed yet
+	ResultActive = "active"
+
+	// ResultSucceeded the result of a launched resource which completed successfully
+	ResultSucceeded = "succeeded"
+
+	// ResultFailed the result of a launched resource which failed
+	ResultFailed = "failed"
+
+	// ResultDeleted the result of a launched resource which was deleted before it completed
+	ResultDeleted = "deleted"
+
+	// CommitAuthorAnnotation the annotation on launched resources which records the author of the commit
+	CommitAuthorAnnotation = "git-operator.jenkins.io/commit-author"
+
+	// CommitCommitterAnnotation the annotation on launched resources which records the committer of the commit
+	CommitCommitterAnnotation = "git-operator.jenkins.io/commit-committer"
+
+	// CommitTimestampAnnotation the annotation on launched resources which records the RFC 3339 commit timestamp
+	CommitTimestampAnnotation = "git-operator.jenkins.io/commit-timestamp"
+
+	// CommitSubjectAnnotation the annotation on launched resources which records the subject line of the commit message
+	CommitSubjectAnnotation = "git-operator.jenkins.io/commit-subject"
+
+	// CommitBranchAnnotation the annotation on launched resources which records the branch of the commit
+	CommitBranchAnnotation = "git-operator.jenkins.io/commit-branch"
+
+	// CommitTagsAnnotation the annotation on launched resources which records the comma separated tags of the commit
+	CommitTagsAnnotation = "git-operator.jenkins.io/commit-tags"
+
+	// ChangelogAnnotation the annotation on launched resources which records the commits covered by the launch since
+	// the previously launched commit
+	ChangelogAnnotation = "git-operator.jenkins.io/changelog"
+
+	// RollbackLabelKey the label key on resources launched to roll back a repository to its last successful commit
+	// after the launch of a newer commit failed
+	RollbackLabelKey = "git-operator.jenkins.io/rollback"
+
+	// RollbackOfAnnotation the annotation on rollback resources which records the failed commit sha being rolled back
+	RollbackOfAnnotation = "git-operator.jenkins.io/rollback-of"
+
+	// RolledBackToAnnotation the annotation on the failed resources of a commit which records the commit sha the
+	// repository was rolled back to
+	RolledBackToAnnotation = "git-operator.jenkins.io/rolled-back-to"
+
+	// PreflightAnnotation the annotation on launched resources which records the result of the pre-flight check
+	// which ran before they were created
+	PreflightAnnotation = "git-operator.jenkins.io/preflight"
+
+	// AttemptAnnotation the annotation on launched resources which records the attempt of launching the commit sha.
+	// Each relaunch of a commit is a new attempt whose resources are named with a `-a<attempt>` suffix
+	AttemptAnnotation = "git-operator.jenkins.io/attempt"
+
+	// RelaunchRequestAnnotation the annotation on relaunched resources which records the request which relaunched
+	// the commit sha so that the same request never launches another attempt
+	RelaunchRequestAnnotation = "git-operator.jenkins.io/relaunch-request"
+
+	// GitSecretAnnotation the annotation on a default Job launched in a remote cluster which records the name of the
+	// Secret the launcher copies the git URL and credentials of the repository into, as a Job cannot reference the
+	// repository Secret in another cluster
+	GitSecretAnnotation = "git-operator.jenkins.io/git-secret"
+
+	// TriggerLabelKey the label key on launched resources which records how the launch was triggered such as `poll`,
+	// `webhook` or `retry`
+	TriggerLabelKey = "git-operator.jenkins.io/trigger-source"
+
+	// PostJobLabelKey the label key on the post-success Jobs created once the Jobs of a commit have succeeded
+	PostJobLabelKey = "git-operator.jenkins.io/post-job"
+
+	// ApprovalAnnotation the annotation on Jobs created suspended which records their approval state: `pending`
+	// until an approver sets it to `approved`, after which the operator resumes the Job and sets it to `resumed`
+	ApprovalAnnotation = "git-operator.jenkins.io/approval"
+
+	// ApprovalPending the approval state of a suspended Job waiting for approval
+	ApprovalPending = "pending"
+
+	// ApprovalApproved the approval state set by an approver to run a suspended Job
+	ApprovalApproved = "approved"
+
+	// ApprovalResumed the approval state of an approved Job which the operator has resumed
+	ApprovalResumed = "resumed"
 )
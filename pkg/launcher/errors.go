@@ -0,0 +1,64 @@
+package launcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OwnershipConflictError the error returned when a repository has resources launched by a different operator instance
+type OwnershipConflictError struct {
+	// Repository the name of the repository
+	Repository string
+
+	// Owner the operator instance which launched the existing resources
+	Owner string
+
+	// Resource the name of the existing resource
+	Resource string
+}
+
+// Error returns the error message
+func (e *OwnershipConflictError) Error() string {
+	return fmt.Sprintf("repository %s has resource %s launched by another operator %s", e.Repository, e.Resource, e.Owner)
+}
+
+// IsOwnershipConflict returns true if the error is an OwnershipConflictError
+func IsOwnershipConflict(err error) bool {
+	_, ok := err.(*OwnershipConflictError)
+	return ok
+}
+
+// PreflightError the error returned when the pre-flight check of a repository fails so the commit is not launched
+type PreflightError struct {
+	// Repository the name of the repository
+	Repository string
+
+	// GitSHA the commit sha which was not launched
+	GitSHA string
+
+	// Output the trimmed output of the failed check
+	Output string
+}
+
+// Error returns the error message
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("pre-flight check of repository %s sha %s failed: %s", e.Repository, e.GitSHA, e.Output)
+}
+
+// ValidationError the error returned when any of the resources of a repository fail validation so that none of
+// the resources are applied
+type ValidationError struct {
+	// Repository the name of the repository
+	Repository string
+
+	// GitSHA the commit sha which was not launched
+	GitSHA string
+
+	// Failures the validation failure of each invalid resource
+	Failures []string
+}
+
+// Error returns the error message
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d resources of repository %s sha %s failed validation:\n%s", len(e.Failures), e.Repository, e.GitSHA, strings.Join(e.Failures, "\n"))
+}
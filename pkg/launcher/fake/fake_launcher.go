@@ -0,0 +1,81 @@
+package fake
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Launcher a fake launcher which records the LaunchOptions it is invoked with and returns canned results
+type Launcher struct {
+	// Invocations the recorded options in the order they were launched
+	Invocations []launcher.LaunchOptions
+
+	// LaunchFunc if specified this callback returns the results and error
+	LaunchFunc func(opts launcher.LaunchOptions) ([]runtime.Object, error)
+
+	// ResultObjects default objects returned if no LaunchFunc
+	ResultObjects []runtime.Object
+
+	// ResultError default error returned if no LaunchFunc
+	ResultError error
+
+	// DriftFunc if specified this callback returns the drift of a repository; otherwise no drift is detected
+	DriftFunc func(opts launcher.LaunchOptions) (*launcher.Drift, error)
+
+	lock sync.Mutex
+}
+
+// ExpectedLaunch the expected repository and git sha of a launch
+type ExpectedLaunch struct {
+	Name   string
+	GitSHA string
+}
+
+// Launch records the launch options and returns the canned results
+func (f *Launcher) Launch(opts launcher.LaunchOptions) ([]runtime.Object, error) {
+	f.lock.Lock()
+	f.Invocations = append(f.Invocations, opts)
+	f.lock.Unlock()
+
+	if f.LaunchFunc != nil {
+		return f.LaunchFunc(opts)
+	}
+	return f.ResultObjects, f.ResultError
+}
+
+// DetectDrift returns the drift from the DriftFunc or no drift
+func (f *Launcher) DetectDrift(opts launcher.LaunchOptions) (*launcher.Drift, error) {
+	if f.DriftFunc != nil {
+		return f.DriftFunc(opts)
+	}
+	return &launcher.Drift{}, nil
+}
+
+// ExpectLaunches asserts the launcher was invoked with the given repositories and git shas in order
+func (f *Launcher) ExpectLaunches(t *testing.T, expected ...ExpectedLaunch) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, o := range f.Invocations {
+		t.Logf("got launch for repository %s sha %s\n", o.Repository.Name, o.GitSHA)
+	}
+	require.Equal(t, len(expected), len(f.Invocations), "expected launch invocations")
+
+	for i, e := range expected {
+		o := f.Invocations[i]
+		assert.Equal(t, e.Name, o.Repository.Name, "repository name for launch %d", i+1)
+		assert.Equal(t, e.GitSHA, o.GitSHA, "git sha for launch %d", i+1)
+	}
+}
+
+// Reset clears the recorded invocations
+func (f *Launcher) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.Invocations = nil
+}
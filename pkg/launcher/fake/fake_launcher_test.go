@@ -0,0 +1,38 @@
+package fake_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/fake"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFakeLauncher(t *testing.T) {
+	f := &fake.Launcher{
+		ResultObjects: []runtime.Object{
+			&v1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "myjob",
+				},
+			},
+		},
+	}
+
+	var l launcher.Interface = f
+	objects, err := l.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{Name: "myrepo"},
+		GitSHA:     "abc1234",
+	})
+	require.NoError(t, err, "failed to launch")
+	require.Len(t, objects, 1, "should have returned the canned objects")
+
+	f.ExpectLaunches(t, fake.ExpectedLaunch{Name: "myrepo", GitSHA: "abc1234"})
+
+	f.Reset()
+	f.ExpectLaunches(t)
+}
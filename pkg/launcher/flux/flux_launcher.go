@@ -0,0 +1,280 @@
+package flux
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// LauncherName the name the Flux launcher is registered with
+	LauncherName = "flux"
+
+	// defaultInterval the interval at which Flux reconciles the generated resources
+	defaultInterval = "10m"
+
+	// reconcileRequestAnnotation the annotation which requests Flux to reconcile a resource straight away
+	reconcileRequestAnnotation = "reconcile.fluxcd.io/requestedAt"
+)
+
+var (
+	// GitRepositoryResource the resource of the Flux `GitRepository`
+	GitRepositoryResource = schema.GroupVersionResource{
+		Group:    "source.toolkit.fluxcd.io",
+		Version:  "v1beta1",
+		Resource: "gitrepositories",
+	}
+
+	// KustomizationResource the resource of the Flux `Kustomization`
+	KustomizationResource = schema.GroupVersionResource{
+		Group:    "kustomize.toolkit.fluxcd.io",
+		Version:  "v1beta1",
+		Resource: "kustomizations",
+	}
+)
+
+func init() {
+	launcher.Register(LauncherName, func(o launcher.FactoryOptions) (launcher.Interface, error) {
+		return NewLauncher(o.DynamicClient, o.Namespace, o.Selector)
+	})
+}
+
+type client struct {
+	dynamicClient dynamic.Interface
+	ns            string
+	selector      string
+}
+
+// NewLauncher creates a new launcher which, rather than creating a Job, creates or updates a Flux `GitRepository`
+// pinned to the commit sha of the repository along with a `Kustomization` which reconciles the `resources`
+// directory of the git operator folder, so that repositories can be handed over to Flux using the same discovery.
+//
+// If nil is passed in the dynamic client will be lazily created
+func NewLauncher(dynamicClient dynamic.Interface, ns string, selector string) (launcher.Interface, error) {
+	if dynamicClient == nil {
+		f := kubeclient.NewFactory()
+		cfg, err := f.CreateKubeConfig()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create kube config")
+		}
+
+		dynamicClient, err = dynamic.NewForConfig(cfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create the dynamic client")
+		}
+
+		if ns == "" {
+			ns, err = kubeclient.CurrentNamespace()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find the current namespace")
+			}
+		}
+	}
+	return &client{
+		dynamicClient: dynamicClient,
+		ns:            ns,
+		selector:      selector,
+	}, nil
+}
+
+// Launch creates or updates the GitRepository and Kustomization of the repository if the commit sha has changed.
+// If the repository is relaunched Flux is requested to reconcile the resources again
+func (c *client) Launch(opts launcher.LaunchOptions) ([]runtime.Object, error) {
+	ns := opts.Repository.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	safeName := naming.ToValidValue(opts.Repository.Name)
+	safeSha := naming.ToValidValue(opts.GitSHA)
+
+	gitRepoInterface := c.dynamicClient.Resource(GitRepositoryResource).Namespace(ns)
+	existing, err := gitRepoInterface.Get(safeName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get GitRepository %s in namespace %s", safeName, ns)
+		}
+		existing = nil
+	}
+	if existing != nil && existing.GetLabels()[launcher.CommitShaLabelKey] == safeSha && !opts.Relaunch {
+		return nil, nil
+	}
+
+	path, err := resourcesPath(opts.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the resources of repository %s", safeName)
+	}
+	if opts.Tenant != nil {
+		err = validateTenant(opts, safeName, ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := opts.Repository
+	branch := r.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	gitURL := repo.RedactGitURL(r.GitURL)
+	gitRepoSpec := map[string]interface{}{
+		"url":      gitURL,
+		"interval": defaultInterval,
+		"ref": map[string]interface{}{
+			"branch": branch,
+			"commit": opts.GitSHA,
+		},
+	}
+	if gitURL != r.GitURL {
+		// lets use the username and password of the repository Secret or its credentials Secret
+		secretName := r.CredentialsSecret
+		if secretName == "" {
+			secretName = r.Name
+		}
+		gitRepoSpec["secretRef"] = map[string]interface{}{
+			"name": secretName,
+		}
+	}
+	gitRepo := newResource("source.toolkit.fluxcd.io/v1beta1", "GitRepository", gitRepoSpec)
+
+	kustomizationSpec := map[string]interface{}{
+		"interval": defaultInterval,
+		"path":     path,
+		"prune":    true,
+		"sourceRef": map[string]interface{}{
+			"kind": "GitRepository",
+			"name": safeName,
+		},
+	}
+	if opts.Tenant != nil {
+		// lets make sure resources without a namespace are reconciled in the validated namespace
+		kustomizationSpec["targetNamespace"] = ns
+	}
+	kustomization := newResource("kustomize.toolkit.fluxcd.io/v1beta1", "Kustomization", kustomizationSpec)
+
+	var answer []runtime.Object
+	for _, u := range []*unstructured.Unstructured{gitRepo, kustomization} {
+		u.SetName(safeName)
+		u.SetNamespace(ns)
+		u.SetLabels(launchLabels(safeName, safeSha))
+		annotations := opts.Commit.Annotations(nil)
+		if opts.Relaunch {
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[reconcileRequestAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		}
+		u.SetAnnotations(annotations)
+
+		gvr := GitRepositoryResource
+		if u.GetKind() == "Kustomization" {
+			gvr = KustomizationResource
+		}
+		result, err := c.apply(gvr, u)
+		if err != nil {
+			return answer, err
+		}
+		answer = append(answer, result)
+	}
+	return answer, nil
+}
+
+// apply creates the resource or updates it if it already exists
+func (c *client) apply(gvr schema.GroupVersionResource, u *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	kind := u.GetKind()
+	name := u.GetName()
+	ns := u.GetNamespace()
+	resourceInterface := c.dynamicClient.Resource(gvr).Namespace(ns)
+	existing, err := resourceInterface.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get %s %s in namespace %s", kind, name, ns)
+		}
+		answer, err := resourceInterface.Create(u, metav1.CreateOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s %s in namespace %s", kind, name, ns)
+		}
+		log.Logger().Infof("created %s %s in namespace %s for sha %s", kind, name, ns, u.GetLabels()[launcher.CommitShaLabelKey])
+		return answer, nil
+	}
+
+	u.SetResourceVersion(existing.GetResourceVersion())
+	answer, err := resourceInterface.Update(u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update %s %s in namespace %s", kind, name, ns)
+	}
+	log.Logger().Infof("updated %s %s in namespace %s to sha %s", kind, name, ns, u.GetLabels()[launcher.CommitShaLabelKey])
+	return answer, nil
+}
+
+// validateTenant validates the namespaces and service accounts of the resources which Flux reconciles from the
+// `resources` directory are allowed by the tenant of the repository
+func validateTenant(opts launcher.LaunchOptions, safeName string, ns string) error {
+	folder, err := job.FindGitOperatorFolder(opts.Dir)
+	if err != nil {
+		return err
+	}
+	resources, _, err := job.LoadApplyResources(nil, folder, safeName, ns, "")
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the resources of repository %s", safeName)
+	}
+	err = opts.Tenant.Validate(ns, resources)
+	if err != nil {
+		return errors.Wrapf(err, "repository %s is not allowed to launch", opts.Repository.Name)
+	}
+	return nil
+}
+
+// resourcesPath returns the path of the `resources` directory of the git operator folder relative to the root of
+// the git clone in the form used by a Flux `Kustomization`
+func resourcesPath(dir string) (string, error) {
+	folder, err := job.FindGitOperatorFolder(dir)
+	if err != nil {
+		return "", err
+	}
+	resourcesDir := filepath.Join(folder, job.ResourcesDirName)
+	exists, err := files.DirExists(resourcesDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check if resources directory %s exists", resourcesDir)
+	}
+	if !exists {
+		return "", errors.Errorf("there is no resources directory %s for Flux to reconcile", resourcesDir)
+	}
+	rel, err := filepath.Rel(dir, resourcesDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find the path of %s relative to %s", resourcesDir, dir)
+	}
+	return "./" + filepath.ToSlash(rel), nil
+}
+
+func newResource(apiVersion string, kind string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"spec":       spec,
+		},
+	}
+}
+
+func launchLabels(safeName string, safeSha string) map[string]string {
+	return map[string]string{
+		constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+		launcher.RepositoryLabelKey:  safeName,
+		launcher.CommitShaLabelKey:   safeSha,
+	}
+}
@@ -0,0 +1,263 @@
+package launcher
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultRegistry the registry of images which do not specify one
+	defaultRegistry = "docker.io"
+
+	// DefaultCABundleKey the default key of the CA bundle in the CA bundle ConfigMap
+	DefaultCABundleKey = "ca-bundle.crt"
+
+	// CABundleVolumeName the name of the volume of the CA bundle injected into the pods of Jobs
+	CABundleVolumeName = "git-operator-ca-bundle"
+
+	// CABundleMountPath the directory the CA bundle is mounted in within the containers of Jobs
+	CABundleMountPath = "/etc/git-operator/ca"
+)
+
+// proxyEnvVars the names of the proxy environment variables of the operator which are propagated into Jobs. Both
+// cases are propagated as tools such as `curl` and `git` only read some of them in lower case
+var proxyEnvVars = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// caBundleEnvVars the names of the environment variables which point tools at the injected CA bundle
+var caBundleEnvVars = []string{"SSL_CERT_FILE", "GIT_SSL_CAINFO"}
+
+// InjectOptions the platform configuration injected into the pods of every launched Job so that settings such as
+// proxies, registry mirrors, image pull secrets or feature flags are configured once on the operator rather than
+// per repository
+type InjectOptions struct {
+	// Env the optional YAML or JSON list of environment variables such as `[{"name": "HTTP_PROXY", "value": "..."}]`.
+	// Entries can use `valueFrom` to reference a `secretKeyRef` or `configMapKeyRef`
+	Env string `env:"JOB_ENV"`
+
+	// EnvFrom the optional YAML or JSON list of `envFrom` sources such as `[{"secretRef": {"name": "proxy"}}]`
+	EnvFrom string `env:"JOB_ENV_FROM"`
+
+	// ImagePullSecrets the optional names of the image pull secrets added to the pods of every Job
+	ImagePullSecrets []string `env:"JOB_IMAGE_PULL_SECRETS"`
+
+	// RegistryMirrors the optional mirrors of container registries as `registry=mirror` pairs such as
+	// `docker.io=mirror.example.com/dockerhub`. The images of the containers from a mirrored registry are pulled
+	// from the mirror instead
+	RegistryMirrors []string `env:"JOB_REGISTRY_MIRRORS"`
+
+	// PodSecurityContext the optional YAML or JSON pod `securityContext` of the pods which do not specify one such
+	// as `{"runAsNonRoot": true}`
+	PodSecurityContext string `env:"JOB_POD_SECURITY_CONTEXT"`
+
+	// SecurityContext the optional YAML or JSON `securityContext` of the containers which do not specify one such
+	// as `{"allowPrivilegeEscalation": false}`
+	SecurityContext string `env:"JOB_SECURITY_CONTEXT"`
+
+	// Proxy if enabled the `HTTP_PROXY`, `HTTPS_PROXY` and `NO_PROXY` environment variables of the operator are
+	// propagated into the containers of every Job so that boot pipelines work behind the same proxy as the operator
+	Proxy bool `env:"JOB_PROXY"`
+
+	// CABundleConfigMap the optional name of the ConfigMap, in the namespace of the Jobs, containing the CA bundle
+	// which is mounted into the containers of every Job, with `SSL_CERT_FILE` and `GIT_SSL_CAINFO` pointing at it, so
+	// that the certificates of TLS intercepting proxies and internal git servers are trusted
+	CABundleConfigMap string `env:"JOB_CA_BUNDLE_CONFIGMAP"`
+
+	// CABundleKey the key of the CA bundle in the ConfigMap. Defaults to `ca-bundle.crt`
+	CABundleKey string `env:"JOB_CA_BUNDLE_KEY"`
+
+	// Platform the platform the Jobs run on: `kubernetes`, `openshift` or `auto` (the default) to detect it. On
+	// OpenShift the fixed user and group IDs are removed from the security contexts of the pods so that the security
+	// context constraints can assign them
+	Platform string `env:"JOB_PLATFORM"`
+}
+
+// Validate validates the injected environment variables and registry mirrors can be parsed
+func (o InjectOptions) Validate() error {
+	_, err := o.EnvVars()
+	if err != nil {
+		return err
+	}
+	_, err = o.EnvFromSources()
+	if err != nil {
+		return err
+	}
+	_, err = o.Mirrors()
+	if err != nil {
+		return err
+	}
+	_, err = o.PodSecurityContextValue()
+	if err != nil {
+		return err
+	}
+	_, err = o.SecurityContextValue()
+	if err != nil {
+		return err
+	}
+	if strings.Contains(o.CABundleKey, "/") {
+		return errors.Errorf("invalid CA bundle key %s in $JOB_CA_BUNDLE_KEY. It must be the name of a key of the ConfigMap", o.CABundleKey)
+	}
+	switch o.Platform {
+	case "", PlatformAuto, PlatformKubernetes, PlatformOpenShift:
+		return nil
+	default:
+		return errors.Errorf("invalid platform %s in $JOB_PLATFORM. Expected one of %s, %s or %s", o.Platform, PlatformAuto, PlatformKubernetes, PlatformOpenShift)
+	}
+}
+
+// OpenShift returns true if the Jobs run on OpenShift
+func (o InjectOptions) OpenShift() bool {
+	return o.Platform == PlatformOpenShift
+}
+
+// ForRepository returns the options with any image pull secrets or registry mirrors overridden by the repository
+func (o InjectOptions) ForRepository(r repo.Repository) InjectOptions {
+	if r.ImagePullSecrets != nil {
+		o.ImagePullSecrets = r.ImagePullSecrets
+	}
+	if r.RegistryMirrors != nil {
+		o.RegistryMirrors = r.RegistryMirrors
+	}
+	return o
+}
+
+// Mirrors returns the mirror of each registry
+func (o InjectOptions) Mirrors() (map[string]string, error) {
+	answer := map[string]string{}
+	for _, text := range o.RegistryMirrors {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		parts := strings.SplitN(text, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid registry mirror %s. Expected registry=mirror such as docker.io=mirror.example.com/dockerhub", text)
+		}
+		answer[parts[0]] = strings.TrimSuffix(parts[1], "/")
+	}
+	return answer, nil
+}
+
+// MirrorImage returns the image pulled from the mirror of its registry or the image itself if its registry is not
+// mirrored. Images without a registry are from `docker.io`
+func MirrorImage(image string, mirrors map[string]string) string {
+	if len(mirrors) == 0 || image == "" {
+		return image
+	}
+	registry := defaultRegistry
+	path := image
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		registry = parts[0]
+		path = parts[1]
+	} else if len(parts) == 1 {
+		path = "library/" + image
+	}
+	mirror := mirrors[registry]
+	if mirror == "" {
+		return image
+	}
+	return mirror + "/" + path
+}
+
+// EnvVars returns the environment variables to inject
+func (o InjectOptions) EnvVars() ([]corev1.EnvVar, error) {
+	var answer []corev1.EnvVar
+	if o.Env == "" {
+		return o.addProxyEnvVars(answer), nil
+	}
+	err := yaml.Unmarshal([]byte(o.Env), &answer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the injected environment variables $JOB_ENV")
+	}
+	for i, e := range answer {
+		if e.Name == "" {
+			return nil, errors.Errorf("missing name of injected environment variable %d in $JOB_ENV", i)
+		}
+	}
+	return o.addProxyEnvVars(answer), nil
+}
+
+// addProxyEnvVars adds the propagated proxy environment variables of the operator and the environment variables of
+// the CA bundle which are not already in the given environment variables
+func (o InjectOptions) addProxyEnvVars(env []corev1.EnvVar) []corev1.EnvVar {
+	names := map[string]bool{}
+	for _, e := range env {
+		names[e.Name] = true
+	}
+	if o.Proxy {
+		for _, name := range proxyEnvVars {
+			value := os.Getenv(name)
+			if value != "" && !names[name] {
+				env = append(env, corev1.EnvVar{Name: name, Value: value})
+			}
+		}
+	}
+	if o.CABundleConfigMap != "" {
+		for _, name := range caBundleEnvVars {
+			if !names[name] {
+				env = append(env, corev1.EnvVar{Name: name, Value: o.CABundleFile()})
+			}
+		}
+	}
+	return env
+}
+
+// CABundleFile returns the path of the CA bundle file mounted into the containers of Jobs
+func (o InjectOptions) CABundleFile() string {
+	key := o.CABundleKey
+	if key == "" {
+		key = DefaultCABundleKey
+	}
+	return path.Join(CABundleMountPath, key)
+}
+
+// EnvFromSources returns the `envFrom` sources to inject
+func (o InjectOptions) EnvFromSources() ([]corev1.EnvFromSource, error) {
+	var answer []corev1.EnvFromSource
+	if o.EnvFrom == "" {
+		return answer, nil
+	}
+	err := yaml.Unmarshal([]byte(o.EnvFrom), &answer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the injected envFrom sources $JOB_ENV_FROM")
+	}
+	for i, e := range answer {
+		if e.SecretRef == nil && e.ConfigMapRef == nil {
+			return nil, errors.Errorf("injected envFrom source %d in $JOB_ENV_FROM must have a secretRef or configMapRef", i)
+		}
+	}
+	return answer, nil
+}
+
+// PodSecurityContextValue returns the pod security context to inject or nil if there is none
+func (o InjectOptions) PodSecurityContextValue() (map[string]interface{}, error) {
+	if o.PodSecurityContext == "" {
+		return nil, nil
+	}
+	sc := &corev1.PodSecurityContext{}
+	err := yaml.Unmarshal([]byte(o.PodSecurityContext), sc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the injected pod security context $JOB_POD_SECURITY_CONTEXT")
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(sc)
+}
+
+// SecurityContextValue returns the container security context to inject or nil if there is none
+func (o InjectOptions) SecurityContextValue() (map[string]interface{}, error) {
+	if o.SecurityContext == "" {
+		return nil, nil
+	}
+	sc := &corev1.SecurityContext{}
+	err := yaml.Unmarshal([]byte(o.SecurityContext), sc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the injected security context $JOB_SECURITY_CONTEXT")
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(sc)
+}
@@ -0,0 +1,68 @@
+package launcher_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMirrorImage(t *testing.T) {
+	o := launcher.InjectOptions{
+		RegistryMirrors: []string{"docker.io=mirror.example.com/dockerhub/", "gcr.io=mirror.example.com/gcr", "localhost:5000=mirror.example.com/local"},
+	}
+	mirrors, err := o.Mirrors()
+	require.NoError(t, err, "failed to parse registry mirrors")
+
+	testCases := []struct {
+		image    string
+		expected string
+	}{
+		{"alpine", "mirror.example.com/dockerhub/library/alpine"},
+		{"alpine:3.12", "mirror.example.com/dockerhub/library/alpine:3.12"},
+		{"jenkinsxio/jx:2.0.0", "mirror.example.com/dockerhub/jenkinsxio/jx:2.0.0"},
+		{"docker.io/jenkinsxio/jx:2.0.0", "mirror.example.com/dockerhub/jenkinsxio/jx:2.0.0"},
+		{"gcr.io/jenkinsxio-labs/jx-gitops:0.0.30", "mirror.example.com/gcr/jenkinsxio-labs/jx-gitops:0.0.30"},
+		{"localhost:5000/myimage", "mirror.example.com/local/myimage"},
+		{"quay.io/myorg/myimage", "quay.io/myorg/myimage"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, launcher.MirrorImage(tc.image, mirrors), "mirror of image %s", tc.image)
+	}
+
+	err = launcher.InjectOptions{RegistryMirrors: []string{"docker.io"}}.Validate()
+	require.Error(t, err, "should fail to parse a registry mirror without a mirror")
+}
+
+func TestInjectOptionsForRepository(t *testing.T) {
+	o := launcher.InjectOptions{
+		ImagePullSecrets: []string{"platform-registry"},
+		RegistryMirrors:  []string{"docker.io=mirror.example.com"},
+	}
+	assert.Equal(t, o, o.ForRepository(repo.Repository{}), "should use the operator settings by default")
+
+	actual := o.ForRepository(repo.Repository{ImagePullSecrets: []string{"team-registry"}, RegistryMirrors: []string{}})
+	assert.Equal(t, []string{"team-registry"}, actual.ImagePullSecrets, "should override the image pull secrets")
+	assert.Empty(t, actual.RegistryMirrors, "should disable the registry mirrors")
+}
+
+func TestDetectPlatform(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	platform, err := launcher.DetectPlatform(kubeClient)
+	require.NoError(t, err, "failed to detect the platform")
+	assert.Equal(t, launcher.PlatformKubernetes, platform, "platform")
+
+	kubeClient.Resources = []*metav1.APIResourceList{{GroupVersion: "security.openshift.io/v1"}}
+	platform, err = launcher.DetectPlatform(kubeClient)
+	require.NoError(t, err, "failed to detect the platform")
+	assert.Equal(t, launcher.PlatformOpenShift, platform, "platform")
+
+	err = launcher.InjectOptions{Platform: "windows"}.Validate()
+	require.Error(t, err, "should fail to validate an unknown platform")
+	err = launcher.InjectOptions{PodSecurityContext: `{"runAsUser": "root"}`}.Validate()
+	require.Error(t, err, "should fail to parse an invalid pod security context")
+}
@@ -1,7 +1,11 @@
 package launcher
 
 import (
+	"time"
+
 	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/tenant"
+	"github.com/jenkins-x/jx-git-operator/pkg/timing"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -13,11 +17,118 @@ type LaunchOptions struct {
 	// GitSHA the latest git commit SHA of the repository
 	GitSHA string
 
+	// Commit the optional metadata of the commit which is recorded as annotations on the launched resources
+	Commit *Commit
+
+	// Branch the branch of the repository the commit was pulled from
+	Branch string
+
+	// PreviousSHA the optional commit sha of the repository which was previously launched by the operator. Empty if
+	// no commit has been launched since the operator started
+	PreviousSHA string
+
+	// Trigger how the launch was triggered: `poll`, `webhook`, `manual`, `schedule`, `retry`, `rollback` or `drift`,
+	// which is recorded as a label on the launched resources
+	Trigger string
+
+	// Parameters the optional parameters of the repository which are available to launchers
+	Parameters map[string]string
+
+	// Changelog the optional one line summaries of the commits covered by this launch since the previously
+	// launched commit, which is recorded as an annotation on the launched resources
+	Changelog string
+
 	// 	Dir the root directory of the git clone of the repository
 	Dir string
 
+	// ChangedFiles the optional paths changed since the previously launched commit which select the job files to
+	// launch via the `triggers.yaml` file of the repository. Nil if the changes are not known
+	ChangedFiles []string
+
 	// NoResourceApply if specified disable applying resources found in `.jx/git-operator/resources/*.yaml`
 	NoResourceApply bool
+
+	// DefaultJob the optional default Job to create if the repository does not contain a job file
+	DefaultJob DefaultJobOptions
+
+	// Naming the naming strategy of the launched resources
+	Naming NamingOptions
+
+	// Tenant the optional tenant of the repository which restricts the namespaces and service accounts that can be used
+	Tenant *tenant.Tenant
+
+	// PriorityClassName the optional priority class name to use for the pods of the Job if it does not specify one
+	PriorityClassName string
+
+	// Inject the platform configuration injected into the containers of the Job
+	Inject InjectOptions
+
+	// JobDefaults the defaults of the specs of the Jobs which are injected into the Jobs which do not specify them
+	JobDefaults JobDefaults
+
+	// Relaunch if enabled the resources are launched again even if the commit sha has already been launched
+	// such as to correct drift of the applied resources
+	Relaunch bool
+
+	// RelaunchRequest the optional identifier of the request to relaunch the commit sha, such as the value of the
+	// trigger annotation of the repository, which is recorded on the relaunched resources so that a request which
+	// was launched before the operator restarted is not launched again
+	RelaunchRequest string
+
+	// Owner the optional identifier of the operator instance launching the repository which is recorded on the
+	// launched resources so that conflicting operators can be detected
+	Owner string
+
+	// RollbackOf the optional failed commit sha of the repository which this launch of the last successful commit
+	// rolls back. Rollback resources are named and labelled separately from the resources of a normal launch
+	RollbackOf string
+
+	// PreflightScript if enabled the optional pre-flight script of the repository is run and must succeed before
+	// the resources are applied and launched
+	PreflightScript bool
+
+	// ValidateResources if enabled the resources to be applied are validated via a server side dry run before any
+	// of them are applied so that an invalid resource fails the launch rather than leaving a partial apply
+	ValidateResources bool
+
+	// Suspend if enabled the Jobs are created with `spec.suspend: true` so that they only run once approved via the
+	// approval annotation
+	Suspend bool
+
+	// Timings the optional breakdown of the durations of the phases of the launch which is nil unless the phase
+	// timings are enabled
+	Timings *timing.Breakdown
+
+	// Decision the optional decision of the launch which is populated by launchers which support it when no
+	// resources are launched, such as when the launch is blocked by an active Job of a previous commit
+	Decision *Decision
+}
+
+// Decision why a launch did not create any resources
+type Decision struct {
+	// BlockedBy the name of the active resource launched for a previous commit which blocked the launch
+	BlockedBy string
+
+	// Namespace the namespace of the active resource
+	Namespace string
+}
+
+// DefaultJobOptions the configuration of the default Job created if a repository does not have a job file
+type DefaultJobOptions struct {
+	// Enabled if enabled a default Job is created for repositories which do not have a `job.yaml` file
+	Enabled bool `env:"DEFAULT_JOB"`
+
+	// Image the container image of the default Job
+	Image string `env:"DEFAULT_JOB_IMAGE"`
+
+	// Command the optional command of the default Job container
+	Command []string `env:"DEFAULT_JOB_COMMAND"`
+
+	// Args the optional arguments of the default Job container
+	Args []string `env:"DEFAULT_JOB_ARGS"`
+
+	// ServiceAccount the optional service account name of the default Job
+	ServiceAccount string `env:"DEFAULT_JOB_SERVICE_ACCOUNT"`
 }
 
 // Interface the interface for launching Jobs/Tasks when there is a git commit in a repository
@@ -26,3 +137,109 @@ type Interface interface {
 	// since the last
 	Launch(opts LaunchOptions) ([]runtime.Object, error)
 }
+
+// Drift the result of comparing the live state of the cluster with the resources applied for a commit
+type Drift struct {
+	// Drifted true if the live state differs from the resources in the repository
+	Drifted bool
+
+	// Diff the differences between the live state and the resources in the repository
+	Diff string
+}
+
+// DriftDetector is implemented by launchers which can detect drift of the live cluster state from the resources
+// applied for the last launched commit of a repository
+type DriftDetector interface {
+	// DetectDrift compares the live state of the cluster with the resources of the repository at the given commit
+	DetectDrift(opts LaunchOptions) (*Drift, error)
+}
+
+// GarbageCollector is implemented by launchers which keep an inventory of the resources they apply so that the
+// resources of repositories which have been removed can be deleted
+type GarbageCollector interface {
+	// GarbageCollect deletes the applied resources of any repositories which are not in the given list returning
+	// the number of deleted resources
+	GarbageCollect(repositories []repo.Repository) (int, error)
+}
+
+// Cleaner is implemented by launchers which can delete the resources launched and applied for a repository when
+// the repository is removed
+type Cleaner interface {
+	// Cleanup deletes the resources launched and applied for the given repository
+	Cleanup(repository repo.Repository) error
+}
+
+// Cacher is implemented by launchers which can look up the resources they previously launched from an informer
+// cache rather than listing them from the API server for every launch
+type Cacher interface {
+	// StartCache starts caching the launched resources in the given namespace, or all namespaces if empty, until
+	// the stop channel is closed. Lookups fall back to listing the resources until the cache has synced
+	StartCache(namespace string, stop <-chan struct{})
+}
+
+// CompletionWatcher is implemented by launchers which can watch the resources they launched until they complete
+type CompletionWatcher interface {
+	// WatchCompletion watches the given objects returned by launching the given options until they complete or
+	// the stop channel is closed, returning a result whose callbacks fire as each of them completes. Objects which
+	// do not complete, such as a `ConfigMap`, are not watched
+	WatchCompletion(opts LaunchOptions, objects []runtime.Object, stop <-chan struct{}) (*LaunchResult, error)
+}
+
+// LaunchRecord a resource launched for a commit of a repository
+type LaunchRecord struct {
+	// Name the name of the launched resource
+	Name string `json:"name"`
+
+	// Namespace the namespace of the launched resource
+	Namespace string `json:"namespace"`
+
+	// Kind the kind of the launched resource
+	Kind string `json:"kind"`
+
+	// GitSHA the commit sha which was launched
+	GitSHA string `json:"gitSha"`
+
+	// Created when the resource was launched
+	Created time.Time `json:"created"`
+
+	// Result the result of the launch: `active`, `succeeded` or `failed`
+	Result string `json:"result"`
+
+	// Started the optional time the resource started running
+	Started *time.Time `json:"started,omitempty"`
+
+	// Completed the optional time the launch succeeded or failed
+	Completed *time.Time `json:"completed,omitempty"`
+
+	// Rollback true if the resource was launched to roll back a failed commit
+	Rollback bool `json:"rollback,omitempty"`
+
+	// PostJob the optional name of the post-success Job of the launch whose result is included in the result
+	PostJob string `json:"postJob,omitempty"`
+
+	// Cluster the kubeconfig Secret name of the remote cluster the resource was launched in
+	Cluster string `json:"cluster,omitempty"`
+
+	// Clusters the records of each remote cluster the commit was launched in when the repository is launched in
+	// remote clusters. The result of the launch is then the aggregate result of these records
+	Clusters []LaunchRecord `json:"clusters,omitempty"`
+}
+
+// JobName returns the name of the resource which determined the result of the launch, which is the post-success
+// Job if there is one
+func (r *LaunchRecord) JobName() string {
+	if r.PostJob != "" {
+		return r.PostJob
+	}
+	return r.Name
+}
+
+// HistoryProvider is implemented by launchers which can list the resources previously launched for a repository
+// and their logs
+type HistoryProvider interface {
+	// History returns the resources launched for the repository with the most recent first
+	History(repository repo.Repository) ([]LaunchRecord, error)
+
+	// Logs returns the logs of the given launched resource of the repository
+	Logs(repository repo.Repository, name string) (string, error)
+}
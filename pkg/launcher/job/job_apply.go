@@ -0,0 +1,209 @@
+package job
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// applyPhases the kinds of resource which are applied in order before any other resources
+var applyPhases = [][]string{
+	{"Namespace"},
+	{"CustomResourceDefinition"},
+	{"ServiceAccount", "ClusterRole", "Role", "ClusterRoleBinding", "RoleBinding"},
+}
+
+const (
+	// crdPhase the index of the phase which applies the CustomResourceDefinitions
+	crdPhase = 1
+
+	// applyRetries the number of attempts to apply resources whose kind is not yet known to the cluster
+	applyRetries = 5
+
+	// applyRetryDelay the delay between attempts to apply resources whose kind is not yet known to the cluster
+	applyRetryDelay = 5 * time.Second
+
+	// crdEstablishedTimeout how long to wait for the CustomResourceDefinitions to be established
+	crdEstablishedTimeout = "60s"
+)
+
+// applyDir the copies of the resources of a repository to be applied which are labelled with the repository
+type applyDir struct {
+	// dir the temporary directory containing the resources
+	dir string
+
+	// resources the labelled resources
+	resources []*unstructured.Unstructured
+}
+
+// newApplyDir labels the given resources with the repository so that they can be tracked and garbage collected,
+// then saves them to a temporary directory to be applied
+func newApplyDir(resources []*unstructured.Unstructured, safeName string) (*applyDir, error) {
+	dir, err := ioutil.TempDir("", "jx-git-operator-apply-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create temp dir")
+	}
+	d := &applyDir{
+		dir:       dir,
+		resources: resources,
+	}
+	for _, r := range resources {
+		labels := r.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[launcher.RepositoryLabelKey] = safeName
+		r.SetLabels(labels)
+	}
+	err = saveResources(d.allDir(), resources)
+	if err != nil {
+		d.cleanup()
+		return nil, err
+	}
+	return d, nil
+}
+
+// allDir returns the directory containing all of the resources
+func (d *applyDir) allDir() string {
+	return filepath.Join(d.dir, "all")
+}
+
+// cleanup removes the temporary directory
+func (d *applyDir) cleanup() {
+	err := os.RemoveAll(d.dir)
+	if err != nil {
+		log.Logger().Warnf("failed to remove temporary dir %s: %s", d.dir, err.Error())
+	}
+}
+
+// applyResources applies the resources in the given apply directory.
+//
+// If there are any `Namespace` or `CustomResourceDefinition` resources the resources are applied in dependency
+// order: namespaces, custom resource definitions (waiting for them to be established), RBAC resources and then the
+// remaining resources; retrying any resources whose kind is not yet known by the cluster.
+// Otherwise the resources are applied in one go
+func (c *client) applyResources(d *applyDir, kubeConfigFile string) error {
+	if !requiresOrderedApply(d.resources) {
+		return c.applyWithRetry(d.allDir(), kubeConfigFile)
+	}
+
+	phases := make([][]*unstructured.Unstructured, len(applyPhases)+1)
+	for _, r := range d.resources {
+		phases[applyPhase(r)] = append(phases[applyPhase(r)], r)
+	}
+	for i, phaseResources := range phases {
+		if len(phaseResources) == 0 {
+			continue
+		}
+		phaseDir := filepath.Join(d.dir, fmt.Sprintf("phase-%d", i))
+		err := saveResources(phaseDir, phaseResources)
+		if err != nil {
+			return err
+		}
+
+		err = c.applyWithRetry(phaseDir, kubeConfigFile)
+		if err != nil {
+			return err
+		}
+
+		if i == crdPhase {
+			log.Logger().Infof("waiting for %d CustomResourceDefinitions to be established", len(phaseResources))
+			_, err = c.kubectl(kubeConfigFile, "wait", "--for", "condition=established", "--timeout", crdEstablishedTimeout, "-f", phaseDir)
+			if err != nil {
+				return errors.Wrapf(err, "failed to wait for the CustomResourceDefinitions to be established")
+			}
+		}
+	}
+	return nil
+}
+
+// saveResources saves each resource to a separate file in the given directory
+func saveResources(dir string, resources []*unstructured.Unstructured) error {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	for i, r := range resources {
+		fileName := filepath.Join(dir, fmt.Sprintf("%03d.yaml", i))
+		err = yamls.SaveFile(r.Object, fileName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save %s %s to %s", r.GetKind(), r.GetName(), fileName)
+		}
+	}
+	return nil
+}
+
+// applyWithRetry applies the resources in the given directory retrying if a kind is not yet known by the cluster.
+// Transient errors are retried by the command runner
+func (c *client) applyWithRetry(dir string, kubeConfigFile string) error {
+	var err error
+	for i := 1; i <= applyRetries; i++ {
+		c.limiter.Wait()
+		var text string
+		text, err = c.kubectl(kubeConfigFile, "apply", "-f", dir)
+		if err != nil {
+			c.limiter.Observe(errors.Wrap(err, text))
+		} else {
+			c.limiter.Observe(nil)
+		}
+		if err == nil || !isUnknownKind(text, err) {
+			return err
+		}
+		if i < applyRetries {
+			log.Logger().Warnf("resource kind not known yet, retrying apply in %s: %s", applyRetryDelay.String(), err.Error())
+			time.Sleep(applyRetryDelay)
+		}
+	}
+	return err
+}
+
+// kubectl runs kubectl with the given arguments against the cluster of the optional kubeconfig file
+func (c *client) kubectl(kubeConfigFile string, args ...string) (string, error) {
+	if kubeConfigFile != "" {
+		args = append(args, "--kubeconfig", kubeConfigFile)
+	}
+	cmd := &cmdrunner.Command{
+		Name: "kubectl",
+		Args: args,
+	}
+	log.Logger().Infof("running command: %s", cmd.CLI())
+	return c.runner(cmd)
+}
+
+// requiresOrderedApply returns true if any of the resources need to be applied before the other resources
+func requiresOrderedApply(resources []*unstructured.Unstructured) bool {
+	for _, r := range resources {
+		if applyPhase(r) <= crdPhase {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPhase returns the index of the phase the resource is applied in
+func applyPhase(r *unstructured.Unstructured) int {
+	kind := r.GetKind()
+	for i, kinds := range applyPhases {
+		if stringhelpers.StringArrayIndex(kinds, kind) >= 0 {
+			return i
+		}
+	}
+	return len(applyPhases)
+}
+
+// isUnknownKind returns true if the apply failed due to a kind which is not yet known by the cluster
+func isUnknownKind(text string, err error) bool {
+	message := text + " " + err.Error()
+	return strings.Contains(message, "no matches for kind") || strings.Contains(message, "ensure CRDs are installed first")
+}
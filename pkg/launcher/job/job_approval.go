@@ -0,0 +1,106 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// jobsResource the resource of the Jobs created via the dynamic client so that fields which the typed client does
+// not support, such as `spec.suspend`, are kept
+var jobsResource = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+
+// createSuspendedJob creates the Job with `spec.suspend: true` so that reviewers can inspect the rendered Job
+// before it runs, along with a PendingApproval Event on the Job. The dynamic client is used as the typed client
+// does not support the field
+func createSuspendedJob(clients *clusterClients, resource *unstructured.Unstructured, ns string) (runtime.Object, error) {
+	err := unstructured.SetNestedField(resource.Object, true, "spec", "suspend")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to suspend Job %s", resource.GetName())
+	}
+	annotations := resource.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[launcher.ApprovalAnnotation] = launcher.ApprovalPending
+	resource.SetAnnotations(annotations)
+
+	j, err := createUntypedJob(clients, resource, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Job %s is suspended pending approval. Annotate it with %s=%s to run it", j.Name, launcher.ApprovalAnnotation, launcher.ApprovalApproved)
+	err = createJobEvent(clients, j, "PendingApproval", message)
+	if err != nil {
+		log.Logger().Warnf("failed to create the PendingApproval Event of Job %s: %s", j.Name, err.Error())
+	}
+	return j, nil
+}
+
+// resumeApprovedJob resumes the suspended Job if it has been approved via the ApprovalAnnotation
+func resumeApprovedJob(clients *clusterClients, j *v1.Job) error {
+	if j.Annotations[launcher.ApprovalAnnotation] != launcher.ApprovalApproved {
+		return nil
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				launcher.ApprovalAnnotation: launcher.ApprovalResumed,
+			},
+		},
+		"spec": map[string]interface{}{
+			"suspend": false,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal the patch of Job %s", j.Name)
+	}
+	_, err = clients.kubeClient.BatchV1().Jobs(j.Namespace).Patch(j.Name, types.MergePatchType, data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resume approved Job %s in namespace %s", j.Name, j.Namespace)
+	}
+	log.Logger().Infof("resumed approved Job %s in namespace %s", j.Name, j.Namespace)
+	return nil
+}
+
+// createJobEvent creates a normal Event on the given Job
+func createJobEvent(clients *clusterClients, j *v1.Job, reason string, message string) error {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", j.Name, now.UnixNano()),
+			Namespace: j.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+			Name:       j.Name,
+			Namespace:  j.Namespace,
+			UID:        j.UID,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "jx-git-operator"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err := clients.kubeClient.CoreV1().Events(j.Namespace).Create(event)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create Event for Job %s in namespace %s", j.Name, j.Namespace)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package job
+
+import (
+	"sync"
+
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// jobPageSize the maximum number of Jobs returned by each page of a list of Jobs
+const jobPageSize = 500
+
+// jobCache the informer cache of the Jobs launched by the operator in the local cluster
+type jobCache struct {
+	lock      sync.Mutex
+	namespace string
+	lister    batchlisters.JobLister
+	synced    cache.InformerSynced
+}
+
+// StartCache starts an informer which caches the Jobs launched by the operator in the namespace, or all namespaces
+// if empty, so that the Jobs of a repository are looked up from the cache rather than listed for every launch
+func (c *client) StartCache(namespace string, stop <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = c.selector
+		}))
+	informer := factory.Batch().V1().Jobs()
+
+	c.cache.lock.Lock()
+	c.cache.namespace = namespace
+	c.cache.lister = informer.Lister()
+	c.cache.synced = informer.Informer().HasSynced
+	c.cache.lock.Unlock()
+
+	factory.Start(stop)
+	log.Logger().Infof("caching the Jobs in namespace %s with selector %s", namespace, c.selector)
+}
+
+// cachedJobs returns the Jobs in the namespace matching the selector from the cache or false if the cache has not
+// synced or does not cover the namespace
+func (c *client) cachedJobs(ns string, selector string) ([]v1.Job, bool, error) {
+	c.cache.lock.Lock()
+	lister, synced, namespace := c.cache.lister, c.cache.synced, c.cache.namespace
+	c.cache.lock.Unlock()
+
+	if lister == nil || !synced() || (namespace != "" && namespace != ns) {
+		return nil, false, nil
+	}
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to parse selector %s", selector)
+	}
+	jobs, err := lister.Jobs(ns).List(s)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to find cached Jobs in namespace %s with selector %s", ns, selector)
+	}
+	answer := make([]v1.Job, 0, len(jobs))
+	for _, j := range jobs {
+		answer = append(answer, *j.DeepCopy())
+	}
+	return answer, true, nil
+}
+
+// listJobs returns the Jobs in the namespace of the cluster matching the selector. Jobs in the local cluster are
+// looked up from the cache if it has synced, otherwise the Jobs are listed a page at a time so that namespaces with
+// thousands of Jobs are never returned in a single response
+func (c *client) listJobs(clients *clusterClients, ns string, selector string) ([]v1.Job, error) {
+	if clients.local != nil {
+		answer, ok, err := c.cachedJobs(ns, selector)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return answer, nil
+		}
+	}
+
+	var answer []v1.Job
+	listOptions := metav1.ListOptions{
+		LabelSelector: selector,
+		Limit:         jobPageSize,
+	}
+	for {
+		c.limiter.Wait()
+		list, err := clients.kubeClient.BatchV1().Jobs(ns).List(listOptions)
+		c.limiter.Observe(err)
+		if err != nil && apierrors.IsResourceExpired(err) && listOptions.Continue != "" {
+			// lets start again if the Jobs changed so much while paging that the continue token expired
+			answer = nil
+			listOptions.Continue = ""
+			continue
+		}
+		if err != nil && apierrors.IsNotFound(err) {
+			return answer, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find Jobs in namespace %s with selector %s", ns, selector)
+		}
+		answer = append(answer, list.Items...)
+		if list.Continue == "" {
+			return answer, nil
+		}
+		listOptions.Continue = list.Continue
+	}
+}
@@ -0,0 +1,176 @@
+package job
+
+import (
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rewatchDelay the delay before watching a Job again if the watch fails or is closed before the Job completes
+const rewatchDelay = 5 * time.Second
+
+// WatchCompletion watches the launched Jobs, in the local cluster or the remote clusters they were launched in,
+// until they complete or the stop channel is closed
+func (c *client) WatchCompletion(opts launcher.LaunchOptions, objects []runtime.Object, stop <-chan struct{}) (*launcher.LaunchResult, error) {
+	var jobs []*v1.Job
+	for _, o := range objects {
+		if j, ok := o.(*v1.Job); ok {
+			jobs = append(jobs, j)
+		}
+	}
+	result := launcher.NewLaunchResult(objects, len(jobs))
+	for _, j := range jobs {
+		cluster := jobCluster(opts, j)
+		clients, err := c.clientsFor(opts.Repository, cluster, j.Namespace)
+		if err != nil {
+			return result, err
+		}
+		// only the client is used to watch the Job so the kubeconfig file is not needed
+		clients.cleanup()
+		go watchJobCompletion(clients.kubeClient, cluster, j, result, stop)
+	}
+	return result, nil
+}
+
+// jobCluster returns the name of the kubeconfig Secret of the remote cluster the Job was launched in or blank for
+// the local cluster
+func jobCluster(opts launcher.LaunchOptions, j *v1.Job) string {
+	label := j.Labels[launcher.ClusterLabelKey]
+	if label == "" {
+		return ""
+	}
+	for _, cluster := range opts.Repository.KubeConfigSecrets {
+		if naming.ToValidValue(cluster) == label {
+			return cluster
+		}
+	}
+	return label
+}
+
+// watchJobCompletion watches the Job until it completes, is deleted or the stop channel is closed, completing the
+// result with the completion of the Job
+func watchJobCompletion(kubeClient kubernetes.Interface, cluster string, launched *v1.Job, result *launcher.LaunchResult, stop <-chan struct{}) {
+	for {
+		if watchJobOnce(kubeClient, cluster, launched, result, stop) {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
+
+// watchJobOnce watches the Job until it completes or the watch is closed, returning true if the Job completed or
+// the stop channel was closed
+func watchJobOnce(kubeClient kubernetes.Interface, cluster string, launched *v1.Job, result *launcher.LaunchResult, stop <-chan struct{}) bool {
+	ns := launched.Namespace
+	name := launched.Name
+	jobs := kubeClient.BatchV1().Jobs(ns)
+	j, err := jobs.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		result.Complete(ToCompletion(launched, cluster, true))
+		return true
+	}
+	if err != nil {
+		log.Logger().Warnf("failed to get Job %s in namespace %s: %s", name, ns, err.Error())
+		return false
+	}
+	if IsJobFinished(*j) {
+		result.Complete(ToCompletion(j, cluster, false))
+		return true
+	}
+
+	w, err := jobs.Watch(metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: j.ResourceVersion,
+	})
+	if err != nil {
+		log.Logger().Warnf("failed to watch Job %s in namespace %s: %s", name, ns, err.Error())
+		return false
+	}
+	defer w.Stop()
+	for {
+		select {
+		case <-stop:
+			return true
+		case we, ok := <-w.ResultChan():
+			if !ok {
+				return false
+			}
+			j, ok := we.Object.(*v1.Job)
+			if !ok || j.Name != name {
+				continue
+			}
+			deleted := we.Type == watch.Deleted
+			if deleted || IsJobFinished(*j) {
+				result.Complete(ToCompletion(j, cluster, deleted))
+				return true
+			}
+		}
+	}
+}
+
+// ToCompletion returns the completion of the Job which has succeeded, failed after exhausting its retries or been
+// deleted
+func ToCompletion(j *v1.Job, cluster string, deleted bool) launcher.Completion {
+	answer := launcher.Completion{
+		Kind:        "Job",
+		Name:        j.Name,
+		Namespace:   j.Namespace,
+		Cluster:     cluster,
+		GitSHA:      j.Labels[launcher.CommitShaLabelKey],
+		Started:     j.CreationTimestamp.Time,
+		LogSelector: "job-name=" + j.Name,
+	}
+	if j.Status.StartTime != nil {
+		answer.Started = j.Status.StartTime.Time
+	}
+	switch {
+	case deleted && !IsJobFinished(*j):
+		answer.Result = launcher.ResultDeleted
+	case JobResult(*j) == launcher.ResultSucceeded:
+		answer.Result = launcher.ResultSucceeded
+		answer.Completed = JobCompletionTime(*j)
+	default:
+		answer.Result = launcher.ResultFailed
+		answer.Completed = JobCompletionTime(*j)
+	}
+	if answer.Completed.IsZero() {
+		answer.Completed = time.Now()
+	}
+	return answer
+}
+
+// JobResult returns the result of the Job from its `Complete` and `Failed` conditions. A Job whose pods have failed
+// but which is still retrying within its `backoffLimit` is active
+func JobResult(j v1.Job) string {
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case v1.JobComplete:
+			return launcher.ResultSucceeded
+		case v1.JobFailed:
+			return launcher.ResultFailed
+		}
+	}
+	return launcher.ResultActive
+}
+
+// IsJobFinished returns true if the Job has succeeded or has failed after exhausting its retries
+func IsJobFinished(j v1.Job) bool {
+	return JobResult(j) != launcher.ResultActive
+}
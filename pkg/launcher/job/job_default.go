@@ -0,0 +1,171 @@
+package job
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultJob creates the default Job from the operator configuration for a repository which does not have a job file.
+//
+// The git URL is passed into the container via the `GIT_URL`, `GIT_USERNAME` and `GIT_PASSWORD` environment variables
+// which reference the repository `Secret` along with the commit sha via `GIT_REVISION`. For a repository declared by
+// a `ConfigMap` the git URL references the `ConfigMap` and the credentials its optional credentials `Secret`.
+//
+// If the repository is launched in a remote cluster the environment variables reference the Secret named by the
+// GitSecretAnnotation instead which the launcher creates in the remote cluster via GitSecret
+func DefaultJob(opts launcher.LaunchOptions) (*unstructured.Unstructured, error) {
+	d := opts.DefaultJob
+	if d.Image == "" {
+		return nil, errors.Errorf("no default Job image configured")
+	}
+	r := opts.Repository
+	secretName := r.Name
+	remote := len(r.KubeConfigSecrets) > 0 || r.ManagedCluster != ""
+	if remote {
+		secretName = GitSecretName(r)
+	}
+	urlRef := secretKeyRef(secretName, "url", nil)
+	if r.Kind == repo.ConfigMapKind && !remote {
+		secretName = r.CredentialsSecret
+		urlRef = &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: r.Name,
+				},
+				Key: "url",
+			},
+		}
+	}
+	optional := true
+	env := []corev1.EnvVar{
+		{
+			Name:      "GIT_URL",
+			ValueFrom: urlRef,
+		},
+	}
+	if secretName != "" {
+		env = append(env,
+			corev1.EnvVar{
+				Name:      "GIT_USERNAME",
+				ValueFrom: secretKeyRef(secretName, "username", &optional),
+			},
+			corev1.EnvVar{
+				Name:      "GIT_PASSWORD",
+				ValueFrom: secretKeyRef(secretName, "password", &optional),
+			},
+		)
+	}
+	env = append(env, corev1.EnvVar{
+		Name:  "GIT_REVISION",
+		Value: opts.GitSHA,
+	})
+	j := &v1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{},
+		},
+		Spec: v1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    "boot",
+							Image:   d.Image,
+							Command: d.Command,
+							Args:    d.Args,
+							Env:     env,
+						},
+					},
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: d.ServiceAccount,
+				},
+			},
+		},
+	}
+	if remote {
+		j.Annotations[launcher.GitSecretAnnotation] = secretName
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(j)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert the default Job")
+	}
+	u := &unstructured.Unstructured{Object: m}
+	u.SetAPIVersion("batch/v1")
+	u.SetKind("Job")
+	return u, nil
+}
+
+func secretKeyRef(name string, key string, optional *bool) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{
+				Name: name,
+			},
+			Key:      key,
+			Optional: optional,
+		},
+	}
+}
+
+// GitSecretName returns the name of the Secret containing the copy of the git URL and credentials of the repository
+// which its default Job references in a remote cluster
+func GitSecretName(r repo.Repository) string {
+	return naming.ToValidName(r.Name + "-git")
+}
+
+// GitSecret returns the Secret with the given name containing a copy of the `url`, `username` and `password` of the
+// repository for its default Job in a remote cluster. The lookup function returns the data of the `Secret` or
+// `ConfigMap` with the given name in the namespace of the repository
+func GitSecret(r repo.Repository, name string, ns string, lookup func(kind string, name string) (map[string][]byte, error)) (*corev1.Secret, error) {
+	data := map[string][]byte{}
+	secretName := r.Name
+	if r.Kind == repo.ConfigMapKind {
+		secretName = r.CredentialsSecret
+		values, err := lookup(repo.ConfigMapKind, r.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find the git URL of repository %s", r.Name)
+		}
+		data["url"] = values["url"]
+	}
+	if secretName != "" {
+		values, err := lookup("Secret", secretName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find the git credentials of repository %s", r.Name)
+		}
+		keys := []string{"url", "username", "password"}
+		if r.Kind == repo.ConfigMapKind {
+			keys = keys[1:]
+		}
+		for _, k := range keys {
+			if len(values[k]) > 0 {
+				data[k] = values[k]
+			}
+		}
+	}
+	if len(data["url"]) == 0 {
+		data["url"] = []byte(r.GitURL)
+	}
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+				launcher.RepositoryLabelKey:  naming.ToValidValue(r.Name),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package job
+
+import (
+	"encoding/json"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// injectJobDefaults injects the backoff limit and pod failure policy of the Job defaults into the Job if it does
+// not specify them. The pod failure policy is only injected into Jobs whose pods are never restarted as Kubernetes
+// rejects it otherwise
+func injectJobDefaults(defaults launcher.JobDefaults, resource *unstructured.Unstructured) error {
+	if !IsJobResource(resource) {
+		return nil
+	}
+	backoffLimit, ok, err := defaults.BackoffLimitValue()
+	if err != nil {
+		return err
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(resource.Object, "spec", "backoffLimit"); ok && !found {
+		err = unstructured.SetNestedField(resource.Object, backoffLimit, "spec", "backoffLimit")
+		if err != nil {
+			return errors.Wrapf(err, "failed to set the backoff limit")
+		}
+	}
+
+	policy, err := defaults.PodFailurePolicyValue()
+	if err != nil || policy == nil || hasPodFailurePolicy(resource) {
+		return err
+	}
+	restartPolicy, _, _ := unstructured.NestedString(resource.Object, "spec", "template", "spec", "restartPolicy")
+	if restartPolicy != "Never" {
+		log.Logger().Debugf("not injecting the pod failure policy into %s %s as its restartPolicy is not Never", resource.GetKind(), resource.GetName())
+		return nil
+	}
+	err = unstructured.SetNestedField(resource.Object, policy, "spec", "podFailurePolicy")
+	if err != nil {
+		return errors.Wrapf(err, "failed to set the pod failure policy")
+	}
+	return nil
+}
+
+// hasPodFailurePolicy returns true if the Job has a pod failure policy which the typed client does not support
+func hasPodFailurePolicy(resource *unstructured.Unstructured) bool {
+	_, found, _ := unstructured.NestedFieldNoCopy(resource.Object, "spec", "podFailurePolicy")
+	return found
+}
+
+// createUntypedJob creates the Job via the dynamic client so that any fields which the typed client does not
+// support are kept
+func createUntypedJob(clients *clusterClients, resource *unstructured.Unstructured, ns string) (*v1.Job, error) {
+	dynamicClient, err := clients.getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	u, err := dynamicClient.Resource(jobsResource).Namespace(ns).Create(resource, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	j, err := ToJob(u)
+	if err != nil {
+		return nil, err
+	}
+	if j.Namespace == "" {
+		j.Namespace = ns
+	}
+	return j, nil
+}
+
+// expireSupersededJobs sets the time to live of the Job defaults on the completed Jobs of the older commits of the
+// repository once a newer commit has been launched. The latest Jobs are never given a time to live as the launch of
+// their commit is only skipped while they exist. Failures are logged as the Jobs are expired by the next launch
+func expireSupersededJobs(defaults launcher.JobDefaults, clients *clusterClients, l *launched, ns string) {
+	ttl, ok := defaults.TTLSecondsAfterFinished()
+	if !ok || len(l.supersededJobs) == 0 {
+		return
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"ttlSecondsAfterFinished": ttl,
+		},
+	})
+	if err != nil {
+		log.Logger().Warnf("failed to marshal the time to live patch of Jobs: %s", err.Error())
+		return
+	}
+	for _, name := range l.supersededJobs {
+		_, err = clients.kubeClient.BatchV1().Jobs(ns).Patch(name, types.MergePatchType, data)
+		if err != nil {
+			log.Logger().Warnf("failed to set the time to live of superseded Job %s in namespace %s: %s", name, ns, err.Error())
+			continue
+		}
+		log.Logger().Infof("set the time to live of superseded Job %s in namespace %s to %ds", name, ns, ttl)
+	}
+}
@@ -0,0 +1,317 @@
+package job
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/podspecs"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/timing"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DeploymentLauncherName the name the Deployment launcher is registered with
+	DeploymentLauncherName = "deployment"
+
+	// DeploymentFileName the name of the file in the git operator folder containing the Deployment to launch
+	DeploymentFileName = "deployment.yaml"
+
+	// GitSHAEnvVar the environment variable set to the commit sha in the containers of a launched Deployment so
+	// that each commit rolls out new pods
+	GitSHAEnvVar = "GIT_SHA"
+
+	// restartedAtAnnotation the pod template annotation which restarts the pods of a Deployment when it changes
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+func init() {
+	launcher.Register(DeploymentLauncherName, func(o launcher.FactoryOptions) (launcher.Interface, error) {
+		l, err := NewDeploymentLauncher(o.KubeClient, o.DynamicClient, o.Namespace, o.Selector, o.CommandRunner)
+		if err != nil {
+			return nil, err
+		}
+		l.(*deploymentClient).jobs.limiter = o.RateLimiter
+		l.(*deploymentClient).jobs.noResourceApply = o.NoResourceApply
+		return l, nil
+	})
+}
+
+type deploymentClient struct {
+	jobs *client
+}
+
+// NewDeploymentLauncher creates a new launcher for long running reconcilers which, rather than creating a Job for
+// each commit, creates the Deployment in the `deployment.yaml` file of the git operator folder and rolls it to each
+// new commit by updating the commit sha label and `$GIT_SHA` environment variable of its pods.
+//
+// The resources of the repository are applied before the Deployment is rolled in the same way as the Job launcher
+func NewDeploymentLauncher(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, ns string, selector string, runner cmdrunner.CommandRunner) (launcher.Interface, error) {
+	l, err := NewLauncher(kubeClient, dynamicClient, ns, selector, runner)
+	if err != nil {
+		return nil, err
+	}
+	return &deploymentClient{jobs: l.(*client)}, nil
+}
+
+// Launch creates or rolls the Deployment of the repository in each of its clusters if the commit sha has changed
+func (c *deploymentClient) Launch(opts launcher.LaunchOptions) ([]runtime.Object, error) {
+	opts.NoResourceApply = opts.NoResourceApply || c.jobs.noResourceApply
+	ns := opts.Repository.Namespace
+	if ns == "" {
+		ns = c.jobs.ns
+	}
+	safeName := naming.ToValidValue(opts.Repository.Name)
+	safeSha := naming.ToValidValue(opts.GitSHA)
+
+	folder, err := FindGitOperatorFolder(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	fileName := filepath.Join(folder, DeploymentFileName)
+	resources, err := LoadResources(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load Deployment file %s in repository %s", fileName, safeName)
+	}
+	if len(resources) != 1 || resources[0].GetKind() != "Deployment" {
+		return nil, errors.Errorf("the file %s in repository %s should contain a single Deployment", fileName, safeName)
+	}
+	deployment := resources[0]
+	if deployment.GetName() == "" {
+		deployment.SetName(safeName)
+	}
+
+	clusters := opts.Repository.KubeConfigSecrets
+	if len(clusters) == 0 {
+		clusters = []string{""}
+	}
+	var answer []runtime.Object
+	var failed []string
+	for _, cluster := range clusters {
+		object, err := c.launchInCluster(opts, cluster, folder, deployment.DeepCopy(), ns, safeName, safeSha)
+		if object != nil {
+			answer = append(answer, object)
+		}
+		if launcher.IsOwnershipConflict(err) {
+			return answer, err
+		}
+		if err != nil {
+			if len(clusters) == 1 {
+				return answer, err
+			}
+			log.Logger().Warnf("failed to launch repository %s in cluster %s: %s", safeName, cluster, err.Error())
+			failed = append(failed, cluster)
+		}
+	}
+	if len(failed) > 0 {
+		return answer, errors.Errorf("failed to launch repository %s in %d of %d clusters: %s", safeName, len(failed), len(clusters), strings.Join(failed, ", "))
+	}
+	return answer, nil
+}
+
+// launchInCluster creates the Deployment or rolls it to the commit sha in the local cluster or the remote cluster
+// for the given kubeconfig Secret name
+func (c *deploymentClient) launchInCluster(opts launcher.LaunchOptions, cluster string, folder string, u *unstructured.Unstructured, ns string, safeName string, safeSha string) (runtime.Object, error) {
+	clients, err := c.jobs.clientsFor(opts.Repository, cluster, ns)
+	if err != nil {
+		return nil, err
+	}
+	defer clients.cleanup()
+
+	name := u.GetName()
+	deployInterface := clients.kubeClient.AppsV1().Deployments(ns)
+	existing, err := deployInterface.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Deployment %s in namespace %s", name, ns)
+		}
+		existing = nil
+	}
+	if existing != nil {
+		owner := existing.Annotations[repo.OwnerAnnotation]
+		if opts.Owner != "" && owner != "" && owner != opts.Owner {
+			return nil, &launcher.OwnershipConflictError{Repository: safeName, Owner: owner, Resource: name}
+		}
+		if existing.Labels[launcher.CommitShaLabelKey] == safeSha && !opts.Relaunch {
+			return nil, nil
+		}
+	}
+
+	err = c.jobs.validateTenant(opts, folder, []*unstructured.Unstructured{u}, ns, cluster)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := c.jobs.applyRepositoryResources(opts, clients, folder, ns, safeName, safeSha)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[constants.DefaultSelectorKey] = constants.DefaultSelectorValue
+	labels[launcher.RepositoryLabelKey] = safeName
+	labels[launcher.CommitShaLabelKey] = safeSha
+	if clients.name != "" {
+		labels[launcher.ClusterLabelKey] = naming.ToValidValue(clients.name)
+	}
+	u.SetLabels(labels)
+
+	annotations := opts.Commit.Annotations(u.GetAnnotations())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if diff != "" {
+		annotations[launcher.DiffAnnotation] = trimDiff(diff)
+	}
+	if opts.Changelog != "" {
+		annotations[launcher.ChangelogAnnotation] = opts.Changelog
+	}
+	if opts.Owner != "" {
+		annotations[repo.OwnerAnnotation] = opts.Owner
+	}
+	u.SetAnnotations(annotations)
+
+	err = rollPodTemplate(u, opts.GitSHA, safeSha, opts.Relaunch)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to roll Deployment %s to sha %s", name, safeSha)
+	}
+	err = injectPodSpecDefaults(opts, u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to modify Deployment %s", name)
+	}
+
+	d := &appsv1.Deployment{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, d)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert to a Deployment")
+	}
+	d.Namespace = ns
+
+	var answer *appsv1.Deployment
+	start := time.Now()
+	err = retryTransient("apply of Deployment "+name, func() error {
+		c.jobs.limiter.Wait()
+		if existing == nil {
+			answer, err = deployInterface.Create(d)
+		} else {
+			d.ResourceVersion = existing.ResourceVersion
+			answer, err = deployInterface.Update(d)
+		}
+		c.jobs.limiter.Observe(err)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to apply Deployment %s in namespace %s", name, ns)
+	}
+	opts.Timings.Since(timing.PhaseCreate, start)
+	if existing == nil {
+		log.Logger().Infof("created Deployment %s in namespace %s for sha %s", name, ns, safeSha)
+	} else {
+		log.Logger().Infof("rolled Deployment %s in namespace %s to sha %s", name, ns, safeSha)
+	}
+	return answer, nil
+}
+
+// GarbageCollect deletes the applied resources of any repositories which are not in the given list
+func (c *deploymentClient) GarbageCollect(repositories []repo.Repository) (int, error) {
+	return c.jobs.GarbageCollect(repositories)
+}
+
+// Cleanup deletes the Deployments launched for the repository along with its applied resources in each of the
+// clusters the repository is launched in
+func (c *deploymentClient) Cleanup(r repo.Repository) error {
+	ns := r.Namespace
+	if ns == "" {
+		ns = c.jobs.ns
+	}
+	safeName := naming.ToValidValue(r.Name)
+
+	clusters := r.KubeConfigSecrets
+	if len(clusters) == 0 {
+		clusters = []string{""}
+	}
+	for _, cluster := range clusters {
+		clients, err := c.jobs.clientsFor(r, cluster, ns)
+		if err != nil {
+			return err
+		}
+		selector := c.jobs.repositorySelector(safeName, cluster)
+		deployInterface := clients.kubeClient.AppsV1().Deployments(ns)
+		list, err := deployInterface.List(metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			clients.cleanup()
+			return errors.Wrapf(err, "failed to find Deployments in namespace %s with selector %s", ns, selector)
+		}
+		for _, d := range list.Items {
+			err = deployInterface.Delete(d.Name, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				clients.cleanup()
+				return errors.Wrapf(err, "failed to delete Deployment %s in namespace %s", d.Name, ns)
+			}
+			log.Logger().Infof("deleted Deployment %s in namespace %s of removed repository %s", d.Name, ns, safeName)
+		}
+		clients.cleanup()
+	}
+	return c.jobs.Cleanup(r)
+}
+
+// rollPodTemplate labels the pod template with the commit sha and sets the `$GIT_SHA` environment variable of its
+// containers so that the pods are replaced for each commit. If relaunched the pods are restarted
+func rollPodTemplate(u *unstructured.Unstructured, sha string, safeSha string, relaunch bool) error {
+	err := unstructured.SetNestedField(u.Object, safeSha, "spec", "template", "metadata", "labels", launcher.CommitShaLabelKey)
+	if err != nil {
+		return err
+	}
+	if relaunch {
+		err = unstructured.SetNestedField(u.Object, time.Now().UTC().Format(time.RFC3339), "spec", "template", "metadata", "annotations", restartedAtAnnotation)
+		if err != nil {
+			return err
+		}
+	}
+	return podspecs.Modify(u, func(podSpec map[string]interface{}) error {
+		containers, _, err := unstructured.NestedSlice(podSpec, "containers")
+		if err != nil {
+			return err
+		}
+		for i := range containers {
+			container, ok := containers[i].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			env, _, err := unstructured.NestedSlice(container, "env")
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, e := range env {
+				if m, ok := e.(map[string]interface{}); ok && m["name"] == GitSHAEnvVar {
+					m["value"] = sha
+					found = true
+				}
+			}
+			if !found {
+				env = append(env, map[string]interface{}{"name": GitSHAEnvVar, "value": sha})
+			}
+			container["env"] = env
+		}
+		podSpec["containers"] = containers
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package job_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeploymentLauncher(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	runner := &fakerunner.FakeRunner{}
+
+	client, err := job.NewDeploymentLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "sha1",
+		Dir:    filepath.Join("test_data", "deployment"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the Deployment")
+	require.Len(t, objects, 1, "should have created the Deployment")
+	assertDeploymentSha(t, kubeClient, ns, "sha1")
+	require.Len(t, runner.OrderedCommands, 2, "should have previewed and applied the resources")
+
+	jobs, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	assert.Len(t, jobs.Items, 0, "should not have created any Jobs")
+
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the Deployment")
+	assert.Len(t, objects, 0, "should not roll the Deployment for the same sha")
+
+	o.GitSHA = "sha2"
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the Deployment")
+	require.Len(t, objects, 1, "should have rolled the Deployment")
+	d := assertDeploymentSha(t, kubeClient, ns, "sha2")
+	assert.Equal(t, "reconciler", d.Spec.Template.Labels["app"], "should keep the pod template labels")
+	assert.Empty(t, d.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"], "should not restart the pods")
+
+	o.Relaunch = true
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to relaunch the Deployment")
+	require.Len(t, objects, 1, "should have restarted the Deployment")
+	d = assertDeploymentSha(t, kubeClient, ns, "sha2")
+	assert.NotEmpty(t, d.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"], "should restart the pods")
+
+	err = client.(launcher.Cleaner).Cleanup(o.Repository)
+	require.NoError(t, err, "failed to clean up the repository")
+	deployments, err := kubeClient.AppsV1().Deployments(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Deployments")
+	assert.Len(t, deployments.Items, 0, "should have deleted the Deployment")
+}
+
+func assertDeploymentSha(t *testing.T, kubeClient *fake.Clientset, ns string, sha string) *appsv1.Deployment {
+	d, err := kubeClient.AppsV1().Deployments(ns).Get("reconciler", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get Deployment")
+	testhelpers.AssertLabel(t, launcher.CommitShaLabelKey, sha, d.ObjectMeta, "Deployment")
+	assert.Equal(t, sha, d.Spec.Template.Labels[launcher.CommitShaLabelKey], "pod template sha label")
+
+	require.Len(t, d.Spec.Template.Spec.Containers, 1, "containers")
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: job.GitSHAEnvVar, Value: sha}, "container env")
+	return d
+}
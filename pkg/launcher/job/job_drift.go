@@ -0,0 +1,108 @@
+package job
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DetectDrift compares the live state of the resources applied from the chart, helmfile or `resources` directory of
+// the repository with the content at the given commit sha using `kubectl diff` which performs a server side dry run.
+//
+// Drift is only checked once the resources for the commit sha have been launched and are no longer active
+func (c *client) DetectDrift(opts launcher.LaunchOptions) (*launcher.Drift, error) {
+	answer := &launcher.Drift{}
+	if opts.NoResourceApply || c.noResourceApply {
+		return answer, nil
+	}
+	ns := opts.Repository.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	safeName := naming.ToValidValue(opts.Repository.Name)
+	safeSha := naming.ToValidValue(opts.GitSHA)
+
+	folder, resources, err := LoadLaunchResources(opts)
+	if err != nil {
+		return nil, err
+	}
+	clusters := opts.Repository.KubeConfigSecrets
+	if len(clusters) == 0 {
+		clusters = []string{""}
+	}
+	for _, cluster := range clusters {
+		diff, err := c.detectDriftInCluster(opts, cluster, folder, resources, ns, safeName, safeSha)
+		if err != nil {
+			return nil, err
+		}
+		if diff != "" {
+			answer.Drifted = true
+			answer.Diff += diff + "\n"
+		}
+	}
+	return answer, nil
+}
+
+// detectDriftInCluster returns the differences between the live state of the given cluster and the resources applied
+// from the git operator folder
+func (c *client) detectDriftInCluster(opts launcher.LaunchOptions, cluster string, folder string, resources []*unstructured.Unstructured, ns string, safeName string, safeSha string) (string, error) {
+	clients, err := c.clientsFor(opts.Repository, cluster, ns)
+	if err != nil {
+		return "", err
+	}
+	defer clients.cleanup()
+
+	l, err := c.findLaunched(clients, resources, ns, c.repositorySelector(safeName, cluster), safeSha, opts.Owner)
+	if err != nil {
+		return "", err
+	}
+	if l.conflict != nil {
+		log.Logger().Warnf("not checking drift of repository %s as its resource %s was launched by another operator %s", safeName, l.conflict.Resource, l.conflict.Owner)
+		return "", nil
+	}
+	if !l.foundSha || l.activeName != "" {
+		log.Logger().Infof("not checking drift of repository %s sha %s as it has not completed launching", safeName, safeSha)
+		return "", nil
+	}
+
+	applyResources, source, err := LoadApplyResources(c.runner, folder, safeName, ns, cluster)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load resources to apply in repository %s", safeName)
+	}
+	if applyResources == nil {
+		return "", nil
+	}
+	d, err := newApplyDir(applyResources, safeName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to save resources of %s in repository %s", source, safeName)
+	}
+	defer d.cleanup()
+
+	return c.diffResources(d.allDir(), clients.kubeConfigFile)
+}
+
+// diffResources returns the differences between the live state of the cluster and the resources in the given
+// directory using `kubectl diff` or an empty string if there are no differences
+func (c *client) diffResources(dir string, kubeConfigFile string) (string, error) {
+	text, err := c.kubectl(kubeConfigFile, "diff", "-f", dir)
+	if err != nil {
+		if !IsDiffFound(err) {
+			return "", errors.Wrapf(err, "failed to diff resources in dir %s", dir)
+		}
+		if text == "" {
+			text = "resources differ"
+		}
+		return text, nil
+	}
+	return "", nil
+}
+
+// IsDiffFound returns true if the error is caused by `kubectl diff` exiting with status 1 which indicates
+// that differences were found rather than a failure to run the diff. Errors of replayed commands which record
+// their exit code are also supported
+func IsDiffFound(err error) bool {
+	exitErr, ok := errors.Cause(err).(interface{ ExitCode() int })
+	return ok && exitErr.ExitCode() == 1
+}
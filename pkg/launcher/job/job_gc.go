@@ -0,0 +1,137 @@
+package job
+
+import (
+	"sort"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GarbageCollect deletes the resources recorded in the inventories of any repositories which are not in the given
+// list. Only the inventories in the local cluster which were recorded by this operator instance and selector are
+// garbage collected, keyed by the namespace and name of their repository.
+//
+// Nothing is deleted if the launcher never applies resources or if the list of repositories is empty
+func (c *client) GarbageCollect(repositories []repo.Repository) (int, error) {
+	if c.noResourceApply {
+		return 0, nil
+	}
+	if len(repositories) == 0 {
+		log.Logger().Warnf("not garbage collecting the applied resources as no repositories were found")
+		return 0, nil
+	}
+	keys := map[string]bool{}
+	for _, r := range repositories {
+		ns := r.Namespace
+		if ns == "" {
+			ns = c.ns
+		}
+		keys[ns+"/"+naming.ToValidValue(r.Name)] = true
+	}
+
+	// lets look in every namespace so that the inventories of repositories removed from other namespaces are found
+	entries, err := inventory.GarbageCollect(c.kubeClient, c.runner, "", c.owner, c.selector, keys)
+	if err == nil || !apierrors.IsForbidden(errors.Cause(err)) {
+		return len(entries), err
+	}
+
+	// otherwise fall back to the namespaces of the current repositories and those seen previously
+	count := 0
+	for _, ns := range c.gcNamespacesFor(repositories) {
+		entries, err := inventory.GarbageCollect(c.kubeClient, c.runner, ns, c.owner, c.selector, keys)
+		count += len(entries)
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// gcNamespacesFor returns the namespaces to garbage collect in when the inventories of every namespace cannot be
+// listed. The namespaces of the given repositories are remembered so that a repository removed from a namespace
+// with no other repositories is still garbage collected
+func (c *client) gcNamespacesFor(repositories []repo.Repository) []string {
+	c.gcLock.Lock()
+	defer c.gcLock.Unlock()
+
+	if c.gcNamespaces == nil {
+		c.gcNamespaces = map[string]bool{}
+	}
+	c.gcNamespaces[c.ns] = true
+	for _, r := range repositories {
+		if r.Namespace != "" {
+			c.gcNamespaces[r.Namespace] = true
+		}
+	}
+	var answer []string
+	for ns := range c.gcNamespaces {
+		answer = append(answer, ns)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// Cleanup deletes the Jobs launched for the repository and the resources recorded in its inventory in each of the
+// clusters the repository is launched in
+func (c *client) Cleanup(r repo.Repository) error {
+	ns := r.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	safeName := naming.ToValidValue(r.Name)
+
+	clusters := r.KubeConfigSecrets
+	if len(clusters) == 0 {
+		clusters = []string{""}
+	}
+	for _, cluster := range clusters {
+		err := c.cleanupCluster(r, cluster, ns, safeName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupCluster deletes the Jobs and applied resources of the repository in the given cluster
+func (c *client) cleanupCluster(r repo.Repository, cluster string, ns string, safeName string) error {
+	clients, err := c.clientsFor(r, cluster, ns)
+	if err != nil {
+		return err
+	}
+	defer clients.cleanup()
+
+	selector := c.repositorySelector(safeName, cluster)
+	jobInterface := clients.kubeClient.BatchV1().Jobs(ns)
+	jobs, err := c.listJobs(clients, ns, selector)
+	if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	for _, j := range jobs {
+		err = jobInterface.Delete(j.Name, &metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete Job %s in namespace %s", j.Name, ns)
+		}
+		log.Logger().Infof("deleted Job %s in namespace %s of removed repository %s", j.Name, ns, safeName)
+	}
+
+	if c.noResourceApply {
+		return nil
+	}
+	entries, err := inventory.Remove(clients.kubeClient, c.runner, ns, c.owner, safeName, clients.kubeConfigFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove the applied resources of repository %s", safeName)
+	}
+	if len(entries) > 0 {
+		log.Logger().Infof("deleted %d applied resources of removed repository %s", len(entries), safeName)
+	}
+	return nil
+}
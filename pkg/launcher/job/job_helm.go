@@ -0,0 +1,116 @@
+package job
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// ChartFileName the name of the file which indicates the git operator folder is a helm chart
+	ChartFileName = "Chart.yaml"
+
+	// HelmfileFileName the name of the file which indicates the git operator folder contains a helmfile
+	HelmfileFileName = "helmfile.yaml"
+
+	// ResourcesDirName the name of the directory of raw YAML resources in the git operator folder
+	ResourcesDirName = "resources"
+
+	// NamespacesDirName the name of the directory within the resources directory containing a directory per target
+	// namespace such as `resources/namespaces/<namespace>/*.yaml`
+	NamespacesDirName = "namespaces"
+)
+
+// LoadApplyResources loads the resources in the git operator folder which are applied before the Job is launched in
+// the given cluster, where an empty cluster is the local cluster.
+//
+// If the folder contains a `Chart.yaml` the chart is rendered via `helm template` or if it contains a `helmfile.yaml`
+// it is rendered via `helmfile template`; otherwise the YAML files in the `resources` directory are loaded, applying
+// the namespace of any `resources/namespaces/<namespace>` directory to the namespaced resources within it. When
+// rendering, the values file `values-<cluster>.yaml` in the folder is also used if it exists so that the values can
+// differ per cluster.
+//
+// The source describes where the resources were loaded from. If there are no resources to apply nil is returned
+func LoadApplyResources(runner cmdrunner.CommandRunner, folder string, release string, ns string, cluster string) ([]*unstructured.Unstructured, string, error) {
+	for _, name := range []string{ChartFileName, HelmfileFileName} {
+		fileName := filepath.Join(folder, name)
+		exists, err := files.FileExists(fileName)
+		if err != nil {
+			return nil, fileName, errors.Wrapf(err, "failed to check if file %s exists", fileName)
+		}
+		if exists {
+			resources, err := renderResources(runner, folder, name, release, ns, cluster)
+			return resources, fileName, err
+		}
+	}
+
+	resourcesDir := filepath.Join(folder, ResourcesDirName)
+	exists, err := files.DirExists(resourcesDir)
+	if err != nil {
+		return nil, resourcesDir, errors.Wrapf(err, "failed to check if resources directory %s exists", resourcesDir)
+	}
+	if !exists {
+		return nil, resourcesDir, nil
+	}
+	resources, err := loadResourcesDirWithNamespaces(resourcesDir)
+	return resources, resourcesDir, err
+}
+
+// renderResources renders the chart or helmfile in the folder into a temporary directory and loads the resources
+func renderResources(runner cmdrunner.CommandRunner, folder string, fileName string, release string, ns string, cluster string) ([]*unstructured.Unstructured, error) {
+	if runner == nil {
+		runner = cmdrunner.DefaultCommandRunner
+	}
+	outDir, err := ioutil.TempDir("", "jx-git-operator-render-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create temp dir")
+	}
+	defer func() {
+		err := os.RemoveAll(outDir)
+		if err != nil {
+			log.Logger().Warnf("failed to remove temporary dir %s: %s", outDir, err.Error())
+		}
+	}()
+
+	valuesFile := ""
+	if cluster != "" {
+		valuesFile = "values-" + cluster + ".yaml"
+		exists, err := files.FileExists(filepath.Join(folder, valuesFile))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check if values file %s exists in %s", valuesFile, folder)
+		}
+		if !exists {
+			valuesFile = ""
+		}
+	}
+
+	cmd := &cmdrunner.Command{
+		Dir: folder,
+	}
+	if fileName == ChartFileName {
+		cmd.Name = "helm"
+		cmd.Args = []string{"template", release, ".", "--namespace", ns, "--include-crds", "--output-dir", outDir}
+		if valuesFile != "" {
+			cmd.Args = append(cmd.Args, "--values", valuesFile)
+		}
+	} else {
+		cmd.Name = "helmfile"
+		cmd.Args = []string{"--file", fileName, "--namespace", ns}
+		if valuesFile != "" {
+			cmd.Args = append(cmd.Args, "--state-values-file", valuesFile)
+		}
+		cmd.Args = append(cmd.Args, "template", "--output-dir", outDir)
+	}
+	log.Logger().Infof("running command: %s", cmd.CLI())
+	_, err = runner(cmd)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render %s in %s", fileName, folder)
+	}
+	return LoadResourcesDir(outDir)
+}
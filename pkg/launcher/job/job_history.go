@@ -0,0 +1,261 @@
+package job
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// History returns the Jobs launched for the repository with the most recent first.
+//
+// If the repository is launched in remote clusters the Jobs of each cluster are combined into one record per
+// launch of a commit sha whose result is the aggregate result of the clusters
+func (c *client) History(r repo.Repository) ([]launcher.LaunchRecord, error) {
+	ns := r.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	safeName := naming.ToValidValue(r.Name)
+	if len(r.KubeConfigSecrets) == 0 {
+		answer, err := c.clusterHistory(c.kubeClient, ns, c.repositorySelector(safeName, ""), "")
+		if err != nil {
+			return nil, err
+		}
+		sortHistory(answer)
+		return answer, nil
+	}
+
+	var records []launcher.LaunchRecord
+	var failed []string
+	for _, cluster := range r.KubeConfigSecrets {
+		_, kubeClient, _, err := c.remoteClients(r, cluster, ns)
+		if err == nil {
+			var clusterRecords []launcher.LaunchRecord
+			clusterRecords, err = c.clusterHistory(kubeClient, ns, c.repositorySelector(safeName, cluster), cluster)
+			records = append(records, clusterRecords...)
+		}
+		if err != nil {
+			log.Logger().Warnf("failed to find the Jobs of repository %s in cluster %s: %s", safeName, cluster, err.Error())
+			failed = append(failed, cluster)
+		}
+	}
+	if len(failed) == len(r.KubeConfigSecrets) {
+		return nil, errors.Errorf("failed to find the Jobs of repository %s in any of its clusters: %s", safeName, strings.Join(failed, ", "))
+	}
+	return aggregateHistory(records), nil
+}
+
+// clusterHistory returns the records of the Jobs launched for the repository in a cluster
+func (c *client) clusterHistory(kubeClient kubernetes.Interface, ns string, selector string, cluster string) ([]launcher.LaunchRecord, error) {
+	jobs, err := c.listJobs(&clusterClients{kubeClient: kubeClient, local: c}, ns, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	// the post-success Jobs are part of the launch of their commit sha
+	posts := map[string]v1.Job{}
+	for _, j := range jobs {
+		if naming.ToValidValue(cluster) == j.Labels[launcher.ClusterLabelKey] && j.Labels[launcher.PostJobLabelKey] == "true" {
+			posts[j.Labels[launcher.CommitShaLabelKey]] = j
+		}
+	}
+
+	var answer []launcher.LaunchRecord
+	for _, j := range jobs {
+		if naming.ToValidValue(cluster) != j.Labels[launcher.ClusterLabelKey] || j.Labels[launcher.PostJobLabelKey] == "true" {
+			continue
+		}
+		record := launcher.LaunchRecord{
+			Name:      j.Name,
+			Namespace: ns,
+			Kind:      "Job",
+			GitSHA:    j.Labels[launcher.CommitShaLabelKey],
+			Created:   j.CreationTimestamp.Time,
+			Result:    JobResult(j),
+			Rollback:  j.Labels[launcher.RollbackLabelKey] == "true",
+			Cluster:   cluster,
+		}
+		if j.Status.StartTime != nil {
+			started := j.Status.StartTime.Time
+			record.Started = &started
+		}
+		completed := JobCompletionTime(j)
+		if post, ok := posts[record.GitSHA]; ok && !record.Rollback && record.Result == launcher.ResultSucceeded {
+			record.PostJob = post.Name
+			record.Result = JobResult(post)
+			completed = JobCompletionTime(post)
+		}
+		if !completed.IsZero() {
+			record.Completed = &completed
+		}
+		answer = append(answer, record)
+	}
+	return answer, nil
+}
+
+// aggregateHistory combines the records of the remote clusters into one record per launch of a commit sha.
+//
+// The nth launch of a commit sha in each cluster is part of the same launch. The launch has failed if any cluster
+// failed, is active if any cluster is still active and has only completed once every cluster has completed
+func aggregateHistory(records []launcher.LaunchRecord) []launcher.LaunchRecord {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Created.Before(records[j].Created)
+	})
+	var answer []*launcher.LaunchRecord
+	launches := map[string]*launcher.LaunchRecord{}
+	attempts := map[string]int{}
+	for _, record := range records {
+		key := fmt.Sprintf("%s/%t", record.GitSHA, record.Rollback)
+		attempt := attempts[record.Cluster+"/"+key]
+		attempts[record.Cluster+"/"+key] = attempt + 1
+		key = fmt.Sprintf("%s/%d", key, attempt)
+
+		launch := launches[key]
+		if launch == nil {
+			launch = &launcher.LaunchRecord{
+				Name:      record.Name,
+				Namespace: record.Namespace,
+				Kind:      record.Kind,
+				GitSHA:    record.GitSHA,
+				Created:   record.Created,
+				Result:    record.Result,
+				Started:   record.Started,
+				Completed: record.Completed,
+				Rollback:  record.Rollback,
+				PostJob:   record.PostJob,
+			}
+			launches[key] = launch
+			answer = append(answer, launch)
+		} else {
+			if launch.Started == nil || (record.Started != nil && record.Started.Before(*launch.Started)) {
+				launch.Started = record.Started
+			}
+			if launch.Completed != nil && (record.Completed == nil || record.Completed.After(*launch.Completed)) {
+				launch.Completed = record.Completed
+			}
+			switch {
+			case launch.Result == launcher.ResultFailed || record.Result == launcher.ResultFailed:
+				launch.Result = launcher.ResultFailed
+			case launch.Result == launcher.ResultActive || record.Result == launcher.ResultActive:
+				launch.Result = launcher.ResultActive
+			}
+		}
+		launch.Clusters = append(launch.Clusters, record)
+	}
+
+	results := make([]launcher.LaunchRecord, 0, len(answer))
+	for _, launch := range answer {
+		results = append(results, *launch)
+	}
+	sortHistory(results)
+	return results
+}
+
+// sortHistory sorts the records with the most recent first
+func sortHistory(records []launcher.LaunchRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Created.After(records[j].Created)
+	})
+}
+
+// Logs returns the logs of the containers of the pods of the given Job of the repository.
+//
+// If the repository is launched in remote clusters the logs of the Job in each cluster are returned
+func (c *client) Logs(r repo.Repository, name string) (string, error) {
+	ns := r.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	if len(r.KubeConfigSecrets) == 0 {
+		return jobLogs(c.kubeClient, r, ns, name)
+	}
+
+	buf := strings.Builder{}
+	found := false
+	for _, cluster := range r.KubeConfigSecrets {
+		_, kubeClient, _, err := c.remoteClients(r, cluster, ns)
+		if err != nil {
+			return buf.String(), err
+		}
+		_, err = kubeClient.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		found = true
+		buf.WriteString(fmt.Sprintf("==> cluster %s <==\n", cluster))
+		logs, err := jobLogs(kubeClient, r, ns, name)
+		buf.WriteString(logs)
+		if err != nil {
+			return buf.String(), errors.Wrapf(err, "failed to get the logs in cluster %s", cluster)
+		}
+	}
+	if !found {
+		return "", errors.Errorf("failed to find Job %s in namespace %s in any cluster of repository %s", name, ns, r.Name)
+	}
+	return buf.String(), nil
+}
+
+// jobLogs returns the logs of the containers of the pods of the given Job of the repository in a cluster
+func jobLogs(kubeClient kubernetes.Interface, r repo.Repository, ns string, name string) (string, error) {
+	j, err := kubeClient.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get Job %s in namespace %s", name, ns)
+	}
+	if j.Labels[launcher.RepositoryLabelKey] != naming.ToValidValue(r.Name) {
+		return "", errors.Errorf("Job %s in namespace %s was not launched for repository %s", name, ns, r.Name)
+	}
+
+	selector := "job-name=" + name
+	pods, err := kubeClient.CoreV1().Pods(ns).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find pods in namespace %s with selector %s", ns, selector)
+	}
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+
+	buf := strings.Builder{}
+	for _, pod := range pods.Items {
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, container := range containers {
+			data, err := kubeClient.CoreV1().Pods(ns).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: container.Name,
+			}).DoRaw()
+			if err != nil {
+				return buf.String(), errors.Wrapf(err, "failed to get the logs of container %s of pod %s in namespace %s", container.Name, pod.Name, ns)
+			}
+			buf.WriteString(fmt.Sprintf("==> pod %s container %s <==\n", pod.Name, container.Name))
+			buf.Write(data)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String(), nil
+}
+
+// JobCompletionTime returns when the Job succeeded or failed or the zero time if it has not completed
+func JobCompletionTime(j v1.Job) time.Time {
+	var answer time.Time
+	if j.Status.CompletionTime != nil {
+		answer = j.Status.CompletionTime.Time
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Type == v1.JobFailed && c.Status == corev1.ConditionTrue {
+			answer = c.LastTransitionTime.Time
+		}
+	}
+	return answer
+}
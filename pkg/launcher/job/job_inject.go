@@ -0,0 +1,282 @@
+package job
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/podspecs"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// containerPaths the keys of the containers in a pod spec
+var containerPaths = []string{"initContainers", "containers"}
+
+// injectPodSpecDefaults injects any operator configured defaults into the pod specs of the resource
+func injectPodSpecDefaults(opts launcher.LaunchOptions, resource *unstructured.Unstructured) error {
+	env, err := opts.Inject.EnvVars()
+	if err != nil {
+		return err
+	}
+	envFrom, err := opts.Inject.EnvFromSources()
+	if err != nil {
+		return err
+	}
+	mirrors, err := opts.Inject.Mirrors()
+	if err != nil {
+		return err
+	}
+	podSecurityContext, err := opts.Inject.PodSecurityContextValue()
+	if err != nil {
+		return err
+	}
+	securityContext, err := opts.Inject.SecurityContextValue()
+	if err != nil {
+		return err
+	}
+	openShift := opts.Inject.OpenShift()
+	caBundle := opts.Inject.CABundleConfigMap
+	return podspecs.Modify(resource, func(podSpec map[string]interface{}) error {
+		if opts.PriorityClassName != "" && podSpec["priorityClassName"] == nil {
+			podSpec["priorityClassName"] = opts.PriorityClassName
+		}
+		err := injectImagePullSecrets(podSpec, opts.Inject.ImagePullSecrets)
+		if err != nil {
+			return errors.Wrapf(err, "failed to inject image pull secrets into %s %s", resource.GetKind(), resource.GetName())
+		}
+		if podSecurityContext != nil && podSpec["securityContext"] == nil {
+			podSpec["securityContext"] = runtime.DeepCopyJSON(podSecurityContext)
+		}
+		if openShift {
+			removeFixedIDs(podSpec, "securityContext", podSecurityContextIDs)
+		}
+		if caBundle != "" {
+			err = injectCABundleVolume(podSpec, caBundle)
+			if err != nil {
+				return errors.Wrapf(err, "failed to inject the CA bundle volume into %s %s", resource.GetKind(), resource.GetName())
+			}
+		}
+		if len(env) == 0 && len(envFrom) == 0 && len(mirrors) == 0 && securityContext == nil && !openShift && caBundle == "" {
+			return nil
+		}
+		for _, key := range containerPaths {
+			containers, _, err := unstructured.NestedSlice(podSpec, key)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get %s of %s %s", key, resource.GetKind(), resource.GetName())
+			}
+			for i := range containers {
+				container, ok := containers[i].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if image, ok := container["image"].(string); ok {
+					container["image"] = launcher.MirrorImage(image, mirrors)
+				}
+				if securityContext != nil && container["securityContext"] == nil {
+					container["securityContext"] = runtime.DeepCopyJSON(securityContext)
+				}
+				if openShift {
+					removeFixedIDs(container, "securityContext", containerSecurityContextIDs)
+				}
+				err = injectContainerEnv(container, env, envFrom)
+				if err != nil {
+					return errors.Wrapf(err, "failed to inject environment variables into %s %s", resource.GetKind(), resource.GetName())
+				}
+				if caBundle != "" {
+					err = injectCABundleMount(container)
+					if err != nil {
+						return errors.Wrapf(err, "failed to inject the CA bundle volume mount into %s %s", resource.GetKind(), resource.GetName())
+					}
+				}
+			}
+			if len(containers) > 0 {
+				podSpec[key] = containers
+			}
+		}
+		return nil
+	})
+}
+
+// injectCABundleVolume adds the volume of the CA bundle ConfigMap unless the pod spec already has a volume of the
+// same name
+func injectCABundleVolume(podSpec map[string]interface{}, configMap string) error {
+	volumes, _, err := unstructured.NestedSlice(podSpec, "volumes")
+	if err != nil {
+		return err
+	}
+	if hasNamedItem(volumes, launcher.CABundleVolumeName) {
+		return nil
+	}
+	podSpec["volumes"] = append(volumes, map[string]interface{}{
+		"name": launcher.CABundleVolumeName,
+		"configMap": map[string]interface{}{
+			"name": configMap,
+		},
+	})
+	return nil
+}
+
+// injectCABundleMount mounts the CA bundle volume into the container unless the container already mounts it or
+// something else at the same path
+func injectCABundleMount(container map[string]interface{}) error {
+	mounts, _, err := unstructured.NestedSlice(container, "volumeMounts")
+	if err != nil {
+		return err
+	}
+	if hasNamedItem(mounts, launcher.CABundleVolumeName) {
+		return nil
+	}
+	for _, m := range mounts {
+		if mm, ok := m.(map[string]interface{}); ok && mm["mountPath"] == launcher.CABundleMountPath {
+			return nil
+		}
+	}
+	container["volumeMounts"] = append(mounts, map[string]interface{}{
+		"name":      launcher.CABundleVolumeName,
+		"mountPath": launcher.CABundleMountPath,
+		"readOnly":  true,
+	})
+	return nil
+}
+
+// hasNamedItem returns true if one of the items has the given name
+func hasNamedItem(items []interface{}, name string) bool {
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok && m["name"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// podSecurityContextIDs the fields of a pod security context which fix the user or group IDs of the pod
+var podSecurityContextIDs = []string{"runAsUser", "runAsGroup", "fsGroup", "supplementalGroups"}
+
+// containerSecurityContextIDs the fields of a container security context which fix the user or group IDs of the
+// container
+var containerSecurityContextIDs = []string{"runAsUser", "runAsGroup"}
+
+// removeFixedIDs removes the fixed user and group IDs from the security context at the given key of the pod spec or
+// container as the security context constraints of OpenShift reject the IDs outside of the range of the namespace and
+// assign them instead
+func removeFixedIDs(m map[string]interface{}, key string, fields []string) {
+	sc, ok := m[key].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, field := range fields {
+		delete(sc, field)
+	}
+}
+
+// injectBootInventoryEnv tells the containers of a Job which ConfigMap they can write the inventory of the resources
+// they changed to so that the changes can be traced back to the commit
+func injectBootInventoryEnv(resource *unstructured.Unstructured) error {
+	if resource.GetKind() != "Job" {
+		return nil
+	}
+	env := []corev1.EnvVar{
+		{
+			Name:  inventory.BootEnvVar,
+			Value: inventory.BootConfigMapName(resource.GetName()),
+		},
+	}
+	return podspecs.Modify(resource, func(podSpec map[string]interface{}) error {
+		for _, key := range containerPaths {
+			containers, _, err := unstructured.NestedSlice(podSpec, key)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get %s of %s %s", key, resource.GetKind(), resource.GetName())
+			}
+			for i := range containers {
+				container, ok := containers[i].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				err = injectContainerEnv(container, env, nil)
+				if err != nil {
+					return errors.Wrapf(err, "failed to inject the inventory environment variable into %s %s", resource.GetKind(), resource.GetName())
+				}
+			}
+			if len(containers) > 0 {
+				podSpec[key] = containers
+			}
+		}
+		return nil
+	})
+}
+
+// injectImagePullSecrets adds the image pull secrets which the pod spec does not already reference
+func injectImagePullSecrets(podSpec map[string]interface{}, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	existing, _, err := unstructured.NestedSlice(podSpec, "imagePullSecrets")
+	if err != nil {
+		return err
+	}
+	found := map[string]bool{}
+	for _, s := range existing {
+		if m, ok := s.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				found[name] = true
+			}
+		}
+	}
+	for _, name := range names {
+		if !found[name] {
+			existing = append(existing, map[string]interface{}{"name": name})
+			found[name] = true
+		}
+	}
+	podSpec["imagePullSecrets"] = existing
+	return nil
+}
+
+// injectContainerEnv adds the environment variables which the container does not already define so that the
+// `job.yaml` of a repository can override them. The envFrom sources are added before those of the container
+// so that the sources of the container take precedence
+func injectContainerEnv(container map[string]interface{}, env []corev1.EnvVar, envFrom []corev1.EnvFromSource) error {
+	existing, _, err := unstructured.NestedSlice(container, "env")
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, e := range existing {
+		if m, ok := e.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				names[name] = true
+			}
+		}
+	}
+	for _, e := range env {
+		if names[e.Name] {
+			continue
+		}
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&e)
+		if err != nil {
+			return err
+		}
+		existing = append(existing, m)
+	}
+	if len(existing) > 0 {
+		container["env"] = existing
+	}
+
+	if len(envFrom) > 0 {
+		var sources []interface{}
+		for i := range envFrom {
+			m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&envFrom[i])
+			if err != nil {
+				return err
+			}
+			sources = append(sources, m)
+		}
+		existingFrom, _, err := unstructured.NestedSlice(container, "envFrom")
+		if err != nil {
+			return err
+		}
+		container["envFrom"] = append(sources, existingFrom...)
+	}
+	return nil
+}
@@ -3,35 +3,84 @@ package job
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/kube"
 	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/timing"
 	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/pkg/files"
 	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
-	"github.com/jenkins-x/jx-helpers/pkg/yamls"
 	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
 	"github.com/jenkins-x/jx-logging/pkg/log"
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 
 	v1 "k8s.io/api/batch/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	v12 "k8s.io/client-go/kubernetes/typed/batch/v1"
 )
 
+const (
+	// LauncherName the name the Job launcher is registered with
+	LauncherName = "job"
+
+	// maxDiffLength the maximum length of the diff recorded on a launched resource
+	maxDiffLength = 64 * 1024
+)
+
+func init() {
+	launcher.Register(LauncherName, func(o launcher.FactoryOptions) (launcher.Interface, error) {
+		l, err := NewLauncher(o.KubeClient, o.DynamicClient, o.Namespace, o.Selector, o.CommandRunner)
+		if err != nil {
+			return nil, err
+		}
+		l.(*client).limiter = o.RateLimiter
+		l.(*client).noResourceApply = o.NoResourceApply
+		l.(*client).owner = o.Owner
+		return l, nil
+	})
+}
+
 type client struct {
-	kubeClient kubernetes.Interface
-	ns         string
-	selector   string
-	runner     cmdrunner.CommandRunner
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+	ns            string
+	selector      string
+	runner        cmdrunner.CommandRunner
+	limiter       *kube.AdaptiveLimiter
+	locks         launchLocks
+	cache         jobCache
+
+	// owner the optional identifier of the operator instance whose applied resources are garbage collected
+	owner string
+
+	// gcLock guards the namespaces of the inventories seen by garbage collection
+	gcLock       sync.Mutex
+	gcNamespaces map[string]bool
+
+	// noResourceApply if enabled resources are never applied, diffed or deleted via kubectl
+	noResourceApply bool
 }
 
 // NewLauncher creates a new launcher for Jobs using the given kubernetes client and namespace
-// if nil is passed in the kubernetes client will be lazily created
-func NewLauncher(kubeClient kubernetes.Interface, ns string, selector string, runner cmdrunner.CommandRunner) (launcher.Interface, error) {
+// if nil is passed in the kubernetes client will be lazily created.
+//
+// The dynamic client is used to create any resources declared in the job file which are not a `Job`;
+// if nil it is lazily created the first time it is required
+func NewLauncher(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, ns string, selector string, runner cmdrunner.CommandRunner) (launcher.Interface, error) {
 	if kubeClient == nil {
 		f := kubeclient.NewFactory()
 		cfg, err := f.CreateKubeConfig()
@@ -44,6 +93,13 @@ func NewLauncher(kubeClient kubernetes.Interface, ns string, selector string, ru
 			return nil, errors.Wrapf(err, "failed to create the kube client")
 		}
 
+		if dynamicClient == nil {
+			dynamicClient, err = dynamic.NewForConfig(cfg)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to create the dynamic client")
+			}
+		}
+
 		if ns == "" {
 			ns, err = kubeclient.CurrentNamespace()
 			if err != nil {
@@ -55,143 +111,819 @@ func NewLauncher(kubeClient kubernetes.Interface, ns string, selector string, ru
 		runner = cmdrunner.DefaultCommandRunner
 	}
 	return &client{
-		kubeClient: kubeClient,
-		ns:         ns,
-		selector:   selector,
-		runner:     runner,
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		ns:            ns,
+		selector:      selector,
+		runner:        runner,
 	}, nil
 }
 
 // Launch launches a job for the given commit
 func (c *client) Launch(opts launcher.LaunchOptions) ([]runtime.Object, error) {
+	opts.NoResourceApply = opts.NoResourceApply || c.noResourceApply
 	ns := opts.Repository.Namespace
 	if ns == "" {
 		ns = c.ns
 	}
 	safeName := naming.ToValidValue(opts.Repository.Name)
 	safeSha := naming.ToValidValue(opts.GitSHA)
+
+	folder, resources, err := LoadLaunchResources(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+	opts.Repository, err = resolveJobMetadata(opts.Repository, folder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid job metadata of repository %s", safeName)
+	}
+
+	clusters := opts.Repository.KubeConfigSecrets
+	if len(clusters) == 0 {
+		return c.launchInCluster(opts, "", folder, resources, ns, safeName, safeSha)
+	}
+
+	// lets launch in each of the target clusters
+	var answer []runtime.Object
+	var failed []string
+	for _, cluster := range clusters {
+		var clusterResources []*unstructured.Unstructured
+		for _, r := range resources {
+			clusterResources = append(clusterResources, r.DeepCopy())
+		}
+		objects, err := c.launchInCluster(opts, cluster, folder, clusterResources, ns, safeName, safeSha)
+		answer = append(answer, objects...)
+		if launcher.IsOwnershipConflict(err) {
+			return answer, err
+		}
+		if err != nil {
+			log.Logger().Warnf("failed to launch repository %s in cluster %s: %s", safeName, cluster, err.Error())
+			failed = append(failed, cluster)
+		}
+	}
+	if len(failed) > 0 {
+		return answer, errors.Errorf("failed to launch repository %s in %d of %d clusters: %s", safeName, len(failed), len(clusters), strings.Join(failed, ", "))
+	}
+	if len(clusters) > 1 {
+		log.Logger().Infof("launched repository %s sha %s in all %d clusters", safeName, safeSha, len(clusters))
+	}
+	return answer, nil
+}
+
+// launchInCluster launches the resources in the local cluster or the remote cluster for the given kubeconfig Secret name
+func (c *client) launchInCluster(opts launcher.LaunchOptions, cluster string, folder string, resources []*unstructured.Unstructured, ns string, safeName string, safeSha string) ([]runtime.Object, error) {
+	clients, err := c.clientsFor(opts.Repository, cluster, ns)
+	if err != nil {
+		return nil, err
+	}
+	defer clients.cleanup()
+
+	unlock := c.locks.lockRepository(ns, safeName, cluster)
+	defer unlock()
+
+	selector := c.repositorySelector(safeName, cluster)
+	l, err := c.findLaunched(clients, resources, ns, selector, safeSha, opts.Owner)
+	if err != nil {
+		return nil, err
+	}
+	if l.conflict != nil {
+		l.conflict.Repository = safeName
+		return nil, l.conflict
+	}
+	for _, j := range l.approvedJobs {
+		err = resumeApprovedJob(clients, j)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !l.lastCompleted.IsZero() {
+		r := opts.Repository
+		metrics.LastJobDuration.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(l.lastDuration.Seconds())
+	}
+
+	if opts.RollbackOf != "" {
+		return c.launchRollback(opts, clients, l, folder, resources, ns, safeName, safeSha)
+	}
+
+	attempt := 1
+	if l.foundSha && opts.Relaunch && opts.RelaunchRequest != "" && opts.RelaunchRequest == l.relaunchRequest {
+		// the operator restarted after launching this request but before acknowledging it
+		log.Logger().Infof("not relaunching repository %s sha %s in namespace %s as attempt %d was launched by the same request", safeName, safeSha, ns, l.attempt)
+		opts.Relaunch = false
+	}
+	if l.foundSha && opts.Relaunch && l.activeName == "" {
+		log.Logger().Infof("relaunching repository %s sha %s in namespace %s", safeName, safeSha, ns)
+		err = deleteLaunched(clients, l, ns)
+		if err != nil {
+			return nil, err
+		}
+		l.foundSha = false
+		attempt = l.attempt + 1
+	}
+
+	if !l.foundSha {
+		if l.activeName != "" {
+			log.Logger().Infof("not creating a Job in namespace %s for repo %s sha %s yet as there is an active job %s", ns, safeName, safeSha, l.activeName)
+			if opts.Decision != nil {
+				opts.Decision.BlockedBy = l.activeName
+				opts.Decision.Namespace = ns
+			}
+			return nil, nil
+		}
+		objects, err := c.startNewJob(opts, clients, folder, resources, ns, safeName, safeSha, attempt)
+		if err == nil && len(objects) > 0 {
+			expireSupersededJobs(opts.JobDefaults, clients, l, ns)
+		}
+		return objects, err
+	}
+	if l.activeName == "" && l.mainJobs > 0 && l.succeededJobs == l.mainJobs && !l.postLaunched {
+		return c.startPostJob(opts, clients, folder, ns, safeName, safeSha, l.attempt)
+	}
+	return nil, nil
+}
+
+// launchRollback launches the last successful commit sha again to roll back the failed commit unless it has
+// already been rolled back. Any previous rollback Jobs of the sha are replaced if a relaunch is requested
+func (c *client) launchRollback(opts launcher.LaunchOptions, clients *clusterClients, l *launched, folder string, resources []*unstructured.Unstructured, ns string, safeName string, safeSha string) ([]runtime.Object, error) {
+	attempt := 1
+	if len(l.rollbackJobs) > 0 {
+		if !opts.Relaunch {
+			return nil, nil
+		}
+		if l.activeName != "" {
+			log.Logger().Infof("not rolling back repository %s to sha %s yet as there is an active job %s", safeName, safeSha, l.activeName)
+			return nil, nil
+		}
+		err := deleteLaunched(clients, &launched{shaJobs: l.rollbackJobs}, ns)
+		if err != nil {
+			return nil, err
+		}
+		attempt = l.rollbackAttempt + 1
+	}
+	if l.activeName != "" {
+		log.Logger().Infof("not rolling back repository %s to sha %s yet as there is an active job %s", safeName, safeSha, l.activeName)
+		return nil, nil
+	}
+	log.Logger().Infof("rolling back repository %s from failed sha %s to sha %s in namespace %s", safeName, opts.RollbackOf, safeSha, ns)
+	return c.startNewJob(opts, clients, folder, resources, ns, safeName, safeSha, attempt)
+}
+
+// launched the resources previously launched for a repository in a cluster
+type launched struct {
+	// foundSha true if resources have been launched for the commit sha
+	foundSha bool
+
+	// activeName the name of the first resource which is still active
+	activeName string
+
+	// shaJobs the names of the Jobs launched for the commit sha
+	shaJobs []string
+
+	// shaResources the other resources launched for the commit sha
+	shaResources []*unstructured.Unstructured
+
+	// rollbackJobs the names of the rollback Jobs launched for the commit sha
+	rollbackJobs []string
+
+	// attempt the latest attempt of launching the commit sha or zero if it has not been launched
+	attempt int
+
+	// relaunchRequest the request which launched the latest attempt or blank if it was not a requested relaunch
+	relaunchRequest string
+
+	// rollbackAttempt the latest attempt of rolling back to the commit sha or zero if it has not been rolled back to
+	rollbackAttempt int
+
+	// mainJobs the number of Jobs launched for the commit sha excluding post-success Jobs
+	mainJobs int
+
+	// succeededJobs the number of mainJobs which have succeeded
+	succeededJobs int
+
+	// postLaunched true if a post-success Job has been launched for the commit sha
+	postLaunched bool
+
+	// approvedJobs the suspended Jobs which have been approved
+	approvedJobs []*v1.Job
+
+	// conflict the first resource found which was launched by a different operator instance
+	conflict *launcher.OwnershipConflictError
+
+	// lastCompleted when the most recently completed Job completed
+	lastCompleted time.Time
+
+	// lastDuration the run duration of the most recently completed Job
+	lastDuration time.Duration
+
+	// supersededJobs the names of the completed Jobs of other commit shas which do not have a time to live
+	supersededJobs []string
+}
+
+// repositorySelector returns the label selector of the resources launched for the repository in the given cluster
+func (c *client) repositorySelector(safeName string, cluster string) string {
 	selector := fmt.Sprintf("%s,%s=%s", c.selector, launcher.RepositoryLabelKey, safeName)
-	jobInterface := c.kubeClient.BatchV1().Jobs(ns)
-	list, err := jobInterface.List(metav1.ListOptions{
-		LabelSelector: selector,
-	})
-	if err != nil && apierrors.IsNotFound(err) {
-		err = nil
+	if cluster != "" {
+		selector += fmt.Sprintf(",%s=%s", launcher.ClusterLabelKey, naming.ToValidValue(cluster))
 	}
+	return selector
+}
+
+// findLaunched finds the Jobs and any other kinds of resource previously launched for the repository.
+//
+// If an owner is specified any resources launched by a different owner are recorded as a conflict
+func (c *client) findLaunched(clients *clusterClients, resources []*unstructured.Unstructured, ns string, selector string, safeSha string, owner string) (*launched, error) {
+	answer := &launched{}
+	jobs, err := c.listJobs(clients, ns, selector)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to find Jobs in namespace %s with selector %s", ns, selector)
+		return nil, err
 	}
 
-	var jobsForSha []v1.Job
-	var activeJobs []v1.Job
-	for _, r := range list.Items {
+	for i, r := range jobs {
 		log.Logger().Infof("found Job %s", r.Name)
 
 		if r.Labels[launcher.CommitShaLabelKey] == safeSha {
-			jobsForSha = append(jobsForSha, r)
+			attempt := attemptOf(r.Annotations)
+			if r.Labels[launcher.RollbackLabelKey] == "true" {
+				answer.rollbackJobs = append(answer.rollbackJobs, r.Name)
+				if attempt > answer.rollbackAttempt {
+					answer.rollbackAttempt = attempt
+				}
+			} else {
+				answer.foundSha = true
+				if attempt > answer.attempt {
+					answer.attempt = attempt
+					answer.relaunchRequest = r.Annotations[launcher.RelaunchRequestAnnotation]
+				}
+				answer.shaJobs = append(answer.shaJobs, r.Name)
+				if r.Labels[launcher.PostJobLabelKey] == "true" {
+					answer.postLaunched = true
+				} else {
+					answer.mainJobs++
+					if r.Status.Succeeded > 0 {
+						answer.succeededJobs++
+					}
+				}
+			}
+		}
+
+		if r.Labels[launcher.CommitShaLabelKey] != safeSha && !IsJobActive(r) && r.Spec.TTLSecondsAfterFinished == nil {
+			answer.supersededJobs = append(answer.supersededJobs, r.Name)
+		}
+
+		if r.Annotations[launcher.ApprovalAnnotation] == launcher.ApprovalApproved {
+			answer.approvedJobs = append(answer.approvedJobs, jobs[i].DeepCopy())
 		}
 
 		// is the job active
-		if IsJobActive(r) {
-			activeJobs = append(activeJobs, r)
+		if IsJobActive(r) && answer.activeName == "" {
+			answer.activeName = r.Name
 		}
+		answer.checkCompleted(r)
+		answer.checkOwner(r.Annotations, r.Name, owner)
 	}
 
-	if len(jobsForSha) == 0 {
-		if len(activeJobs) > 0 {
-			log.Logger().Infof("not creating a Job in namespace %s for repo %s sha %s yet as there is an active job %s", ns, safeName, safeSha, activeJobs[0].Name)
-			return nil, nil
+	// lets find any other kinds of resource launched for this repository
+	for _, gvr := range nonJobResourceTypes(resources) {
+		dynamicClient, err := clients.getDynamicClient()
+		if err != nil {
+			return nil, err
+		}
+		ulist, err := dynamicClient.Resource(gvr).Namespace(ns).List(metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil && apierrors.IsNotFound(err) {
+			err = nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find %s in namespace %s with selector %s", gvr.Resource, ns, selector)
+		}
+		if ulist == nil {
+			continue
+		}
+		for i := range ulist.Items {
+			r := &ulist.Items[i]
+			log.Logger().Infof("found %s %s", r.GetKind(), r.GetName())
+
+			if r.GetLabels()[launcher.CommitShaLabelKey] == safeSha {
+				answer.foundSha = true
+				answer.shaResources = append(answer.shaResources, r)
+				if attempt := attemptOf(r.GetAnnotations()); attempt > answer.attempt {
+					answer.attempt = attempt
+					answer.relaunchRequest = r.GetAnnotations()[launcher.RelaunchRequestAnnotation]
+				}
+			}
+			if IsResourceActive(r) && answer.activeName == "" {
+				answer.activeName = r.GetName()
+			}
+			answer.checkOwner(r.GetAnnotations(), r.GetName(), owner)
 		}
-		return c.startNewJob(opts, jobInterface, ns, safeName, safeSha)
 	}
-	return nil, nil
+	return answer, nil
 }
 
-// IsJobActive returns true if the job has not completed or terminated yet
+// checkCompleted records the run duration of the Job if it is the most recently completed Job
+func (l *launched) checkCompleted(j v1.Job) {
+	if IsJobActive(j) || j.Status.StartTime == nil {
+		return
+	}
+	completed := JobCompletionTime(j)
+	if completed.IsZero() || completed.Before(l.lastCompleted) {
+		return
+	}
+	l.lastCompleted = completed
+	l.lastDuration = completed.Sub(j.Status.StartTime.Time)
+}
+
+// checkOwner records a conflict if the resource was launched by a different owner
+func (l *launched) checkOwner(annotations map[string]string, name string, owner string) {
+	resourceOwner := annotations[repo.OwnerAnnotation]
+	if owner == "" || resourceOwner == "" || resourceOwner == owner || l.conflict != nil {
+		return
+	}
+	l.conflict = &launcher.OwnershipConflictError{
+		Owner:    resourceOwner,
+		Resource: name,
+	}
+}
+
+// deleteLaunched deletes the resources launched for a commit sha so that they can be launched again
+func deleteLaunched(clients *clusterClients, l *launched, ns string) error {
+	propagation := metav1.DeletePropagationBackground
+	deleteOptions := &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	}
+	for _, name := range l.shaJobs {
+		err := clients.kubeClient.BatchV1().Jobs(ns).Delete(name, deleteOptions)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete Job %s in namespace %s", name, ns)
+		}
+	}
+	for _, r := range l.shaResources {
+		dynamicClient, err := clients.getDynamicClient()
+		if err != nil {
+			return err
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(r.GroupVersionKind())
+		err = dynamicClient.Resource(gvr).Namespace(ns).Delete(r.GetName(), deleteOptions)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete %s %s in namespace %s", r.GetKind(), r.GetName(), ns)
+		}
+	}
+	return nil
+}
+
+// LoadLaunchResources finds the git operator folder in the git clone and loads the resources to launch from its
+// `job.yaml` file, or the job files selected for the changed paths by its `triggers.yaml` file, or creates the
+// default Job if there is no `job.yaml` file and the default Job is enabled.
+//
+// No resources are returned if none of the changed paths are relevant to the boot
+func LoadLaunchResources(opts launcher.LaunchOptions) (string, []*unstructured.Unstructured, error) {
+	safeName := naming.ToValidValue(opts.Repository.Name)
+	folder, err := FindGitOperatorFolder(opts.Dir)
+	if err != nil {
+		return "", nil, err
+	}
+	triggers, err := LoadTriggers(folder)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to load the triggers of repository %s", safeName)
+	}
+	jobFiles := []string{"job.yaml"}
+	if triggers != nil {
+		jobFiles = triggers.JobFiles(opts.ChangedFiles)
+		if len(jobFiles) == 0 {
+			log.Logger().Infof("not launching repository %s sha %s as none of the changed paths are relevant", safeName, opts.GitSHA)
+			return folder, nil, nil
+		}
+	}
+	var resources []*unstructured.Unstructured
+	for _, name := range jobFiles {
+		fileName := filepath.Join(folder, name)
+		exists, err := files.FileExists(fileName)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to find file %s in repository %s", fileName, safeName)
+		}
+		if !exists {
+			if name == "job.yaml" && len(jobFiles) > 1 {
+				// the job.yaml file is optional if the triggers select other job files
+				continue
+			}
+			if name != "job.yaml" {
+				return "", nil, errors.Errorf("repository %s does not have the Job file %s of its triggers", safeName, fileName)
+			}
+			if !opts.DefaultJob.Enabled {
+				return "", nil, errors.Errorf("repository %s does not have a Job file: %s", safeName, fileName)
+			}
+			log.Logger().Infof("repository %s does not have a Job file %s so using the default Job", safeName, fileName)
+			u, err := DefaultJob(opts)
+			if err != nil {
+				return "", nil, errors.Wrapf(err, "failed to create the default Job for repository %s", safeName)
+			}
+			resources = append(resources, u)
+			continue
+		}
+		err = ValidateJobFile(fileName)
+		if err != nil {
+			return "", nil, err
+		}
+		fileResources, err := LoadResources(fileName)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to load Job file %s in repository %s", fileName, safeName)
+		}
+		resources = append(resources, fileResources...)
+	}
+	// the patches of job files which were not selected for the changed paths are not an error
+	err = patchLaunchResources(opts.Dir, resources, triggers != nil && opts.ChangedFiles != nil)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to patch the Job files of repository %s", safeName)
+	}
+	return folder, resources, nil
+}
+
+// validateTenant validates the resources to be launched and applied in the cluster are allowed by the tenant of
+// the repository, if it has one. As the resources to apply may need rendering this is only called once a new Job or
+// Deployment is about to be created rather than on every poll
+func (c *client) validateTenant(opts launcher.LaunchOptions, folder string, resources []*unstructured.Unstructured, ns string, cluster string) error {
+	if opts.Tenant == nil {
+		return nil
+	}
+	all := resources
+	if !opts.NoResourceApply {
+		applyResources, _, err := LoadApplyResources(c.runner, folder, naming.ToValidValue(opts.Repository.Name), ns, cluster)
+		if err != nil {
+			return err
+		}
+		all = append(append([]*unstructured.Unstructured{}, resources...), applyResources...)
+	}
+	err := opts.Tenant.Validate(ns, all)
+	if err != nil {
+		return errors.Wrapf(err, "repository %s is not allowed to launch", opts.Repository.Name)
+	}
+	return nil
+}
+
+// IsJobActive returns true if the job has not succeeded or failed after exhausting its retries yet, so a Job whose
+// pods have failed but which is still retrying within its `backoffLimit` is active
 func IsJobActive(r v1.Job) bool {
-	return r.Status.Succeeded == 0 && r.Status.Failed == 0
+	return !IsJobFinished(r)
+}
+
+// IsResourceActive returns true if the resource has a status which indicates it has not completed yet.
+//
+// A `status.phase` of `Succeeded` or `Failed` (such as for a `Pod`) or a `Succeeded`, `Complete` or `Failed`
+// condition with a known status (such as for a `TaskRun`) is treated as completed. Resources without any
+// completion status (such as a `CronJob`) are never considered active
+func IsResourceActive(u *unstructured.Unstructured) bool {
+	phase, found, _ := unstructured.NestedString(u.Object, "status", "phase")
+	if found && phase != "" {
+		return phase != "Succeeded" && phase != "Failed"
+	}
+	conditions, found, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if !found {
+		return false
+	}
+	active := false
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _ := m["type"].(string)
+		status, _ := m["status"].(string)
+		switch conditionType {
+		case "Succeeded":
+			if status == "True" || status == "False" {
+				return false
+			}
+			active = true
+		case "Complete", "Failed":
+			if status == "True" {
+				return false
+			}
+			active = true
+		}
+	}
+	return active
 }
 
-// startNewJob lets create a new Job resource
-func (c *client) startNewJob(opts launcher.LaunchOptions, jobInterface v12.JobInterface, ns string, safeName string, safeSha string) ([]runtime.Object, error) {
+// startNewJob lets create the new Job resources for the given attempt of launching the commit sha
+func (c *client) startNewJob(opts launcher.LaunchOptions, clients *clusterClients, folder string, resources []*unstructured.Unstructured, ns string, safeName string, safeSha string, attempt int) ([]runtime.Object, error) {
 	log.Logger().Infof("about to create a new job for name %s and sha %s", safeName, safeSha)
 
-	// lets see if we are using a version stream to store the git operator configuration
-	folder := filepath.Join(opts.Dir, "versionStream", "git-operator")
-	exists, err := files.DirExists(folder)
+	err := c.validateTenant(opts, folder, resources, ns, clients.name)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to check if folder exists %s", folder)
+		return nil, err
 	}
-	if !exists {
-		// lets try the original location
-		folder = filepath.Join(opts.Dir, ".jx", "git-operator")
+	preflight, err := c.runPreflight(opts, clients, folder, ns, safeName, safeSha)
+	if err != nil {
+		return nil, err
 	}
-
-	fileName := filepath.Join(folder, "job.yaml")
-	exists, err = files.FileExists(fileName)
+	diff, err := c.applyRepositoryResources(opts, clients, folder, ns, safeName, safeSha)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to find file %s in repository %s", fileName, safeName)
+		return nil, err
 	}
-	if !exists {
-		return nil, errors.Errorf("repository %s does not have a Job file: %s", safeName, fileName)
+	err = c.copyGitSecrets(opts.Repository, clients, resources, ns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to copy the git Secret of repository %s", safeName)
 	}
 
-	resource := &v1.Job{}
-	err = yamls.LoadFile(fileName, resource)
+	resourceName, err := opts.Naming.ResourceName(safeName, safeSha)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to load Job file %s in repository %s", fileName, safeName)
+		return nil, errors.Wrapf(err, "failed to generate the name of the resources of repository %s", safeName)
+	}
+	resourceName = launcher.PrefixedName(opts.Repository.JobNamePrefix, resourceName, safeName, safeSha)
+	labels := map[string]string{}
+	annotations := map[string]string{
+		launcher.AttemptAnnotation: strconv.Itoa(attempt),
 	}
+	if opts.Relaunch && opts.RelaunchRequest != "" {
+		annotations[launcher.RelaunchRequestAnnotation] = opts.RelaunchRequest
+	}
+	if diff != "" {
+		annotations[launcher.DiffAnnotation] = trimDiff(diff)
+	}
+	if opts.Changelog != "" {
+		annotations[launcher.ChangelogAnnotation] = opts.Changelog
+	}
+	if opts.RollbackOf != "" {
+		resourceName = launcher.SuffixedName(resourceName, "-rollback", safeName, safeSha)
+		labels[launcher.RollbackLabelKey] = "true"
+		annotations[launcher.RollbackOfAnnotation] = opts.RollbackOf
+	}
+	resourceName = attemptName(resourceName, attempt, safeName, safeSha)
+	if preflight {
+		annotations[launcher.PreflightAnnotation] = launcher.ResultSucceeded
+	}
+	return c.createResources(opts, clients, resources, ns, safeName, safeSha, resourceName, labels, annotations)
+}
 
-	if !opts.NoResourceApply {
-		// now lets check if there is a resources dir
-		resourcesDir := filepath.Join(folder, "resources")
-		exists, err = files.DirExists(resourcesDir)
+// createResources creates the resources launched for the commit sha with the given name, adding the labels of the
+// repository and commit along with the given labels and annotations
+func (c *client) createResources(opts launcher.LaunchOptions, clients *clusterClients, resources []*unstructured.Unstructured, ns string, safeName string, safeSha string, resourceName string, extraLabels map[string]string, extraAnnotations map[string]string) ([]runtime.Object, error) {
+	defer opts.Timings.Since(timing.PhaseCreate, time.Now())
+
+	var answer []runtime.Object
+	for i, resource := range resources {
+		name := resourceName
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", resourceName, i)
+		}
+		resource.SetName(name)
+
+		labels := resource.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for k, v := range opts.Repository.JobLabels {
+			labels[k] = v
+		}
+		labels[constants.DefaultSelectorKey] = constants.DefaultSelectorValue
+		labels[launcher.RepositoryLabelKey] = safeName
+		labels[launcher.CommitShaLabelKey] = safeSha
+		if clients.name != "" {
+			labels[launcher.ClusterLabelKey] = naming.ToValidValue(clients.name)
+		}
+		if opts.Repository.Parent != "" {
+			labels[launcher.ParentRepositoryLabelKey] = naming.ToValidValue(opts.Repository.Parent)
+		}
+		if opts.Trigger != "" {
+			labels[launcher.TriggerLabelKey] = opts.Trigger
+		}
+		for k, v := range extraLabels {
+			labels[k] = v
+		}
+		resource.SetLabels(labels)
+
+		annotations := opts.Commit.Annotations(resource.GetAnnotations())
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for k, v := range opts.Repository.JobAnnotations {
+			annotations[k] = v
+		}
+		if opts.Owner != "" {
+			annotations[repo.OwnerAnnotation] = opts.Owner
+		}
+		if opts.Repository.Folder != "" {
+			annotations[launcher.FolderAnnotation] = opts.Repository.Folder
+		}
+		for k, v := range extraAnnotations {
+			annotations[k] = v
+		}
+		resource.SetAnnotations(annotations)
+		addOwnerReference(resource, opts.Repository, clients, ns)
+
+		err := injectPodSpecDefaults(opts, resource)
+		if err == nil {
+			err = injectJobDefaults(opts.JobDefaults, resource)
+		}
+		if err == nil {
+			err = injectBootInventoryEnv(resource)
+		}
+		if err == nil {
+			err = addPodTemplateLabels(resource, opts.Repository.JobLabels)
+		}
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to check if resources directory %s exists in repository %s", resourcesDir, safeName)
+			return answer, errors.Wrapf(err, "failed to modify %s %s", resource.GetKind(), name)
 		}
-		if exists {
-			absDir, err := filepath.Abs(resourcesDir)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to get absolute resources dir %s", resourcesDir)
-			}
 
-			cmd := &cmdrunner.Command{
-				Name: "kubectl",
-				Args: []string{"apply", "-f", absDir},
+		var r2 runtime.Object
+		err = retryTransient(fmt.Sprintf("create of %s %s", resource.GetKind(), name), func() error {
+			var err error
+			c.limiter.Wait()
+			if opts.Suspend && IsJobResource(resource) {
+				r2, err = createSuspendedJob(clients, resource, ns)
+			} else {
+				r2, err = createResource(clients, resource, ns)
+			}
+			c.limiter.Observe(err)
+			return err
+		})
+		if err != nil && apierrors.IsAlreadyExists(errors.Cause(err)) {
+			concurrent, getErr := isConcurrentLaunch(clients, resource, ns)
+			if getErr != nil {
+				return answer, getErr
 			}
-			log.Logger().Infof("running command: %s", cmd.CLI())
-			_, err = c.runner(cmd)
+			if concurrent {
+				// the names are deterministic so a concurrent launch of the same commit and attempt created it first
+				log.Logger().Infof("%s %s in namespace %s has already been created by a concurrent launch", resource.GetKind(), name, ns)
+				continue
+			}
+		}
+		if err != nil {
+			return answer, errors.Wrapf(err, "failed to create %s %s in namespace %s", resource.GetKind(), name, ns)
+		}
+		log.Logger().Infof("created %s %s in namespace %s", resource.GetKind(), name, ns)
+		answer = append(answer, r2)
+	}
+	return answer, nil
+}
+
+// applyRepositoryResources applies the chart, helmfile or resources dir of the git operator folder in the cluster
+// and saves the inventory of the applied resources, returning the diff of the changes which were applied
+func (c *client) applyRepositoryResources(opts launcher.LaunchOptions, clients *clusterClients, folder string, ns string, safeName string, safeSha string) (string, error) {
+	if opts.NoResourceApply {
+		return "", nil
+	}
+
+	// now lets check if there is a chart, helmfile or resources dir to apply
+	applyResources, source, err := LoadApplyResources(c.runner, folder, safeName, ns, clients.name)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load resources to apply in repository %s", safeName)
+	}
+	if applyResources == nil {
+		return "", nil
+	}
+	d, err := newApplyDir(applyResources, safeName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to save resources of %s in repository %s", source, safeName)
+	}
+	defer d.cleanup()
+
+	if opts.ValidateResources {
+		err = c.validateResources(opts, d, clients.kubeConfigFile)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// lets record what the apply is going to change
+	start := time.Now()
+	diff, err := c.diffResources(d.allDir(), clients.kubeConfigFile)
+	opts.Timings.Since(timing.PhaseDiff, start)
+	if err != nil {
+		log.Logger().Warnf("failed to preview the changes to the resources of repository %s: %s", safeName, err.Error())
+	} else if diff != "" {
+		log.Logger().Infof("applying changes to the resources of repository %s:\n%s", safeName, diff)
+	}
+
+	start = time.Now()
+	err = c.applyResources(d, clients.kubeConfigFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to apply resources of %s in repository %s", source, safeName)
+	}
+	r := opts.Repository
+	metrics.ApplyDuration.WithLabelValues(r.Tenant, r.Namespace, r.Name).Observe(time.Since(start).Seconds())
+	opts.Timings.Since(timing.PhaseApply, start)
+
+	inv := inventory.NewInventory(safeName, safeSha, d.resources)
+	inv.Namespace = ns
+	inv.Owner = opts.Owner
+	inv.Selector = c.selector
+	err = inventory.Save(clients.kubeClient, ns, inv)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to save the inventory of repository %s", safeName)
+	}
+	return diff, nil
+}
+
+// createResource creates the resource using the typed client for a `Job` or the dynamic client for any other kind
+func createResource(clients *clusterClients, resource *unstructured.Unstructured, ns string) (runtime.Object, error) {
+	if IsJobResource(resource) {
+		if hasPodFailurePolicy(resource) {
+			j, err := createUntypedJob(clients, resource, ns)
 			if err != nil {
-				return nil, errors.Wrapf(err, "failed to apply resources in dir %s", absDir)
+				return nil, err
 			}
+			return j, nil
+		}
+		j, err := ToJob(resource)
+		if err != nil {
+			return nil, err
 		}
+		return clients.kubeClient.BatchV1().Jobs(ns).Create(j)
 	}
 
-	// lets try use a maximum of 31 characters and a minimum of 10 for the sha
-	namePrefix := trimLength(safeName, 20)
-	maxShaLen := 30 - len(namePrefix)
+	dynamicClient, err := clients.getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(resource.GroupVersionKind())
+	return dynamicClient.Resource(gvr).Namespace(ns).Create(resource, metav1.CreateOptions{})
+}
 
-	resourceName := namePrefix + "-" + trimLength(safeSha, maxShaLen)
-	resource.Name = resourceName
+func (c *client) getDynamicClient() (dynamic.Interface, error) {
+	if c.dynamicClient == nil {
+		f := kubeclient.NewFactory()
+		cfg, err := f.CreateKubeConfig()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create kube config")
+		}
+		c.dynamicClient, err = dynamic.NewForConfig(cfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create the dynamic client")
+		}
+	}
+	return c.dynamicClient, nil
+}
+
+// ResourceName returns the name of the resource launched for the given repository name and commit sha using the
+// default naming strategy
+func ResourceName(safeName string, safeSha string) string {
+	return launcher.TrimmedName(safeName, safeSha)
+}
 
-	if resource.Labels == nil {
-		resource.Labels = map[string]string{}
+// FindGitOperatorFolder returns the folder containing the git operator configuration in the given git clone
+func FindGitOperatorFolder(dir string) (string, error) {
+	// lets see if we are using a version stream to store the git operator configuration
+	folder := filepath.Join(dir, "versionStream", "git-operator")
+	exists, err := files.DirExists(folder)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check if folder exists %s", folder)
+	}
+	if !exists {
+		// lets try the original location
+		folder = filepath.Join(dir, ".jx", "git-operator")
 	}
-	resource.Labels[constants.DefaultSelectorKey] = constants.DefaultSelectorValue
-	resource.Labels[launcher.RepositoryLabelKey] = safeName
-	resource.Labels[launcher.CommitShaLabelKey] = safeSha
+	return folder, nil
+}
 
-	r2, err := jobInterface.Create(resource)
+// GitOperatorFolders returns every folder of the given git clone which the launcher reads files from: the folder
+// returned by FindGitOperatorFolder and, when a version stream is used, the `.jx/git-operator` folder containing the
+// local customisations such as the `job-patch.yaml` file if it exists
+func GitOperatorFolders(dir string) ([]string, error) {
+	folder, err := FindGitOperatorFolder(dir)
+	if err != nil {
+		return nil, err
+	}
+	answer := []string{folder}
+	local := filepath.Join(dir, ".jx", "git-operator")
+	if local == folder {
+		return answer, nil
+	}
+	exists, err := files.DirExists(local)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create Job %s in namespace %s", resourceName, ns)
+		return nil, errors.Wrapf(err, "failed to check if folder exists %s", local)
+	}
+	if exists {
+		answer = append(answer, local)
+	}
+	return answer, nil
+}
+
+// nonJobResourceTypes returns the distinct resource types of any resources which are not a `Job`
+func nonJobResourceTypes(resources []*unstructured.Unstructured) []schema.GroupVersionResource {
+	var answer []schema.GroupVersionResource
+	m := map[schema.GroupVersionResource]bool{}
+	for _, r := range resources {
+		if IsJobResource(r) {
+			continue
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(r.GroupVersionKind())
+		if !m[gvr] {
+			m[gvr] = true
+			answer = append(answer, gvr)
+		}
 	}
-	log.Logger().Infof("created Job %s in namespace %s", resourceName, ns)
-	return []runtime.Object{r2}, nil
+	return answer
 }
 
-func trimLength(text string, length int) string {
-	if len(text) <= length {
-		return text
+// trimDiff trims the diff so that it fits comfortably within the size limit of the annotations of a resource
+func trimDiff(diff string) string {
+	if len(diff) <= maxDiffLength {
+		return diff
 	}
-	return text[0:length]
+	return diff[0:maxDiffLength] + "\n... diff truncated"
 }
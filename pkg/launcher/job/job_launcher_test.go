@@ -1,20 +1,38 @@
 package job_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
 	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
 	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
 	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-git-operator/pkg/tenant"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner/fakerunner"
 	"github.com/jenkins-x/jx-helpers/pkg/testhelpers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestJobLauncher(t *testing.T) {
@@ -23,9 +41,6 @@ func TestJobLauncher(t *testing.T) {
 	gitURL := "https://github.com/jenkins-x/fake-repository.git"
 	gitSha := "dummysha1234"
 
-	resourcesDir, err := filepath.Abs(filepath.Join("test_data", "somerepo", "versionStream", "git-operator", "resources"))
-	require.NoError(t, err, "failed to get absolute dir %s", resourcesDir)
-
 	kubeClient := fake.NewSimpleClientset(
 		&corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
@@ -40,9 +55,19 @@ func TestJobLauncher(t *testing.T) {
 			},
 		},
 	)
-	runner := &fakerunner.FakeRunner{}
+	var applied []*unstructured.Unstructured
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if c.Args[0] == "apply" {
+				resources, err := job.LoadResourcesDir(c.Args[2])
+				require.NoError(t, err, "failed to load applied resources in %s", c.Args[2])
+				applied = append(applied, resources...)
+			}
+			return "", nil
+		},
+	}
 
-	client, err := job.NewLauncher(kubeClient, ns, constants.DefaultSelector, runner.Run)
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
 	require.NoError(t, err, "failed to create launcher client")
 
 	o := launcher.LaunchOptions{
@@ -69,14 +94,1767 @@ func TestJobLauncher(t *testing.T) {
 	testhelpers.AssertLabel(t, launcher.RepositoryLabelKey, repoName, j1.ObjectMeta, msg)
 	testhelpers.AssertLabel(t, launcher.CommitShaLabelKey, gitSha, j1.ObjectMeta, msg)
 
-	runner.ExpectResults(t,
-		fakerunner.FakeResult{
-			CLI: "kubectl apply -f " + resourcesDir,
+	require.Len(t, runner.OrderedCommands, 2, "should have previewed and applied the resources")
+	assert.Equal(t, "diff", runner.OrderedCommands[0].Args[0], "first command")
+	assert.Equal(t, "apply", runner.OrderedCommands[1].Args[0], "second command")
+
+	// the applied resources are labelled and recorded in the inventory
+	require.Len(t, applied, 1, "should have applied one resource")
+	assert.Equal(t, repoName, applied[0].GetLabels()[launcher.RepositoryLabelKey], "repository label of applied %s", applied[0].GetKind())
+
+	inventories, err := inventory.List(kubeClient, ns)
+	require.NoError(t, err, "failed to list inventories")
+	require.Len(t, inventories, 1, "inventories")
+	assert.Equal(t, []inventory.Entry{
+		{
+			APIVersion: "v1",
+			Kind:       applied[0].GetKind(),
+			Name:       applied[0].GetName(),
 		},
-	)
+	}, inventories[0].Entries, "inventory entries")
 
 	// we should not recreate the Job if we try to launch again as it already exists
 	objects, err = client.Launch(o)
 	require.NoError(t, err, "failed to launch the job")
 	require.Len(t, objects, 0, "should not have a created a runtime.Object as we already have one for the commit sha")
 }
+
+func TestJobLauncherMultipleJobs(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	gitSha := "dummysha1234"
+
+	for _, dirName := range []string{"multidoc", "list"} {
+		kubeClient := fake.NewSimpleClientset()
+		runner := &fakerunner.FakeRunner{}
+
+		client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+		require.NoError(t, err, "failed to create launcher client")
+
+		o := launcher.LaunchOptions{
+			Repository: repo.Repository{
+				Name:      repoName,
+				Namespace: ns,
+				GitURL:    gitURL,
+			},
+			GitSHA: gitSha,
+			Dir:    filepath.Join("test_data", dirName),
+		}
+		objects, err := client.Launch(o)
+		require.NoError(t, err, "failed to launch the jobs for %s", dirName)
+		require.Len(t, objects, 2, "should have created two runtime.Objects for %s", dirName)
+
+		for _, o1 := range objects {
+			j1, ok := o1.(*v1.Job)
+			require.True(t, ok, "could not convert object %#v to a Job", o1)
+
+			t.Logf("created Job with name %s for %s", j1.Name, dirName)
+
+			msg := "created Job for " + dirName
+			testhelpers.AssertLabel(t, launcher.RepositoryLabelKey, repoName, j1.ObjectMeta, msg)
+			testhelpers.AssertLabel(t, launcher.CommitShaLabelKey, gitSha, j1.ObjectMeta, msg)
+		}
+		assert.NotEqual(t, objects[0].(*v1.Job).Name, objects[1].(*v1.Job).Name, "Job names should be unique for %s", dirName)
+
+		objects, err = client.Launch(o)
+		require.NoError(t, err, "failed to launch the jobs for %s", dirName)
+		require.Len(t, objects, 0, "should not have a created a runtime.Object as we already have jobs for the commit sha for %s", dirName)
+	}
+}
+
+func TestJobLauncherUnstructuredResource(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	gitSha := "dummysha1234"
+
+	kubeClient := fake.NewSimpleClientset()
+	dynamicClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	runner := &fakerunner.FakeRunner{}
+
+	client, err := job.NewLauncher(kubeClient, dynamicClient, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    gitURL,
+		},
+		GitSHA: gitSha,
+		Dir:    filepath.Join("test_data", "taskrun"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the TaskRun")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	u, ok := objects[0].(*unstructured.Unstructured)
+	require.True(t, ok, "could not convert object %#v to an Unstructured", objects[0])
+	assert.Equal(t, "TaskRun", u.GetKind(), "kind")
+	assert.Equal(t, repoName, u.GetLabels()[launcher.RepositoryLabelKey], "label %s", launcher.RepositoryLabelKey)
+	assert.Equal(t, gitSha, u.GetLabels()[launcher.CommitShaLabelKey], "label %s", launcher.CommitShaLabelKey)
+
+	t.Logf("created %s with name %s", u.GetKind(), u.GetName())
+
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the TaskRun")
+	require.Len(t, objects, 0, "should not have a created a runtime.Object as we already have one for the commit sha")
+
+	// lets mark the TaskRun as running so that the next commit is not launched yet
+	gvr := schema.GroupVersionResource{Group: "tekton.dev", Version: "v1beta1", Resource: "taskruns"}
+	err = unstructured.SetNestedSlice(u.Object, []interface{}{
+		map[string]interface{}{"type": "Succeeded", "status": "Unknown"},
+	}, "status", "conditions")
+	require.NoError(t, err, "failed to set the status conditions")
+	u, err = dynamicClient.Resource(gvr).Namespace(ns).Update(u, metav1.UpdateOptions{})
+	require.NoError(t, err, "failed to update the TaskRun")
+	assert.True(t, job.IsResourceActive(u), "TaskRun should be active")
+
+	o.GitSHA = "new-commit-sha"
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the TaskRun")
+	require.Len(t, objects, 0, "should not have a created a runtime.Object as there is an active TaskRun")
+
+	err = unstructured.SetNestedSlice(u.Object, []interface{}{
+		map[string]interface{}{"type": "Succeeded", "status": "True"},
+	}, "status", "conditions")
+	require.NoError(t, err, "failed to set the status conditions")
+	u, err = dynamicClient.Resource(gvr).Namespace(ns).Update(u, metav1.UpdateOptions{})
+	require.NoError(t, err, "failed to update the TaskRun")
+	assert.False(t, job.IsResourceActive(u), "TaskRun should not be active")
+
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the TaskRun")
+	require.Len(t, objects, 1, "should have created a runtime.Object for the new commit sha")
+}
+
+func TestJobLauncherDefaultJob(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	gitSha := "dummysha1234"
+	image := "gcr.io/jenkinsxio/jx-boot:1.2.3"
+
+	kubeClient := fake.NewSimpleClientset()
+	runner := &fakerunner.FakeRunner{}
+
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    gitURL,
+		},
+		GitSHA: gitSha,
+		Dir:    filepath.Join("test_data", "nojob"),
+	}
+	_, err = client.Launch(o)
+	require.Error(t, err, "should fail to launch a repository without a job file if there is no default Job")
+
+	o.DefaultJob = launcher.DefaultJobOptions{
+		Enabled:        true,
+		Image:          image,
+		Command:        []string{"make"},
+		Args:           []string{"apply"},
+		ServiceAccount: "jx-boot-job",
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the default job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j1, ok := objects[0].(*v1.Job)
+	require.True(t, ok, "could not convert object %#v to a Job", objects[0])
+	testhelpers.AssertLabel(t, launcher.RepositoryLabelKey, repoName, j1.ObjectMeta, "default Job")
+	testhelpers.AssertLabel(t, launcher.CommitShaLabelKey, gitSha, j1.ObjectMeta, "default Job")
+
+	podSpec := j1.Spec.Template.Spec
+	assert.Equal(t, "jx-boot-job", podSpec.ServiceAccountName, "serviceAccountName")
+	require.Len(t, podSpec.Containers, 1, "containers")
+	c := podSpec.Containers[0]
+	assert.Equal(t, image, c.Image, "image")
+	assert.Equal(t, []string{"make"}, c.Command, "command")
+	assert.Equal(t, []string{"apply"}, c.Args, "args")
+
+	found := false
+	for _, e := range c.Env {
+		if e.Name == "GIT_REVISION" {
+			found = true
+			assert.Equal(t, gitSha, e.Value, "GIT_REVISION")
+		}
+		if e.Name == "GIT_URL" {
+			require.NotNil(t, e.ValueFrom, "GIT_URL should use a valueFrom")
+			require.NotNil(t, e.ValueFrom.SecretKeyRef, "GIT_URL should use a secretKeyRef")
+			assert.Equal(t, repoName, e.ValueFrom.SecretKeyRef.Name, "GIT_URL secret name")
+		}
+	}
+	assert.True(t, found, "should have found GIT_REVISION env var")
+}
+
+func TestJobLauncherDefaultJobRemoteCluster(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	kubeConfigSecret := "remote-cluster"
+
+	remoteKubeClient := fake.NewSimpleClientset()
+	oldNewRemoteClients := job.NewRemoteClients
+	defer func() {
+		job.NewRemoteClients = oldNewRemoteClients
+	}()
+	job.NewRemoteClients = func(kubeConfig []byte) (kubernetes.Interface, dynamic.Interface, error) {
+		return remoteKubeClient, nil, nil
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      kubeConfigSecret,
+				Namespace: ns,
+			},
+			Data: map[string][]byte{
+				job.KubeConfigSecretKey: []byte("dummy-kubeconfig"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      repoName,
+				Namespace: ns,
+			},
+			Data: map[string][]byte{
+				"url":      []byte(gitURL),
+				"username": []byte("myuser"),
+				"password": []byte("mytoken"),
+			},
+		},
+	)
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	objects, err := client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:              repoName,
+			Namespace:         ns,
+			GitURL:            gitURL,
+			KubeConfigSecrets: []string{kubeConfigSecret},
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "nojob"),
+		DefaultJob: launcher.DefaultJobOptions{
+			Enabled: true,
+			Image:   "gcr.io/jenkinsxio/jx-boot:1.2.3",
+		},
+	})
+	require.NoError(t, err, "failed to launch the default job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	secretName := repoName + "-git"
+	secret, err := remoteKubeClient.CoreV1().Secrets(ns).Get(secretName, metav1.GetOptions{})
+	require.NoError(t, err, "should have copied the git Secret into the remote cluster")
+	assert.Equal(t, gitURL, string(secret.Data["url"]), "url")
+	assert.Equal(t, "myuser", string(secret.Data["username"]), "username")
+	assert.Equal(t, "mytoken", string(secret.Data["password"]), "password")
+
+	j1 := objects[0].(*v1.Job)
+	testhelpers.AssertAnnotation(t, launcher.GitSecretAnnotation, secretName, j1.ObjectMeta, "remote default Job")
+	for _, e := range j1.Spec.Template.Spec.Containers[0].Env {
+		if e.ValueFrom != nil {
+			require.NotNil(t, e.ValueFrom.SecretKeyRef, "%s should use a secretKeyRef", e.Name)
+			assert.Equal(t, secretName, e.ValueFrom.SecretKeyRef.Name, "%s secret name", e.Name)
+		}
+	}
+}
+
+func TestJobLauncherRemoteCluster(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	gitSha := "dummysha1234"
+	kubeConfigSecret := "remote-cluster"
+
+	remoteKubeClient := fake.NewSimpleClientset()
+	oldNewRemoteClients := job.NewRemoteClients
+	defer func() {
+		job.NewRemoteClients = oldNewRemoteClients
+	}()
+	job.NewRemoteClients = func(kubeConfig []byte) (kubernetes.Interface, dynamic.Interface, error) {
+		assert.Equal(t, "dummy-kubeconfig", string(kubeConfig), "kubeconfig")
+		return remoteKubeClient, nil, nil
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      kubeConfigSecret,
+				Namespace: ns,
+			},
+			Data: map[string][]byte{
+				job.KubeConfigSecretKey: []byte("dummy-kubeconfig"),
+			},
+		},
+	)
+	runner := &fakerunner.FakeRunner{}
+
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:              repoName,
+			Namespace:         ns,
+			GitURL:            gitURL,
+			KubeConfigSecrets: []string{kubeConfigSecret},
+		},
+		GitSHA: gitSha,
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	remoteJobs, err := remoteKubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list remote Jobs")
+	assert.Len(t, remoteJobs.Items, 1, "should have created a Job in the remote cluster")
+
+	localJobs, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list local Jobs")
+	assert.Len(t, localJobs.Items, 0, "should not have created a Job in the local cluster")
+
+	require.Len(t, runner.OrderedCommands, 2, "should have previewed and applied the resources")
+	for _, c := range runner.OrderedCommands {
+		assert.Contains(t, c.Args, "--kubeconfig", "should diff and apply resources in the remote cluster")
+	}
+	testhelpers.AssertLabel(t, launcher.ClusterLabelKey, kubeConfigSecret, remoteJobs.Items[0].ObjectMeta, "remote Job")
+
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 0, "should not have a created a runtime.Object as the remote cluster already has one for the commit sha")
+}
+
+func TestJobLauncherMultipleClusters(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+	gitSha := "dummysha1234"
+	clusterNames := []string{"cluster-a", "cluster-b"}
+
+	remoteKubeClients := map[string]*fake.Clientset{}
+	kubeClient := fake.NewSimpleClientset()
+	for _, name := range clusterNames {
+		remoteKubeClients[name] = fake.NewSimpleClientset()
+		_, err := kubeClient.CoreV1().Secrets(ns).Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+			Data: map[string][]byte{
+				job.KubeConfigSecretKey: []byte(name),
+			},
+		})
+		require.NoError(t, err, "failed to create kubeconfig Secret %s", name)
+	}
+
+	oldNewRemoteClients := job.NewRemoteClients
+	defer func() {
+		job.NewRemoteClients = oldNewRemoteClients
+	}()
+	job.NewRemoteClients = func(kubeConfig []byte) (kubernetes.Interface, dynamic.Interface, error) {
+		return remoteKubeClients[string(kubeConfig)], nil, nil
+	}
+
+	runner := &fakerunner.FakeRunner{}
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:              repoName,
+			Namespace:         ns,
+			GitURL:            gitURL,
+			KubeConfigSecrets: clusterNames,
+		},
+		GitSHA: gitSha,
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 2, "should have created a Job in each cluster")
+
+	for _, name := range clusterNames {
+		jobs, err := remoteKubeClients[name].BatchV1().Jobs(ns).List(metav1.ListOptions{})
+		require.NoError(t, err, "failed to list Jobs in cluster %s", name)
+		require.Len(t, jobs.Items, 1, "should have created a Job in cluster %s", name)
+		testhelpers.AssertLabel(t, launcher.ClusterLabelKey, name, jobs.Items[0].ObjectMeta, "Job in cluster "+name)
+	}
+
+	// lets mark the first cluster as complete so only that cluster gets the next commit
+	firstJobs, err := remoteKubeClients["cluster-a"].BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	firstJob := firstJobs.Items[0]
+	firstJob.Status.Succeeded = 1
+	firstJob.Status.Conditions = append(firstJob.Status.Conditions, v1.JobCondition{Type: v1.JobComplete, Status: corev1.ConditionTrue})
+	_, err = remoteKubeClients["cluster-a"].BatchV1().Jobs(ns).Update(&firstJob)
+	require.NoError(t, err, "failed to update Job")
+
+	o.GitSHA = "new-commit-sha"
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should only have launched in the cluster without an active Job")
+	j1 := objects[0].(*v1.Job)
+	testhelpers.AssertLabel(t, launcher.ClusterLabelKey, "cluster-a", j1.ObjectMeta, "new Job")
+
+	historyProvider, ok := client.(launcher.HistoryProvider)
+	require.True(t, ok, "launcher should provide the history")
+	records, err := historyProvider.History(o.Repository)
+	require.NoError(t, err, "failed to find the history")
+	launches := map[string]launcher.LaunchRecord{}
+	for _, record := range records {
+		launches[record.GitSHA] = record
+	}
+	require.Len(t, launches, 2, "should have one launch record per commit sha")
+	assert.Len(t, launches[gitSha].Clusters, 2, "should have a record for each cluster of the first commit sha")
+	assert.Equal(t, launcher.ResultActive, launches[gitSha].Result, "result of the first commit sha while cluster-b is active")
+	assert.Nil(t, launches[gitSha].Completed, "first commit sha should not have completed")
+	assert.Len(t, launches["new-commit-sha"].Clusters, 1, "should only have a record for cluster-a of the second commit sha")
+
+	secondJobs, err := remoteKubeClients["cluster-b"].BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	secondJob := secondJobs.Items[0]
+	secondJob.Status.Failed = 1
+	updated, err := remoteKubeClients["cluster-b"].BatchV1().Jobs(ns).Update(&secondJob)
+	require.NoError(t, err, "failed to update Job")
+
+	records, err = historyProvider.History(o.Repository)
+	require.NoError(t, err, "failed to find the history")
+	for _, record := range records {
+		if record.GitSHA == gitSha {
+			assert.Equal(t, launcher.ResultActive, record.Result, "result of the first commit sha while cluster-b is retrying")
+		}
+	}
+
+	updated.Status.Conditions = append(updated.Status.Conditions, v1.JobCondition{Type: v1.JobFailed, Status: corev1.ConditionTrue})
+	_, err = remoteKubeClients["cluster-b"].BatchV1().Jobs(ns).Update(updated)
+	require.NoError(t, err, "failed to update Job")
+
+	records, err = historyProvider.History(o.Repository)
+	require.NoError(t, err, "failed to find the history")
+	for _, record := range records {
+		if record.GitSHA == gitSha {
+			assert.Equal(t, launcher.ResultFailed, record.Result, "result of the first commit sha when cluster-b failed")
+			results := map[string]string{}
+			for _, clusterRecord := range record.Clusters {
+				results[clusterRecord.Cluster] = clusterRecord.Result
+			}
+			assert.Equal(t, map[string]string{"cluster-a": launcher.ResultSucceeded, "cluster-b": launcher.ResultFailed}, results, "results of the clusters")
+		}
+	}
+}
+
+func TestJobLauncherPriorityClassName(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	objects, err := client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA:            "dummysha1234",
+		Dir:               filepath.Join("test_data", "somerepo"),
+		PriorityClassName: "high-priority",
+	})
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j1 := objects[0].(*v1.Job)
+	assert.Equal(t, "high-priority", j1.Spec.Template.Spec.PriorityClassName, "priorityClassName")
+}
+
+func TestJobLauncherInjectEnv(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	objects, err := client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "somerepo"),
+		Inject: launcher.InjectOptions{
+			Env: `
+- name: HTTP_PROXY
+  value: http://proxy:3128
+- name: GIT_SUB_DIR
+  value: overridden
+- name: FEATURE_TOKEN
+  valueFrom:
+    secretKeyRef:
+      name: features
+      key: token
+`,
+			EnvFrom: `[{"configMapRef": {"name": "platform"}}]`,
+		},
+	})
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j1 := objects[0].(*v1.Job)
+	podSpec := j1.Spec.Template.Spec
+	containers := append(podSpec.InitContainers, podSpec.Containers...)
+	require.NotEmpty(t, containers, "containers")
+	for _, c := range containers {
+		env := map[string]corev1.EnvVar{}
+		for _, e := range c.Env {
+			env[e.Name] = e
+		}
+		assert.Equal(t, "http://proxy:3128", env["HTTP_PROXY"].Value, "injected env var of container %s", c.Name)
+		require.NotNil(t, env["FEATURE_TOKEN"].ValueFrom, "injected secretKeyRef of container %s", c.Name)
+		assert.Equal(t, "features", env["FEATURE_TOKEN"].ValueFrom.SecretKeyRef.Name, "injected secretKeyRef of container %s", c.Name)
+		require.NotEmpty(t, c.EnvFrom, "envFrom of container %s", c.Name)
+		assert.Equal(t, "platform", c.EnvFrom[0].ConfigMapRef.Name, "injected envFrom of container %s", c.Name)
+	}
+	require.NotEmpty(t, podSpec.InitContainers, "init containers")
+	for _, e := range podSpec.InitContainers[0].Env {
+		if e.Name == "GIT_SUB_DIR" {
+			assert.Equal(t, "source", e.Value, "should not override the env vars of the job.yaml")
+		}
+	}
+
+	err = launcher.InjectOptions{Env: `[{"value": "no-name"}]`}.Validate()
+	require.Error(t, err, "should fail to inject an env var without a name")
+}
+
+func TestJobLauncherInjectImagePullSecretsAndMirrors(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	objects, err := client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "somerepo"),
+		Inject: launcher.InjectOptions{
+			ImagePullSecrets: []string{"platform-registry"},
+			RegistryMirrors:  []string{"gcr.io=mirror.example.com/gcr"},
+		},
+	})
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	podSpec := objects[0].(*v1.Job).Spec.Template.Spec
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "platform-registry"}}, podSpec.ImagePullSecrets, "imagePullSecrets")
+	require.NotEmpty(t, podSpec.InitContainers, "init containers")
+	assert.Equal(t, "mirror.example.com/gcr/jenkinsxio-labs-private/jx-gitops:0.0.30", podSpec.InitContainers[0].Image, "mirrored image")
+}
+
+func TestJobLauncherOpenShiftSecurityContext(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "openshift"),
+		Inject: launcher.InjectOptions{
+			PodSecurityContext: `{"runAsNonRoot": true, "fsGroup": 1000}`,
+			SecurityContext:    `{"allowPrivilegeEscalation": false}`,
+			Platform:           launcher.PlatformOpenShift,
+		},
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	podSpec := objects[0].(*v1.Job).Spec.Template.Spec
+	require.NotNil(t, podSpec.SecurityContext, "pod security context")
+	assert.Nil(t, podSpec.SecurityContext.FSGroup, "the fsGroup should be assigned by OpenShift")
+	require.NotNil(t, podSpec.SecurityContext.RunAsNonRoot, "injected runAsNonRoot")
+	assert.True(t, *podSpec.SecurityContext.RunAsNonRoot, "injected runAsNonRoot")
+
+	initContainer := podSpec.InitContainers[0]
+	require.NotNil(t, initContainer.SecurityContext, "security context of the init container")
+	assert.Nil(t, initContainer.SecurityContext.RunAsUser, "the fixed user ID should be removed")
+	assert.Nil(t, initContainer.SecurityContext.RunAsGroup, "the fixed group ID should be removed")
+	require.NotNil(t, initContainer.SecurityContext.ReadOnlyRootFilesystem, "readOnlyRootFilesystem should be kept")
+	assert.Nil(t, initContainer.SecurityContext.AllowPrivilegeEscalation, "should not inject into an existing security context")
+
+	container := podSpec.Containers[0]
+	require.NotNil(t, container.SecurityContext, "injected security context of the container")
+	require.NotNil(t, container.SecurityContext.AllowPrivilegeEscalation, "injected allowPrivilegeEscalation")
+	assert.False(t, *container.SecurityContext.AllowPrivilegeEscalation, "injected allowPrivilegeEscalation")
+}
+
+func TestJobLauncherProxyAndCABundle(t *testing.T) {
+	for k, v := range map[string]string{"HTTPS_PROXY": "http://proxy.example.com:3128", "NO_PROXY": ".cluster.local"} {
+		old, found := os.LookupEnv(k)
+		require.NoError(t, os.Setenv(k, v))
+		defer func(k, old string, found bool) {
+			if found {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k, old, found)
+	}
+
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "proxy"),
+		Inject: launcher.InjectOptions{
+			Proxy:             true,
+			CABundleConfigMap: "trusted-ca",
+		},
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	podSpec := objects[0].(*v1.Job).Spec.Template.Spec
+	require.Len(t, podSpec.Volumes, 2, "volumes")
+	assert.Equal(t, "workspace", podSpec.Volumes[0].Name, "existing volume")
+	assert.Equal(t, launcher.CABundleVolumeName, podSpec.Volumes[1].Name, "CA bundle volume")
+	require.NotNil(t, podSpec.Volumes[1].ConfigMap, "CA bundle ConfigMap volume")
+	assert.Equal(t, "trusted-ca", podSpec.Volumes[1].ConfigMap.Name, "CA bundle ConfigMap")
+
+	caFile := filepath.Join(launcher.CABundleMountPath, launcher.DefaultCABundleKey)
+	for _, c := range append(podSpec.InitContainers, podSpec.Containers...) {
+		require.Len(t, c.VolumeMounts, 2, "volume mounts of container %s", c.Name)
+		assert.Equal(t, launcher.CABundleVolumeName, c.VolumeMounts[1].Name, "CA bundle volume mount of container %s", c.Name)
+		assert.Equal(t, launcher.CABundleMountPath, c.VolumeMounts[1].MountPath, "CA bundle mount path of container %s", c.Name)
+		assert.True(t, c.VolumeMounts[1].ReadOnly, "CA bundle mount of container %s should be read only", c.Name)
+
+		env := map[string]string{}
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		assert.Equal(t, "http://proxy.example.com:3128", env["HTTPS_PROXY"], "HTTPS_PROXY of container %s", c.Name)
+		assert.Equal(t, caFile, env["SSL_CERT_FILE"], "SSL_CERT_FILE of container %s", c.Name)
+		assert.Equal(t, caFile, env["GIT_SSL_CAINFO"], "GIT_SSL_CAINFO of container %s", c.Name)
+
+		expectedNoProxy := ".cluster.local"
+		if c.Name == "job" {
+			expectedNoProxy = "my-git-server"
+		}
+		assert.Equal(t, expectedNoProxy, env["NO_PROXY"], "NO_PROXY of container %s", c.Name)
+	}
+}
+
+func TestJobLauncherDrift(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitSha := "dummysha1234"
+
+	diffErr := exec.Command("sh", "-c", "exit 1").Run()
+	require.Error(t, diffErr, "should have created a diff exit error")
+
+	drifted := false
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if drifted && len(c.Args) > 0 && c.Args[0] == "diff" {
+				return "-  replicas: 1\n+  replicas: 2", diffErr
+			}
+			return "", nil
+		},
+	}
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: gitSha,
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	detector := client.(launcher.DriftDetector)
+
+	drift, err := detector.DetectDrift(o)
+	require.NoError(t, err, "failed to detect drift")
+	assert.False(t, drift.Drifted, "should not detect drift before launching")
+
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+	j1 := objects[0].(*v1.Job)
+
+	drifted = true
+	drift, err = detector.DetectDrift(o)
+	require.NoError(t, err, "failed to detect drift")
+	assert.False(t, drift.Drifted, "should not detect drift while the Job is active")
+
+	j1.Status.Succeeded = 1
+	j1.Status.Conditions = append(j1.Status.Conditions, v1.JobCondition{Type: v1.JobComplete, Status: corev1.ConditionTrue})
+	_, err = kubeClient.BatchV1().Jobs(ns).Update(j1)
+	require.NoError(t, err, "failed to update Job")
+
+	drift, err = detector.DetectDrift(o)
+	require.NoError(t, err, "failed to detect drift")
+	assert.True(t, drift.Drifted, "should detect drift")
+	assert.Contains(t, drift.Diff, "replicas: 2", "drift diff")
+
+	o.Relaunch = true
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to relaunch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after relaunching")
+
+	j2 := objects[0].(*v1.Job)
+	assert.Equal(t, j1.Name+"-a2", j2.Name, "relaunched Job name")
+	assert.Equal(t, "2", j2.Annotations[launcher.AttemptAnnotation], "relaunched Job attempt")
+	assert.Equal(t, int32(0), j2.Status.Succeeded, "relaunched Job should be active")
+	assert.Contains(t, j2.Annotations[launcher.DiffAnnotation], "replicas: 2", "relaunched Job should record the diff it applied")
+}
+
+func TestJobLauncherConcurrentLaunch(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitSha := "dummysha1234"
+
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: gitSha,
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+	j1 := objects[0].(*v1.Job)
+	assert.Equal(t, "1", j1.Annotations[launcher.AttemptAnnotation], "first attempt")
+
+	// lets simulate a concurrent launch whose list of Jobs has not caught up yet
+	stale := true
+	kubeClient.PrependReactor("list", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return stale, &v1.JobList{}, nil
+	})
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "creating an existing Job of the same commit and attempt should succeed")
+	assert.Len(t, objects, 0, "should not have created a runtime.Object as the Job already exists")
+
+	// a Job of another repository with the same name is still a failure
+	o.Repository.Name = "fake-repository-other"
+	o.Naming.Strategy = launcher.NamingTemplate
+	o.Naming.Template = "fake-repository-{{ .SHA }}"
+	_, err = client.Launch(o)
+	require.Error(t, err, "should fail to create a Job whose name is used by another repository")
+
+	stale = false
+	jobs, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	assert.Len(t, jobs.Items, 1, "Jobs")
+}
+
+func TestJobLauncherRelaunchRequest(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	succeed := func(objects []runtime.Object) {
+		require.Len(t, objects, 1, "should have created one runtime.Object")
+		j := objects[0].(*v1.Job)
+		j.Status.Succeeded = 1
+		j.Status.Conditions = append(j.Status.Conditions, v1.JobCondition{Type: v1.JobComplete, Status: corev1.ConditionTrue})
+		_, err := kubeClient.BatchV1().Jobs(ns).Update(j)
+		require.NoError(t, err, "failed to update Job")
+	}
+
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	succeed(objects)
+
+	o.Relaunch = true
+	o.RelaunchRequest = "2020-01-02T03:04:05Z"
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to relaunch the job")
+	require.Len(t, objects, 1, "should have relaunched the job")
+	j2 := objects[0].(*v1.Job)
+	assert.Equal(t, "2", j2.Annotations[launcher.AttemptAnnotation], "relaunched Job attempt")
+	assert.Equal(t, o.RelaunchRequest, j2.Annotations[launcher.RelaunchRequestAnnotation], "relaunch request")
+	succeed(objects)
+
+	// lets simulate the operator restarting before the relaunch request was acknowledged
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the same relaunch request")
+	assert.Len(t, objects, 0, "should not relaunch the same request again")
+
+	o.RelaunchRequest = "2020-01-02T03:05:00Z"
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to relaunch the job")
+	require.Len(t, objects, 1, "should relaunch a new request")
+	assert.Equal(t, "3", objects[0].(*v1.Job).Annotations[launcher.AttemptAnnotation], "relaunched Job attempt")
+}
+
+func TestJobLauncherPagination(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	// the Job of the previous commit is only on the second page of Jobs
+	active := &v1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "fake-repository-oldsha",
+			Namespace: ns,
+			Labels: map[string]string{
+				constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+				launcher.RepositoryLabelKey:  repoName,
+				launcher.CommitShaLabelKey:   "oldsha",
+			},
+		},
+	}
+	pages := 0
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("list", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pages++
+		if pages == 1 {
+			return true, &v1.JobList{ListMeta: metav1.ListMeta{Continue: "page2"}}, nil
+		}
+		return true, &v1.JobList{Items: []v1.Job{*active}}, nil
+	})
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	decision := &launcher.Decision{}
+	objects, err := client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA:   "newsha",
+		Dir:      filepath.Join("test_data", "somerepo"),
+		Decision: decision,
+	})
+	require.NoError(t, err, "failed to launch the job")
+	assert.Len(t, objects, 0, "should not launch while the Job on the second page is active")
+	assert.Equal(t, 2, pages, "pages of Jobs listed")
+	assert.Equal(t, "fake-repository-oldsha", decision.BlockedBy, "should record the Job blocking the launch")
+	assert.Equal(t, ns, decision.Namespace, "namespace of the blocking Job")
+}
+
+func TestJobLauncherCache(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	kubeClient := fake.NewSimpleClientset(
+		&v1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fake-repository-oldsha",
+				Namespace: ns,
+				Labels: map[string]string{
+					constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+					launcher.RepositoryLabelKey:  repoName,
+					launcher.CommitShaLabelKey:   "oldsha",
+				},
+			},
+		},
+	)
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	client.(launcher.Cacher).StartCache(ns, stop)
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "newsha",
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	assert.Eventually(t, func() bool {
+		kubeClient.ClearActions()
+		objects, err := client.Launch(o)
+		if err != nil || len(objects) > 0 {
+			return false
+		}
+		for _, action := range kubeClient.Actions() {
+			if action.Matches("list", "jobs") {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond, "should look up the active Job from the cache once it has synced")
+}
+
+func TestJobLauncherWatchCompletion(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+	j := objects[0].(*v1.Job)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	result, err := client.(launcher.CompletionWatcher).WatchCompletion(o, objects, stop)
+	require.NoError(t, err, "failed to watch the completion of the job")
+
+	completions := make(chan launcher.Completion, 1)
+	result.OnComplete(func(c launcher.Completion) {
+		completions <- c
+	})
+
+	started := metav1.NewTime(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC))
+	completed := metav1.NewTime(started.Add(90 * time.Second))
+	assert.Eventually(t, func() bool {
+		// lets keep updating the Job until the watch has started
+		latest, err := kubeClient.BatchV1().Jobs(ns).Get(j.Name, metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		latest.Status.Succeeded = 1
+		latest.Status.StartTime = &started
+		latest.Status.CompletionTime = &completed
+		latest.Status.Conditions = []v1.JobCondition{{Type: v1.JobComplete, Status: corev1.ConditionTrue}}
+		_, err = kubeClient.BatchV1().Jobs(ns).Update(latest)
+		if err != nil {
+			return false
+		}
+
+		select {
+		case <-result.Done():
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond, "should complete once the Job succeeds")
+
+	c := <-completions
+	assert.Equal(t, j.Name, c.Name, "completion name")
+	assert.Equal(t, launcher.ResultSucceeded, c.Result, "completion result")
+	assert.Equal(t, "dummysha1234", c.GitSHA, "completion git sha")
+	assert.Equal(t, 90*time.Second, c.Duration(), "completion duration")
+	assert.Equal(t, "job-name="+j.Name, c.LogSelector, "completion log selector")
+	assert.True(t, result.Succeeded(), "result should have succeeded")
+}
+
+func TestJobLauncherWatchCompletionRetrying(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+	j := objects[0].(*v1.Job)
+
+	// the first pod failed but the Job is still retrying within its backoffLimit
+	latest, err := kubeClient.BatchV1().Jobs(ns).Get(j.Name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the Job")
+	latest.Status.Failed = 1
+	_, err = kubeClient.BatchV1().Jobs(ns).Update(latest)
+	require.NoError(t, err, "failed to update the Job")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	result, err := client.(launcher.CompletionWatcher).WatchCompletion(o, objects, stop)
+	require.NoError(t, err, "failed to watch the completion of the job")
+
+	completions := make(chan launcher.Completion, 1)
+	result.OnComplete(func(c launcher.Completion) {
+		completions <- c
+	})
+	assert.Never(t, func() bool {
+		select {
+		case <-completions:
+			return true
+		default:
+			return false
+		}
+	}, 200*time.Millisecond, 10*time.Millisecond, "should not complete while the Job is retrying")
+
+	assert.Equal(t, launcher.ResultActive, job.JobResult(*latest), "result of a retrying Job")
+
+	// lets fail the Job once it has exhausted its retries
+	assert.Eventually(t, func() bool {
+		latest, err := kubeClient.BatchV1().Jobs(ns).Get(j.Name, metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		latest.Status.Failed = 2
+		latest.Status.Conditions = []v1.JobCondition{{Type: v1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+		_, err = kubeClient.BatchV1().Jobs(ns).Update(latest)
+		if err != nil {
+			return false
+		}
+		select {
+		case <-result.Done():
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond, "should complete once the Job has failed")
+
+	c := <-completions
+	assert.Equal(t, launcher.ResultFailed, c.Result, "completion result")
+}
+
+func TestJobLauncherJobMetadata(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitSha := "dummysha1234"
+
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+			JobLabels: map[string]string{"team": "team-a"},
+		},
+		GitSHA:  gitSha,
+		Dir:     filepath.Join("test_data", "metadata"),
+		Trigger: launcher.TriggerWebhook,
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j := objects[0].(*v1.Job)
+	assert.Equal(t, "platform-fake-repository-dummysha1234", j.Name, "Job name")
+	msg := "created Job"
+	testhelpers.AssertLabel(t, "cost-center", "1234", j.ObjectMeta, msg)
+	testhelpers.AssertLabel(t, "team", "team-a", j.ObjectMeta, msg)
+	testhelpers.AssertLabel(t, launcher.RepositoryLabelKey, repoName, j.ObjectMeta, msg)
+	testhelpers.AssertLabel(t, launcher.TriggerLabelKey, launcher.TriggerWebhook, j.ObjectMeta, msg)
+	testhelpers.AssertAnnotation(t, "example.com/owner", "platform@example.com", j.ObjectMeta, msg)
+	assert.Equal(t, map[string]string{"app": "boot", "cost-center": "1234", "team": "team-a"}, j.Spec.Template.Labels, "pod template labels")
+
+	// the labels of the operator are reserved
+	o.GitSHA = "othersha"
+	o.Repository.JobLabels = map[string]string{launcher.RepositoryLabelKey: "another-repository"}
+	_, err = client.Launch(o)
+	require.Error(t, err, "should not allow overriding the labels of the operator")
+	assert.Contains(t, err.Error(), "reserved", "error")
+}
+
+func TestJobLauncherOwnerReferences(t *testing.T) {
+	ns := "jx"
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Secret", Name: "fake-repository", UID: "fake-repository-uid"}
+
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:           "fake-repository",
+			Namespace:      ns,
+			GitURL:         "https://github.com/jenkins-x/fake-repository.git",
+			OwnerReference: &owner,
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "metadata"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j := objects[0].(*v1.Job)
+	assert.Empty(t, j.OwnerReferences, "the Job should not be owned by the Secret unless cascade deletion is enabled")
+
+	// lets use another repository as the active Job blocks launching a new commit
+	o.Repository.Name = "another-repository"
+	o.Repository.CascadeDelete = true
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j = objects[0].(*v1.Job)
+	assert.Equal(t, []metav1.OwnerReference{owner}, j.OwnerReferences, "owner references of the Job")
+}
+
+func TestJobLauncherJobDefaults(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	kubeClient := fake.NewSimpleClientset(
+		&v1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fake-repository-oldsha",
+				Namespace: ns,
+				Labels: map[string]string{
+					constants.DefaultSelectorKey: constants.DefaultSelectorValue,
+					launcher.RepositoryLabelKey:  repoName,
+					launcher.CommitShaLabelKey:   "oldsha",
+				},
+			},
+			Status: v1.JobStatus{Succeeded: 1, Conditions: []v1.JobCondition{{Type: v1.JobComplete, Status: corev1.ConditionTrue}}},
+		},
+	)
+	dynamicClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client, err := job.NewLauncher(kubeClient, dynamicClient, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "newsha",
+		Dir:    filepath.Join("test_data", "defaults"),
+		JobDefaults: launcher.JobDefaults{
+			TTLAfterFinished:   time.Hour,
+			BackoffLimit:       "1",
+			TransientExitCodes: []int{137},
+		},
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	j := objects[0].(*v1.Job)
+	require.NotNil(t, j.Spec.BackoffLimit, "backoff limit")
+	assert.Equal(t, int32(1), *j.Spec.BackoffLimit, "backoff limit")
+	assert.Nil(t, j.Spec.TTLSecondsAfterFinished, "the latest Job should not have a time to live")
+
+	// the pod failure policy is not supported by the typed client so the Job is created via the dynamic client
+	u, err := dynamicClient.Resource(schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}).Namespace(ns).Get(j.Name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the Job %s", j.Name)
+	rules, _, _ := unstructured.NestedSlice(u.Object, "spec", "podFailurePolicy", "rules")
+	assert.Len(t, rules, 2, "rules of the pod failure policy of Job %s", j.Name)
+
+	old, err := kubeClient.BatchV1().Jobs(ns).Get("fake-repository-oldsha", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the superseded Job")
+	require.NotNil(t, old.Spec.TTLSecondsAfterFinished, "the superseded Job should have a time to live")
+	assert.Equal(t, int32(3600), *old.Spec.TTLSecondsAfterFinished, "time to live of the superseded Job")
+}
+
+func TestJobLauncherOrderedApply(t *testing.T) {
+	ns := "jx"
+
+	var applied [][]string
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if len(c.Args) < 3 || c.Args[0] != "apply" {
+				return "", nil
+			}
+			resources, err := job.LoadResourcesDir(c.Args[2])
+			require.NoError(t, err, "failed to load applied resources in %s", c.Args[2])
+
+			var kinds []string
+			for _, r := range resources {
+				kinds = append(kinds, r.GetKind())
+			}
+			applied = append(applied, kinds)
+			return "", nil
+		},
+	}
+	client, err := job.NewLauncher(fake.NewSimpleClientset(), nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	_, err = client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "crds"),
+	})
+	require.NoError(t, err, "failed to launch the job")
+
+	assert.Equal(t, [][]string{
+		{"Namespace"},
+		{"CustomResourceDefinition"},
+		{"ServiceAccount"},
+		{"Widget"},
+	}, applied, "applied resource kinds in order")
+
+	var waited bool
+	for _, c := range runner.OrderedCommands {
+		if len(c.Args) > 0 && c.Args[0] == "wait" {
+			waited = true
+		}
+	}
+	assert.True(t, waited, "should have waited for the CustomResourceDefinitions to be established")
+}
+
+func TestJobLauncherTenantOnlyRendersNewJobs(t *testing.T) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+
+	// lets fake helm rendering a Deployment in the namespace of the last values file
+	renders := 0
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if c.Name != "helm" {
+				return "", nil
+			}
+			renders++
+			outDir := ""
+			for i := 0; i+1 < len(c.Args); i++ {
+				if c.Args[i] == "--output-dir" {
+					outDir = c.Args[i+1]
+				}
+			}
+			dir := filepath.Join(outDir, "boot-resources", "templates")
+			err := os.MkdirAll(dir, os.ModePerm)
+			require.NoError(t, err, "failed to create dir %s", dir)
+			text := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: " + c.Args[1] + "\n  namespace: " + ns + "\n"
+			return "", ioutil.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(text), 0600)
+		},
+	}
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "chart"),
+		Tenant: &tenant.Tenant{Name: "team-a", Namespaces: []string{"team-a"}},
+	}
+	_, err = client.Launch(o)
+	require.Error(t, err, "should not launch in a namespace the tenant is not allowed to use")
+	assert.Contains(t, err.Error(), "is not allowed to launch", "error")
+	jobs, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	assert.Empty(t, jobs.Items, "should not create a Job for a disallowed tenant")
+
+	o.Tenant = &tenant.Tenant{Name: "team-a", Namespaces: []string{ns}}
+	renders = 0
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+	assert.Equal(t, 2, renders, "should render the chart to validate the tenant and to apply it")
+
+	renders = 0
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to poll the launched job")
+	assert.Empty(t, objects, "should not launch the same sha again")
+	assert.Equal(t, 0, renders, "should not render the chart to validate the tenant if no new Job is created")
+}
+
+func TestJobLauncherHelmChart(t *testing.T) {
+	ns := "jx"
+	kubeConfigSecret := "remote-cluster"
+
+	remoteKubeClient := fake.NewSimpleClientset()
+	oldNewRemoteClients := job.NewRemoteClients
+	defer func() {
+		job.NewRemoteClients = oldNewRemoteClients
+	}()
+	job.NewRemoteClients = func(kubeConfig []byte) (kubernetes.Interface, dynamic.Interface, error) {
+		return remoteKubeClient, nil, nil
+	}
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      kubeConfigSecret,
+				Namespace: ns,
+			},
+			Data: map[string][]byte{
+				job.KubeConfigSecretKey: []byte("dummy-kubeconfig"),
+			},
+		},
+	)
+
+	// lets fake helm rendering the chart using the replicas of any values file
+	var applied []*unstructured.Unstructured
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			switch {
+			case c.Name == "helm":
+				replicas := "1"
+				outDir := ""
+				for i := 0; i+1 < len(c.Args); i++ {
+					switch c.Args[i] {
+					case "--output-dir":
+						outDir = c.Args[i+1]
+					case "--values":
+						replicas = "3"
+					}
+				}
+				require.NotEmpty(t, outDir, "should render the chart to an output dir")
+				dir := filepath.Join(outDir, "boot-resources", "templates")
+				err := os.MkdirAll(dir, os.ModePerm)
+				require.NoError(t, err, "failed to create dir %s", dir)
+				text := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: " + c.Args[1] + "\nspec:\n  replicas: " + replicas + "\n"
+				return "", ioutil.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(text), 0600)
+			case len(c.Args) > 2 && c.Args[0] == "apply":
+				resources, err := job.LoadResourcesDir(c.Args[2])
+				require.NoError(t, err, "failed to load applied resources in %s", c.Args[2])
+				applied = append(applied, resources...)
+			}
+			return "", nil
+		},
+	}
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "chart"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	require.Len(t, applied, 1, "should have applied the rendered chart")
+	assert.Equal(t, "Deployment", applied[0].GetKind(), "applied kind")
+	assert.Equal(t, "fake-repository", applied[0].GetName(), "should use the repository as the release name")
+	testhelpers.AssertLabel(t, launcher.RepositoryLabelKey, "fake-repository", metav1.ObjectMeta{Labels: applied[0].GetLabels()}, "applied Deployment")
+	replicas, _, _ := unstructured.NestedFieldNoCopy(applied[0].Object, "spec", "replicas")
+	assert.EqualValues(t, 1, replicas, "should use the default values in the local cluster")
+
+	helm := runner.OrderedCommands[0]
+	assert.Equal(t, "helm", helm.Name, "should render the chart before applying it")
+	assert.Equal(t, []string{"template", "fake-repository", ".", "--namespace", ns, "--include-crds"}, helm.Args[0:6], "helm arguments")
+
+	// lets use the values file of the remote cluster
+	applied = nil
+	o.Repository.KubeConfigSecrets = []string{kubeConfigSecret}
+	_, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the job in the remote cluster")
+	require.Len(t, applied, 1, "should have applied the rendered chart in the remote cluster")
+	replicas, _, _ = unstructured.NestedFieldNoCopy(applied[0].Object, "spec", "replicas")
+	assert.EqualValues(t, 3, replicas, "should use the values file of the remote cluster")
+	assert.Contains(t, runner.OrderedCommands[len(runner.OrderedCommands)-3].Args, "values-remote-cluster.yaml", "helm arguments in the remote cluster")
+}
+
+func TestJobLauncherRetryTransientApply(t *testing.T) {
+	ns := "jx"
+
+	failures := 1
+	fakeRunner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if len(c.Args) > 0 && c.Args[0] == "apply" && failures > 0 {
+				failures--
+				return "", errors.Errorf("dial tcp 10.0.0.1:443: connect: connection refused")
+			}
+			return "", nil
+		},
+	}
+	client, err := job.NewLauncher(fake.NewSimpleClientset(), nil, ns, constants.DefaultSelector, fakeRunner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	_, err = client.Launch(o)
+	require.Error(t, err, "the launcher should leave retrying transient command errors to the command runner")
+
+	// the command runner retries transient errors
+	failures = 1
+	commands := &runner.Options{Retries: 2, RetryBackoff: time.Millisecond}
+	commandRunner, err := commands.Wrap(fakeRunner.Run)
+	require.NoError(t, err, "failed to wrap the command runner")
+	client, err = job.NewLauncher(fake.NewSimpleClientset(), nil, ns, constants.DefaultSelector, commandRunner)
+	require.NoError(t, err, "failed to create launcher client")
+
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "should have retried the transient apply error")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+
+	// permanent errors are not retried
+	fakeRunner.CommandRunner = func(c *cmdrunner.Command) (string, error) {
+		if len(c.Args) > 0 && c.Args[0] == "apply" {
+			return "", errors.Errorf(`error validating "sa.yaml": unknown field "foo"`)
+		}
+		return "", nil
+	}
+	fakeRunner.OrderedCommands = nil
+	o.GitSHA = "anothersha5678"
+	client, err = job.NewLauncher(fake.NewSimpleClientset(), nil, ns, constants.DefaultSelector, commandRunner)
+	require.NoError(t, err, "failed to create launcher client")
+
+	_, err = client.Launch(o)
+	require.Error(t, err, "should have failed to apply the invalid resources")
+
+	applies := 0
+	for _, c := range fakeRunner.OrderedCommands {
+		if c.Args[0] == "apply" {
+			applies++
+		}
+	}
+	assert.Equal(t, 1, applies, "should not retry a permanent apply error")
+}
+
+func TestJobLauncherSuspendApproval(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+
+	kubeClient := fake.NewSimpleClientset()
+	dynamicClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client, err := job.NewLauncher(kubeClient, dynamicClient, ns, constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA:  "dummysha1234",
+		Dir:     filepath.Join("test_data", "somerepo"),
+		Suspend: true,
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created one runtime.Object after launching")
+	j1 := objects[0].(*v1.Job)
+	assert.Equal(t, launcher.ApprovalPending, j1.Annotations[launcher.ApprovalAnnotation], "approval annotation")
+
+	u, err := dynamicClient.Resource(schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}).Namespace(ns).Get(j1.Name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the suspended Job %s", j1.Name)
+	suspend, _, _ := unstructured.NestedBool(u.Object, "spec", "suspend")
+	assert.True(t, suspend, "spec.suspend of Job %s", j1.Name)
+
+	events, err := kubeClient.CoreV1().Events(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Events")
+	require.Len(t, events.Items, 1, "should create a PendingApproval Event")
+	assert.Equal(t, "PendingApproval", events.Items[0].Reason, "Event reason")
+	assert.Equal(t, j1.Name, events.Items[0].InvolvedObject.Name, "Event involved object")
+
+	// lets simulate the Job being visible via the typed client and approved
+	j1.Annotations[launcher.ApprovalAnnotation] = launcher.ApprovalApproved
+	_, err = kubeClient.BatchV1().Jobs(ns).Create(j1)
+	require.NoError(t, err, "failed to create the Job %s", j1.Name)
+
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	assert.Len(t, objects, 0, "should not create another Job")
+	j2, err := kubeClient.BatchV1().Jobs(ns).Get(j1.Name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the Job %s", j1.Name)
+	assert.Equal(t, launcher.ApprovalResumed, j2.Annotations[launcher.ApprovalAnnotation], "approval annotation once resumed")
+}
+
+func TestJobLauncherNoResourceApply(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitSha := "dummysha1234"
+
+	runner := &fakerunner.FakeRunner{}
+	kubeClient := fake.NewSimpleClientset()
+	client, err := launcher.New(job.LauncherName, launcher.FactoryOptions{
+		KubeClient:      kubeClient,
+		Namespace:       ns,
+		Selector:        constants.DefaultSelector,
+		CommandRunner:   runner.Run,
+		NoResourceApply: true,
+	})
+	require.NoError(t, err, "failed to create launcher client")
+
+	r := repo.Repository{
+		Name:      repoName,
+		Namespace: ns,
+		GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+	}
+	o := launcher.LaunchOptions{
+		Repository: r,
+		GitSHA:     gitSha,
+		Dir:        filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch the job")
+	require.Len(t, objects, 1, "should have created the Job")
+
+	drift, err := client.(launcher.DriftDetector).DetectDrift(o)
+	require.NoError(t, err, "failed to detect drift")
+	assert.False(t, drift.Drifted, "should not detect drift")
+
+	_, err = client.(launcher.GarbageCollector).GarbageCollect(nil)
+	require.NoError(t, err, "failed to garbage collect")
+	err = client.(launcher.Cleaner).Cleanup(r)
+	require.NoError(t, err, "failed to clean up")
+
+	assert.Empty(t, runner.OrderedCommands, "should not have run kubectl")
+	inventories, err := inventory.List(kubeClient, ns)
+	require.NoError(t, err, "failed to list inventories")
+	assert.Empty(t, inventories, "should not have recorded an inventory")
+}
+
+func TestJobLauncherValidateResources(t *testing.T) {
+	ns := "jx"
+	repoName := "fake-repository"
+	gitSha := "dummysha1234"
+
+	dryRunErr := exec.Command("sh", "-c", "exit 1").Run()
+	require.Error(t, dryRunErr, "should have created a dry run exit error")
+
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if len(c.Args) < 4 || c.Args[1] != "--dry-run=server" {
+				return "", nil
+			}
+			resources, err := job.LoadResources(c.Args[3])
+			if err != nil {
+				// lets fail the dry run of the whole directory
+				return "error validating data", dryRunErr
+			}
+			switch resources[0].GetKind() {
+			case "CustomResourceDefinition":
+				return "The CustomResourceDefinition \"widgets.example.com\" is invalid: spec.versions: Required value", dryRunErr
+			case "Namespace":
+				return "", nil
+			default:
+				return "no matches for kind \"Widget\" in version \"example.com/v1\"", dryRunErr
+			}
+		},
+	}
+	kubeClient := fake.NewSimpleClientset()
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(t, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      repoName,
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA:            gitSha,
+		Dir:               filepath.Join("test_data", "crds"),
+		ValidateResources: true,
+	}
+	_, err = client.Launch(o)
+	require.Error(t, err, "should fail to launch invalid resources")
+	validationErr, ok := errors.Cause(err).(*launcher.ValidationError)
+	require.True(t, ok, "should return a ValidationError but got %s", err.Error())
+	require.Len(t, validationErr.Failures, 1, "should only report the invalid resource")
+	assert.Contains(t, validationErr.Failures[0], "CustomResourceDefinition", "validation failure")
+	assert.Contains(t, validationErr.Failures[0], "spec.versions: Required value", "validation failure")
+
+	for _, c := range runner.OrderedCommands {
+		assert.Equal(t, "--dry-run=server", c.Args[1], "should only have run dry runs: %s", c.CLI())
+	}
+	jobs, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	assert.Empty(t, jobs.Items, "should not have created a Job")
+}
+
+func TestJobLauncherNamespaceDirs(t *testing.T) {
+	folder := filepath.Join("test_data", "namespaces", ".jx", "git-operator")
+	resources, source, err := job.LoadApplyResources(nil, folder, "fake-repository", "jx", "")
+	require.NoError(t, err, "failed to load the resources to apply")
+	assert.Equal(t, filepath.Join(folder, job.ResourcesDirName), source, "source")
+
+	namespaces := map[string]string{}
+	for _, r := range resources {
+		namespaces[r.GetKind()+"/"+r.GetName()] = r.GetNamespace()
+	}
+	assert.Equal(t, map[string]string{
+		"ServiceAccount/my-job":    "",
+		"ConfigMap/boot-config":    "staging",
+		"ClusterRole/boot-staging": "",
+	}, namespaces, "namespaces of the resources")
+}
+
+func TestJobLauncherVersionStreamPatch(t *testing.T) {
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: "jx",
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "layered"),
+	}
+	folder, resources, err := job.LoadLaunchResources(o)
+	require.NoError(t, err, "failed to load the launch resources")
+	assert.Equal(t, filepath.Join("test_data", "layered", "versionStream", "git-operator"), folder, "should use the version stream folder")
+	require.Len(t, resources, 1, "resources")
+
+	j, err := job.ToJob(resources[0])
+	require.NoError(t, err, "failed to convert to a Job")
+	require.NotNil(t, j.Spec.BackoffLimit, "backoffLimit")
+	assert.Equal(t, int32(2), *j.Spec.BackoffLimit, "patched backoffLimit")
+	require.Len(t, j.Spec.Template.Spec.InitContainers, 1, "should keep the init containers of the version stream")
+	require.Len(t, j.Spec.Template.Spec.Containers, 1, "should merge the containers by name")
+
+	c := j.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "gcr.io/myorg/jx-gitops:1.2.3", c.Image, "patched image")
+	assert.Equal(t, []string{"make"}, c.Command, "should keep the command of the version stream")
+	assert.Equal(t, []corev1.EnvVar{{Name: "MY_SETTING", Value: "local"}}, c.Env, "patched env")
+}
+
+func TestJobLauncherInvalidJobFile(t *testing.T) {
+	fileName := filepath.Join("test_data", "invalid", ".jx", "git-operator", "job.yaml")
+	err := job.ValidateJobFile(fileName)
+	require.Error(t, err, "should fail to validate %s", fileName)
+	schemaErr, ok := err.(*job.SchemaError)
+	require.True(t, ok, "should return a SchemaError but got %s", err.Error())
+	assert.Equal(t, []job.SchemaProblem{
+		{Line: 6, Path: "spec.backoffLimitt", Message: "unknown field"},
+		{Line: 12, Path: "spec.template.spec.containers[0].imagePullPolicyy", Message: "unknown field"},
+		{Line: 18, Path: "apiVersion", Message: "unknown apiVersion batch/v2 for kind Job"},
+	}, schemaErr.Problems, "problems")
+	assert.Contains(t, err.Error(), fileName+": line 6: spec.backoffLimitt: unknown field", "error message")
+
+	client, err := job.NewLauncher(fake.NewSimpleClientset(), nil, "jx", constants.DefaultSelector, (&fakerunner.FakeRunner{}).Run)
+	require.NoError(t, err, "failed to create launcher client")
+	_, err = client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: "jx",
+		},
+		GitSHA: "dummysha1234",
+		Dir:    filepath.Join("test_data", "invalid"),
+	})
+	require.Error(t, err, "should fail to launch an invalid job file")
+	_, ok = errors.Cause(err).(*job.SchemaError)
+	assert.True(t, ok, "should return a SchemaError but got %s", err.Error())
+
+	for _, dir := range []string{"somerepo", "layered", "crds", "namespaces"} {
+		folder, err := job.FindGitOperatorFolder(filepath.Join("test_data", dir))
+		require.NoError(t, err, "failed to find folder in %s", dir)
+		assert.NoError(t, job.ValidateJobFile(filepath.Join(folder, "job.yaml")), "should validate the job file of %s", dir)
+	}
+}
+
+func TestJobLauncherTriggers(t *testing.T) {
+	triggers := &job.Triggers{
+		Include: []string{"config/**", "apps/**", "*.yaml"},
+		Exclude: []string{"**/README.md", "config/dev/*"},
+		Jobs: []job.JobTrigger{
+			{Paths: []string{"apps/**"}, Job: "apps-job.yaml"},
+			{Paths: []string{"config/prod/*.yaml"}, Job: "prod-job.yaml"},
+		},
+	}
+	testCases := []struct {
+		changed  []string
+		expected []string
+	}{
+		{nil, []string{"job.yaml", "apps-job.yaml", "prod-job.yaml"}},
+		{[]string{}, nil},
+		{[]string{"docs/intro.md", "apps/README.md", "config/dev/values.yaml"}, nil},
+		{[]string{"apps/myapp/values.yaml"}, []string{"apps-job.yaml"}},
+		{[]string{"config/prod/values.yaml", "apps/myapp/values.yaml"}, []string{"apps-job.yaml", "prod-job.yaml"}},
+		{[]string{"config/staging/values.yaml"}, []string{"job.yaml"}},
+		{[]string{"Makefile.yaml"}, []string{"job.yaml"}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, triggers.JobFiles(tc.changed), "job files for changed paths %v", tc.changed)
+	}
+}
+
+func BenchmarkJobLauncher(b *testing.B) {
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset()
+	runner := &fakerunner.FakeRunner{}
+	client, err := job.NewLauncher(kubeClient, nil, ns, constants.DefaultSelector, runner.Run)
+	require.NoError(b, err, "failed to create launcher client")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:      "fake-repository",
+			Namespace: ns,
+			GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+		},
+		Dir: filepath.Join("test_data", "somerepo"),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		// lets remove the active Job of the previous commit
+		jobs, err := kubeClient.BatchV1().Jobs(ns).List(metav1.ListOptions{})
+		require.NoError(b, err, "failed to list the Jobs")
+		for _, j := range jobs.Items {
+			err = kubeClient.BatchV1().Jobs(ns).Delete(j.Name, nil)
+			require.NoError(b, err, "failed to delete Job %s", j.Name)
+		}
+		o.GitSHA = fmt.Sprintf("sha%d", i)
+		b.StartTimer()
+
+		_, err = client.Launch(o)
+		require.NoError(b, err, "failed to launch the job")
+	}
+}
@@ -0,0 +1,195 @@
+package job
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// clusterScopedKinds the well known kinds of cluster scoped resources which are not moved into the namespace of a
+// `namespaces/<namespace>` directory
+var clusterScopedKinds = []string{
+	"APIService",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"CustomResourceDefinition",
+	"MutatingWebhookConfiguration",
+	"Namespace",
+	"PersistentVolume",
+	"PriorityClass",
+	"StorageClass",
+	"ValidatingWebhookConfiguration",
+}
+
+// LoadResources loads the resources from the given file which may contain multiple `---` separated documents
+// or `List` resources. Documents without a kind are assumed to be a `Job`
+func LoadResources(fileName string) ([]*unstructured.Unstructured, error) {
+	answer, err := loadResources(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(answer) == 0 {
+		return nil, errors.Errorf("no resources found in file %s", fileName)
+	}
+	return answer, nil
+}
+
+// LoadResourcesDir loads all the resources in the `*.yaml` and `*.yml` files in the given directory tree
+func LoadResourcesDir(dir string) ([]*unstructured.Unstructured, error) {
+	var answer []*unstructured.Unstructured
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		resources, err := loadResources(path)
+		if err != nil {
+			return err
+		}
+		answer = append(answer, resources...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load resources in dir %s", dir)
+	}
+	return answer, nil
+}
+
+// loadResourcesDirWithNamespaces loads all the resources in the given directory tree like LoadResourcesDir. The
+// resources in a `namespaces/<namespace>` directory are moved to that namespace, overriding any namespace in the
+// file, other than cluster scoped resources
+func loadResourcesDirWithNamespaces(dir string) ([]*unstructured.Unstructured, error) {
+	var answer []*unstructured.Unstructured
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		resources, err := loadResources(path)
+		if err != nil {
+			return err
+		}
+		ns := targetNamespace(dir, path)
+		for _, r := range resources {
+			if ns != "" && !isClusterScoped(r) {
+				r.SetNamespace(ns)
+			}
+		}
+		answer = append(answer, resources...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load resources in dir %s", dir)
+	}
+	return answer, nil
+}
+
+// targetNamespace returns the namespace of the `namespaces/<namespace>` directory containing the given file within
+// the resources directory or an empty string if the file is not in a namespace directory
+func targetNamespace(dir string, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 3 || parts[0] != NamespacesDirName {
+		return ""
+	}
+	return parts[1]
+}
+
+// isClusterScoped returns true if the resource is a well known cluster scoped kind which cannot have a namespace
+func isClusterScoped(r *unstructured.Unstructured) bool {
+	return stringhelpers.StringArrayIndex(clusterScopedKinds, r.GetKind()) >= 0
+}
+
+func loadResources(fileName string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open file %s", fileName)
+	}
+	defer f.Close()
+
+	var answer []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		m := map[string]interface{}{}
+		err = decoder.Decode(&m)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse YAML in file %s", fileName)
+		}
+		if len(m) == 0 {
+			continue
+		}
+		resources, err := toResources(m)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load resource in file %s", fileName)
+		}
+		answer = append(answer, resources...)
+	}
+	return answer, nil
+}
+
+// IsJobResource returns true if the resource is a batch `Job`
+func IsJobResource(u *unstructured.Unstructured) bool {
+	gvk := u.GroupVersionKind()
+	return gvk.Kind == "Job" && gvk.Group == "batch"
+}
+
+// ToJob converts the unstructured resource to a Job
+func ToJob(u *unstructured.Unstructured) (*v1.Job, error) {
+	j := &v1.Job{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, j)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert to a Job")
+	}
+	return j, nil
+}
+
+// toResources converts the given document to resources, expanding any `List` resources
+func toResources(m map[string]interface{}) ([]*unstructured.Unstructured, error) {
+	kind, _ := m["kind"].(string)
+	if kind == "List" || kind == "JobList" {
+		var answer []*unstructured.Unstructured
+		items, _ := m["items"].([]interface{})
+		for _, item := range items {
+			im, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("unsupported item in %s: %#v", kind, item)
+			}
+			resources, err := toResources(im)
+			if err != nil {
+				return nil, err
+			}
+			answer = append(answer, resources...)
+		}
+		return answer, nil
+	}
+
+	u := &unstructured.Unstructured{Object: m}
+	if kind == "" {
+		u.SetAPIVersion("batch/v1")
+		u.SetKind("Job")
+	}
+	if u.GetAPIVersion() == "" {
+		return nil, errors.Errorf("resource of kind %s has no apiVersion", kind)
+	}
+	return []*unstructured.Unstructured{u}, nil
+}
@@ -0,0 +1,106 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// launchLocks the in-memory locks of the repositories being launched so that concurrent launches of the same
+// repository, such as by a webhook and a poll, never race to create its resources. Each lock only lives while the
+// repository is being launched
+type launchLocks struct {
+	lock  sync.Mutex
+	repos map[string]*launchLock
+}
+
+// launchLock the lock of a repository and the number of launches holding or waiting for it
+type launchLock struct {
+	sync.Mutex
+	users int
+}
+
+// lockRepository locks the repository in the cluster returning the function to unlock it
+func (l *launchLocks) lockRepository(ns string, safeName string, cluster string) func() {
+	key := ns + "/" + safeName + "/" + cluster
+	l.lock.Lock()
+	if l.repos == nil {
+		l.repos = map[string]*launchLock{}
+	}
+	m := l.repos[key]
+	if m == nil {
+		m = &launchLock{}
+		l.repos[key] = m
+	}
+	m.users++
+	l.lock.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		m.users--
+		if m.users == 0 {
+			delete(l.repos, key)
+		}
+	}
+}
+
+// attemptOf returns the launch attempt recorded on a resource. Resources which do not record an attempt were
+// launched by the first attempt
+func attemptOf(annotations map[string]string) int {
+	attempt, err := strconv.Atoi(annotations[launcher.AttemptAnnotation])
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
+
+// attemptName returns the name of the resources of the given launch attempt. The first attempt uses the name as is
+func attemptName(name string, attempt int, safeName string, safeSha string) string {
+	if attempt <= 1 {
+		return name
+	}
+	return launcher.SuffixedName(name, fmt.Sprintf("-a%d", attempt), safeName, safeSha)
+}
+
+// isConcurrentLaunch returns true if the existing resource with the name of the given resource was launched for the
+// same repository, commit sha and attempt, such as by a concurrent launch which listed the resources before this
+// one was created, so that creating the resource again can be treated as a success
+func isConcurrentLaunch(clients *clusterClients, resource *unstructured.Unstructured, ns string) (bool, error) {
+	var existing metav1.Object
+	name := resource.GetName()
+	if IsJobResource(resource) {
+		j, err := clients.kubeClient.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to get Job %s in namespace %s", name, ns)
+		}
+		existing = j
+	} else {
+		dynamicClient, err := clients.getDynamicClient()
+		if err != nil {
+			return false, err
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(resource.GroupVersionKind())
+		u, err := dynamicClient.Resource(gvr).Namespace(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to get %s %s in namespace %s", resource.GetKind(), name, ns)
+		}
+		existing = u
+	}
+	for _, key := range []string{launcher.RepositoryLabelKey, launcher.CommitShaLabelKey, launcher.ClusterLabelKey, launcher.RollbackLabelKey, launcher.PostJobLabelKey} {
+		if existing.GetLabels()[key] != resource.GetLabels()[key] {
+			return false, nil
+		}
+	}
+	return attemptOf(existing.GetAnnotations()) == attemptOf(resource.GetAnnotations()), nil
+}
@@ -0,0 +1,169 @@
+package job
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// JobMetadataFileName the name of the optional file in the git operator folder which specifies the name prefix,
+	// labels and annotations of the resources launched for the repository
+	JobMetadataFileName = "job-metadata.yaml"
+
+	// maxNamePrefixLength the maximum length of the name prefix of launched resources
+	maxNamePrefixLength = 20
+
+	// reservedPrefix the prefix of the labels and annotations which are reserved for the operator
+	reservedPrefix = "git-operator.jenkins.io/"
+)
+
+// JobMetadata the configuration of the `job-metadata.yaml` file in the git operator folder
+type JobMetadata struct {
+	// NamePrefix the optional prefix of the names of the launched resources such as `team-a`
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// Labels the optional labels added to the launched resources and the pods of their Jobs
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations the optional annotations added to the launched resources
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// LoadJobMetadata loads the `job-metadata.yaml` file in the given git operator folder returning nil if it does not
+// exist
+func LoadJobMetadata(folder string) (*JobMetadata, error) {
+	fileName := filepath.Join(folder, JobMetadataFileName)
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file %s exists", fileName)
+	}
+	if !exists {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load file %s", fileName)
+	}
+	m := &JobMetadata{}
+	err = yaml.UnmarshalStrict(data, m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal YAML file %s", fileName)
+	}
+	return m, nil
+}
+
+// resolveJobMetadata returns the repository with the name prefix, labels and annotations of its launched resources
+// resolved from the `job-metadata.yaml` file in the git operator folder, which are overridden by those specified
+// via the annotations of the repository
+func resolveJobMetadata(r repo.Repository, folder string) (repo.Repository, error) {
+	m, err := LoadJobMetadata(folder)
+	if err != nil {
+		return r, err
+	}
+	if m == nil {
+		m = &JobMetadata{}
+	}
+	if r.JobNamePrefix != "" {
+		m.NamePrefix = r.JobNamePrefix
+	}
+	m.Labels = mergeMaps(m.Labels, r.JobLabels)
+	m.Annotations = mergeMaps(m.Annotations, r.JobAnnotations)
+	err = m.Validate()
+	if err != nil {
+		return r, err
+	}
+	r.JobNamePrefix = m.NamePrefix
+	r.JobLabels = m.Labels
+	r.JobAnnotations = m.Annotations
+	return r, nil
+}
+
+// Validate validates the name prefix and labels are valid and that the labels and annotations are not reserved for
+// the operator
+func (m *JobMetadata) Validate() error {
+	if m.NamePrefix != "" {
+		if len(m.NamePrefix) > maxNamePrefixLength {
+			return errors.Errorf("the name prefix %s is longer than %d characters", m.NamePrefix, maxNamePrefixLength)
+		}
+		if problems := validation.IsDNS1123Label(m.NamePrefix); len(problems) > 0 {
+			return errors.Errorf("invalid name prefix %s: %s", m.NamePrefix, strings.Join(problems, ", "))
+		}
+	}
+	for k, v := range m.Labels {
+		if strings.HasPrefix(k, reservedPrefix) {
+			return errors.Errorf("the label %s is reserved for the operator", k)
+		}
+		problems := append(validation.IsQualifiedName(k), validation.IsValidLabelValue(v)...)
+		if len(problems) > 0 {
+			return errors.Errorf("invalid label %s=%s: %s", k, v, strings.Join(problems, ", "))
+		}
+	}
+	for k := range m.Annotations {
+		if strings.HasPrefix(k, reservedPrefix) {
+			return errors.Errorf("the annotation %s is reserved for the operator", k)
+		}
+		if problems := validation.IsQualifiedName(k); len(problems) > 0 {
+			return errors.Errorf("invalid annotation %s: %s", k, strings.Join(problems, ", "))
+		}
+	}
+	return nil
+}
+
+// addPodTemplateLabels adds the labels to the pod template of a Job so that the labels of the repository are also
+// on the pods of the Job such as for cost allocation
+func addPodTemplateLabels(resource *unstructured.Unstructured, labels map[string]string) error {
+	if len(labels) == 0 || !IsJobResource(resource) {
+		return nil
+	}
+	podLabels, _, err := unstructured.NestedStringMap(resource.Object, "spec", "template", "metadata", "labels")
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the pod template labels")
+	}
+	if podLabels == nil {
+		podLabels = map[string]string{}
+	}
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+	return unstructured.SetNestedStringMap(resource.Object, podLabels, "spec", "template", "metadata", "labels")
+}
+
+// addOwnerReference adds the owner reference of the repository resource to the resource if the repository opted in
+// to cascade deletion so that deleting the repository resource deletes the resource too. Owner references cannot span
+// namespaces or clusters so they are only added to resources created in the namespace of the repository in the local
+// cluster
+func addOwnerReference(resource *unstructured.Unstructured, r repo.Repository, clients *clusterClients, ns string) {
+	if !r.CascadeDelete || r.OwnerReference == nil || clients.local == nil || ns != r.Namespace {
+		return
+	}
+	owners := resource.GetOwnerReferences()
+	for _, o := range owners {
+		if o.UID == r.OwnerReference.UID {
+			return
+		}
+	}
+	resource.SetOwnerReferences(append(owners, *r.OwnerReference))
+}
+
+// mergeMaps returns the values of the first map overridden by those of the second map
+func mergeMaps(m1 map[string]string, m2 map[string]string) map[string]string {
+	if len(m2) == 0 {
+		return m1
+	}
+	answer := map[string]string{}
+	for k, v := range m1 {
+		answer[k] = v
+	}
+	for k, v := range m2 {
+		answer[k] = v
+	}
+	return answer
+}
@@ -0,0 +1,108 @@
+package job
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// JobPatchFileName the name of the optional file in the `.jx/git-operator` folder of a repository containing the
+// strategic merge patches applied to the resources of the `job.yaml` file, such as the one in the version stream,
+// so that local customisations survive upgrades of the version stream
+const JobPatchFileName = "job-patch.yaml"
+
+// patchLaunchResources applies the patches in the `job-patch.yaml` file in the `.jx/git-operator` folder of the git
+// clone, if it exists, to the given resources.
+//
+// Each document in the file is a strategic merge patch of the resources of the same kind and, if specified, name.
+// Known kinds such as a `Job` use the patch strategy of their type so that containers and environment variables are
+// merged by name; other kinds use a JSON merge patch. A patch which matches no resource fails so that mistakes are
+// not silently ignored unless only some of the job files have been loaded
+func patchLaunchResources(dir string, resources []*unstructured.Unstructured, partial bool) error {
+	fileName := filepath.Join(dir, ".jx", "git-operator", JobPatchFileName)
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file %s exists", fileName)
+	}
+	if !exists {
+		return nil
+	}
+	patches, err := loadResources(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load patches")
+	}
+	for _, patch := range patches {
+		kind := patch.GetKind()
+		name := patch.GetName()
+		matched := false
+		for _, r := range resources {
+			if r.GetKind() != kind || (name != "" && r.GetName() != name) {
+				continue
+			}
+			err = patchResource(r, patch.Object)
+			if err != nil {
+				return errors.Wrapf(err, "failed to patch %s %s with %s", kind, r.GetName(), fileName)
+			}
+			matched = true
+		}
+		if !matched && !partial {
+			return errors.Errorf("the patch of %s %s in %s does not match any resource", kind, name, fileName)
+		}
+	}
+	return nil
+}
+
+// patchResource applies the strategic merge patch to the resource
+func patchResource(r *unstructured.Unstructured, patch map[string]interface{}) error {
+	dataStruct, err := scheme.Scheme.New(r.GroupVersionKind())
+	if err != nil {
+		// lets use a JSON merge patch for kinds without a known patch strategy such as custom resources
+		r.Object = mergePatch(r.Object, patch).(map[string]interface{})
+		return nil
+	}
+	original, err := json.Marshal(r.Object)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal resource")
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal patch")
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, data, dataStruct)
+	if err != nil {
+		return err
+	}
+	object := map[string]interface{}{}
+	err = json.Unmarshal(patched, &object)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmarshal patched resource")
+	}
+	r.Object = object
+	return nil
+}
+
+// mergePatch applies the RFC 7386 JSON merge patch to the given value: maps are merged recursively, null values
+// remove the key and any other values replace the original
+func mergePatch(original interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	originalMap, ok := original.(map[string]interface{})
+	if !ok {
+		originalMap = map[string]interface{}{}
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(originalMap, k)
+			continue
+		}
+		originalMap[k] = mergePatch(originalMap[k], v)
+	}
+	return originalMap
+}
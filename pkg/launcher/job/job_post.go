@@ -0,0 +1,55 @@
+package job
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PostJobFileName the name of the optional file in the git operator folder containing the Job to create once the
+// Job of a commit has succeeded
+const PostJobFileName = "post-job.yaml"
+
+// startPostJob creates the post-success Job of the git operator folder, if present, for the commit sha whose Jobs
+// have all succeeded. The post-success Job is labelled with the same repository and commit sha so that it is part
+// of the same launch
+func (c *client) startPostJob(opts launcher.LaunchOptions, clients *clusterClients, folder string, ns string, safeName string, safeSha string, attempt int) ([]runtime.Object, error) {
+	fileName := filepath.Join(folder, PostJobFileName)
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find file %s in repository %s", fileName, safeName)
+	}
+	if !exists {
+		return nil, nil
+	}
+	resources, err := LoadResources(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load post-success Job file %s in repository %s", fileName, safeName)
+	}
+	if opts.Tenant != nil {
+		err = opts.Tenant.Validate(ns, resources)
+		if err != nil {
+			return nil, errors.Wrapf(err, "repository %s is not allowed to launch its post-success Job", safeName)
+		}
+	}
+	resourceName, err := opts.Naming.ResourceName(safeName, safeSha)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate the name of the resources of repository %s", safeName)
+	}
+	resourceName = launcher.PrefixedName(opts.Repository.JobNamePrefix, resourceName, safeName, safeSha)
+	// the post-success Job is covered by the approval of the Job of the commit
+	opts.Suspend = false
+	log.Logger().Infof("about to create the post-success job for name %s and sha %s", safeName, safeSha)
+	labels := map[string]string{
+		launcher.PostJobLabelKey: "true",
+	}
+	annotations := map[string]string{
+		launcher.AttemptAnnotation: strconv.Itoa(attempt),
+	}
+	return c.createResources(opts, clients, resources, ns, safeName, safeSha, launcher.SuffixedName(attemptName(resourceName, attempt, safeName, safeSha), "-post", safeName, safeSha), labels, annotations)
+}
@@ -0,0 +1,67 @@
+package job
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PreflightFileName the name of the optional script in the git operator folder which must succeed before the
+	// resources of a commit are applied and its Job is created
+	PreflightFileName = "preflight.sh"
+
+	// maxPreflightOutput the maximum length of the output of a failed pre-flight script included in its error
+	maxPreflightOutput = 2000
+)
+
+// runPreflight runs the pre-flight script of the git operator folder, if enabled and present, in the root of the git
+// clone returning true if it ran and succeeded. A failing script returns a PreflightError so that the launch is
+// retried on the next poll
+func (c *client) runPreflight(opts launcher.LaunchOptions, clients *clusterClients, folder string, ns string, safeName string, safeSha string) (bool, error) {
+	if !opts.PreflightScript {
+		return false, nil
+	}
+	fileName := filepath.Join(folder, PreflightFileName)
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to find file %s in repository %s", fileName, safeName)
+	}
+	if !exists {
+		return false, nil
+	}
+	env := map[string]string{
+		"REPOSITORY": opts.Repository.Name,
+		"NAMESPACE":  ns,
+		"GIT_SHA":    opts.GitSHA,
+	}
+	if clients.kubeConfigFile != "" {
+		env["KUBECONFIG"] = clients.kubeConfigFile
+	}
+	cmd := &cmdrunner.Command{
+		Dir:  opts.Dir,
+		Name: "sh",
+		Args: []string{fileName},
+		Env:  env,
+	}
+	log.Logger().Infof("running pre-flight script of repository %s sha %s: %s", safeName, safeSha, cmd.CLI())
+	_, err = c.runner(cmd)
+	if err != nil {
+		output := err.Error()
+		if len(output) > maxPreflightOutput {
+			output = "...\n" + output[len(output)-maxPreflightOutput:]
+		}
+		return false, &launcher.PreflightError{
+			Repository: safeName,
+			GitSHA:     safeSha,
+			Output:     strings.TrimSpace(output),
+		}
+	}
+	log.Logger().Infof("pre-flight script of repository %s sha %s succeeded", safeName, safeSha)
+	return true, nil
+}
@@ -0,0 +1,176 @@
+package job
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubeConfigSecretKey the key in a kubeconfig Secret which contains the kubeconfig of a remote cluster
+const KubeConfigSecretKey = "kubeconfig"
+
+// NewRemoteClients creates the clients for a remote cluster from the given kubeconfig.
+// It can be replaced in tests to use fake clients
+var NewRemoteClients = func(kubeConfig []byte) (kubernetes.Interface, dynamic.Interface, error) {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse kubeconfig")
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create the kube client")
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create the dynamic client")
+	}
+	return kubeClient, dynamicClient, nil
+}
+
+// clusterClients the clients for the cluster a repository is launched in
+type clusterClients struct {
+	name           string
+	kubeClient     kubernetes.Interface
+	dynamicClient  dynamic.Interface
+	kubeConfigFile string
+	local          *client
+}
+
+// getDynamicClient returns the dynamic client for the cluster, lazily creating it for the local cluster
+func (cc *clusterClients) getDynamicClient() (dynamic.Interface, error) {
+	if cc.dynamicClient == nil && cc.local != nil {
+		return cc.local.getDynamicClient()
+	}
+	return cc.dynamicClient, nil
+}
+
+// cleanup removes any temporary kubeconfig file
+func (cc *clusterClients) cleanup() {
+	if cc.kubeConfigFile != "" {
+		err := os.Remove(cc.kubeConfigFile)
+		if err != nil {
+			log.Logger().Warnf("failed to remove temporary kubeconfig file %s: %s", cc.kubeConfigFile, err.Error())
+		}
+	}
+}
+
+// clientsFor returns the clients for the cluster the repository should be launched in.
+//
+// If a kubeconfig Secret name is specified the clients are created for the remote cluster
+// otherwise the local clients are used
+func (c *client) clientsFor(r repo.Repository, kubeConfigSecret string, ns string) (*clusterClients, error) {
+	if kubeConfigSecret == "" {
+		return &clusterClients{
+			kubeClient:    c.kubeClient,
+			dynamicClient: c.dynamicClient,
+			local:         c,
+		}, nil
+	}
+
+	kubeConfig, kubeClient, dynamicClient, err := c.remoteClients(r, kubeConfigSecret, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	// lets save the kubeconfig so we can apply resources to the remote cluster
+	f, err := ioutil.TempFile("", "jx-git-operator-kubeconfig-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create temporary kubeconfig file")
+	}
+	fileName := f.Name()
+	_, err = f.Write(kubeConfig)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		os.Remove(fileName)
+		return nil, errors.Wrapf(err, "failed to save kubeconfig file %s", fileName)
+	}
+
+	log.Logger().Infof("launching repository %s in the remote cluster from kubeconfig Secret %s", r.Name, kubeConfigSecret)
+	return &clusterClients{
+		name:           kubeConfigSecret,
+		kubeClient:     kubeClient,
+		dynamicClient:  dynamicClient,
+		kubeConfigFile: fileName,
+	}, nil
+}
+
+// remoteClients returns the kubeconfig and the clients of the remote cluster for the given kubeconfig Secret name
+func (c *client) remoteClients(r repo.Repository, kubeConfigSecret string, ns string) ([]byte, kubernetes.Interface, dynamic.Interface, error) {
+	secret, err := c.kubeClient.CoreV1().Secrets(ns).Get(kubeConfigSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "failed to find kubeconfig Secret %s in namespace %s for repository %s", kubeConfigSecret, ns, r.Name)
+	}
+	kubeConfig := secret.Data[KubeConfigSecretKey]
+	if len(kubeConfig) == 0 {
+		return nil, nil, nil, errors.Errorf("kubeconfig Secret %s in namespace %s for repository %s has no %s key", kubeConfigSecret, ns, r.Name, KubeConfigSecretKey)
+	}
+
+	kubeClient, dynamicClient, err := NewRemoteClients(kubeConfig)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "failed to create clients from kubeconfig Secret %s in namespace %s", kubeConfigSecret, ns)
+	}
+	return kubeConfig, kubeClient, dynamicClient, nil
+}
+
+// copyGitSecrets creates or updates the Secret containing the git URL and credentials of the repository in the
+// cluster for any default Job which references one via the GitSecretAnnotation
+func (c *client) copyGitSecrets(r repo.Repository, clients *clusterClients, resources []*unstructured.Unstructured, ns string) error {
+	for _, resource := range resources {
+		name := resource.GetAnnotations()[launcher.GitSecretAnnotation]
+		if name == "" {
+			continue
+		}
+		secret, err := GitSecret(r, name, ns, func(kind string, name string) (map[string][]byte, error) {
+			if kind == repo.ConfigMapKind {
+				cm, err := c.kubeClient.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				data := map[string][]byte{}
+				for k, v := range cm.Data {
+					data[k] = []byte(v)
+				}
+				return data, nil
+			}
+			s, err := c.kubeClient.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return s.Data, nil
+		})
+		if err != nil {
+			return err
+		}
+		secrets := clients.kubeClient.CoreV1().Secrets(ns)
+		existing, err := secrets.Get(name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to get Secret %s in namespace %s", name, ns)
+			}
+			_, err = secrets.Create(secret)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create Secret %s in namespace %s", name, ns)
+			}
+			continue
+		}
+		existing.Labels = secret.Labels
+		existing.Data = secret.Data
+		_, err = secrets.Update(existing)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update Secret %s in namespace %s", name, ns)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package job
+
+import (
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// retryBackoff the exponential backoff used when retrying transient errors
+var retryBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Steps:    5,
+}
+
+// retryTransient invokes the function retrying with an exponential backoff while it fails with a transient error.
+// It is only used for API requests; commands such as kubectl are retried by the command runner
+func retryTransient(description string, fn func() error) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(retryBackoff, func() (bool, error) {
+		lastErr = fn()
+		if lastErr == nil {
+			return true, nil
+		}
+		if !runner.IsTransient("", lastErr) {
+			return false, lastErr
+		}
+		log.Logger().Warnf("retrying %s after transient error: %s", description, lastErr.Error())
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return errors.Wrapf(lastErr, "gave up retrying %s", description)
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// SchemaProblem a problem found when validating a resource in a job file
+type SchemaProblem struct {
+	// Line the line of the problem in the file
+	Line int `json:"line"`
+
+	// Path the field path of the problem such as `spec.template.spec.containers[0].imagePullPolicyy`
+	Path string `json:"path,omitempty"`
+
+	// Message the description of the problem
+	Message string `json:"message"`
+}
+
+// String returns the description of the problem
+func (p SchemaProblem) String() string {
+	if p.Path == "" {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Path, p.Message)
+}
+
+// SchemaError the error returned when the resources in a job file have unknown fields or an unknown kind or
+// apiVersion which would otherwise silently produce broken resources
+type SchemaError struct {
+	// File the name of the job file
+	File string
+
+	// Problems the problems found in the file
+	Problems []SchemaProblem
+}
+
+// Error returns the error message
+func (e *SchemaError) Error() string {
+	var lines []string
+	for _, p := range e.Problems {
+		lines = append(lines, e.File+": "+p.String())
+	}
+	return fmt.Sprintf("invalid job file %s:\n%s", e.File, strings.Join(lines, "\n"))
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// ValidateJobFile strictly validates the resources in the given job file returning a SchemaError describing the file,
+// line and field path of every unknown field and of any apiVersion which is not known for the kind of a resource.
+//
+// Documents without a kind are validated as a `Job`, the items of a `List` are validated separately and resources
+// whose kind is not known, such as custom resources, are not validated
+func ValidateJobFile(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %s", fileName)
+	}
+	defer f.Close()
+
+	var problems []SchemaProblem
+	decoder := yaml.NewDecoder(f)
+	for {
+		node := &yaml.Node{}
+		err = decoder.Decode(node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse YAML in file %s", fileName)
+		}
+		problems = append(problems, validateResourceNode(documentContent(node), "")...)
+	}
+	if len(problems) > 0 {
+		return &SchemaError{File: fileName, Problems: problems}
+	}
+	return nil
+}
+
+// validateResourceNode validates the resource in the mapping node using the type of its kind and apiVersion
+func validateResourceNode(node *yaml.Node, path string) []SchemaProblem {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	kindNode := mappingValue(node, "kind")
+	apiVersionNode := mappingValue(node, "apiVersion")
+	kind := scalarValue(kindNode)
+	apiVersion := scalarValue(apiVersionNode)
+	if kind == "" {
+		kind = "Job"
+		if apiVersion == "" {
+			apiVersion = "batch/v1"
+		}
+	}
+	if kind == "List" {
+		var answer []SchemaProblem
+		items := mappingValue(node, "items")
+		if items != nil && items.Kind == yaml.SequenceNode {
+			for i, item := range items.Content {
+				answer = append(answer, validateResourceNode(item, fmt.Sprintf("%sitems[%d].", path, i))...)
+			}
+		}
+		return answer
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return []SchemaProblem{{Line: lineOf(apiVersionNode, node), Path: path + "apiVersion", Message: err.Error()}}
+	}
+	gvk := gv.WithKind(kind)
+	if !scheme.Scheme.Recognizes(gvk) {
+		// lets report a known kind with the wrong apiVersion but ignore unknown kinds such as custom resources
+		var versions []string
+		for known := range scheme.Scheme.AllKnownTypes() {
+			if known.Kind == kind && known.Group == gv.Group {
+				versions = append(versions, known.GroupVersion().String())
+			}
+		}
+		if len(versions) == 0 {
+			return nil
+		}
+		return []SchemaProblem{{
+			Line:    lineOf(apiVersionNode, node),
+			Path:    path + "apiVersion",
+			Message: fmt.Sprintf("unknown apiVersion %s for kind %s", apiVersion, kind),
+		}}
+	}
+	obj, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		return nil
+	}
+	return validateNode(node, reflect.TypeOf(obj), strings.TrimSuffix(path, "."))
+}
+
+// validateNode validates the fields of the YAML node against the given type reporting any unknown fields
+func validateNode(node *yaml.Node, t reflect.Type, path string) []SchemaProblem {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		// types such as quantities and times have their own format
+		return nil
+	}
+	var answer []SchemaProblem
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := jsonFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			fieldPath := joinPath(path, key.Value)
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				answer = append(answer, SchemaProblem{Line: key.Line, Path: fieldPath, Message: "unknown field"})
+				continue
+			}
+			answer = append(answer, validateNode(node.Content[i+1], fieldType, fieldPath)...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			answer = append(answer, validateNode(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode || t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i, item := range node.Content {
+			answer = append(answer, validateNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return answer
+}
+
+// jsonFields returns the types of the fields of the struct indexed by their JSON names including inlined fields
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	answer := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if (f.Anonymous && name == "") || strings.Contains(tag, ",inline") {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					answer[k] = v
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		answer[name] = f.Type
+	}
+	return answer
+}
+
+// documentContent returns the root node of the document
+func documentContent(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// mappingValue returns the value of the given key in the mapping node or nil if it does not exist
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the value of the scalar node or an empty string
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// lineOf returns the line of the node or of the parent node if the node does not exist
+func lineOf(node *yaml.Node, parent *yaml.Node) int {
+	if node != nil {
+		return node.Line
+	}
+	return parent.Line
+}
+
+// joinPath appends the field name to the path
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
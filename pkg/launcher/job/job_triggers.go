@@ -0,0 +1,182 @@
+package job
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// TriggersFileName the name of the optional file in the git operator folder which declares which changed paths of
+// the repository are relevant to the boot and which job files to launch for them
+const TriggersFileName = "triggers.yaml"
+
+// Triggers the configuration of the `triggers.yaml` file in the git operator folder
+type Triggers struct {
+	// Include the optional globs of the paths which are relevant to the boot. If empty every path is included
+	Include []string `json:"include,omitempty"`
+
+	// Exclude the optional globs of the paths which are not relevant to the boot such as `docs/**` or `*.md`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Jobs the optional job files to launch for the relevant paths which match their globs. If no job matches the
+	// relevant paths the `job.yaml` file is launched
+	Jobs []JobTrigger `json:"jobs,omitempty"`
+}
+
+// JobTrigger a job file to launch when a relevant path matching its globs has changed
+type JobTrigger struct {
+	// Paths the globs of the changed paths which launch the job file
+	Paths []string `json:"paths"`
+
+	// Job the name of the job file relative to the git operator folder such as `apps-job.yaml`
+	Job string `json:"job"`
+}
+
+// LoadTriggers loads the `triggers.yaml` file in the given git operator folder returning nil if it does not exist
+func LoadTriggers(folder string) (*Triggers, error) {
+	fileName := filepath.Join(folder, TriggersFileName)
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file %s exists", fileName)
+	}
+	if !exists {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load file %s", fileName)
+	}
+	t := &Triggers{}
+	err = yaml.UnmarshalStrict(data, t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal YAML file %s", fileName)
+	}
+	for i, j := range t.Jobs {
+		if j.Job == "" || len(j.Paths) == 0 {
+			return nil, errors.Errorf("job %d in file %s must have a job file and at least one path", i, fileName)
+		}
+		if filepath.IsAbs(j.Job) || strings.HasPrefix(filepath.Clean(j.Job), "..") {
+			return nil, errors.Errorf("job file %s in file %s must be within the git operator folder", j.Job, fileName)
+		}
+	}
+	return t, nil
+}
+
+// RelevantPaths returns the given changed paths which are included and not excluded
+func (t *Triggers) RelevantPaths(changed []string) []string {
+	var answer []string
+	for _, p := range changed {
+		if (len(t.Include) == 0 || matchesAny(t.Include, p)) && !matchesAny(t.Exclude, p) {
+			answer = append(answer, p)
+		}
+	}
+	return answer
+}
+
+// JobFiles returns the names of the job files, relative to the git operator folder, to launch for the given changed
+// paths. If the changed paths are nil, as they are not known, every job file is returned. Returns no job files if
+// none of the changed paths are relevant
+func (t *Triggers) JobFiles(changed []string) []string {
+	if changed == nil {
+		answer := []string{"job.yaml"}
+		for _, j := range t.Jobs {
+			answer = appendJobFile(answer, j.Job)
+		}
+		return answer
+	}
+	relevant := t.RelevantPaths(changed)
+	if len(relevant) == 0 {
+		return nil
+	}
+	var answer []string
+	for _, j := range t.Jobs {
+		for _, p := range relevant {
+			if matchesAny(j.Paths, p) {
+				answer = appendJobFile(answer, j.Job)
+				break
+			}
+		}
+	}
+	if len(answer) == 0 {
+		answer = append(answer, "job.yaml")
+	}
+	return answer
+}
+
+// IsRelevant returns true if any of the changed paths of the git clone in the given dir are relevant to the boot
+// according to the optional `triggers.yaml` file in its git operator folder. Every change is relevant if there is
+// no `triggers.yaml` file or the changed paths are nil as they are not known
+func IsRelevant(dir string, changed []string) (bool, error) {
+	if changed == nil {
+		return true, nil
+	}
+	folder, err := FindGitOperatorFolder(dir)
+	if err != nil {
+		return false, err
+	}
+	t, err := LoadTriggers(folder)
+	if err != nil {
+		return false, err
+	}
+	return t == nil || len(t.JobFiles(changed)) > 0, nil
+}
+
+// appendJobFile appends the job file if it is not already present
+func appendJobFile(jobFiles []string, name string) []string {
+	for _, f := range jobFiles {
+		if f == name {
+			return jobFiles
+		}
+	}
+	return append(jobFiles, name)
+}
+
+// matchesAny returns true if the slash separated path matches any of the globs
+func matchesAny(globs []string, p string) bool {
+	for _, g := range globs {
+		if matchGlob(g, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob returns true if the slash separated path matches the glob. A `**` segment matches any number of
+// directories and a glob without a `/`, such as `*.md`, matches the file name in any directory
+func matchGlob(glob string, p string) bool {
+	glob = strings.TrimPrefix(glob, "/")
+	if !strings.Contains(glob, "/") {
+		matched, _ := path.Match(glob, path.Base(p))
+		return matched
+	}
+	return matchSegments(strings.Split(glob, "/"), strings.Split(p, "/"))
+}
+
+// matchSegments matches the segments of a path against the segments of a glob
+func matchSegments(globs []string, segments []string) bool {
+	for len(globs) > 0 {
+		if globs[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(globs[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		matched, _ := path.Match(globs[0], segments[0])
+		if !matched {
+			return false
+		}
+		globs = globs[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,54 @@
+package job
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// validateResources validates the resources in the apply directory via a server side dry run so that the schema of
+// every resource is checked by the API server, including any admission webhooks, before any of them are applied.
+//
+// The whole directory is validated in one go and only if that fails is each resource validated separately so that
+// every invalid resource is reported. Resources which fail because they depend on a `Namespace` or
+// `CustomResourceDefinition` which is applied at the same time, or due to a transient error, are not reported
+func (c *client) validateResources(opts launcher.LaunchOptions, d *applyDir, kubeConfigFile string) error {
+	text, err := c.kubectl(kubeConfigFile, "apply", "--dry-run=server", "-f", d.allDir())
+	if err == nil {
+		return nil
+	}
+	log.Logger().Infof("validating each resource of repository %s as the dry run failed: %s", opts.Repository.Name, strings.TrimSpace(text))
+
+	var failures []string
+	for i, r := range d.resources {
+		fileName := filepath.Join(d.allDir(), fmt.Sprintf("%03d.yaml", i))
+		text, err = c.kubectl(kubeConfigFile, "apply", "--dry-run=server", "-f", fileName)
+		if err == nil || isUnknownKind(text, err) || isNamespaceNotFound(text, err) || runner.IsTransient(text, err) {
+			continue
+		}
+		message := strings.TrimSpace(text)
+		if message == "" {
+			message = err.Error()
+		}
+		failures = append(failures, fmt.Sprintf("%s %s: %s", r.GetKind(), r.GetName(), message))
+	}
+	if len(failures) == 0 {
+		log.Logger().Warnf("ignoring the failed dry run of repository %s as no resource is invalid", opts.Repository.Name)
+		return nil
+	}
+	return &launcher.ValidationError{
+		Repository: opts.Repository.Name,
+		GitSHA:     opts.GitSHA,
+		Failures:   failures,
+	}
+}
+
+// isNamespaceNotFound returns true if the dry run failed as the namespace of the resource does not exist yet
+func isNamespaceNotFound(text string, err error) bool {
+	message := text + " " + err.Error()
+	return strings.Contains(message, "namespaces \"") && strings.Contains(message, "not found")
+}
@@ -0,0 +1,115 @@
+package launcher
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// JobDefaults the defaults of the specs of the launched Jobs which are injected into the Jobs which do not specify
+// them so that platform admins can standardise the hygiene of the Jobs of every repository
+type JobDefaults struct {
+	// TTLAfterFinished the optional time to live of finished Jobs such as `72h`. So that their commit is not launched
+	// again the latest Jobs of a repository are kept; the time to live is set on the older Jobs of a repository once
+	// a newer commit has been launched
+	TTLAfterFinished time.Duration `env:"JOB_TTL_AFTER_FINISHED"`
+
+	// BackoffLimit the optional number of retries of the failed pods of a Job such as `2` or `0` to disable retries
+	BackoffLimit string `env:"JOB_BACKOFF_LIMIT"`
+
+	// PodFailurePolicy the optional YAML or JSON `podFailurePolicy` of Jobs such as
+	// `{"rules": [{"action": "FailJob", "onExitCodes": {"operator": "In", "values": [42]}}]}`
+	PodFailurePolicy string `env:"JOB_POD_FAILURE_POLICY"`
+
+	// TransientExitCodes the optional exit codes of containers which failed due to transient errors. If no
+	// PodFailurePolicy is specified the failures of pods with these exit codes, or which were disrupted, are ignored
+	// via the `podFailurePolicy` of Jobs so that they are retried without counting towards the backoff limit
+	TransientExitCodes []int `env:"JOB_TRANSIENT_EXIT_CODES"`
+}
+
+// Validate validates the backoff limit and pod failure policy can be parsed
+func (d JobDefaults) Validate() error {
+	if d.TTLAfterFinished < 0 {
+		return errors.Errorf("invalid Job time to live %s. It must not be negative", d.TTLAfterFinished.String())
+	}
+	_, _, err := d.BackoffLimitValue()
+	if err != nil {
+		return err
+	}
+	_, err = d.PodFailurePolicyValue()
+	return err
+}
+
+// TTLSecondsAfterFinished returns the time to live of finished Jobs in seconds and true if it is specified
+func (d JobDefaults) TTLSecondsAfterFinished() (int64, bool) {
+	if d.TTLAfterFinished <= 0 {
+		return 0, false
+	}
+	seconds := int64(d.TTLAfterFinished.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds, true
+}
+
+// BackoffLimitValue returns the backoff limit and true if it is specified
+func (d JobDefaults) BackoffLimitValue() (int64, bool, error) {
+	text := strings.TrimSpace(d.BackoffLimit)
+	if text == "" {
+		return 0, false, nil
+	}
+	answer, err := strconv.ParseInt(text, 10, 32)
+	if err != nil || answer < 0 {
+		return 0, false, errors.Errorf("invalid Job backoff limit %s. It must be a number which is not negative", text)
+	}
+	return answer, true, nil
+}
+
+// PodFailurePolicyValue returns the pod failure policy or nil if there is none
+func (d JobDefaults) PodFailurePolicyValue() (map[string]interface{}, error) {
+	text := strings.TrimSpace(d.PodFailurePolicy)
+	if text != "" {
+		answer := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(text), &answer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the Job pod failure policy")
+		}
+		rules, ok := answer["rules"].([]interface{})
+		if !ok || len(rules) == 0 {
+			return nil, errors.Errorf("invalid Job pod failure policy. It must have at least one rule")
+		}
+		return answer, nil
+	}
+	if len(d.TransientExitCodes) == 0 {
+		return nil, nil
+	}
+	var codes []interface{}
+	for _, code := range d.TransientExitCodes {
+		if code == 0 {
+			return nil, errors.Errorf("invalid transient exit code 0. Only the exit codes of failed containers can be transient")
+		}
+		codes = append(codes, int64(code))
+	}
+	return map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"action": "Ignore",
+				"onPodConditions": []interface{}{
+					map[string]interface{}{
+						"type": "DisruptionTarget",
+					},
+				},
+			},
+			map[string]interface{}{
+				"action": "Ignore",
+				"onExitCodes": map[string]interface{}{
+					"operator": "In",
+					"values":   codes,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,46 @@
+package launcher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobDefaults(t *testing.T) {
+	d := launcher.JobDefaults{
+		TTLAfterFinished:   72 * time.Hour,
+		BackoffLimit:       "0",
+		TransientExitCodes: []int{137, 143},
+	}
+	require.NoError(t, d.Validate(), "should be valid")
+
+	ttl, ok := d.TTLSecondsAfterFinished()
+	assert.True(t, ok, "should have a time to live")
+	assert.Equal(t, int64(259200), ttl, "time to live in seconds")
+
+	backoffLimit, ok, err := d.BackoffLimitValue()
+	require.NoError(t, err)
+	assert.True(t, ok, "a backoff limit of 0 should be specified")
+	assert.Equal(t, int64(0), backoffLimit, "backoff limit")
+
+	policy, err := d.PodFailurePolicyValue()
+	require.NoError(t, err)
+	rules := policy["rules"].([]interface{})
+	require.Len(t, rules, 2, "rules of the pod failure policy")
+	assert.Equal(t, []interface{}{int64(137), int64(143)}, rules[1].(map[string]interface{})["onExitCodes"].(map[string]interface{})["values"], "transient exit codes")
+
+	// an explicit pod failure policy takes precedence over the transient exit codes
+	d.PodFailurePolicy = `rules: [{action: FailJob, onExitCodes: {operator: In, values: [42]}}]`
+	policy, err = d.PodFailurePolicyValue()
+	require.NoError(t, err)
+	assert.Equal(t, "FailJob", policy["rules"].([]interface{})[0].(map[string]interface{})["action"], "action of the pod failure policy")
+
+	_, ok = launcher.JobDefaults{}.TTLSecondsAfterFinished()
+	assert.False(t, ok, "should not have a time to live by default")
+	require.Error(t, launcher.JobDefaults{BackoffLimit: "-1"}.Validate(), "should not allow a negative backoff limit")
+	require.Error(t, launcher.JobDefaults{PodFailurePolicy: "rules: []"}.Validate(), "should require a rule")
+	require.Error(t, launcher.JobDefaults{TransientExitCodes: []int{0}}.Validate(), "should not allow the exit code 0")
+}
@@ -0,0 +1,254 @@
+package manifestwork
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// LauncherName the name the ManifestWork launcher is registered with
+const LauncherName = "manifestwork"
+
+// ManifestWorkResource the resource of the Open Cluster Management `ManifestWork`
+var ManifestWorkResource = schema.GroupVersionResource{
+	Group:    "work.open-cluster-management.io",
+	Version:  "v1",
+	Resource: "manifestworks",
+}
+
+var (
+	secretResource    = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	configMapResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+)
+
+func init() {
+	launcher.Register(LauncherName, func(o launcher.FactoryOptions) (launcher.Interface, error) {
+		l, err := NewLauncher(o.DynamicClient, o.Namespace, o.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if o.CommandRunner != nil {
+			l.(*client).runner = o.CommandRunner
+		}
+		return l, nil
+	})
+}
+
+type client struct {
+	dynamicClient dynamic.Interface
+	ns            string
+	selector      string
+	runner        cmdrunner.CommandRunner
+}
+
+// NewLauncher creates a new launcher which wraps the Job and resources of a repository in an Open Cluster Management
+// `ManifestWork` in the namespace of the target `ManagedCluster` so that the work agent creates them in the spoke cluster.
+//
+// If nil is passed in the dynamic client will be lazily created
+func NewLauncher(dynamicClient dynamic.Interface, ns string, selector string) (launcher.Interface, error) {
+	if dynamicClient == nil {
+		f := kubeclient.NewFactory()
+		cfg, err := f.CreateKubeConfig()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create kube config")
+		}
+
+		dynamicClient, err = dynamic.NewForConfig(cfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create the dynamic client")
+		}
+
+		if ns == "" {
+			ns, err = kubeclient.CurrentNamespace()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find the current namespace")
+			}
+		}
+	}
+	return &client{
+		dynamicClient: dynamicClient,
+		ns:            ns,
+		selector:      selector,
+		runner:        cmdrunner.DefaultCommandRunner,
+	}, nil
+}
+
+// Launch creates or updates the ManifestWork for the repository if the commit sha has changed
+func (c *client) Launch(opts launcher.LaunchOptions) ([]runtime.Object, error) {
+	ns := opts.Repository.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	cluster := opts.Repository.ManagedCluster
+	if cluster == "" {
+		return nil, errors.Errorf("repository %s does not specify a ManagedCluster via the %s annotation", opts.Repository.Name, repo.ManagedClusterAnnotation)
+	}
+	safeName := naming.ToValidValue(opts.Repository.Name)
+	safeSha := naming.ToValidValue(opts.GitSHA)
+
+	workInterface := c.dynamicClient.Resource(ManifestWorkResource).Namespace(cluster)
+	existing, err := workInterface.Get(safeName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get ManifestWork %s in namespace %s", safeName, cluster)
+		}
+		existing = nil
+	}
+	if existing != nil && existing.GetLabels()[launcher.CommitShaLabelKey] == safeSha {
+		return nil, nil
+	}
+
+	folder, resources, err := job.LoadLaunchResources(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	var manifests []interface{}
+	var extraResources []*unstructured.Unstructured
+	if !opts.NoResourceApply {
+		extraResources, _, err = job.LoadApplyResources(c.runner, folder, safeName, ns, cluster)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load resources in repository %s", safeName)
+		}
+		for _, r := range extraResources {
+			manifests = append(manifests, r.Object)
+		}
+	}
+
+	resourceName, err := opts.Naming.ResourceName(safeName, safeSha)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate the name of the resources of repository %s", safeName)
+	}
+	for i, r := range resources {
+		name := resourceName
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", resourceName, i)
+		}
+		r.SetName(name)
+		r.SetNamespace(ns)
+		r.SetLabels(launchLabels(r.GetLabels(), safeName, safeSha))
+		annotations := opts.Commit.Annotations(r.GetAnnotations())
+		if opts.Changelog != "" {
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[launcher.ChangelogAnnotation] = opts.Changelog
+		}
+		r.SetAnnotations(annotations)
+		manifests = append(manifests, r.Object)
+
+		// the default Job cannot reference the repository Secret on the hub so lets include a copy of it
+		secretName := annotations[launcher.GitSecretAnnotation]
+		if secretName != "" {
+			secret, err := c.gitSecret(opts.Repository, secretName, ns)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to copy the git Secret of repository %s", safeName)
+			}
+			manifests = append([]interface{}{secret.Object}, manifests...)
+		}
+	}
+	if opts.Tenant != nil {
+		err = opts.Tenant.Validate(ns, append(append([]*unstructured.Unstructured{}, resources...), extraResources...))
+		if err != nil {
+			return nil, errors.Wrapf(err, "repository %s is not allowed to launch", opts.Repository.Name)
+		}
+	}
+
+	work := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "work.open-cluster-management.io/v1",
+			"kind":       "ManifestWork",
+			"spec": map[string]interface{}{
+				"workload": map[string]interface{}{
+					"manifests": manifests,
+				},
+			},
+		},
+	}
+	work.SetName(safeName)
+	work.SetNamespace(cluster)
+	work.SetLabels(launchLabels(nil, safeName, safeSha))
+
+	var answer *unstructured.Unstructured
+	if existing == nil {
+		answer, err = workInterface.Create(work, metav1.CreateOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create ManifestWork %s in namespace %s", safeName, cluster)
+		}
+		log.Logger().Infof("created ManifestWork %s in namespace %s for sha %s", safeName, cluster, safeSha)
+	} else {
+		work.SetResourceVersion(existing.GetResourceVersion())
+		answer, err = workInterface.Update(work, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to update ManifestWork %s in namespace %s", safeName, cluster)
+		}
+		log.Logger().Infof("updated ManifestWork %s in namespace %s to sha %s", safeName, cluster, safeSha)
+	}
+	return []runtime.Object{answer}, nil
+}
+
+// gitSecret returns the copy of the git URL and credentials of the repository for its default Job
+func (c *client) gitSecret(r repo.Repository, name string, ns string) (*unstructured.Unstructured, error) {
+	secret, err := job.GitSecret(r, name, ns, func(kind string, name string) (map[string][]byte, error) {
+		resource := secretResource
+		if kind == repo.ConfigMapKind {
+			resource = configMapResource
+		}
+		u, err := c.dynamicClient.Resource(resource).Namespace(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		data := map[string][]byte{}
+		if kind == repo.ConfigMapKind {
+			cm := &corev1.ConfigMap{}
+			err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, cm)
+			for k, v := range cm.Data {
+				data[k] = []byte(v)
+			}
+		} else {
+			s := &corev1.Secret{}
+			err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, s)
+			data = s.Data
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert %s %s", kind, name)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert Secret %s", name)
+	}
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+func launchLabels(labels map[string]string, safeName string, safeSha string) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[constants.DefaultSelectorKey] = constants.DefaultSelectorValue
+	labels[launcher.RepositoryLabelKey] = safeName
+	labels[launcher.CommitShaLabelKey] = safeSha
+	return labels
+}
@@ -0,0 +1,159 @@
+package manifestwork_test
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/manifestwork"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestManifestWorkLauncher(t *testing.T) {
+	ns := "jx"
+	cluster := "spoke1"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+
+	dynamicClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client, err := manifestwork.NewLauncher(dynamicClient, ns, constants.DefaultSelector)
+	require.NoError(t, err, "failed to create launcher")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:           repoName,
+			Namespace:      ns,
+			GitURL:         gitURL,
+			ManagedCluster: cluster,
+		},
+		GitSHA: "sha1",
+		Dir:    filepath.Join("test_data", "somerepo"),
+	}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch")
+	require.Len(t, objects, 1, "should have created a ManifestWork")
+
+	work, err := dynamicClient.Resource(manifestwork.ManifestWorkResource).Namespace(cluster).Get(repoName, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get ManifestWork")
+	assert.Equal(t, "sha1", work.GetLabels()[launcher.CommitShaLabelKey], "ManifestWork sha label")
+
+	manifests, _, err := unstructured.NestedSlice(work.Object, "spec", "workload", "manifests")
+	require.NoError(t, err, "failed to get manifests")
+	require.Len(t, manifests, 2, "should have the ServiceAccount and Job manifests")
+
+	var kinds []string
+	for _, m := range manifests {
+		u := &unstructured.Unstructured{Object: m.(map[string]interface{})}
+		kinds = append(kinds, u.GetKind())
+		if u.GetKind() == "Job" {
+			assert.Equal(t, ns, u.GetNamespace(), "Job namespace")
+			assert.Equal(t, "sha1", u.GetLabels()[launcher.CommitShaLabelKey], "Job sha label")
+		}
+	}
+	assert.Equal(t, []string{"ServiceAccount", "Job"}, kinds, "manifest kinds")
+
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch")
+	assert.Len(t, objects, 0, "should not update the ManifestWork for the same sha")
+
+	o.GitSHA = "sha2"
+	objects, err = client.Launch(o)
+	require.NoError(t, err, "failed to launch")
+	require.Len(t, objects, 1, "should have updated the ManifestWork")
+
+	work, err = dynamicClient.Resource(manifestwork.ManifestWorkResource).Namespace(cluster).Get(repoName, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get ManifestWork")
+	assert.Equal(t, "sha2", work.GetLabels()[launcher.CommitShaLabelKey], "ManifestWork sha label")
+}
+
+func TestManifestWorkLauncherTenant(t *testing.T) {
+	ns := "jx"
+	cluster := "spoke1"
+	repoName := "fake-repository"
+
+	dynamicClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client, err := manifestwork.NewLauncher(dynamicClient, ns, constants.DefaultSelector)
+	require.NoError(t, err, "failed to create launcher")
+
+	o := launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:           repoName,
+			Namespace:      ns,
+			GitURL:         "https://github.com/jenkins-x/fake-repository.git",
+			ManagedCluster: cluster,
+		},
+		GitSHA: "sha1",
+		Dir:    filepath.Join("test_data", "somerepo"),
+		Tenant: &tenant.Tenant{Name: "team-a", Namespaces: []string{ns}, ServiceAccounts: []string{"team-a"}},
+	}
+	_, err = client.Launch(o)
+	require.Error(t, err, "should not allow the Job to use another service account")
+	assert.Contains(t, err.Error(), "service account my-job", "error")
+
+	o.Tenant.ServiceAccounts = []string{"my-job"}
+	objects, err := client.Launch(o)
+	require.NoError(t, err, "failed to launch")
+	require.Len(t, objects, 1, "should have created a ManifestWork")
+}
+
+func TestManifestWorkLauncherDefaultJob(t *testing.T) {
+	ns := "jx"
+	cluster := "spoke1"
+	repoName := "fake-repository"
+	gitURL := "https://github.com/jenkins-x/fake-repository.git"
+
+	repoSecret := &unstructured.Unstructured{}
+	repoSecret.SetAPIVersion("v1")
+	repoSecret.SetKind("Secret")
+	repoSecret.SetName(repoName)
+	repoSecret.SetNamespace(ns)
+	repoSecret.Object["data"] = map[string]interface{}{
+		"url":      base64.StdEncoding.EncodeToString([]byte(gitURL)),
+		"password": base64.StdEncoding.EncodeToString([]byte("mytoken")),
+	}
+	dynamicClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), repoSecret)
+	client, err := manifestwork.NewLauncher(dynamicClient, ns, constants.DefaultSelector)
+	require.NoError(t, err, "failed to create launcher")
+
+	_, err = client.Launch(launcher.LaunchOptions{
+		Repository: repo.Repository{
+			Name:           repoName,
+			Namespace:      ns,
+			GitURL:         gitURL,
+			ManagedCluster: cluster,
+		},
+		GitSHA: "sha1",
+		Dir:    filepath.Join("test_data", "nojob"),
+		DefaultJob: launcher.DefaultJobOptions{
+			Enabled: true,
+			Image:   "gcr.io/jenkinsxio/jx-boot:1.2.3",
+		},
+		NoResourceApply: true,
+	})
+	require.NoError(t, err, "failed to launch")
+
+	work, err := dynamicClient.Resource(manifestwork.ManifestWorkResource).Namespace(cluster).Get(repoName, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get ManifestWork")
+	manifests, _, err := unstructured.NestedSlice(work.Object, "spec", "workload", "manifests")
+	require.NoError(t, err, "failed to get manifests")
+	require.Len(t, manifests, 2, "should have the git Secret and Job manifests")
+
+	secret := &unstructured.Unstructured{Object: manifests[0].(map[string]interface{})}
+	assert.Equal(t, "Secret", secret.GetKind(), "first manifest kind")
+	assert.Equal(t, repoName+"-git", secret.GetName(), "git Secret name")
+	assert.Equal(t, ns, secret.GetNamespace(), "git Secret namespace")
+	password, _, _ := unstructured.NestedString(secret.Object, "data", "password")
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("mytoken")), password, "git Secret password")
+
+	j := &unstructured.Unstructured{Object: manifests[1].(map[string]interface{})}
+	assert.Equal(t, repoName+"-git", j.GetAnnotations()[launcher.GitSecretAnnotation], "Job git Secret annotation")
+}
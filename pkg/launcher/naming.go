@@ -0,0 +1,179 @@
+package launcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NamingTrim the default naming strategy which trims the repository name and commit sha to 31 characters
+	NamingTrim = "trim"
+
+	// NamingHash the naming strategy which appends a hash of the full repository name and commit sha to the
+	// trimmed name so that repositories with long shared prefixes do not collide
+	NamingHash = "hash"
+
+	// NamingSha the naming strategy which uses the full commit sha
+	NamingSha = "sha"
+
+	// NamingTemplate the naming strategy which uses a go template
+	NamingTemplate = "template"
+
+	// MaxNameLength the maximum length of a generated name which leaves room for the index suffix of multiple
+	// resources within the 63 character limit of label values
+	MaxNameLength = 58
+
+	// hashLength the number of hex characters of the hash used in names
+	hashLength = 8
+)
+
+// NamingOptions the configuration of how launched resources are named
+type NamingOptions struct {
+	// Strategy the naming strategy: `trim` (the default), `hash`, `sha` or `template`
+	Strategy string `env:"JOB_NAMING"`
+
+	// Template the go template used by the `template` strategy such as `{{ .Name }}-{{ .ShortSHA }}`
+	Template string `env:"JOB_NAME_TEMPLATE"`
+}
+
+// NameData the data available to a naming template
+type NameData struct {
+	// Name the safe name of the repository
+	Name string
+
+	// SHA the safe full commit sha
+	SHA string
+
+	// ShortSHA the first 7 characters of the commit sha
+	ShortSHA string
+
+	// Hash a short hash of the repository name and commit sha
+	Hash string
+}
+
+// Validate validates the naming strategy and template
+func (o NamingOptions) Validate() error {
+	switch o.Strategy {
+	case "", NamingTrim, NamingHash, NamingSha:
+		return nil
+	case NamingTemplate:
+		// lets make sure different commits get different names
+		name1, err := o.ResourceName("myrepo", "1111111111111111111111111111111111111111")
+		if err != nil {
+			return err
+		}
+		name2, err := o.ResourceName("myrepo", "2222222222222222222222222222222222222222")
+		if err != nil {
+			return err
+		}
+		if name1 == name2 {
+			return errors.Errorf("the naming template %s must use the .SHA, .ShortSHA or .Hash to generate unique names", o.Template)
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown naming strategy %s. Supported values: %s", o.Strategy, strings.Join([]string{NamingTrim, NamingHash, NamingSha, NamingTemplate}, ", "))
+	}
+}
+
+// ResourceName returns the name of the resource launched for the given safe repository name and commit sha
+func (o NamingOptions) ResourceName(safeName string, safeSha string) (string, error) {
+	switch o.Strategy {
+	case "", NamingTrim:
+		return TrimmedName(safeName, safeSha), nil
+	case NamingHash:
+		return fmt.Sprintf("%s-%s-%s", trimLength(safeName, 20), trimLength(safeSha, 10), nameHash(safeName, safeSha)), nil
+	case NamingSha:
+		available := MaxNameLength - len(safeSha) - 1
+		if len(safeName) <= available {
+			return safeName + "-" + safeSha, nil
+		}
+		// lets include a hash of the full name if we have to trim it
+		available -= hashLength + 1
+		if available <= 0 {
+			return limitLength(safeSha, safeName, safeSha), nil
+		}
+		return strings.TrimRight(trimLength(safeName, available), "-.") + "-" + nameHash(safeName, safeSha) + "-" + safeSha, nil
+	case NamingTemplate:
+		tmpl, err := template.New("name").Parse(o.Template)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse naming template %s", o.Template)
+		}
+		data := NameData{
+			Name:     safeName,
+			SHA:      safeSha,
+			ShortSHA: trimLength(safeSha, 7),
+			Hash:     nameHash(safeName, safeSha),
+		}
+		buf := &bytes.Buffer{}
+		err = tmpl.Execute(buf, data)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to evaluate naming template %s", o.Template)
+		}
+		name := naming.ToValidName(buf.String())
+		if name == "" {
+			return "", errors.Errorf("naming template %s generated an empty name", o.Template)
+		}
+		return limitLength(name, safeName, safeSha), nil
+	default:
+		return "", errors.Errorf("unknown naming strategy %s", o.Strategy)
+	}
+}
+
+// TrimmedName returns the name of the resource using the default `trim` naming strategy
+func TrimmedName(safeName string, safeSha string) string {
+	// lets try use a maximum of 31 characters and a minimum of 10 for the sha
+	namePrefix := trimLength(safeName, 20)
+	maxShaLen := 30 - len(namePrefix)
+
+	return namePrefix + "-" + trimLength(safeSha, maxShaLen)
+}
+
+// PrefixedName returns the name of the resource launched for the given safe repository name and commit sha with the
+// given prefix, trimming it and adding a hash of the repository name and commit sha if required so that it stays
+// unique
+func PrefixedName(prefix string, name string, safeName string, safeSha string) string {
+	if prefix == "" {
+		return name
+	}
+	return limitLength(prefix+"-"+name, safeName, safeSha)
+}
+
+// SuffixedName returns the name of the resource with the suffix such as `-rollback`, trimming the name if required so
+// that the suffix fits within the maximum length. As the trimmed characters may be the hash or commit sha which make
+// the name unique, a trimmed name has a hash of the repository name, commit sha and full name added before the suffix
+func SuffixedName(name string, suffix string, safeName string, safeSha string) string {
+	if len(name)+len(suffix) <= MaxNameLength {
+		return name + suffix
+	}
+	prefix := strings.TrimRight(trimLength(name, MaxNameLength-len(suffix)-hashLength-1), "-.")
+	return prefix + "-" + nameHash(safeName, safeSha+"/"+name+suffix) + suffix
+}
+
+// limitLength trims the name to the maximum length if required, adding a hash of the repository name and commit sha
+// so that the trimmed name stays unique
+func limitLength(name string, safeName string, safeSha string) string {
+	if len(name) <= MaxNameLength {
+		return name
+	}
+	prefix := strings.TrimRight(name[0:MaxNameLength-hashLength-1], "-.")
+	return prefix + "-" + nameHash(safeName, safeSha)
+}
+
+// nameHash returns a short hash of the repository name and commit sha
+func nameHash(safeName string, safeSha string) string {
+	sum := sha256.Sum256([]byte(safeName + "/" + safeSha))
+	return fmt.Sprintf("%x", sum)[0:hashLength]
+}
+
+func trimLength(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+	return text[0:length]
+}
@@ -0,0 +1,101 @@
+package launcher_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	sha := "5b2d3e1f9a8c7b6d5e4f3a2b1c0d9e8f7a6b5c4d"
+	longName := "my-organisation-environment-production-cluster"
+
+	testCases := []struct {
+		options  launcher.NamingOptions
+		name     string
+		expected string
+	}{
+		{launcher.NamingOptions{}, "myrepo", "myrepo-5b2d3e1f9a8c7b6d5e4f3a2b"},
+		{launcher.NamingOptions{Strategy: launcher.NamingTrim}, longName, "my-organisation-envi-5b2d3e1f9a"},
+		{launcher.NamingOptions{Strategy: launcher.NamingSha}, "myrepo", "myrepo-" + sha},
+		{launcher.NamingOptions{Strategy: launcher.NamingTemplate, Template: "boot-{{ .Name }}-{{ .ShortSHA }}"}, "myrepo", "boot-myrepo-5b2d3e1"},
+	}
+	for _, tc := range testCases {
+		name, err := tc.options.ResourceName(tc.name, sha)
+		require.NoError(t, err, "failed to generate name for strategy %s", tc.options.Strategy)
+		assert.Equal(t, tc.expected, name, "name for strategy %s", tc.options.Strategy)
+	}
+
+	// repositories with long shared prefixes must not collide
+	strategies := []launcher.NamingOptions{
+		{Strategy: launcher.NamingHash},
+		{Strategy: launcher.NamingSha},
+		{Strategy: launcher.NamingTemplate, Template: "{{ .Name }}-{{ .SHA }}"},
+	}
+	for _, o := range strategies {
+		name1, err := o.ResourceName(longName+"-a", sha)
+		require.NoError(t, err, "failed to generate name for strategy %s", o.Strategy)
+		name2, err := o.ResourceName(longName+"-b", sha)
+		require.NoError(t, err, "failed to generate name for strategy %s", o.Strategy)
+
+		assert.NotEqual(t, name1, name2, "names for strategy %s", o.Strategy)
+		for _, n := range []string{name1, name2} {
+			assert.True(t, len(n) <= launcher.MaxNameLength, "name %s for strategy %s is too long", n, o.Strategy)
+			assert.False(t, strings.HasSuffix(n, "-"), "name %s for strategy %s should not end with a dash", n, o.Strategy)
+		}
+		t.Logf("strategy %s generated names %s and %s", o.Strategy, name1, name2)
+	}
+}
+
+func TestNamingValidate(t *testing.T) {
+	assert.NoError(t, launcher.NamingOptions{}.Validate(), "default strategy")
+	assert.NoError(t, launcher.NamingOptions{Strategy: launcher.NamingTemplate, Template: "{{ .Name }}-{{ .Hash }}"}.Validate(), "valid template")
+	assert.Error(t, launcher.NamingOptions{Strategy: "cheese"}.Validate(), "unknown strategy")
+	assert.Error(t, launcher.NamingOptions{Strategy: launcher.NamingTemplate, Template: "{{ .Name }}"}.Validate(), "template without the sha")
+	assert.Error(t, launcher.NamingOptions{Strategy: launcher.NamingTemplate, Template: "{{ .Name "}.Validate(), "invalid template")
+}
+
+func TestSuffixedName(t *testing.T) {
+	safeName := "my-organisation-environment-production-cluster"
+	prefix := "platform-team-boot"
+	shas := []string{
+		"5b2d3e1f9a8c7b6d5e4f3a2b1c0d9e8f7a6b5c4d",
+		"5b2d3e1f8a8c7b6d5e4f3a2b1c0d9e8f7a6b5c4d",
+	}
+	strategies := []launcher.NamingOptions{
+		{},
+		{Strategy: launcher.NamingHash},
+		{Strategy: launcher.NamingSha},
+		{Strategy: launcher.NamingTemplate, Template: "{{ .Name }}-{{ .Hash }}"},
+	}
+
+	for _, o := range strategies {
+		names := map[string]string{}
+		for _, sha := range shas {
+			name, err := o.ResourceName(safeName, sha)
+			require.NoError(t, err, "failed to generate name for strategy %s", o.Strategy)
+			name = launcher.PrefixedName(prefix, name, safeName, sha)
+
+			rollback := launcher.SuffixedName(name, "-rollback", safeName, sha)
+			candidates := map[string]string{
+				"launch":                name,
+				"rollback":              rollback,
+				"relaunch":              launcher.SuffixedName(name, "-a2", safeName, sha),
+				"rollback relaunch":     launcher.SuffixedName(rollback, "-a2", safeName, sha),
+				"post-success Job":      launcher.SuffixedName(name, "-post", safeName, sha),
+				"relaunch post-success": launcher.SuffixedName(launcher.SuffixedName(name, "-a2", safeName, sha), "-post", safeName, sha),
+			}
+			for kind, n := range candidates {
+				description := kind + " of sha " + sha
+				assert.True(t, len(n) <= launcher.MaxNameLength, "name %s of the %s for strategy %s is too long", n, description, o.Strategy)
+				assert.False(t, strings.Contains(n, "--"), "name %s of the %s for strategy %s should not contain a double dash", n, description, o.Strategy)
+				other, found := names[n]
+				assert.False(t, found, "name %s of the %s for strategy %s collides with the %s", n, description, o.Strategy, other)
+				names[n] = description
+			}
+		}
+	}
+}
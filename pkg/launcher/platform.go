@@ -0,0 +1,35 @@
+package launcher
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// PlatformAuto detects the platform the Jobs run on from the API groups of the cluster
+	PlatformAuto = "auto"
+
+	// PlatformKubernetes a vanilla Kubernetes cluster
+	PlatformKubernetes = "kubernetes"
+
+	// PlatformOpenShift an OpenShift cluster whose security context constraints assign the user and group IDs of pods
+	PlatformOpenShift = "openshift"
+
+	// openShiftSecurityGroup the API group of the security context constraints of OpenShift
+	openShiftSecurityGroup = "security.openshift.io"
+)
+
+// DetectPlatform returns PlatformOpenShift if the cluster serves the OpenShift security API group or
+// PlatformKubernetes otherwise
+func DetectPlatform(kubeClient kubernetes.Interface) (string, error) {
+	groups, err := kubeClient.Discovery().ServerGroups()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to discover the API groups of the cluster")
+	}
+	for _, g := range groups.Groups {
+		if g.Name == openShiftSecurityGroup {
+			return PlatformOpenShift, nil
+		}
+	}
+	return PlatformKubernetes, nil
+}
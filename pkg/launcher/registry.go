@@ -0,0 +1,85 @@
+package launcher
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FactoryOptions the options passed to a Factory to create a launcher
+type FactoryOptions struct {
+	// KubeClient the kubernetes client; if nil the launcher should lazily create it
+	KubeClient kubernetes.Interface
+
+	// DynamicClient the optional dynamic client
+	DynamicClient dynamic.Interface
+
+	// Namespace the default namespace to launch in
+	Namespace string
+
+	// Selector the label selector of the resources created by the operator
+	Selector string
+
+	// Owner the optional identifier of the operator instance so that garbage collection and cleanup only remove
+	// the resources applied by this instance
+	Owner string
+
+	// CommandRunner the optional command runner
+	CommandRunner cmdrunner.CommandRunner
+
+	// RateLimiter the optional adaptive rate limiter of the requests to create resources
+	RateLimiter *kube.AdaptiveLimiter
+
+	// NoResourceApply if enabled the launcher must never apply, diff or delete the resources of repositories
+	// itself, regardless of the LaunchOptions, so that the operator only needs permission to create its own
+	// resources and does not need `kubectl`
+	NoResourceApply bool
+}
+
+// Factory creates a new launcher from the given options
+type Factory func(o FactoryOptions) (Interface, error)
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Factory{}
+)
+
+// Register registers a launcher Factory with the given name so that it can be selected via the operator configuration.
+//
+// This is typically called from an `init()` function of the package implementing the launcher; registering the same
+// name twice replaces the previous Factory
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[name] = factory
+}
+
+// New creates a new launcher using the Factory registered with the given name
+func New(name string, o FactoryOptions) (Interface, error) {
+	registryLock.RLock()
+	factory := registry[name]
+	registryLock.RUnlock()
+
+	if factory == nil {
+		return nil, errors.Errorf("no launcher registered with name %s. Available launchers: %v", name, Names())
+	}
+	return factory(o)
+}
+
+// Names returns the sorted names of the registered launchers
+func Names() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var answer []string
+	for k := range registry {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
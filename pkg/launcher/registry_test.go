@@ -0,0 +1,37 @@
+package launcher_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type fakeLauncher struct {
+	namespace string
+}
+
+func (f *fakeLauncher) Launch(opts launcher.LaunchOptions) ([]runtime.Object, error) {
+	return nil, nil
+}
+
+func TestLauncherRegistry(t *testing.T) {
+	name := "test-registry"
+	launcher.Register(name, func(o launcher.FactoryOptions) (launcher.Interface, error) {
+		return &fakeLauncher{namespace: o.Namespace}, nil
+	})
+
+	assert.Contains(t, launcher.Names(), name, "registered launcher names")
+
+	l, err := launcher.New(name, launcher.FactoryOptions{Namespace: "jx"})
+	require.NoError(t, err, "failed to create launcher %s", name)
+	f, ok := l.(*fakeLauncher)
+	require.True(t, ok, "launcher should be a fakeLauncher but was %#v", l)
+	assert.Equal(t, "jx", f.namespace, "launcher namespace")
+
+	_, err = launcher.New("does-not-exist", launcher.FactoryOptions{})
+	require.Error(t, err, "should fail to create an unregistered launcher")
+	t.Logf("got expected error: %s", err.Error())
+}
@@ -0,0 +1,25 @@
+package launcher
+
+const (
+	// TriggerPoll a launch of a new commit found by polling the repository
+	TriggerPoll = "poll"
+
+	// TriggerWebhook a launch of a new commit found by polling the repository when a push event was received
+	TriggerWebhook = "webhook"
+
+	// TriggerManual a launch of the latest commit requested via the trigger annotation, the trigger endpoint,
+	// ChatOps or the admin API
+	TriggerManual = "manual"
+
+	// TriggerSchedule a launch of the latest commit on a schedule
+	TriggerSchedule = "schedule"
+
+	// TriggerRetry a launch of a commit whose previous launch by the operator failed
+	TriggerRetry = "retry"
+
+	// TriggerRollback a launch of the last successful commit to roll back a failed commit
+	TriggerRollback = "rollback"
+
+	// TriggerDrift a relaunch of the latest commit to correct drift of the applied resources
+	TriggerDrift = "drift"
+)
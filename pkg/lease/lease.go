@@ -0,0 +1,171 @@
+package lease
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultHolder the holder identity of the leases of an operator without an `OPERATOR_ID`
+	DefaultHolder = "jx-git-operator"
+
+	// GitSHAAnnotation the annotation on a boot lease which records the commit sha being booted
+	GitSHAAnnotation = "git-operator.jenkins.io/commit-sha"
+
+	// JobAnnotation the annotation on a boot lease which records the name of the active Job of the boot
+	JobAnnotation = "git-operator.jenkins.io/job"
+
+	// nameSuffix the suffix of the name of the boot lease of a repository
+	nameSuffix = "-boot"
+)
+
+// Boot a lease which records that a boot of a repository is in progress
+type Boot struct {
+	// Name the name of the lease
+	Name string `json:"name"`
+
+	// Namespace the namespace of the lease and repository
+	Namespace string `json:"namespace"`
+
+	// Repository the safe name of the repository being booted
+	Repository string `json:"repository"`
+
+	// Holder the identity of the operator which holds the lease
+	Holder string `json:"holder"`
+
+	// GitSHA the commit sha being booted
+	GitSHA string `json:"gitSha,omitempty"`
+
+	// Job the name of the active Job of the boot
+	Job string `json:"job,omitempty"`
+
+	// Acquired when the boot started
+	Acquired time.Time `json:"acquired"`
+
+	// Renewed when the holder last renewed the lease
+	Renewed time.Time `json:"renewed"`
+
+	// Expired true if the holder has not renewed the lease within its duration so the lease is stuck
+	Expired bool `json:"expired"`
+}
+
+// Name returns the name of the boot lease of the repository
+func Name(r repo.Repository) string {
+	return naming.ToValidName(r.Name + nameSuffix)
+}
+
+// Acquire creates or renews the boot lease of the repository recording the commit sha and Job being booted
+func Acquire(kubeClient kubernetes.Interface, r repo.Repository, holder string, gitSHA string, job string, duration time.Duration) error {
+	leases := kubeClient.CoordinationV1().Leases(r.Namespace)
+	name := Name(r)
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(duration.Seconds())
+
+	l, err := leases.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get Lease %s in namespace %s", name, r.Namespace)
+		}
+		l = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: r.Namespace,
+			},
+		}
+	}
+	if l.Labels == nil {
+		l.Labels = map[string]string{}
+	}
+	l.Labels[constants.DefaultSelectorKey] = constants.DefaultSelectorValue
+	l.Labels[launcher.RepositoryLabelKey] = naming.ToValidValue(r.Name)
+	if l.Annotations == nil {
+		l.Annotations = map[string]string{}
+	}
+	if l.Annotations[GitSHAAnnotation] != gitSHA || l.Spec.HolderIdentity == nil || *l.Spec.HolderIdentity != holder {
+		// a new boot starts when the commit or holder changes
+		l.Spec.AcquireTime = &now
+	}
+	l.Annotations[GitSHAAnnotation] = gitSHA
+	l.Annotations[JobAnnotation] = job
+	l.Spec.HolderIdentity = &holder
+	l.Spec.LeaseDurationSeconds = &seconds
+	l.Spec.RenewTime = &now
+
+	if l.ResourceVersion == "" {
+		_, err = leases.Create(l)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create Lease %s in namespace %s", name, r.Namespace)
+		}
+		return nil
+	}
+	_, err = leases.Update(l)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update Lease %s in namespace %s", name, r.Namespace)
+	}
+	return nil
+}
+
+// Release deletes the boot lease of the repository if it exists
+func Release(kubeClient kubernetes.Interface, r repo.Repository) error {
+	name := Name(r)
+	err := kubeClient.CoordinationV1().Leases(r.Namespace).Delete(name, nil)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete Lease %s in namespace %s", name, r.Namespace)
+	}
+	return nil
+}
+
+// List returns the boot leases in the given namespace, or every namespace if blank, sorted by namespace and name
+func List(kubeClient kubernetes.Interface, ns string, now time.Time) ([]Boot, error) {
+	list, err := kubeClient.CoordinationV1().Leases(ns).List(metav1.ListOptions{
+		LabelSelector: constants.DefaultSelector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Leases in namespace %s", ns)
+	}
+	var answer []Boot
+	for i := range list.Items {
+		answer = append(answer, ToBoot(&list.Items[i], now))
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		if answer[i].Namespace != answer[j].Namespace {
+			return answer[i].Namespace < answer[j].Namespace
+		}
+		return answer[i].Name < answer[j].Name
+	})
+	return answer, nil
+}
+
+// ToBoot returns the boot of the given lease at the given time
+func ToBoot(l *coordinationv1.Lease, now time.Time) Boot {
+	b := Boot{
+		Name:       l.Name,
+		Namespace:  l.Namespace,
+		Repository: l.Labels[launcher.RepositoryLabelKey],
+		GitSHA:     l.Annotations[GitSHAAnnotation],
+		Job:        l.Annotations[JobAnnotation],
+	}
+	if l.Spec.HolderIdentity != nil {
+		b.Holder = *l.Spec.HolderIdentity
+	}
+	if l.Spec.AcquireTime != nil {
+		b.Acquired = l.Spec.AcquireTime.Time
+	}
+	if l.Spec.RenewTime != nil {
+		b.Renewed = l.Spec.RenewTime.Time
+	}
+	if l.Spec.LeaseDurationSeconds != nil {
+		b.Expired = now.After(b.Renewed.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second))
+	}
+	return b
+}
@@ -0,0 +1,194 @@
+package lighthouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+const (
+	// Path the path of the endpoint which receives the push events relayed by Lighthouse
+	Path = "/hooks/lighthouse"
+
+	// maxPayloadSize the maximum size of an event payload
+	maxPayloadSize = 10 * 1024 * 1024
+)
+
+// eventHeaders the headers which specify the type of a relayed event
+var eventHeaders = []string{"X-GitHub-Event", "X-Gitea-Event", "X-Gogs-Event", "X-Gitlab-Event", "X-Event-Key"}
+
+// PushEvent a push event relayed by Lighthouse. Both the webhook payload of the git provider and the `scm.PushHook`
+// of go-scm are supported as JSON field names are matched case insensitively
+type PushEvent struct {
+	// Ref the git reference which was pushed such as `refs/heads/master`
+	Ref string `json:"ref"`
+
+	// After the commit sha after the push
+	After string `json:"after"`
+
+	// Repository the repository of a git provider webhook payload
+	Repository EventRepository `json:"repository"`
+
+	// Repo the repository of a go-scm `PushHook`
+	Repo EventRepository `json:"repo"`
+}
+
+// EventRepository the URLs of the repository of a push event
+type EventRepository struct {
+	// CloneURL the http clone URL of a GitHub, Gitea or Gogs payload
+	CloneURL string `json:"clone_url"`
+
+	// SSHURL the ssh clone URL of a GitHub, Gitea or Gogs payload
+	SSHURL string `json:"ssh_url"`
+
+	// HTMLURL the web URL of a GitHub, Gitea or Gogs payload
+	HTMLURL string `json:"html_url"`
+
+	// GitHTTPURL the http clone URL of a GitLab payload
+	GitHTTPURL string `json:"git_http_url"`
+
+	// GitSSHURL the ssh clone URL of a GitLab payload
+	GitSSHURL string `json:"git_ssh_url"`
+
+	// Clone the http clone URL of a go-scm repository
+	Clone string `json:"clone"`
+
+	// CloneSSH the ssh clone URL of a go-scm repository
+	CloneSSH string `json:"clonessh"`
+
+	// Link the web URL of a go-scm repository
+	Link string `json:"link"`
+}
+
+// Branch returns the branch which was pushed or an empty string if a tag or other reference was pushed
+func (e *PushEvent) Branch() string {
+	if !strings.HasPrefix(e.Ref, "refs/heads/") {
+		return ""
+	}
+	return strings.TrimPrefix(e.Ref, "refs/heads/")
+}
+
+// GitURLKeys returns the keys of all the git URLs of the repository which was pushed
+func (e *PushEvent) GitURLKeys() map[string]bool {
+	answer := map[string]bool{}
+	for _, r := range []EventRepository{e.Repository, e.Repo} {
+		for _, u := range []string{r.CloneURL, r.SSHURL, r.HTMLURL, r.GitHTTPURL, r.GitSSHURL, r.Clone, r.CloneSSH, r.Link} {
+			key := repo.GitURLKey(u)
+			if key != "" {
+				answer[key] = true
+			}
+		}
+	}
+	return answer
+}
+
+// Handler receives the push events relayed by Lighthouse as an external plugin so that clusters already running
+// Lighthouse do not need a second public webhook endpoint. The repository and branch filtering of Lighthouse is
+// shared so only the events of repositories Lighthouse is configured to relay are received.
+//
+// Each request must be signed with the HMAC token via the `X-Hub-Signature-256` or `X-Hub-Signature` header. A push
+// to the branch of any discovered repository wakes up the poll loop so that the commit is launched straight away
+type Handler struct {
+	hmacToken []byte
+	poller    *poller.Options
+}
+
+// NewHandler creates a new handler of relayed push events which verifies the signature with the given HMAC token
+func NewHandler(hmacToken string, p *poller.Options) *Handler {
+	return &Handler{
+		hmacToken: []byte(hmacToken),
+		poller:    p,
+	}
+}
+
+// ServeHTTP handles a relayed event
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read the payload", http.StatusBadRequest)
+		return
+	}
+	if !signature.Valid(h.hmacToken, payload, r.Header) {
+		log.Logger().Warnf("rejected event from %s with an invalid signature", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	for _, header := range eventHeaders {
+		eventType := r.Header.Get(header)
+		if eventType != "" && !strings.Contains(strings.ToLower(eventType), "push") {
+			writeText(w, http.StatusOK, fmt.Sprintf("ignored %s event", eventType))
+			return
+		}
+	}
+
+	event := &PushEvent{}
+	err = json.Unmarshal(payload, event)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse the push event: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	repos, err := h.matchRepositories(event)
+	if err != nil {
+		log.Logger().Warnf("failed to match the push event to repositories: %s", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(repos) == 0 {
+		writeText(w, http.StatusOK, "no repositories match the push event")
+		return
+	}
+	var names []string
+	for _, rp := range repos {
+		names = append(names, rp.Namespace+"/"+rp.Name)
+	}
+	log.Logger().Infof("polling repositories %s as a push of %s to branch %s was relayed by Lighthouse", strings.Join(names, ", "), event.After, event.Branch())
+	for _, rp := range repos {
+		h.poller.Push(rp.Namespace, rp.Name)
+	}
+	writeText(w, http.StatusAccepted, fmt.Sprintf("polling repositories %s", strings.Join(names, ", ")))
+}
+
+// matchRepositories returns the discovered repositories whose git URL and branch match the push event
+func (h *Handler) matchRepositories(event *PushEvent) ([]repo.Repository, error) {
+	branch := event.Branch()
+	keys := event.GitURLKeys()
+	if branch == "" || len(keys) == 0 {
+		return nil, nil
+	}
+	repos, err := h.poller.RepoClient.List()
+	if err != nil {
+		return nil, err
+	}
+	var answer []repo.Repository
+	for _, r := range repos {
+		repoBranch := r.Branch
+		if repoBranch == "" {
+			repoBranch = repo.DefaultBranch
+		}
+		if repoBranch == branch && keys[repo.GitURLKey(r.GitURL)] {
+			answer = append(answer, r)
+		}
+	}
+	return answer, nil
+}
+
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(text + "\n"))
+	if err != nil {
+		log.Logger().Warnf("failed to write response: %s", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package lighthouse_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/harness"
+	"github.com/jenkins-x/jx-git-operator/pkg/lighthouse"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+const hmacToken = "myhmac"
+
+func TestLighthouseHandler(t *testing.T) {
+	h := harness.NewHarness(t, "jx", nil)
+	h.AddRepository(t, "myrepo", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.Poll(t)
+
+	handler := lighthouse.NewHandler(hmacToken, h.Poller)
+
+	githubPush := `{"ref": "refs/heads/master", "after": "sha2", "repository": {"clone_url": "https://github.com/jenkins-x/fake-repository.git"}}`
+	w := post(handler, githubPush, "push", hmacToken)
+	assert.Equal(t, http.StatusAccepted, w.Code, "GitHub push: %s", w.Body.String())
+	assert.Contains(t, w.Body.String(), "jx/myrepo", "should poll the repository")
+
+	scmPush := `{"Ref": "refs/heads/master", "After": "sha2", "Repo": {"CloneSSH": "git@github.com:jenkins-x/Fake-Repository.git"}}`
+	w = post(handler, scmPush, "", hmacToken)
+	assert.Equal(t, http.StatusAccepted, w.Code, "go-scm push: %s", w.Body.String())
+
+	otherBranch := `{"ref": "refs/heads/feature", "repository": {"clone_url": "https://github.com/jenkins-x/fake-repository.git"}}`
+	w = post(handler, otherBranch, "push", hmacToken)
+	assert.Equal(t, http.StatusOK, w.Code, "push to another branch")
+	assert.Contains(t, w.Body.String(), "no repositories match", "push to another branch")
+
+	w = post(handler, githubPush, "pull_request", hmacToken)
+	assert.Equal(t, http.StatusOK, w.Code, "pull request event")
+	assert.Contains(t, w.Body.String(), "ignored", "pull request event")
+
+	w = post(handler, githubPush, "push", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject an invalid signature")
+}
+
+func post(handler http.Handler, payload string, event string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, lighthouse.Path, strings.NewReader(payload))
+	req.Header.Set("X-Hub-Signature-256", signature.Sign([]byte(token), []byte(payload)))
+	if event != "" {
+		req.Header.Set("X-GitHub-Event", event)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
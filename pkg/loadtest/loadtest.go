@@ -0,0 +1,425 @@
+package loadtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo/secret"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-helpers/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// SelectorKey the label key of the repository Secrets of a load test which are labelled differently to the
+	// repository Secrets of an operator so that they are never processed by an operator running in the same namespace
+	SelectorKey = "git-operator.jenkins.io/kind"
+
+	// SelectorValue the label value of the repository Secrets of a load test
+	SelectorValue = "git-operator-loadtest"
+
+	// Selector the label selector of the repository Secrets of a load test
+	Selector = SelectorKey + "=" + SelectorValue
+
+	// eventsPerCommit the number of events buffered for each commit of each repository so that no launch events are
+	// dropped while the repositories are polled
+	eventsPerCommit = 16
+
+	// branch the branch of the simulated repositories
+	branch = "master"
+
+	// jobFile the `job.yaml` of the simulated repositories which runs a container which exits straight away
+	jobFile = `apiVersion: batch/v1
+kind: Job
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: boot
+        image: busybox
+        command: ["true"]
+`
+)
+
+// Options the options of a load test
+type Options struct {
+	// KubeClient the kubernetes client of the real or fake cluster the Jobs are created in
+	KubeClient kubernetes.Interface
+
+	// Namespace the namespace of the repository Secrets and Jobs
+	Namespace string
+
+	// Repositories the number of simulated repositories
+	Repositories int
+
+	// Commits the number of commits launched for each repository
+	Commits int
+
+	// Dir the directory of the simulated git repositories and the work directory of the operator. If not specified
+	// a temporary directory is created and removed once the load test completes
+	Dir string
+
+	// GitClient the git client used to create the simulated repositories and by the operator
+	GitClient gitclient.Interface
+}
+
+// Report the results of a load test
+type Report struct {
+	// Repositories the number of simulated repositories
+	Repositories int `json:"repositories"`
+
+	// Commits the number of commits pushed to the repositories
+	Commits int `json:"commits"`
+
+	// Launched the number of commits launched
+	Launched int `json:"launched"`
+
+	// DurationSeconds the total duration of the polls
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	// Throughput the number of commits launched per second
+	Throughput float64 `json:"throughput"`
+
+	// Latency the time from pushing each commit to creating its Job
+	Latency Summary `json:"latency"`
+
+	// DroppedEvents the number of operator events dropped by the load test so the latency of some launches was
+	// not measured
+	DroppedEvents int `json:"droppedEvents,omitempty"`
+
+	// PollDuration the duration of each poll of every repository
+	PollDuration Summary `json:"pollDuration"`
+
+	// MaxHeapBytes the maximum heap allocated by the operator after any poll
+	MaxHeapBytes uint64 `json:"maxHeapBytes"`
+
+	// SysBytes the memory obtained from the operating system by the end of the load test
+	SysBytes uint64 `json:"sysBytes"`
+
+	// MaxGoroutines the maximum number of goroutines after any poll
+	MaxGoroutines int `json:"maxGoroutines"`
+}
+
+// Summary the distribution of a duration in seconds
+type Summary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// String returns a human readable summary
+func (s Summary) String() string {
+	return fmt.Sprintf("min %.3fs mean %.3fs p50 %.3fs p90 %.3fs p99 %.3fs max %.3fs", s.Min, s.Mean, s.P50, s.P90, s.P99, s.Max)
+}
+
+// Run simulates the repositories by creating local bare git repositories with generated commits and registering
+// them via labelled Secrets, then polls them with the operator launching each commit in the cluster, returning
+// the throughput, the latency from pushing each commit to creating its Job and the resource usage of the operator.
+//
+// The Jobs of each round of commits are deleted once they are created so that the next commit of each repository
+// can be launched and the Secrets and Jobs are deleted once the load test completes
+func (o *Options) Run() (*Report, error) {
+	if o.Repositories <= 0 || o.Commits <= 0 {
+		return nil, errors.Errorf("the number of repositories and commits must be positive")
+	}
+	if o.GitClient == nil {
+		o.GitClient = cli.NewCLIClient("", nil)
+	}
+	if o.Dir == "" {
+		dir, err := ioutil.TempDir("", "jx-git-operator-loadtest-")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create temp dir")
+		}
+		defer os.RemoveAll(dir)
+		o.Dir = dir
+	}
+	names := make([]string, o.Repositories)
+	for i := range names {
+		names[i] = fmt.Sprintf("loadtest-%d", i)
+	}
+	defer o.cleanup(names)
+
+	pushed := map[string]time.Time{}
+	for i := range names {
+		sha, err := o.createRepository(names[i])
+		if err != nil {
+			return nil, err
+		}
+		pushed[names[i]+"/"+sha] = time.Now()
+	}
+
+	l, err := launcher.New(job.LauncherName, launcher.FactoryOptions{
+		KubeClient:      o.KubeClient,
+		Namespace:       o.Namespace,
+		Selector:        constants.DefaultSelector,
+		NoResourceApply: true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create launcher")
+	}
+	repoClient, err := secret.NewClient(o.KubeClient, o.Namespace, Selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create repo client")
+	}
+	p := &poller.Options{
+		GitClient:       o.GitClient,
+		RepoClient:      repoClient,
+		Launcher:        l,
+		KubeClient:      o.KubeClient,
+		Events:          stream.NewBroker(),
+		Dir:             filepath.Join(o.Dir, "operator"),
+		Namespace:       o.Namespace,
+		NoLoop:          true,
+		NoResourceApply: true,
+	}
+
+	// lets record when each commit is launched, buffering the events of every commit so that none are dropped
+	events := p.Events.SubscribeWithBuffer(o.Repositories * o.Commits * eventsPerCommit)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	launched := map[string]time.Time{}
+	wg.Add(1)
+	go func(events <-chan stream.Event) {
+		defer wg.Done()
+		for e := range events {
+			if e.Type == stream.EventLaunched {
+				lock.Lock()
+				launched[e.Repository+"/"+e.GitSHA] = e.Time
+				lock.Unlock()
+			}
+		}
+	}(events)
+	dropped := 0
+	var stopOnce sync.Once
+	stopEvents := func() {
+		stopOnce.Do(func() {
+			dropped = p.Events.Dropped(events)
+			p.Events.Unsubscribe(events)
+			close(events)
+			wg.Wait()
+		})
+	}
+	defer stopEvents()
+
+	report := &Report{Repositories: o.Repositories}
+	var pollDurations []time.Duration
+	var total time.Duration
+	for round := 0; round < o.Commits; round++ {
+		if round > 0 {
+			for _, name := range names {
+				sha, err := o.commit(name, round)
+				if err != nil {
+					return nil, err
+				}
+				pushed[name+"/"+sha] = time.Now()
+			}
+		}
+		start := time.Now()
+		err = p.Poll()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to poll round %d", round)
+		}
+		d := time.Since(start)
+		total += d
+		pollDurations = append(pollDurations, d)
+		log.Logger().Infof("round %d polled %d repositories in %s", round, o.Repositories, d.String())
+
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapAlloc > report.MaxHeapBytes {
+			report.MaxHeapBytes = m.HeapAlloc
+		}
+		report.SysBytes = m.Sys
+		if g := runtime.NumGoroutine(); g > report.MaxGoroutines {
+			report.MaxGoroutines = g
+		}
+
+		err = o.deleteJobs(names)
+		if err != nil {
+			return nil, err
+		}
+	}
+	stopEvents()
+
+	var latencies []time.Duration
+	lock.Lock()
+	for key, t := range launched {
+		if p, ok := pushed[key]; ok {
+			latencies = append(latencies, t.Sub(p))
+		}
+	}
+	lock.Unlock()
+
+	if dropped > 0 {
+		log.Logger().Warnf("dropped %d events so the latency of some launches is not reported", dropped)
+	}
+	report.DroppedEvents = dropped
+	report.Commits = len(pushed)
+	report.Launched = len(latencies)
+	report.DurationSeconds = total.Seconds()
+	if total > 0 {
+		report.Throughput = float64(report.Launched) / total.Seconds()
+	}
+	report.Latency = summarize(latencies)
+	report.PollDuration = summarize(pollDurations)
+	return report, nil
+}
+
+// createRepository creates the bare git repository and its Secret and pushes the initial commit returning its sha
+func (o *Options) createRepository(name string) (string, error) {
+	bareDir := filepath.Join(o.Dir, "remotes", name+".git")
+	workDir := filepath.Join(o.Dir, "work", name)
+	gitOperatorDir := filepath.Join(workDir, ".jx", "git-operator")
+	err := os.MkdirAll(bareDir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create dir %s", bareDir)
+	}
+	err = os.MkdirAll(gitOperatorDir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create dir %s", gitOperatorDir)
+	}
+	err = ioutil.WriteFile(filepath.Join(gitOperatorDir, "job.yaml"), []byte(jobFile), files.DefaultFileWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to write the job.yaml of repository %s", name)
+	}
+	for _, args := range [][]string{
+		{"init", "--bare", bareDir},
+		{"-C", bareDir, "symbolic-ref", "HEAD", "refs/heads/" + branch},
+		{"init", workDir},
+		{"-C", workDir, "remote", "add", "origin", bareDir},
+	} {
+		_, err = o.GitClient.Command(o.Dir, args...)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to create repository %s", name)
+		}
+	}
+
+	_, err = o.KubeClient.CoreV1().Secrets(o.Namespace).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: o.Namespace,
+			Labels: map[string]string{
+				SelectorKey: SelectorValue,
+			},
+		},
+		Data: map[string][]byte{
+			"url": []byte("file://" + filepath.ToSlash(bareDir)),
+		},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create the Secret of repository %s", name)
+	}
+	return o.commit(name, 0)
+}
+
+// commit generates a commit of the repository and pushes it returning its sha
+func (o *Options) commit(name string, round int) (string, error) {
+	workDir := filepath.Join(o.Dir, "work", name)
+	err := ioutil.WriteFile(filepath.Join(workDir, "round.txt"), []byte(fmt.Sprintf("%d\n", round)), files.DefaultFileWritePermissions)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to write a change to repository %s", name)
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=loadtest", "-c", "user.email=loadtest@example.com", "commit", "-m", fmt.Sprintf("round %d", round)},
+		{"push", "origin", "HEAD:refs/heads/" + branch},
+	} {
+		_, err = o.GitClient.Command(workDir, args...)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to commit round %d of repository %s", round, name)
+		}
+	}
+	sha, err := o.GitClient.Command(workDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find the commit sha of repository %s", name)
+	}
+	return strings.TrimSpace(sha), nil
+}
+
+// deleteJobs deletes the Jobs of the simulated repositories so that the next commit of each repository is launched
+func (o *Options) deleteJobs(names []string) error {
+	selector := launcher.RepositoryLabelKey + " in (" + strings.Join(names, ",") + ")"
+	jobs := o.KubeClient.BatchV1().Jobs(o.Namespace)
+	list, err := jobs.List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the Jobs in namespace %s with selector %s", o.Namespace, selector)
+	}
+	propagation := metav1.DeletePropagationBackground
+	for _, j := range list.Items {
+		err = jobs.Delete(j.Name, &metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete Job %s in namespace %s", j.Name, o.Namespace)
+		}
+	}
+	return nil
+}
+
+// cleanup deletes the Secrets and Jobs of the simulated repositories
+func (o *Options) cleanup(names []string) {
+	err := o.deleteJobs(names)
+	if err != nil {
+		log.Logger().Warnf("%s", err.Error())
+	}
+	for _, name := range names {
+		err = o.KubeClient.CoreV1().Secrets(o.Namespace).Delete(name, nil)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Logger().Warnf("failed to delete Secret %s in namespace %s: %s", name, o.Namespace, err.Error())
+		}
+	}
+}
+
+// summarize returns the distribution of the durations
+func summarize(durations []time.Duration) Summary {
+	if len(durations) == 0 {
+		return Summary{}
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	percentile := func(p int) float64 {
+		i := (len(durations)*p + 99) / 100
+		if i > 0 {
+			i--
+		}
+		return durations[i].Seconds()
+	}
+	return Summary{
+		Min:  durations[0].Seconds(),
+		Mean: (total / time.Duration(len(durations))).Seconds(),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P99:  percentile(99),
+		Max:  durations[len(durations)-1].Seconds(),
+	}
+}
@@ -0,0 +1,40 @@
+package loadtest_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/loadtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadTest(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("the load test requires git")
+	}
+	kubeClient := fake.NewSimpleClientset()
+	o := &loadtest.Options{
+		KubeClient:   kubeClient,
+		Namespace:    "jx",
+		Repositories: 3,
+		Commits:      2,
+	}
+	report, err := o.Run()
+	require.NoError(t, err, "failed to run the load test")
+	assert.Equal(t, 3, report.Repositories, "repositories")
+	assert.Equal(t, 6, report.Commits, "commits")
+	assert.Equal(t, 6, report.Launched, "should launch every commit")
+	assert.True(t, report.Latency.Max >= report.Latency.P50, "latency max %f should be at least the p50 %f", report.Latency.Max, report.Latency.P50)
+	assert.True(t, report.Throughput > 0, "throughput")
+	assert.Equal(t, 0, report.DroppedEvents, "dropped events")
+
+	secrets, err := kubeClient.CoreV1().Secrets("jx").List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Secrets")
+	assert.Empty(t, secrets.Items, "should have deleted the repository Secrets")
+	jobs, err := kubeClient.BatchV1().Jobs("jx").List(metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	assert.Empty(t, jobs.Items, "should have deleted the Jobs")
+}
@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "jx_git_operator"
+
+var (
+	// Launches the number of launches which created resources for a new git commit
+	Launches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "launches_total",
+		Help:      "The number of launches which created resources for a new git commit",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// TriggeredLaunches the number of launches which created resources by how they were triggered such as `poll`,
+	// `webhook` or `retry` so that GitOps activity can be distinguished from launches by the operator itself
+	TriggeredLaunches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "triggered_launches_total",
+		Help:      "The number of launches which created resources by how they were triggered",
+	}, []string{"tenant", "namespace", "repository", "trigger"})
+
+	// PollErrors the number of failed polls of a repository
+	PollErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "poll_errors_total",
+		Help:      "The number of failed polls of a repository",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// TenancyViolations the number of repositories rejected due to the tenancy configuration
+	TenancyViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tenancy_violations_total",
+		Help:      "The number of polls of a repository rejected due to the tenancy configuration",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// OwnershipConflicts the number of polls of a repository skipped as it is managed by another operator instance
+	OwnershipConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ownership_conflicts_total",
+		Help:      "The number of polls of a repository skipped as it is managed by another operator instance",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// Drifted whether the live state of the resources applied from a repository has drifted from the last launched commit
+	Drifted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "drifted",
+		Help:      "Whether the live state of the resources applied from a repository has drifted from the last launched commit",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// LaunchBlocked whether the launch of the latest commit of a repository is blocked by the active Job of a previous commit
+	LaunchBlocked = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "launch_blocked",
+		Help:      "Whether the launch of the latest commit of a repository is blocked by the active Job of a previous commit",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// LaunchBlockedDuration how long the launch of the latest commit of a repository has been blocked
+	LaunchBlockedDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "launch_blocked_seconds",
+		Help:      "How long the launch of the latest commit of a repository has been blocked by the active Job of a previous commit",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// ActiveJob whether a repository has an active Job
+	ActiveJob = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_job",
+		Help:      "Whether a repository has an active Job",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// PendingLaunches the number of commits of a repository queued to be launched in order
+	PendingLaunches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_launches",
+		Help:      "The number of commits of a repository queued to be launched in order",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// DroppedPendingLaunches the number of pending commits dropped as the queue of a repository exceeded its limit
+	DroppedPendingLaunches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dropped_pending_launches_total",
+		Help:      "The number of pending commits dropped as the queue of a repository exceeded its limit",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// GitDuration the duration of cloning or pulling a repository
+	GitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "git_duration_seconds",
+		Help:      "The duration of cloning or pulling a repository",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 12),
+	}, []string{"tenant", "namespace", "repository", "operation"})
+
+	// ApplyDuration the duration of applying the resources of a repository
+	ApplyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "apply_duration_seconds",
+		Help:      "The duration of applying the resources of a repository",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 12),
+	}, []string{"tenant", "namespace", "repository"})
+
+	// PhaseDuration the duration of each phase of polling and launching repositories such as `list`, `clone`,
+	// `diff`, `apply` and `create` which is only observed if the phase timings are enabled
+	PhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "phase_duration_seconds",
+		Help:      "The duration of each phase of polling and launching repositories",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"phase"})
+
+	// LaunchLatency the time from detecting a new commit of a repository to launching it
+	LaunchLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "launch_latency_seconds",
+		Help:      "The time from detecting a new commit of a repository to launching it",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	}, []string{"tenant", "namespace", "repository"})
+
+	// LastJobDuration the run duration of the most recently completed Job of a repository
+	LastJobDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_job_duration_seconds",
+		Help:      "The run duration of the most recently completed Job of a repository",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// LastSuccessTimestamp the unix time the most recent successful launch of a repository completed
+	LastSuccessTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "The unix time the most recent successful launch of a repository completed",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// StaleBoot whether a repository has not booted successfully within the staleness threshold
+	StaleBoot = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stale_boot",
+		Help:      "Whether a repository has not booted successfully within the staleness threshold",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// ConsecutiveFailures the number of consecutive failed launches of a repository
+	ConsecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consecutive_failures",
+		Help:      "The number of consecutive failed launches of a repository",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// RetryAttempts the number of failed pods of the active Job of a repository which is being retried
+	RetryAttempts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retry_attempts",
+		Help:      "The number of failed pods of the active Job of a repository which is being retried",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// RetryBackoff the current backoff before the active Job of a repository retries a failed pod
+	RetryBackoff = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retry_backoff_seconds",
+		Help:      "The current backoff before the active Job of a repository retries a failed pod",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// NextRetryTimestamp the unix time the active Job of a repository is expected to retry a failed pod
+	NextRetryTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "next_retry_timestamp_seconds",
+		Help:      "The unix time the active Job of a repository is expected to retry a failed pod or 0 if it is not retrying",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// ThrottledRequests the number of kubernetes API requests which were throttled by the `client` or `adaptive` rate
+	// limiters or rejected by the API `server` as there were too many requests
+	ThrottledRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "throttled_requests_total",
+		Help:      "The number of kubernetes API requests which were throttled",
+	}, []string{"source"})
+
+	// ThrottleDelay the duration kubernetes API requests were delayed by the `client` or `adaptive` rate limiters
+	ThrottleDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "throttle_delay_seconds",
+		Help:      "The duration kubernetes API requests were delayed by rate limiting",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"source"})
+
+	// AdaptiveRateLimit the current queries per second allowed by the adaptive rate limiter of the launcher
+	AdaptiveRateLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "adaptive_rate_limit",
+		Help:      "The current queries per second allowed by the adaptive rate limiter of the launcher",
+	})
+
+	// CloneDiskUsage the size in bytes of the git clone of a repository
+	CloneDiskUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "clone_disk_usage_bytes",
+		Help:      "The size in bytes of the git clone of a repository",
+	}, []string{"tenant", "namespace", "repository"})
+
+	// WorkDirDiskUsage the total size in bytes of the git clones in the work directory
+	WorkDirDiskUsage = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "work_dir_disk_usage_bytes",
+		Help:      "The total size in bytes of the git clones in the work directory",
+	})
+
+	// CloneEvictions the number of git clones evicted as the work directory exceeded the disk quota
+	CloneEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "clone_evictions_total",
+		Help:      "The number of git clones evicted as the work directory exceeded the disk quota",
+	})
+
+	// ProviderRateLimitRemaining the number of requests remaining in the current rate limit window of a git provider
+	// API host as reported by its responses
+	ProviderRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "provider_rate_limit_remaining",
+		Help:      "The number of requests remaining in the current rate limit window of a git provider API host",
+	}, []string{"host"})
+
+	// ProviderRateLimitReset the unix time at which the rate limit window of a git provider API host resets
+	ProviderRateLimitReset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "provider_rate_limit_reset_timestamp_seconds",
+		Help:      "The unix time at which the rate limit window of a git provider API host resets",
+	}, []string{"host"})
+
+	// ProviderDeferredRequests the number of git provider API requests which were deferred as the host was near or
+	// over its rate limit
+	ProviderDeferredRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_deferred_requests_total",
+		Help:      "The number of git provider API requests deferred as the host was near or over its rate limit",
+	}, []string{"host"})
+
+	// ProviderCachedResponses the number of git provider API requests answered with `304 Not Modified` so that the
+	// cached response was used
+	ProviderCachedResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_cached_responses_total",
+		Help:      "The number of git provider API requests answered from the ETag cache",
+	}, []string{"host"})
+)
+
+func init() {
+	prometheus.MustRegister(Launches, TriggeredLaunches, PollErrors, TenancyViolations, OwnershipConflicts, Drifted, LaunchBlocked, LaunchBlockedDuration, ActiveJob, PendingLaunches, DroppedPendingLaunches, GitDuration, ApplyDuration, PhaseDuration, LaunchLatency, LastJobDuration, LastSuccessTimestamp, StaleBoot, ConsecutiveFailures, RetryAttempts, RetryBackoff, NextRetryTimestamp, ThrottledRequests, ThrottleDelay, AdaptiveRateLimit, CloneDiskUsage, WorkDirDiskUsage, CloneEvictions, ProviderRateLimitRemaining, ProviderRateLimitReset, ProviderDeferredRequests, ProviderCachedResponses)
+}
+
+// Handler returns the HTTP handler for the prometheus metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
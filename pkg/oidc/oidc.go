@@ -0,0 +1,164 @@
+package oidc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTokenFile the default file containing the Kubernetes service account token of the operator
+	DefaultTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// DefaultLifetime the lifetime of exchanged tokens whose response does not specify when they expire
+	DefaultLifetime = 10 * time.Minute
+
+	// RefreshMargin the duration before the expiry of an exchanged token after which it is refreshed so that it does
+	// not expire during a clone or pull
+	RefreshMargin = 2 * time.Minute
+
+	// GrantTypeTokenExchange the OAuth 2.0 token exchange grant type of RFC 8693
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// TokenTypeJWT the token type of the service account token
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+
+	// TokenTypeAccessToken the requested token type
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Exchanger exchanges the Kubernetes service account token of the operator, which is an OIDC token, for short lived
+// git provider tokens via an RFC 8693 token exchange endpoint such as a GitHub or GitLab token broker or the security
+// token service of a cloud workload identity. Tokens are cached until shortly before they expire
+type Exchanger struct {
+	// URL the URL of the token exchange endpoint
+	URL string
+
+	// TokenFile the file containing the service account token which is read for every exchange as the kubelet
+	// rotates it. Defaults to DefaultTokenFile
+	TokenFile string
+
+	// HTTPClient the HTTP client used for exchanges
+	HTTPClient *http.Client
+
+	// Now returns the current time. Defaults to time.Now
+	Now func() time.Time
+
+	lock  sync.Mutex
+	cache map[string]token
+}
+
+// token an exchanged token and when it should be refreshed
+type token struct {
+	value     string
+	refreshAt time.Time
+}
+
+// NewExchanger creates a new exchanger of the service account token in the given file via the token exchange
+// endpoint with the given URL
+func NewExchanger(exchangeURL string, tokenFile string) *Exchanger {
+	if tokenFile == "" {
+		tokenFile = DefaultTokenFile
+	}
+	return &Exchanger{
+		URL:        exchangeURL,
+		TokenFile:  tokenFile,
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+		Now:        time.Now,
+		cache:      map[string]token{},
+	}
+}
+
+// Token returns a git provider token for the given audience and optional scope, exchanging the service account
+// token if there is no cached token or it is about to expire
+func (e *Exchanger) Token(audience string, scope string) (string, error) {
+	key := audience + "\n" + scope
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	now := e.Now()
+	if t, ok := e.cache[key]; ok && now.Before(t.refreshAt) {
+		return t.value, nil
+	}
+	value, lifetime, err := e.exchange(audience, scope)
+	if err != nil {
+		return "", err
+	}
+	refresh := lifetime - RefreshMargin
+	if refresh < lifetime/2 {
+		refresh = lifetime / 2
+	}
+	e.cache[key] = token{value: value, refreshAt: now.Add(refresh)}
+	return value, nil
+}
+
+// exchange exchanges the service account token returning the token and its lifetime
+func (e *Exchanger) exchange(audience string, scope string) (string, time.Duration, error) {
+	data, err := ioutil.ReadFile(e.TokenFile)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to read the service account token file %s", e.TokenFile)
+	}
+	form := url.Values{}
+	form.Set("grant_type", GrantTypeTokenExchange)
+	form.Set("subject_token", strings.TrimSpace(string(data)))
+	form.Set("subject_token_type", TokenTypeJWT)
+	form.Set("requested_token_type", TokenTypeAccessToken)
+	if audience != "" {
+		form.Set("audience", audience)
+	}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to create the token exchange request to %s", e.URL)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to exchange the service account token via %s", e.URL)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to read the token exchange response of %s", e.URL)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", 0, errors.Errorf("the token exchange via %s failed with status %d: %s", e.URL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// lets support the RFC 8693 response as well as the installation token response of GitHub used by some brokers
+	result := struct {
+		AccessToken string    `json:"access_token"`
+		ExpiresIn   int64     `json:"expires_in"`
+		Token       string    `json:"token"`
+		ExpiresAt   time.Time `json:"expires_at"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "failed to parse the token exchange response of %s", e.URL)
+	}
+	value := result.AccessToken
+	if value == "" {
+		value = result.Token
+	}
+	if value == "" {
+		return "", 0, errors.Errorf("the token exchange response of %s does not contain a token", e.URL)
+	}
+	lifetime := DefaultLifetime
+	if result.ExpiresIn > 0 {
+		lifetime = time.Duration(result.ExpiresIn) * time.Second
+	} else if !result.ExpiresAt.IsZero() {
+		lifetime = result.ExpiresAt.Sub(e.Now())
+	}
+	return value, lifetime, nil
+}
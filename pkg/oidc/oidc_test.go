@@ -0,0 +1,77 @@
+package oidc_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangerToken(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "oidc-")
+	require.NoError(t, err, "failed to create temp dir")
+	defer os.RemoveAll(tmpDir)
+	tokenFile := filepath.Join(tmpDir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("sa-token-1\n"), 0600)
+	require.NoError(t, err, "failed to save token file")
+
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, http.MethodPost, r.Method, "method")
+		assert.Equal(t, oidc.GrantTypeTokenExchange, r.PostForm.Get("grant_type"), "grant_type")
+		assert.Equal(t, oidc.TokenTypeJWT, r.PostForm.Get("subject_token_type"), "subject_token_type")
+		if r.PostForm.Get("audience") == "broken" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":"access_denied"}`)
+			return
+		}
+		exchanges++
+		if r.PostForm.Get("audience") == "github" {
+			fmt.Fprintf(w, `{"token":"gh-%s-%s","expires_at":"2020-01-01T01:00:00Z"}`, r.PostForm.Get("subject_token"), r.PostForm.Get("scope"))
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"%s-%s-%d","expires_in":600}`, r.PostForm.Get("subject_token"), r.PostForm.Get("audience"), exchanges)
+	}))
+	defer server.Close()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := oidc.NewExchanger(server.URL, tokenFile)
+	e.Now = func() time.Time {
+		return now
+	}
+
+	value, err := e.Token("gitlab", "")
+	require.NoError(t, err, "failed to exchange token")
+	assert.Equal(t, "sa-token-1-gitlab-1", value, "token")
+
+	now = now.Add(7 * time.Minute)
+	value, err = e.Token("gitlab", "")
+	require.NoError(t, err, "failed to get cached token")
+	assert.Equal(t, "sa-token-1-gitlab-1", value, "should use the cached token")
+
+	// lets rotate the service account token and refresh the token shortly before it expires
+	err = ioutil.WriteFile(tokenFile, []byte("sa-token-2"), 0600)
+	require.NoError(t, err, "failed to save token file")
+	now = now.Add(2 * time.Minute)
+	value, err = e.Token("gitlab", "")
+	require.NoError(t, err, "failed to refresh token")
+	assert.Equal(t, "sa-token-2-gitlab-2", value, "should refresh the token")
+
+	value, err = e.Token("github", "myorg/myrepo")
+	require.NoError(t, err, "failed to exchange token")
+	assert.Equal(t, "gh-sa-token-2-myorg/myrepo", value, "should support GitHub token responses")
+
+	_, err = e.Token("broken", "")
+	require.Error(t, err, "should fail when the exchange is denied")
+	assert.Contains(t, err.Error(), "access_denied", "error")
+	assert.Equal(t, 3, exchanges, "exchanges")
+}
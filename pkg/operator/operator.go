@@ -0,0 +1,128 @@
+package operator
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/trigger"
+	"github.com/pkg/errors"
+)
+
+// Options the options for running the git operator. Any clients which are not specified are lazily created.
+//
+// The options can be populated from environment variables via `envconfig` in the same way as the `jx-git-operator` binary
+type Options struct {
+	poller.Options
+
+	// HTTPAddress the optional address to serve HTTP endpoints such as `/metrics` on. e.g. `:8080`
+	HTTPAddress string `env:"HTTP_ADDRESS"`
+
+	// AdminToken the optional bearer token of the admin REST API. If not specified the admin API is disabled
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	// LighthouseHMACToken the optional HMAC token which signs the push events relayed by Lighthouse. If not specified
+	// the Lighthouse endpoint is disabled
+	LighthouseHMACToken string `env:"LIGHTHOUSE_HMAC_TOKEN"`
+
+	// TriggerToken the optional bearer or HMAC token of the trigger endpoint. If not specified the trigger endpoint
+	// is disabled
+	TriggerToken string `env:"TRIGGER_TOKEN"`
+
+	// TriggerMinInterval the minimum interval between triggers of the same repository via the trigger endpoint.
+	// Defaults to 1 minute
+	TriggerMinInterval time.Duration `env:"TRIGGER_MIN_INTERVAL"`
+
+	// TriggerTrustedProxies the optional comma separated IP addresses or CIDR ranges of the proxies in front of the
+	// trigger endpoint whose `X-Forwarded-For` header is used as the client address in the audit log
+	TriggerTrustedProxies string `env:"TRIGGER_TRUSTED_PROXIES"`
+
+	// ChatOpsHMACToken the optional HMAC token used to verify the comment events of the git provider. If not
+	// specified ChatOps commands are disabled
+	ChatOpsHMACToken string `env:"CHATOPS_HMAC_TOKEN"`
+
+	// ChatOpsRoles the optional comma separated author associations allowed to run ChatOps commands. Defaults to
+	// `OWNER,MEMBER,COLLABORATOR`
+	ChatOpsRoles string `env:"CHATOPS_ROLES"`
+
+	// AdmissionAddress the optional address of the HTTPS server of the validating admission webhook which rejects
+	// Jobs carrying the labels of the operator unless created by one of the AdmissionAllowedUsers. e.g. `:8443`
+	AdmissionAddress string `env:"ADMISSION_ADDRESS"`
+
+	// AdmissionCertFile the PEM encoded certificate of the admission webhook server
+	AdmissionCertFile string `env:"ADMISSION_CERT_FILE"`
+
+	// AdmissionKeyFile the PEM encoded private key of the admission webhook server
+	AdmissionKeyFile string `env:"ADMISSION_KEY_FILE"`
+
+	// AdmissionAllowedUsers the comma separated user names allowed to create Jobs carrying the labels of the operator
+	// such as the service account of the operator `system:serviceaccount:jx:jx-git-operator`
+	AdmissionAllowedUsers string `env:"ADMISSION_ALLOWED_USERS"`
+}
+
+// Operator discovers git repositories, polls them for changes and launches Jobs for new commits.
+//
+// It can be embedded into other binaries and controllers rather than running a separate `Deployment`
+type Operator struct {
+	options        *Options
+	trustedProxies []*net.IPNet
+}
+
+// New creates a new operator from the given options
+func New(o Options) (*Operator, error) {
+	op := &Operator{options: &o}
+	err := op.options.ValidateOptions()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid options")
+	}
+	op.trustedProxies, err = trigger.ParseTrustedProxies(o.TriggerTrustedProxies)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid $TRIGGER_TRUSTED_PROXIES")
+	}
+	if o.AdmissionAddress != "" {
+		if o.AdmissionCertFile == "" || o.AdmissionKeyFile == "" {
+			return nil, errors.Errorf("the admission webhook requires the $ADMISSION_CERT_FILE and $ADMISSION_KEY_FILE")
+		}
+		if strings.TrimSpace(o.AdmissionAllowedUsers) == "" {
+			return nil, errors.Errorf("the admission webhook requires the $ADMISSION_ALLOWED_USERS")
+		}
+	}
+	return op, nil
+}
+
+// Run runs the operator until the context is done or an error occurs.
+//
+// If `NoLoop` is enabled on the options only a single poll is performed
+func (op *Operator) Run(ctx context.Context) error {
+	if op.options.HTTPAddress != "" {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		err := op.startServer(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to start HTTP server")
+		}
+	}
+	if op.options.AdmissionAddress != "" {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		err := op.startAdmissionServer(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to start admission webhook server")
+		}
+	}
+	return op.options.RunWithContext(ctx)
+}
+
+// Poll performs a single poll of all the repositories
+func (op *Operator) Poll() error {
+	return op.options.Poll()
+}
+
+// Options returns the options used by the operator
+func (op *Operator) Options() *Options {
+	return op.options
+}
@@ -0,0 +1,140 @@
+package operator_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/fake"
+	"github.com/jenkins-x/jx-git-operator/pkg/operator"
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner/fakerunner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepoClient struct {
+	repositories []repo.Repository
+}
+
+func (f *fakeRepoClient) List() ([]repo.Repository, error) {
+	return f.repositories, nil
+}
+
+func TestOperator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-jx-git-operator-")
+	require.NoError(t, err, "failed to create temp dir")
+
+	f := &fake.Launcher{}
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if c.Name == "git" && len(c.Args) > 0 && c.Args[0] == "rev-parse" {
+				return "sha1", nil
+			}
+			return "", nil
+		},
+	}
+
+	op, err := operator.New(operator.Options{
+		Options: poller.Options{
+			CommandRunner: runner.Run,
+			RepoClient: &fakeRepoClient{
+				repositories: []repo.Repository{
+					{
+						Name:      "myrepo",
+						Namespace: "jx",
+						GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+					},
+				},
+			},
+			Launcher: f,
+			Dir:      dir,
+			NoLoop:   true,
+		},
+	})
+	require.NoError(t, err, "failed to create operator")
+
+	err = op.Run(context.Background())
+	require.NoError(t, err, "failed to run operator")
+
+	f.ExpectLaunches(t, fake.ExpectedLaunch{Name: "myrepo", GitSHA: "sha1"})
+}
+
+func TestOperatorWatchdog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-jx-git-operator-")
+	require.NoError(t, err, "failed to create temp dir")
+
+	// lets hang the first two clones until released
+	var clones int32
+	release := make(chan struct{})
+	f := &fake.Launcher{}
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if c.Name == "git" && len(c.Args) > 0 {
+				switch c.Args[0] {
+				case "clone":
+					if atomic.AddInt32(&clones, 1) <= 2 {
+						<-release
+					}
+				case "rev-parse":
+					return "sha1", nil
+				}
+			}
+			return "", nil
+		},
+	}
+
+	op, err := operator.New(operator.Options{
+		Options: poller.Options{
+			CommandRunner: runner.Run,
+			RepoClient: &fakeRepoClient{
+				repositories: []repo.Repository{
+					{
+						Name:      "myrepo",
+						Namespace: "jx",
+						GitURL:    "https://github.com/jenkins-x/fake-repository.git",
+					},
+				},
+			},
+			Launcher:       f,
+			Dir:            dir,
+			PollDuration:   10 * time.Millisecond,
+			WatchdogCycles: 2,
+		},
+	})
+	require.NoError(t, err, "failed to create operator")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- op.Run(ctx)
+	}()
+
+	health := func() int {
+		w := httptest.NewRecorder()
+		op.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, operator.HealthPath, nil))
+		return w.Code
+	}
+
+	// the first clone is abandoned by the reset and the second clone stalls the loop again
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&clones) == 2
+	}, 5*time.Second, 5*time.Millisecond, "should have reset the stalled loop")
+	assert.Equal(t, http.StatusServiceUnavailable, health(), "should fail the liveness probe while stalled")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return health() == http.StatusOK
+	}, 5*time.Second, 5*time.Millisecond, "should recover once a cycle completes")
+
+	cancel()
+	require.NoError(t, <-done, "failed to run operator")
+
+	f.ExpectLaunches(t, fake.ExpectedLaunch{Name: "myrepo", GitSHA: "sha1"})
+}
@@ -0,0 +1,156 @@
+package operator
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/admin"
+	"github.com/jenkins-x/jx-git-operator/pkg/admission"
+	"github.com/jenkins-x/jx-git-operator/pkg/chatops"
+	"github.com/jenkins-x/jx-git-operator/pkg/lighthouse"
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-git-operator/pkg/trigger"
+	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HealthPath the path of the liveness probe endpoint
+const HealthPath = "/healthz"
+
+// Handler returns the HTTP handler for the operator endpoints
+func (op *Operator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc(HealthPath, op.healthHandler)
+	mux.Handle("/status", op.options.Status.StatusHandler(op.options.Commands.RedactText))
+	mux.Handle(status.StatusPath, op.options.Status.StatusHandler(op.options.Commands.RedactText))
+	mux.Handle(status.BadgePath, op.options.Status.BadgeHandler())
+	mux.Handle(stream.Path, op.options.Events.Handler(op.options.Commands.RedactText))
+	if op.options.AdminToken != "" {
+		mux.Handle(admin.Path, admin.NewServer(op.options.AdminToken, &op.options.Options).Handler())
+	}
+	if op.options.LighthouseHMACToken != "" {
+		mux.Handle(lighthouse.Path, lighthouse.NewHandler(op.options.LighthouseHMACToken, &op.options.Options))
+	}
+	if op.options.ChatOpsHMACToken != "" {
+		var roles []string
+		if op.options.ChatOpsRoles != "" {
+			roles = strings.Split(op.options.ChatOpsRoles, ",")
+		}
+		mux.Handle(chatops.Path, chatops.NewHandler(op.options.ChatOpsHMACToken, roles, &op.options.Options))
+	}
+	if op.options.TriggerToken != "" {
+		mux.Handle(trigger.Path, trigger.NewHandler(op.options.TriggerToken, op.options.TriggerMinInterval, op.trustedProxies, &op.options.Options))
+	}
+	return mux
+}
+
+// healthHandler fails if the watchdog has detected that the poll loop is stalled
+func (op *Operator) healthHandler(w http.ResponseWriter, r *http.Request) {
+	err := op.options.Health()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// startServer starts the HTTP server in the background until the context is done
+func (op *Operator) startServer(ctx context.Context) error {
+	address := op.options.HTTPAddress
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", address)
+	}
+
+	server := &http.Server{
+		Handler: op.Handler(),
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Logger().Warnf("failed to shutdown HTTP server: %s", err.Error())
+		}
+	}()
+	go op.watchJobs(ctx)
+	go func() {
+		log.Logger().Infof("serving HTTP on %s", address)
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Logger().Errorf("HTTP server failed: %s", err.Error())
+		}
+	}()
+	return nil
+}
+
+// AdmissionHandler returns the HTTP handler of the validating admission webhook
+func (op *Operator) AdmissionHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(admission.Path, admission.NewHandler(strings.Split(op.options.AdmissionAllowedUsers, ",")))
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+// startAdmissionServer starts the HTTPS server of the admission webhook in the background until the context is done
+func (op *Operator) startAdmissionServer(ctx context.Context) error {
+	address := op.options.AdmissionAddress
+	tlsConfig, err := admission.NewTLSConfig(op.options.AdmissionCertFile, op.options.AdmissionKeyFile)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", address)
+	}
+
+	server := &http.Server{
+		Handler:   op.AdmissionHandler(),
+		TLSConfig: tlsConfig,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Logger().Warnf("failed to shutdown admission webhook server: %s", err.Error())
+		}
+	}()
+	go func() {
+		log.Logger().Infof("serving the admission webhook on %s", address)
+		err := server.ServeTLS(listener, "", "")
+		if err != nil && err != http.ErrServerClosed {
+			log.Logger().Errorf("admission webhook server failed: %s", err.Error())
+		}
+	}()
+	return nil
+}
+
+// watchJobs publishes the lifecycle events of the launched Jobs to the event stream until the context is done
+func (op *Operator) watchJobs(ctx context.Context) {
+	kubeClient := op.options.KubeClient
+	if kubeClient == nil {
+		f := kubeclient.NewFactory()
+		cfg, err := f.CreateKubeConfig()
+		if err == nil {
+			kubeClient, err = kubernetes.NewForConfig(cfg)
+		}
+		if err != nil {
+			log.Logger().Warnf("not streaming Job events as failed to create the kube client: %s", err.Error())
+			return
+		}
+	}
+	op.options.Events.WatchJobs(ctx, kubeClient, op.options.Namespace)
+}
@@ -0,0 +1,52 @@
+package podspecs
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Paths the paths of the pod specs in workload resources such as a `Job` or `CronJob`
+var Paths = [][]string{
+	{"spec", "template", "spec"},
+	{"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// PathsFor returns the paths of the pod specs for the given resource
+func PathsFor(u *unstructured.Unstructured) [][]string {
+	if u.GetKind() == "Pod" {
+		return [][]string{{"spec"}}
+	}
+	return Paths
+}
+
+// Modify invokes the given function on each pod spec in the resource and saves any changes
+func Modify(u *unstructured.Unstructured, fn func(podSpec map[string]interface{}) error) error {
+	for _, path := range PathsFor(u) {
+		podSpec, found, err := unstructured.NestedMap(u.Object, path...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get pod spec of %s %s", u.GetKind(), u.GetName())
+		}
+		if !found {
+			continue
+		}
+		err = fn(podSpec)
+		if err != nil {
+			return err
+		}
+		err = unstructured.SetNestedMap(u.Object, podSpec, path...)
+		if err != nil {
+			return errors.Wrapf(err, "failed to set pod spec of %s %s", u.GetKind(), u.GetName())
+		}
+	}
+	return nil
+}
+
+// Visit invokes the given function on each pod spec in the resource without saving any changes
+func Visit(u *unstructured.Unstructured, fn func(podSpec map[string]interface{})) {
+	for _, path := range PathsFor(u) {
+		podSpec, found, _ := unstructured.NestedMap(u.Object, path...)
+		if found {
+			fn(podSpec)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package poller
+
+import (
+	"context"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// rewatchDelay the delay before watching the Jobs again if the watch fails or is closed
+const rewatchDelay = 5 * time.Second
+
+// adopt rediscovers the resources launched before the operator started so that the last launched commit and any
+// active Job of each repository are known straight away rather than on the next launch attempt of the repository
+func (o *Options) adopt() error {
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return nil
+	}
+	repos, err := o.RepoClient.List()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list repositories")
+	}
+	for _, r := range repos {
+		if r.Deleting || !o.inRepoGroups(r) {
+			continue
+		}
+		records, err := historyProvider.History(r)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find the launches of repository %s in namespace %s", r.Name, r.Namespace)
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		// the records are sorted with the most recent first
+		latest := records[0]
+		active := ""
+		if latest.Result == launcher.ResultActive {
+			active = latest.Name
+			log.Logger().Infof("adopting active %s %s of repository %s in namespace %s", latest.Kind, latest.Name, r.Name, r.Namespace)
+		}
+		o.Status.Adopt(r, latest.GitSHA, latest.Created, active)
+
+		// lets record the windows of the recent Jobs with the oldest first
+		if len(records) > status.MaxJobs {
+			records = records[:status.MaxJobs]
+		}
+		for i := len(records) - 1; i >= 0; i-- {
+			o.Status.StartJob(r, jobWindow(records[i]))
+		}
+		o.state(r.Namespace + "/" + r.Name).lastLaunched = latest.GitSHA
+	}
+	return nil
+}
+
+// watchJobs watches the Jobs launched by the operator until the context is done so that the active Job of a
+// repository is marked as completed as soon as it succeeds or fails, waking up the poll loop to launch any commit
+// which was waiting for it
+func (o *Options) watchJobs(ctx context.Context) {
+	for {
+		w, err := o.KubeClient.BatchV1().Jobs(o.Namespace).Watch(metav1.ListOptions{
+			LabelSelector: constants.DefaultSelector,
+		})
+		if err != nil {
+			log.Logger().Warnf("failed to watch Jobs in namespace %s: %s", o.Namespace, err.Error())
+		} else {
+			o.completeJobs(ctx, w)
+			w.Stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
+
+// jobWindow returns the window of time during which the launched resource of the record was running
+func jobWindow(record launcher.LaunchRecord) status.JobWindow {
+	w := status.JobWindow{
+		Name:      record.Name,
+		Namespace: record.Namespace,
+		GitSHA:    record.GitSHA,
+		Started:   record.Created,
+		Completed: record.Completed,
+		Succeeded: record.Result == launcher.ResultSucceeded,
+	}
+	if record.Started != nil {
+		w.Started = *record.Started
+	}
+	return w
+}
+
+// completeJobs marks the active Jobs of the repositories as completed from the events of the watch, once their
+// `Complete` or `Failed` condition is true or they are deleted, until the watch is closed or the context is done
+func (o *Options) completeJobs(ctx context.Context, w watch.Interface) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case we, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			j, ok := we.Object.(*v1.Job)
+			if !ok {
+				continue
+			}
+			completed := time.Now()
+			if we.Type != watch.Deleted {
+				// lets wait for a Job which is retrying within its backoffLimit to succeed or fail
+				if !job.IsJobFinished(*j) {
+					continue
+				}
+				if t := job.JobCompletionTime(*j); !t.IsZero() {
+					completed = t
+				}
+			}
+			succeeded := job.JobResult(*j) == launcher.ResultSucceeded
+			if o.Status.CompleteJob(j.Namespace, j.Name, succeeded, completed) {
+				log.Logger().Infof("the Job %s in namespace %s of repository %s has completed", j.Name, j.Namespace, j.Labels[launcher.RepositoryLabelKey])
+				o.triggered.wakeUp()
+			}
+		}
+	}
+}
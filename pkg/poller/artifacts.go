@@ -0,0 +1,145 @@
+package poller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// artifactsDirName the directory within the work directory used to collect the failure artifacts before uploading
+const artifactsDirName = ".artifacts"
+
+// captureFailure collects the pod logs, events and manifest of the latest Job of the repository which has failed after
+// exhausting its retries and uploads them to the artifacts bucket, if enabled, recording the URL in the status of the
+// repository and publishing it as an event so that failures can be investigated after the pods have gone. Each Job is
+// only captured once and any failures are logged rather than failing the poll
+func (o *Options) captureFailure(r repo.Repository) {
+	if o.artifactsBucket == nil || o.KubeClient == nil {
+		return
+	}
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return
+	}
+	records, err := historyProvider.History(r)
+	if err != nil {
+		log.Logger().Warnf("failed to find the launches of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+		return
+	}
+	if len(records) > 1 && records[0].Rollback && records[0].Result == launcher.ResultActive {
+		// lets capture the failed Job which was just rolled back
+		records = records[1:]
+	}
+	if len(records) == 0 || records[0].Kind != "Job" || len(records[0].Clusters) > 0 || records[0].Result != launcher.ResultFailed {
+		return
+	}
+	record := records[0]
+	record.Name = record.JobName()
+	key := r.Namespace + "/" + r.Name
+	if o.state(key).capturedFailure == record.Name {
+		return
+	}
+	j, err := o.KubeClient.BatchV1().Jobs(record.Namespace).Get(record.Name, metav1.GetOptions{})
+	if err != nil {
+		log.Logger().Warnf("failed to get the failed Job %s of repository %s: %s", record.Name, key, err.Error())
+		return
+	}
+	if !isJobFailed(j) {
+		// lets wait for the Job controller to exhaust the retries so the pod which failed last is captured
+		return
+	}
+
+	dir := filepath.Join(o.Dir, artifactsDirName, record.Name)
+	defer os.RemoveAll(dir)
+	err = o.collectFailure(historyProvider, r, record, j, dir)
+	if err != nil {
+		log.Logger().Warnf("failed to collect the artifacts of failed Job %s of repository %s: %s", record.Name, key, err.Error())
+		return
+	}
+	u, err := o.artifactsBucket.Upload(dir, path.Join(record.Namespace, r.Name, record.Name))
+	if err != nil {
+		log.Logger().Warnf("failed to upload the artifacts of failed Job %s of repository %s: %s", record.Name, key, err.Error())
+		return
+	}
+	o.state(key).capturedFailure = record.Name
+
+	log.Logger().Infof("uploaded the artifacts of failed Job %s of repository %s to %s", record.Name, key, u)
+	o.Status.SetFailureArtifacts(r, u)
+	event := stream.NewEvent(stream.EventFailureArtifacts, r, record.GitSHA, u)
+	event.Job = record.Name
+	o.Events.Publish(event)
+}
+
+// collectFailure saves the manifest, events and pod logs of the failed Job to the given directory
+func (o *Options) collectFailure(historyProvider launcher.HistoryProvider, r repo.Repository, record launcher.LaunchRecord, j *v1.Job, dir string) error {
+	err := os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	ns := record.Namespace
+	kubeClient := o.KubeClient
+
+	data, err := yaml.Marshal(j)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal Job %s", record.Name)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "job.yaml"), data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save the manifest of Job %s", record.Name)
+	}
+
+	// lets include the events of the Job and its pods
+	names := map[string]bool{record.Name: true}
+	pods, err := kubeClient.CoreV1().Pods(ns).List(metav1.ListOptions{
+		LabelSelector: "job-name=" + record.Name,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the pods of Job %s in namespace %s", record.Name, ns)
+	}
+	for _, pod := range pods.Items {
+		names[pod.Name] = true
+	}
+	events, err := kubeClient.CoreV1().Events(ns).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the events in namespace %s", ns)
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+	buf := strings.Builder{}
+	for _, e := range events.Items {
+		if !names[e.InvolvedObject.Name] {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%s\t%s\t%s/%s\t%s\t%s\n", e.LastTimestamp.UTC().Format("2006-01-02T15:04:05Z"), e.Type, e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason, e.Message))
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "events.txt"), []byte(buf.String()), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save the events of Job %s", record.Name)
+	}
+
+	logs, err := historyProvider.Logs(r, record.Name)
+	if err != nil {
+		// lets upload the logs which could be found along with the error
+		logs += "\nfailed to get the logs: " + err.Error() + "\n"
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "logs.txt"), []byte(logs), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save the logs of Job %s", record.Name)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package poller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// blockLaunch records that the launch of the commit sha of the repository is blocked by the active Job of a previous
+// commit in the status and metrics of the repository and queues it until the Job completes. The Job watch wakes up
+// the poll loop as soon as the blocking Job completes, even if it is not the active Job recorded in the status, so
+// that the commit is launched straight away rather than on the next poll
+func (o *Options) blockLaunch(r repo.Repository, dir string, sha string, decision *launcher.Decision) {
+	log.Logger().Infof("audit: the launch of repository %s in namespace %s sha %s is blocked by the active Job %s in namespace %s", r.Name, r.Namespace, sha, decision.BlockedBy, decision.Namespace)
+	o.Status.Record(r, status.ResultBlocked, sha, fmt.Sprintf("waiting for the active Job %s to complete", decision.BlockedBy))
+	o.Status.SetBlocked(r, &status.Blocked{
+		GitSHA:    sha,
+		Job:       decision.BlockedBy,
+		Namespace: decision.Namespace,
+		Since:     time.Now(),
+	})
+	metrics.LaunchBlocked.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(1)
+	o.deferLaunch(r, skipReasonActiveJob)
+	o.enqueuePending(r, dir, sha)
+}
+
+// unblockLaunch clears any blocked launch of the repository once its latest commit has been launched or no longer
+// needs launching
+func (o *Options) unblockLaunch(r repo.Repository) {
+	o.Status.SetBlocked(r, nil)
+	metrics.LaunchBlocked.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(0)
+	metrics.LaunchBlockedDuration.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(0)
+}
+
+// recordQueueState records whether the repository has an active Job and for how long its launch has been blocked
+// after each poll so that a backlog building up can be alerted on before it becomes an incident
+func (o *Options) recordQueueState(r repo.Repository) {
+	s, found := o.Status.Get(r.Namespace, r.Name)
+	if !found {
+		return
+	}
+	active := 0.0
+	if s.ActiveJob != "" {
+		active = 1
+	}
+	metrics.ActiveJob.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(active)
+	blocked := 0.0
+	if s.Blocked != nil {
+		blocked = time.Since(s.Blocked.Since).Seconds()
+	}
+	metrics.LaunchBlockedDuration.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(blocked)
+}
@@ -0,0 +1,65 @@
+package poller
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/inventory"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// recordBootInventory loads the inventory of the resources changed by the latest completed boot Job of the
+// repository, if the Job wrote one, recording it in the status of the repository and the audit log so that cluster
+// changes can be traced to the commit which made them. Each Job is only checked once
+func (o *Options) recordBootInventory(r repo.Repository) {
+	if !o.BootInventory || o.KubeClient == nil {
+		return
+	}
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return
+	}
+	records, err := historyProvider.History(r)
+	if err != nil {
+		log.Logger().Warnf("failed to find the launches of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+		return
+	}
+
+	// the records are sorted with the most recent first
+	var latest *launcher.LaunchRecord
+	for i := range records {
+		if records[i].Kind == "Job" && records[i].Result != launcher.ResultActive {
+			latest = &records[i]
+			break
+		}
+	}
+	key := r.Namespace + "/" + r.Name
+	if latest == nil || o.state(key).bootInventory == latest.Name {
+		return
+	}
+	inv, err := inventory.LoadBoot(o.KubeClient, latest.Namespace, latest.Name)
+	if err != nil {
+		log.Logger().Warnf("failed to load the inventory of Job %s of repository %s: %s", latest.Name, r.Name, err.Error())
+		return
+	}
+	o.state(key).bootInventory = latest.Name
+	if inv == nil {
+		return
+	}
+
+	bi := status.BootInventory{
+		Job:    latest.Name,
+		GitSHA: latest.GitSHA,
+	}
+	for _, e := range inv.Entries {
+		bi.Resources = append(bi.Resources, e.String())
+	}
+	o.Status.SetBootInventory(r, bi)
+	if len(bi.Resources) == 0 {
+		log.Logger().Infof("audit: Job %s of repository %s for sha %s changed no resources", latest.Name, key, latest.GitSHA)
+		return
+	}
+	log.Logger().Infof("audit: Job %s of repository %s for sha %s changed %d resources:\n  %s", latest.Name, key, latest.GitSHA, len(bi.Resources), strings.Join(bi.Resources, "\n  "))
+}
@@ -0,0 +1,84 @@
+package poller
+
+import (
+	"fmt"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/provider"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// defaultCommitStatusContext the default context of the commit statuses reported by the operator
+const defaultCommitStatusContext = "git-operator"
+
+// commitStates the commit status states of the results of launches
+var commitStates = map[string]string{
+	launcher.ResultActive:    provider.StatePending,
+	launcher.ResultSucceeded: provider.StateSuccess,
+	launcher.ResultFailed:    provider.StateFailure,
+}
+
+// reportCommitStatus reports the result of the latest launch of the repository as a commit status to the git provider
+// of its upstream URL, or of its git URL if it has none, whenever the result changes so that clusters which clone from
+// an internal mirror still surface their results where developers look
+func (o *Options) reportCommitStatus(r repo.Repository) {
+	if !o.CommitStatus || o.statusClient == nil {
+		return
+	}
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return
+	}
+	records, err := historyProvider.History(r)
+	if err != nil {
+		log.Logger().Warnf("failed to find the launches of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	// the records are sorted with the most recent first
+	latest := records[0]
+	state := commitStates[latest.Result]
+	if state == "" || latest.GitSHA == "" {
+		return
+	}
+	key := r.Namespace + "/" + r.Name
+	reported := latest.GitSHA + "/" + state
+	if o.state(key).reportedStatus == reported {
+		return
+	}
+	gitURL := r.UpstreamURL
+	if gitURL == "" {
+		gitURL = r.GitURL
+	}
+	context := o.CommitStatusContext
+	if context == "" {
+		context = defaultCommitStatusContext
+	}
+	if r.Folder != "" {
+		context += "/" + r.Folder
+	}
+	err = o.statusClient.SetCommitStatus(gitURL, latest.GitSHA, provider.CommitStatus{
+		State:       state,
+		Context:     context,
+		Description: fmt.Sprintf("%s %s %s", latest.Kind, latest.JobName(), latest.Result),
+	})
+	if err == provider.ErrUnsupported {
+		log.Logger().Debugf("not reporting the commit status of repository %s as its git provider is not supported", r.Name)
+		o.state(key).reportedStatus = reported
+		return
+	}
+	if provider.IsRateLimited(err) {
+		log.Logger().Infof("deferring the commit status of repository %s: %s", r.Name, err.Error())
+		return
+	}
+	if err != nil {
+		log.Logger().Warnf("failed to report the commit status of repository %s sha %s to %s: %s", r.Name, latest.GitSHA, repo.RedactGitURL(gitURL), err.Error())
+		return
+	}
+	log.Logger().Infof("reported the %s commit status of repository %s sha %s", state, r.Name, latest.GitSHA)
+	o.state(key).reportedStatus = reported
+}
@@ -0,0 +1,54 @@
+package poller
+
+import (
+	"net/url"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// supplyCredentials adds the credentials supplied by the credential provider to the http git URLs of the repositories
+// which do not have a password
+func (o *Options) supplyCredentials(repos []repo.Repository) {
+	if o.CredentialProvider == nil {
+		return
+	}
+	for i := range repos {
+		r := &repos[i]
+		if r.NoCredentialHelper {
+			continue
+		}
+		u := passwordlessHTTPURL(r.GitURL)
+		if u == nil {
+			continue
+		}
+		c, err := o.CredentialProvider.Credentials(*r)
+		if err != nil {
+			log.Logger().Warnf("failed to get the credentials of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+			continue
+		}
+		if c == nil {
+			continue
+		}
+		username := c.Username
+		if username == "" && u.User != nil {
+			username = u.User.Username()
+		}
+		u.User = url.UserPassword(username, c.Password)
+		r.GitURL = u.String()
+	}
+}
+
+// passwordlessHTTPURL returns the parsed git URL if it is an http URL without a password or nil
+func passwordlessHTTPURL(gitURL string) *url.URL {
+	u, err := url.Parse(gitURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return nil
+		}
+	}
+	return u
+}
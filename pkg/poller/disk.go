@@ -0,0 +1,167 @@
+package poller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// cloneUsage the disk usage of the git clone of a repository
+type cloneUsage struct {
+	// size the size of the clone in bytes
+	size int64
+
+	// used when the clone was last pulled
+	used time.Time
+}
+
+// recordClone records the disk usage of the git clone of the repository after it has been cloned or pulled
+func (o *Options) recordClone(r repo.Repository, dir string) {
+	size, err := dirSize(dir)
+	if err != nil {
+		log.Logger().Warnf("failed to find the disk usage of %s: %s", dir, err.Error())
+		return
+	}
+	o.clones[o.cloneKey(r)] = &cloneUsage{size: size, used: time.Now()}
+	metrics.CloneDiskUsage.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(float64(size))
+}
+
+// enforceDiskQuota evicts the git clones of the work directory if their total size exceeds the disk quota.
+//
+// The clones of repositories which no longer exist are evicted first followed by the least recently used clones.
+// Evicted repositories are cloned again the next time they are polled
+func (o *Options) enforceDiskQuota(repos []repo.Repository) error {
+	if o.diskQuota <= 0 {
+		return nil
+	}
+	dirs, err := o.repoDirs()
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, r := range repos {
+		names[o.cloneKey(r)] = true
+	}
+
+	type entry struct {
+		name   string
+		size   int64
+		used   time.Time
+		exists bool
+	}
+	var entries []entry
+	var total int64
+	for name, info := range dirs {
+		e := entry{name: name, used: info.ModTime(), exists: names[name]}
+		if usage := o.clones[name]; usage != nil {
+			e.size = usage.size
+			e.used = usage.used
+		} else {
+			e.size, err = dirSize(filepath.Join(o.Dir, name))
+			if err != nil {
+				return errors.Wrapf(err, "failed to find the disk usage of %s", name)
+			}
+		}
+		total += e.size
+		entries = append(entries, e)
+	}
+	metrics.WorkDirDiskUsage.Set(float64(total))
+	if total <= o.diskQuota {
+		return nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].exists == entries[j].exists && entries[i].used.Equal(entries[j].used) {
+			return entries[i].name < entries[j].name
+		}
+		if entries[i].exists != entries[j].exists {
+			return !entries[i].exists
+		}
+		return entries[i].used.Before(entries[j].used)
+	})
+	for _, e := range entries {
+		if total <= o.diskQuota {
+			break
+		}
+		dir := filepath.Join(o.Dir, e.name)
+		log.Logger().Infof("evicting git clone %s of size %d bytes as the work dir uses %d bytes which exceeds the quota of %d bytes", dir, e.size, total, o.diskQuota)
+		err = os.RemoveAll(dir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to evict git clone %s", dir)
+		}
+		delete(o.clones, e.name)
+		metrics.CloneEvictions.Inc()
+		total -= e.size
+	}
+	metrics.WorkDirDiskUsage.Set(float64(total))
+	if total > o.diskQuota {
+		log.Logger().Warnf("the work dir %s uses %d bytes which still exceeds the quota of %d bytes", o.Dir, total, o.diskQuota)
+	}
+	return nil
+}
+
+// repoDirs returns the directories of the repositories in the work directory indexed by their path relative to the
+// work directory
+func (o *Options) repoDirs() (map[string]os.FileInfo, error) {
+	answer := map[string]os.FileInfo{}
+	infos, err := ioutil.ReadDir(o.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read work dir %s", o.Dir)
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if !info.IsDir() || isWorkDirInternal(name) {
+			continue
+		}
+		if o.WorkDirLayout != LayoutNamespaced {
+			answer[name] = info
+			continue
+		}
+		nsDir := filepath.Join(o.Dir, name)
+		repoInfos, err := ioutil.ReadDir(nsDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read dir %s", nsDir)
+		}
+		for _, repoInfo := range repoInfos {
+			if repoInfo.IsDir() && !isWorkDirInternal(repoInfo.Name()) {
+				answer[filepath.Join(name, repoInfo.Name())] = repoInfo
+			}
+		}
+	}
+	return answer, nil
+}
+
+// parseDiskQuota parses the optional disk quota quantity such as `5Gi` into bytes
+func parseDiskQuota(text string) (int64, error) {
+	if text == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(text)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse the disk quota %s", text)
+	}
+	return q.Value(), nil
+}
+
+// dirSize returns the total size of the files in the given directory
+func dirSize(dir string) (int64, error) {
+	var answer int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			answer += info.Size()
+		}
+		return nil
+	})
+	return answer, err
+}
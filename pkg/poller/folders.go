@@ -0,0 +1,116 @@
+package poller
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/pkg/errors"
+)
+
+// folderUnits returns the launch units of the folders of the git clone in the given dir which match the folder globs
+// of the repository and contain a git operator folder, sorted by folder.
+//
+// Each launch unit is a copy of the repository named after the repository and its folder so that it has its own
+// labels, status, change detection and active Jobs and is launched independently of the other folders
+func folderUnits(r repo.Repository, dir string) ([]repo.Repository, error) {
+	var folders []string
+	for _, glob := range r.Folders {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(strings.Trim(glob, "/"))))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid folder glob %s of repository %s", glob, r.Name)
+		}
+		for _, m := range matches {
+			folder, err := filepath.Rel(dir, m)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to find the relative path of %s", m)
+			}
+			folder = filepath.ToSlash(folder)
+			if folder == "." || strings.HasPrefix(folder, "../") || stringhelpers.StringArrayIndex(folders, folder) >= 0 {
+				continue
+			}
+			gitOperatorFolder, err := job.FindGitOperatorFolder(m)
+			if err != nil {
+				return nil, err
+			}
+			exists, err := files.DirExists(gitOperatorFolder)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to check if dir %s exists", gitOperatorFolder)
+			}
+			if exists {
+				folders = append(folders, folder)
+			}
+		}
+	}
+	sort.Strings(folders)
+
+	var answer []repo.Repository
+	for _, folder := range folders {
+		answer = append(answer, folderUnit(r, folder))
+	}
+	return answer, nil
+}
+
+// folderUnit returns the launch unit of the given folder of the repository
+func folderUnit(r repo.Repository, folder string) repo.Repository {
+	u := r
+	u.Name = naming.ToValidName(r.Name + "-" + strings.ReplaceAll(folder, "/", "-"))
+	u.Folders = nil
+	u.Folder = folder
+	u.Parent = r.Name
+	return u
+}
+
+// parentRepository returns the repository containing the folder of the given launch unit or the repository itself
+// if it is not a launch unit
+func parentRepository(r repo.Repository) repo.Repository {
+	if r.Parent == "" {
+		return r
+	}
+	p := r
+	p.Name = r.Parent
+	p.Folder = ""
+	p.Parent = ""
+	return p
+}
+
+// knownUnits returns the launch units of the repository from its last poll or, if it has not been polled since the
+// operator started, by discovering them in its existing git clone. Returns false if the units are not known
+func (o *Options) knownUnits(r repo.Repository) ([]repo.Repository, bool) {
+	if len(r.Folders) == 0 {
+		return nil, true
+	}
+	if state := o.state(r.Namespace + "/" + r.Name); state.unitsKnown {
+		return state.units, true
+	}
+	dir := o.cloneDir(r)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, false
+	}
+	units, err := folderUnits(r, dir)
+	if err != nil {
+		return nil, false
+	}
+	return units, true
+}
+
+// folderChangedFiles returns the changed paths within the folder of a launch unit relative to the folder
+func folderChangedFiles(folder string, changed []string) []string {
+	if changed == nil {
+		return nil
+	}
+	prefix := folder + "/"
+	answer := []string{}
+	for _, p := range changed {
+		if strings.HasPrefix(p, prefix) {
+			answer = append(answer, strings.TrimPrefix(p, prefix))
+		}
+	}
+	return answer
+}
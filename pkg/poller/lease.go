@@ -0,0 +1,77 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/lease"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// minLeaseDuration the minimum duration of a boot lease
+const minLeaseDuration = time.Minute
+
+// syncLease creates or renews the boot lease of the repository while it has an active Job so that other tooling can
+// detect the boot in progress, releasing the lease once the Job completes
+func (o *Options) syncLease(r repo.Repository) {
+	if !o.BootLeases || o.KubeClient == nil {
+		return
+	}
+	state := o.state(r.Namespace + "/" + r.Name)
+	s, _ := o.Status.Get(r.Namespace, r.Name)
+	if s.ActiveJob == "" || !o.jobActive(r.Namespace, s.ActiveJob) {
+		// a lease may have been left behind before the operator restarted so lets release it once
+		if state.leaseKnown && !state.lease {
+			return
+		}
+		err := lease.Release(o.KubeClient, r)
+		if err != nil {
+			log.Logger().Warnf("failed to release the boot lease of repository %s: %s", r.Name, err.Error())
+			return
+		}
+		state.lease = false
+		state.leaseKnown = true
+		return
+	}
+	holder := o.OperatorID
+	if holder == "" {
+		holder = lease.DefaultHolder
+	}
+	err := lease.Acquire(o.KubeClient, r, holder, s.LaunchedSHA, s.ActiveJob, o.leaseDuration())
+	if err != nil {
+		log.Logger().Warnf("failed to renew the boot lease of repository %s: %s", r.Name, err.Error())
+		return
+	}
+	state.lease = true
+	state.leaseKnown = true
+}
+
+// jobActive returns true if the Job with the given namespace and name exists and has not completed
+func (o *Options) jobActive(ns string, name string) bool {
+	j, err := o.KubeClient.BatchV1().Jobs(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return !apierrors.IsNotFound(err)
+	}
+	return job.IsJobActive(*j)
+}
+
+// releaseLease deletes the boot lease of the repository when it is cleaned up
+func (o *Options) releaseLease(r repo.Repository) error {
+	if !o.BootLeases || o.KubeClient == nil {
+		return nil
+	}
+	return lease.Release(o.KubeClient, r)
+}
+
+// leaseDuration returns the duration of the boot leases which are renewed every poll so that a lease is only
+// expired if the operator has stopped polling for several poll durations
+func (o *Options) leaseDuration() time.Duration {
+	d := 3 * o.PollDuration
+	if d < minLeaseDuration {
+		d = minLeaseDuration
+	}
+	return d
+}
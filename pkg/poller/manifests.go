@@ -0,0 +1,29 @@
+package poller
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/checksums"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+// verifyManifests verifies the signed checksums of every git operator folder of the given launch dir, if a manifest
+// public key is configured, before any of their files are trusted so that the folders cannot be tampered with between
+// review and launch even if commit signing is not enforced. When a version stream is used the `.jx/git-operator`
+// folder must be signed too as the launcher reads its `job-patch.yaml` file
+func (o *Options) verifyManifests(r repo.Repository, launchDir string, sha string) error {
+	if len(o.manifestKeys) == 0 {
+		return nil
+	}
+	folders, err := job.GitOperatorFolders(launchDir)
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		err = checksums.Verify(folder, o.manifestKeys)
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify the signed manifests of repository %s sha %s", r.Name, sha)
+		}
+	}
+	return nil
+}
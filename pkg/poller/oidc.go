@@ -0,0 +1,48 @@
+package poller
+
+import (
+	"net/url"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// defaultOIDCUsername the default user name of the git URLs using exchanged tokens which is accepted by GitHub
+const defaultOIDCUsername = "x-access-token"
+
+// federateCredentials adds short lived tokens, exchanged for the service account token of the operator, to the http
+// git URLs of the repositories which do not have a password. The tokens are refreshed by the exchanger shortly before
+// they expire and the origin of existing clones is updated with the refreshed token
+func (o *Options) federateCredentials(repos []repo.Repository) {
+	if o.tokenExchanger == nil {
+		return
+	}
+	for i := range repos {
+		r := &repos[i]
+		audience := r.OIDCAudience
+		if audience == "" {
+			audience = o.OIDCAudience
+		}
+		if audience == repo.NoneValue {
+			continue
+		}
+		u := passwordlessHTTPURL(r.GitURL)
+		if u == nil {
+			continue
+		}
+		username := o.OIDCUsername
+		if u.User != nil && u.User.Username() != "" {
+			username = u.User.Username()
+		}
+		if username == "" {
+			username = defaultOIDCUsername
+		}
+		token, err := o.tokenExchanger.Token(audience, r.OIDCScope)
+		if err != nil {
+			log.Logger().Warnf("failed to exchange the service account token for repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+			continue
+		}
+		u.User = url.UserPassword(username, token)
+		r.GitURL = u.String()
+	}
+}
@@ -0,0 +1,188 @@
+package poller
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PendingNewest the default pending launches mode where only the latest commit of a repository is launched once
+	// the Job blocking its launch completes
+	PendingNewest = "newest"
+
+	// PendingEvery the pending launches mode where every commit pushed while the launch of a repository is blocked is
+	// launched in order once the Job blocking its launch completes
+	PendingEvery = "every"
+
+	// pendingDirName the directory within the work directory containing the temporary checkouts of the pending
+	// commits which are older than the latest commit
+	pendingDirName = ".pending"
+)
+
+// validatePendingLaunches validates the pending launches mode defaulting to PendingNewest
+func (o *Options) validatePendingLaunches() error {
+	switch o.PendingLaunches {
+	case "":
+		o.PendingLaunches = PendingNewest
+	case PendingNewest, PendingEvery:
+	default:
+		return errors.Errorf("invalid $PENDING_LAUNCHES %s. Supported values are %s and %s", o.PendingLaunches, PendingNewest, PendingEvery)
+	}
+	return nil
+}
+
+// launchesEveryPending returns true if every pending commit of the repository is launched in order, as specified by
+// the repository or the operator. The launch units of the folders of a monorepo only ever launch their newest
+// pending commit
+func (o *Options) launchesEveryPending(r repo.Repository) bool {
+	if r.Folder != "" {
+		return false
+	}
+	switch r.PendingLaunches {
+	case PendingEvery:
+		return true
+	case PendingNewest:
+		return false
+	case "":
+	default:
+		log.Logger().Warnf("ignoring invalid %s annotation %s of repository %s in namespace %s", repo.PendingLaunchesAnnotation, r.PendingLaunches, r.Name, r.Namespace)
+	}
+	return o.PendingLaunches == PendingEvery
+}
+
+// pendingLimit returns the maximum number of pending commits of the repository or zero if there is no limit
+func (o *Options) pendingLimit(r repo.Repository) int {
+	limit := o.PendingLimit
+	if r.PendingLimit != 0 {
+		limit = r.PendingLimit
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// enqueuePending adds the blocked commit sha to the ordered pending queue of the repository. If every pending commit
+// is launched the commits since the newest queued or last launched commit are queued so that the intermediate commits
+// are launched too, otherwise the blocked commit replaces any older pending commit
+func (o *Options) enqueuePending(r repo.Repository, dir string, sha string) {
+	key := r.Namespace + "/" + r.Name
+	queue := o.state(key).pending
+	if !o.launchesEveryPending(r) {
+		queue = []string{sha}
+	} else if indexOf(queue, sha) < 0 {
+		since := o.state(key).lastLaunched
+		if len(queue) > 0 {
+			since = queue[len(queue)-1]
+		}
+		queue = append(queue, o.commitsSince(r, dir, since, sha)...)
+	}
+	o.setPending(r, queue)
+}
+
+// dequeuePending removes the commit sha, along with any commits queued before it, from the pending queue of the
+// repository once it has been launched or no longer needs launching. The queue is cleared if the sha is not queued
+func (o *Options) dequeuePending(r repo.Repository, sha string) {
+	key := r.Namespace + "/" + r.Name
+	queue := o.state(key).pending
+	if len(queue) == 0 {
+		return
+	}
+	i := indexOf(queue, sha)
+	if i < 0 {
+		queue = nil
+	} else {
+		queue = queue[i+1:]
+	}
+	o.setPending(r, queue)
+}
+
+// nextPending returns the commit sha of the repository to launch next: the oldest pending commit if every pending
+// commit is launched, after queuing any commits pushed since the newest queued or last launched commit, otherwise
+// the latest commit. Commits are then never coalesced, even if several were pushed between polls
+func (o *Options) nextPending(r repo.Repository, dir string, latest string) string {
+	if !o.launchesEveryPending(r) {
+		return latest
+	}
+	key := r.Namespace + "/" + r.Name
+	queue := o.state(key).pending
+	if indexOf(queue, latest) < 0 {
+		since := o.state(key).lastLaunched
+		if len(queue) > 0 {
+			since = queue[len(queue)-1]
+		}
+		if since == "" || since == latest {
+			return latest
+		}
+		queue = append(queue, o.commitsSince(r, dir, since, latest)...)
+		o.setPending(r, queue)
+		queue = o.state(key).pending
+	}
+	if len(queue) == 0 {
+		return latest
+	}
+	return queue[0]
+}
+
+// setPending records the pending queue of the repository in its status and metrics. If the queue is longer than the
+// limit of the repository the oldest commits are dropped so that they are covered by the launch of a newer commit
+func (o *Options) setPending(r repo.Repository, queue []string) {
+	key := r.Namespace + "/" + r.Name
+	if limit := o.pendingLimit(r); limit > 0 && len(queue) > limit {
+		dropped := queue[:len(queue)-limit]
+		queue = queue[len(queue)-limit:]
+		log.Logger().Warnf("dropping the %d oldest pending commits of repository %s as it has more than %d pending commits", len(dropped), key, limit)
+		if o.state(key).droppedPending == nil {
+			o.state(key).droppedPending = map[string]bool{}
+		}
+		for _, sha := range dropped {
+			o.state(key).droppedPending[sha] = true
+		}
+		metrics.DroppedPendingLaunches.WithLabelValues(r.Tenant, r.Namespace, r.Name).Add(float64(len(dropped)))
+	}
+	if len(queue) == 0 {
+		o.state(key).pending = nil
+	} else {
+		o.state(key).pending = queue
+	}
+	o.Status.SetPending(r, queue)
+	metrics.PendingLaunches.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(float64(len(queue)))
+}
+
+// commitsSince returns the commit shas after the given commit up to and including the given sha, oldest first.
+// Only the sha is returned if the history between them cannot be found such as after a force push
+func (o *Options) commitsSince(r repo.Repository, dir string, since string, sha string) []string {
+	if since == "" || since == sha {
+		return []string{sha}
+	}
+	text, err := o.GitClient.Command(dir, "rev-list", "--reverse", since+".."+sha)
+	if err != nil {
+		log.Logger().Warnf("failed to find the commits between %s and %s of repository %s: %s", since, sha, r.Name, err.Error())
+		return []string{sha}
+	}
+	var answer []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			answer = append(answer, line)
+		}
+	}
+	if len(answer) == 0 || answer[len(answer)-1] != sha {
+		return []string{sha}
+	}
+	return answer
+}
+
+// indexOf returns the index of the value in the slice or -1 if it is not present
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
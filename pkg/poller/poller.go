@@ -1,25 +1,63 @@
 package poller
 
 import (
+	"context"
+	"crypto"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jenkins-x/jx-git-operator/pkg/artifacts"
+	"github.com/jenkins-x/jx-git-operator/pkg/checksums"
 	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/credentials"
+	"github.com/jenkins-x/jx-git-operator/pkg/features"
+	"github.com/jenkins-x/jx-git-operator/pkg/kube"
 	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	_ "github.com/jenkins-x/jx-git-operator/pkg/launcher/flux"
 	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	_ "github.com/jenkins-x/jx-git-operator/pkg/launcher/manifestwork"
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/oidc"
+	"github.com/jenkins-x/jx-git-operator/pkg/preflight"
+	"github.com/jenkins-x/jx-git-operator/pkg/provider"
 	"github.com/jenkins-x/jx-git-operator/pkg/repo"
 	"github.com/jenkins-x/jx-git-operator/pkg/repo/secret"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-git-operator/pkg/tenant"
+	"github.com/jenkins-x/jx-git-operator/pkg/timing"
 	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/pkg/files"
 	"github.com/jenkins-x/jx-helpers/pkg/gitclient"
 	"github.com/jenkins-x/jx-helpers/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
 	"github.com/jenkins-x/jx-logging/pkg/log"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	// commitLogSeparator separates the fields of the commit metadata output by `git log`
+	commitLogSeparator = "\x1f"
+
+	// commitLogFormat the `git log` format of the author, committer, timestamp and subject of a commit
+	commitLogFormat = "%an <%ae>%x1f%cn <%ce>%x1f%cI%x1f%s"
+
+	// maxChangelogCommits the maximum number of commits included in the changelog of a launch
+	maxChangelogCommits = 50
+)
+
 // Options the configuration options for the poller
 type Options struct {
 	GitClient  gitclient.Interface
@@ -32,9 +70,33 @@ type Options struct {
 	// KubeClient is used to lazy create the repo client and launcher
 	KubeClient kubernetes.Interface
 
+	// DynamicClient is used by the launcher to create any non `Job` resources
+	DynamicClient dynamic.Interface
+
+	// CredentialProvider the optional provider of the credentials of http repositories without a password. Defaults
+	// to the exec provider of the CredentialHelper if specified
+	CredentialProvider credentials.Provider
+
+	// ProviderClient looks up the latest commit sha of repositories via the REST API of their git provider which is
+	// lazily created if ProviderHeadLookup is enabled
+	ProviderClient *provider.Client
+
+	// Status the status of the last poll of each repository which is lazily created
+	Status *status.Store
+
+	// Events the broker of the reconcile events of the repositories which is lazily created
+	Events *stream.Broker
+
 	// Dir is the work directory. If not specified a temporary directory is created on startup.
+	//
+	// If the temporary or home directories are not writable, such as with a read only root filesystem, the
+	// temporary files and the configuration of the git and kubectl commands are also written to the work directory
 	Dir string `env:"WORK_DIR"`
 
+	// DiskQuota the optional maximum size of the git clones in the work directory such as `5Gi`. If exceeded the
+	// clones of removed repositories and then the least recently used clones are evicted after each poll
+	DiskQuota string `env:"DISK_QUOTA"`
+
 	// Namespace the namespace polled for `Secret` resources
 	Namespace string `env:"NAMESPACE"`
 
@@ -47,34 +109,326 @@ type Options struct {
 	// NoLoop disable the polling loop so that a single poll is performed only
 	NoLoop bool `env:"NO_LOOP"`
 
-	// NoResourceApply disable the applying of resources in a git repository at `.jx/git-operator/resources/*.yaml`
+	// NoResourceApply disable the applying of resources in a git repository at `.jx/git-operator/resources/*.yaml`.
+	// This is enforced by the launcher so that the operator never runs `kubectl` to apply, diff or delete resources
+	// and only creates its own resources such as Jobs
 	NoResourceApply bool `env:"NO_RESOURCE_APPLY"`
+
+	// LauncherName the name of the registered launcher to use; defaults to `job`
+	LauncherName string `env:"LAUNCHER"`
+
+	// DefaultJob the default Job to create for repositories without a `job.yaml` file
+	DefaultJob launcher.DefaultJobOptions
+
+	// Naming the naming strategy of launched resources
+	Naming launcher.NamingOptions
+
+	// Inject the environment variables, image pull secrets and registry mirrors injected into every launched Job
+	Inject launcher.InjectOptions
+
+	// JobDefaults the time to live, backoff limit and pod failure policy injected into the launched Jobs which do not
+	// specify them
+	JobDefaults launcher.JobDefaults
+
+	// Kube the QPS and Burst of the lazily created kubernetes clients
+	Kube kube.ClientOptions
+
+	// Commands the timeouts, retries, environment variables, redaction and recording of the git, kubectl and helm
+	// commands run by the operator
+	Commands runner.Options
+
+	// TenantsFile the optional YAML file of the tenants which repositories are grouped into.
+	// If specified every repository must belong to a tenant
+	TenantsFile string `env:"TENANTS_FILE"`
+
+	// PriorityClassName the optional priority class name injected into the Jobs of repositories with a positive priority
+	PriorityClassName string `env:"PRIORITY_CLASS_NAME"`
+
+	// DriftInterval the optional minimum duration between checks of a repository for drift of its applied resources
+	// from the last launched commit. If not specified drift detection is disabled
+	DriftInterval time.Duration `env:"DRIFT_INTERVAL"`
+
+	// DriftRelaunch if enabled the repository is relaunched when drift is detected to correct it
+	DriftRelaunch bool `env:"DRIFT_RELAUNCH"`
+
+	// GarbageCollect if enabled the resources applied for repositories which have been removed are deleted
+	GarbageCollect bool `env:"GARBAGE_COLLECT"`
+
+	// CleanupOnDelete if enabled a finalizer is added to each repository so that its Jobs, applied resources and
+	// git clone are deleted when the repository is removed
+	CleanupOnDelete bool `env:"CLEANUP_ON_DELETE"`
+
+	// RepoGroups the optional groups of the repositories which this operator processes such as `prod`. The group of a
+	// repository is specified via the `git-operator.jenkins.io/group` label on its Secret and the `none` group matches
+	// repositories without a group. If not specified every repository is processed
+	RepoGroups []string `env:"REPO_GROUPS"`
+
+	// OperatorID the optional identifier of this operator instance. If specified repositories and launched resources
+	// are annotated with it so that conflicts with other operator instances with overlapping selectors are detected
+	OperatorID string `env:"OPERATOR_ID"`
+
+	// StaleBootThreshold the optional duration after which a repository which has not booted successfully is
+	// reported as stale via the `StaleBoot` condition of its status and a metric. If not specified staleness
+	// detection is disabled
+	StaleBootThreshold time.Duration `env:"STALE_BOOT_THRESHOLD"`
+
+	// StaleBootWebhook the optional URL which is sent the status of a repository as JSON when it becomes stale
+	StaleBootWebhook string `env:"STALE_BOOT_WEBHOOK"`
+
+	// Preflight if enabled the binaries, RBAC permissions and git connectivity of each repository are checked on
+	// startup and a readiness summary is logged. The operator fails to start if any check fails
+	Preflight bool `env:"PREFLIGHT"`
+
+	// WatchdogCycles the optional number of poll durations after which the poll loop is considered stalled if it has
+	// not completed a cycle. A stalled loop is reset and reported as unhealthy. If not specified the watchdog is disabled
+	WatchdogCycles int `env:"WATCHDOG_CYCLES"`
+
+	// StatusBranch the optional branch of each repository, such as `gitops-status`, which the status of the latest
+	// completed boot is pushed to so that there is a git native record of the cluster state which survives cluster loss
+	StatusBranch string `env:"STATUS_BRANCH"`
+
+	// StatusFile the name of the status file in the status branch; defaults to `status.json`
+	StatusFile string `env:"STATUS_FILE"`
+
+	// BootInventory if enabled the inventory of the resources changed by each boot Job, which the Job can write to
+	// the ConfigMap named by `$GIT_OPERATOR_INVENTORY`, is recorded in the status of the repository and audit logged
+	BootInventory bool `env:"BOOT_INVENTORY"`
+
+	// ArtifactsBucket the optional bucket URL such as `s3://mybucket/prefix`, `gs://mybucket/prefix` or
+	// `az://myaccount/mycontainer/prefix` which the logs, events and manifest of failed Jobs are uploaded to
+	ArtifactsBucket string `env:"ARTIFACTS_BUCKET"`
+
+	// Rollback if enabled the last successful commit of a repository is launched again when the Job of a newer
+	// commit fails and exhausts its retries so that the environment is restored while the failure is investigated
+	Rollback bool `env:"ROLLBACK"`
+
+	// PreflightScripts if enabled the optional `preflight.sh` script in the git operator folder of a repository is
+	// run by the operator and must succeed before each new commit is launched
+	PreflightScripts bool `env:"PREFLIGHT_SCRIPTS"`
+
+	// ValidateResources if enabled the resources of each new commit are validated via a server side dry run before
+	// any of them are applied, failing the launch with the error of each invalid resource
+	ValidateResources bool `env:"VALIDATE_RESOURCES"`
+
+	// LaunchCooldown the optional minimum duration between the launches of new commits of each repository. Newer
+	// commits are deferred until the cool-down since the previous launch has expired
+	LaunchCooldown time.Duration `env:"LAUNCH_COOLDOWN"`
+
+	// RequireApproval if enabled the Jobs of every repository are created with `spec.suspend: true` along with a
+	// PendingApproval Event so that reviewers can inspect each Job before approving it to run
+	RequireApproval bool `env:"REQUIRE_APPROVAL"`
+
+	// PendingLaunches which of the commits pushed while the launch of a repository is blocked by an active Job are
+	// launched once the Job completes: `newest` (the default) only launches the latest commit whereas `every`
+	// launches each pending commit in order so that no commit is lost to history
+	PendingLaunches string `env:"PENDING_LAUNCHES"`
+
+	// PendingLimit the maximum number of pending commits queued for a repository launching every pending commit. When
+	// the queue is full the oldest commits are dropped and covered by the launch of a newer commit. Zero means no limit
+	PendingLimit int `env:"PENDING_LIMIT"`
+
+	// FeatureGates the optional comma separated feature gates such as `Rollback=true,Pruning=false` which
+	// gradually enable new behaviours. An explicitly specified gate overrides the option of the feature
+	FeatureGates string `env:"FEATURE_GATES"`
+
+	// WorkDirLayout the layout of the git clones in the work directory: `flat` (the default) clones each repository
+	// to `<dir>/<repository>` whereas `namespaced` clones each repository to `<dir>/<namespace>/<repository>` and
+	// launches each commit from its own checkout so that repositories of the same name never share a clone
+	WorkDirLayout string `env:"WORK_DIR_LAYOUT"`
+
+	// BootLeases if enabled a coordination Lease named `<repository>-boot` is held in the namespace of each repository
+	// while its Job is active so that other tooling can detect the boot in progress and its commit sha. The Lease is
+	// renewed every poll so a Lease which has expired is stuck and can be deleted
+	BootLeases bool `env:"BOOT_LEASES"`
+
+	// ProviderHeadLookup if enabled the latest commit sha of each repository on GitHub or GitLab is looked up via the
+	// REST API of the provider so that repositories which have not changed are not pulled
+	ProviderHeadLookup bool `env:"PROVIDER_HEAD_LOOKUP"`
+
+	// ProviderRateLimitReserve the number of remaining requests of the rate limit of a provider below which lookups
+	// are deferred until the rate limit resets so that other clients of the same account are not blocked.
+	// Defaults to 100
+	ProviderRateLimitReserve int `env:"PROVIDER_RATE_LIMIT_RESERVE"`
+
+	// SparseCheckout if enabled repositories are cloned via a blobless partial clone and only their git operator
+	// folders are checked out so that very large repositories can be polled without downloading or checking out
+	// every file. The git server must support partial clones
+	SparseCheckout bool `env:"SPARSE_CHECKOUT"`
+
+	// GitURLRewrites the optional `prefix=replacement` rules which rewrite the git URLs of repositories before they
+	// are cloned or pulled, such as `https://github.com/=https://git.example.com/mirrors/github/`, so that
+	// mirrored and air-gapped environments do not need to change the URL of every repository Secret
+	GitURLRewrites []string `env:"GIT_URL_REWRITES"`
+
+	// GitCredentialCache if enabled the credentials of git URLs are passed to git via its in memory credential cache
+	// rather than the git URLs so that they are never written into the git configuration of the clones
+	GitCredentialCache bool `env:"GIT_CREDENTIAL_CACHE"`
+
+	// ShredWorkDir if enabled the git clones and checkouts of the repositories are overwritten and removed after every
+	// poll so that sensitive content of the repositories does not remain on disk. Every poll then clones the
+	// repositories again
+	ShredWorkDir bool `env:"SHRED_WORK_DIR"`
+
+	// ManifestPublicKeyFile the optional file of the PEM encoded ed25519, ECDSA or RSA public keys which must have
+	// signed the `checksums` file of the git operator folder of a commit via its detached `checksums.sig` signature
+	// before any of the files of the folder are trusted
+	ManifestPublicKeyFile string `env:"MANIFEST_PUBLIC_KEY_FILE"`
+
+	// CredentialHelper the optional command line of an exec credential helper, such as
+	// `/usr/local/bin/vault-git-credentials --role git-operator`, which supplies the credentials of http repositories
+	// without a password when they are cloned or pulled
+	CredentialHelper string `env:"CREDENTIAL_HELPER"`
+
+	// OIDCTokenExchangeURL the optional URL of an RFC 8693 token exchange endpoint, such as a GitHub or GitLab token
+	// broker or the security token service of a cloud workload identity, via which the service account token of the
+	// operator is exchanged for short lived tokens used to clone and pull http repositories without a password
+	OIDCTokenExchangeURL string `env:"OIDC_TOKEN_EXCHANGE_URL"`
+
+	// OIDCTokenFile the file containing the service account token which is exchanged, such as a projected service
+	// account token with a specific audience. Defaults to the token of the service account of the pod
+	OIDCTokenFile string `env:"OIDC_TOKEN_FILE"`
+
+	// OIDCAudience the default audience of the exchanged tokens
+	OIDCAudience string `env:"OIDC_AUDIENCE"`
+
+	// OIDCUsername the user name of the git URLs using exchanged tokens. Defaults to `x-access-token`
+	OIDCUsername string `env:"OIDC_USERNAME"`
+
+	// SSHProxyJump the optional ssh `ProxyJump` jump hosts, such as `git@bastion.example.com`, via which the ssh git
+	// URLs of repositories are cloned and pulled when the git server is only reachable via a bastion
+	SSHProxyJump string `env:"SSH_PROXY_JUMP"`
+
+	// SSHProxyCommand the optional ssh `ProxyCommand` via which the ssh git URLs of repositories are cloned and pulled
+	SSHProxyCommand string `env:"SSH_PROXY_COMMAND"`
+
+	// SSHKnownHosts the optional known hosts lines of the git servers and jump hosts which are trusted when cloning and
+	// pulling ssh git URLs
+	SSHKnownHosts string `env:"SSH_KNOWN_HOSTS"`
+
+	// CommitStatus if enabled the result of the latest launch of each repository on GitHub or GitLab is reported as a
+	// commit status to the upstream URL of the repository, or its git URL if it has none, using its token
+	CommitStatus bool `env:"COMMIT_STATUS"`
+
+	// CommitStatusContext the context of the reported commit statuses. Defaults to `git-operator`
+	CommitStatusContext string `env:"COMMIT_STATUS_CONTEXT"`
+
+	// PhaseTimings if enabled the durations of the phases of each poll (listing the repositories, cloning or pulling,
+	// diffing, applying and creating the launched resources) are logged at debug level and observed via the
+	// `phase_duration_seconds` metric so that performance regressions are detectable
+	PhaseTimings bool `env:"PHASE_TIMINGS"`
+
+	// NoJobCache if enabled the Jobs of a repository are listed for every launch rather than looked up from the
+	// informer cache of the Jobs launched by the operator which is used when polling in a loop
+	NoJobCache bool `env:"NO_JOB_CACHE"`
+
+	tenants         *tenant.Config
+	watchdog        *watchdog
+	clones          map[string]*cloneUsage
+	diskQuota       int64
+	urlRewrites     []repo.URLRewrite
+	statusClient    *provider.Client
+	tokenExchanger  *oidc.Exchanger
+	manifestKeys    []crypto.PublicKey
+	sshCommand      string
+	sshConfig       string
+	sshKnownHosts   string
+	workDirReady    bool
+	triggered       *triggers
+	artifactsBucket *artifacts.Bucket
+	states          map[string]*repoState
+	featureGates    *features.Gates
+	locks           *repoLocks
+	commandRunner   cmdrunner.CommandRunner
+}
+
+// detectedCommit the latest commit sha of a repository and when it was first detected
+type detectedCommit struct {
+	sha  string
+	time time.Time
 }
 
 // Run polls for git changes
 func (o *Options) Run() error {
+	return o.RunWithContext(context.Background())
+}
+
+// RunWithContext polls for git changes until the context is done
+func (o *Options) RunWithContext(ctx context.Context) error {
 	err := o.ValidateOptions()
 	if err != nil {
 		return errors.Wrap(err, "invalid options")
 	}
 
+	log.Logger().Infof("using feature gates %s", o.featureGates.String())
+
 	if o.Namespace != "" {
-		log.Logger().Infof("looking in namespace %s for Secret resources with selector %s", o.Namespace, constants.DefaultSelector)
+		log.Logger().Infof("looking in namespace %s for Secret and ConfigMap resources with selector %s", o.Namespace, constants.DefaultSelector)
+	}
+	if len(o.RepoGroups) > 0 {
+		log.Logger().Infof("only processing the repositories in the groups %s", strings.Join(o.RepoGroups, ", "))
 	}
 
+	if o.Preflight {
+		err = o.preflight()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = o.adopt()
+	if err != nil {
+		log.Logger().Warnf("failed to adopt the previously launched resources: %s", err.Error())
+	}
 	if !o.NoLoop {
 		log.Logger().Infof("using poll duration %s", o.PollDuration.String())
+		if o.WatchdogCycles > 0 {
+			go o.watch(ctx)
+		}
+		if o.KubeClient != nil {
+			go o.watchJobs(ctx)
+		}
+		if cacher, ok := o.Launcher.(launcher.Cacher); ok && !o.NoJobCache {
+			cacher.StartCache(o.Namespace, ctx.Done())
+		}
 	}
 	for {
 		err = o.Poll()
+		o.watchdog.completed(err)
+		if isAbandoned(err) {
+			log.Logger().Warnf("continuing after resetting the stalled poll loop: %s", err.Error())
+			err = nil
+		}
 		if err != nil {
 			return err
 		}
 		if o.NoLoop {
 			return nil
 		}
-		time.Sleep(o.PollDuration)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-o.triggered.wake:
+		case <-time.After(o.PollDuration):
+		}
+	}
+}
+
+// preflight checks the operator can discover, poll and launch its repositories before the first poll
+func (o *Options) preflight() error {
+	po := &preflight.Options{
+		KubeClient:      o.KubeClient,
+		RepoClient:      o.RepoClient,
+		GitClient:       o.GitClient,
+		Namespace:       o.Namespace,
+		Dir:             o.Dir,
+		GitBinary:       o.GitBinary,
+		NoResourceApply: o.NoResourceApply,
+		BootLeases:      o.BootLeases,
+		URLRewrites:     o.urlRewrites,
 	}
+	checks := po.Run()
+	log.Logger().Infof("preflight checks:\n%s", preflight.Summary(checks))
+	return preflight.Error(checks)
 }
 
 // Poll polls the available repositories
@@ -84,77 +438,920 @@ func (o *Options) Poll() error {
 		return errors.Wrap(err, "invalid options")
 	}
 
+	start := time.Now()
 	repos, err := o.RepoClient.List()
 	if err != nil {
 		return errors.Wrapf(err, "failed to list repositories")
 	}
+	o.supplyCredentials(repos)
+	o.federateCredentials(repos)
+	o.rewriteGitURLs(repos)
+	err = o.configureSSH(repos)
+	if err != nil {
+		return err
+	}
+	if timings := o.newTimings(); timings != nil {
+		timings.Since(timing.PhaseList, start)
+		log.Logger().Debugf("listed %d repositories in %s", len(repos), timings.Get(timing.PhaseList).String())
+	}
+
+	defer func() {
+		err := o.enforceDiskQuota(repos)
+		if err != nil {
+			log.Logger().Warnf("failed to enforce the disk quota: %s", err.Error())
+		}
+		err = o.shredWorkDir(repos)
+		if err != nil {
+			log.Logger().Warnf("failed to shred the work dir: %s", err.Error())
+		}
+	}()
+
+	o.forgetRemoved(repos)
+	err = o.garbageCollect(repos)
+	if err != nil {
+		return err
+	}
 
 	if len(repos) == 0 {
 		log.Logger().Infof("no repositories found")
 		return nil
 	}
+	// lets poll the higher priority repositories first
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].Priority > repos[j].Priority
+	})
+
 	for _, r := range repos {
-		err = o.pollRepository(r)
+		if !o.inRepoGroups(r) {
+			continue
+		}
+		if r.Deleting {
+			// lets keep polling the other repositories and retry the clean up on the next poll
+			err = o.cleanupRepository(r)
+			if err != nil {
+				metrics.PollErrors.WithLabelValues(r.Tenant, r.Namespace, r.Name).Inc()
+				log.Logger().Warnf("failed to clean up repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+			}
+			continue
+		}
+		if o.CleanupOnDelete {
+			err = o.addFinalizer(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		var t *tenant.Tenant
+		if o.tenants != nil {
+			t, err = o.tenants.FindTenant(r)
+			if err != nil {
+				metrics.TenancyViolations.WithLabelValues(r.Tenant, r.Namespace, r.Name).Inc()
+				log.Logger().Warnf("ignoring repository: %s", err.Error())
+				continue
+			}
+		}
+		if o.OperatorID != "" {
+			owned, err := o.claim(r)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				continue
+			}
+		}
+		if r.Paused {
+			log.Logger().Infof("ignoring repository %s in namespace %s as it is paused", r.Name, r.Namespace)
+			o.Status.Record(r, status.ResultPaused, "", "")
+			o.deferLaunch(r, skipReasonPaused)
+			continue
+		}
+		units, err := o.pollRepository(r, t)
+		if conflict, ok := errors.Cause(err).(*launcher.OwnershipConflictError); ok {
+			o.reportConflict(r, conflict.Error())
+			continue
+		}
+		for _, u := range units {
+			o.checkStale(u)
+			o.recordQueueState(u)
+		}
 		if err != nil {
+			o.discardAbandoned(r, err)
+			if schemaErr, ok := errors.Cause(err).(*job.SchemaError); ok {
+				o.reportInvalidJobFile(r, schemaErr)
+			}
+			metrics.PollErrors.WithLabelValues(r.Tenant, r.Namespace, r.Name).Inc()
+			o.Status.Record(r, status.ResultFailed, "", err.Error())
+			o.Events.Publish(stream.NewEvent(stream.EventPollFailed, r, "", err.Error()))
 			return errors.Wrapf(err, "failed to poll repository %s in namespace %s", r.Name, r.Namespace)
 		}
+		for _, u := range units {
+			o.checkRetries(u)
+			o.reportCommitStatus(u)
+			o.recordBootInventory(u)
+			o.captureFailure(u)
+			o.syncLease(u)
+		}
+		o.pushStatus(r)
 	}
 	return nil
 }
 
-func (o *Options) pollRepository(r repo.Repository) error {
+// pollRepository pulls the latest commit of the repository and launches it returning the launch units which were
+// polled: the repository itself or the launch units of its folders if it is a monorepo
+func (o *Options) pollRepository(r repo.Repository, t *tenant.Tenant) ([]repo.Repository, error) {
+	units := []repo.Repository{r}
 	name := r.Name
-	log.Logger().Infof("polling repository %s in namespace %s with git URL %s", name, r.Namespace, r.GitURL)
+	log.Logger().Infof("polling repository %s in namespace %s with git URL %s", name, r.Namespace, repo.RedactGitURL(r.GitURL))
+
+	unlock := o.locks.lockRepository(r)
+	defer unlock()
 
-	dir := filepath.Join(o.Dir, name)
+	timings := o.newTimings()
+	if timings != nil {
+		defer func() {
+			log.Logger().Debugf("phase timings of repository %s: %s", name, timings.String())
+		}()
+	}
+
+	dir := o.cloneDir(r)
 	exists, err := files.DirExists(dir)
 	if err != nil {
-		return errors.Wrapf(err, "failed to check dir exists %s", dir)
+		return units, errors.Wrapf(err, "failed to check dir exists %s", dir)
+	}
+	branch := r.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
 	}
+	start := time.Now()
 	if !exists {
 		log.Logger().Infof("cloning repository %s to %s", name, dir)
-		_, err = o.GitClient.Command(o.Dir, "clone", r.GitURL, dir)
+		_, err = o.GitClient.Command(o.Dir, append(o.cloneArgs(r), r.GitURL, dir)...)
 		if err != nil {
-			return errors.Wrapf(err, "failed to clone repository %s", name)
+			// lets remove any partial clone so that the next poll clones again rather than pulling
+			removeErr := os.RemoveAll(dir)
+			if removeErr != nil {
+				log.Logger().Warnf("failed to remove partial clone %s: %s", dir, removeErr.Error())
+			}
+			return units, errors.Wrapf(err, "failed to clone repository %s", name)
 		}
-	} else {
-		_, err = o.GitClient.Command(dir, "pull", "origin", "master")
+		metrics.GitDuration.WithLabelValues(r.Tenant, r.Namespace, r.Name, "clone").Observe(time.Since(start).Seconds())
+	} else if !o.unchangedOnProvider(r, dir, branch) {
+		err = o.syncOriginURL(r, dir)
+		if err != nil {
+			return units, err
+		}
+		_, err = o.GitClient.Command(dir, "pull", "origin", branch)
 		if err != nil {
-			return errors.Wrapf(err, "failed to pull repository %s", name)
+			return units, errors.Wrapf(err, "failed to pull repository %s", name)
 		}
+		metrics.GitDuration.WithLabelValues(r.Tenant, r.Namespace, r.Name, "pull").Observe(time.Since(start).Seconds())
+	}
+	err = o.sparseCheckout(r, dir)
+	if err != nil {
+		return units, err
 	}
+	timings.Since(timing.PhaseClone, start)
+	o.recordClone(r, dir)
 	text, err := o.GitClient.Command(dir, "rev-parse", "HEAD")
 	if err != nil {
-		return errors.Wrapf(err, "failed to find latest commit sha for repository %s", name)
+		return units, errors.Wrapf(err, "failed to find latest commit sha for repository %s", name)
 	}
 	text = strings.TrimSpace(text)
 	log.Logger().Infof("repository %s has latest commit sha %s", name, text)
 
 	if text == "" {
-		return errors.Errorf("could not find latest commit sha for repository %s", name)
+		return units, errors.Errorf("could not find latest commit sha for repository %s", name)
+	}
+	key := r.Namespace + "/" + r.Name
+	if o.state(key).detected.sha != text {
+		o.state(key).detected = detectedCommit{sha: text, time: time.Now()}
 	}
 
-	_, err = o.Launcher.Launch(launcher.LaunchOptions{
-		Repository:      r,
-		GitSHA:          text,
-		Dir:             dir,
-		NoResourceApply: o.NoResourceApply,
-	})
+	if remaining := o.cooldownRemaining(r, text); remaining > 0 {
+		log.Logger().Infof("deferring the launch of repository %s sha %s for %s as it is cooling down", name, text, remaining.Round(time.Second).String())
+		o.Status.Record(r, status.ResultCoolingDown, text, "")
+		o.deferLaunch(r, skipReasonCoolingDown)
+		return units, nil
+	}
+
+	launchDir, err := o.checkoutCommit(r, dir, text)
+	if err != nil {
+		return units, err
+	}
+	relaunch := o.triggered.take(key) || r.Triggered
+	trigger := launcher.TriggerPoll
+	if relaunch {
+		log.Logger().Infof("relaunching repository %s as it has been triggered", name)
+		trigger = launcher.TriggerManual
+	} else if o.triggered.takePushed(key) {
+		trigger = launcher.TriggerWebhook
+	}
+	if len(r.Folders) == 0 {
+		sha := o.nextPending(r, dir, text)
+		if sha != text {
+			// lets launch the oldest pending commit from its own checkout
+			log.Logger().Infof("launching the pending commit sha %s of repository %s before the latest commit sha %s", sha, name, text)
+			launchDir = o.internalDir(pendingDirName, r)
+			defer os.RemoveAll(launchDir)
+			err = o.checkoutRollback(dir, launchDir, sha)
+			if err != nil {
+				return units, errors.Wrapf(err, "failed to checkout the pending sha %s of repository %s", sha, name)
+			}
+		}
+		return units, o.launchCommit(r, t, dir, launchDir, branch, sha, relaunch, trigger, timings)
+	}
+
+	units, err = folderUnits(r, launchDir)
+	if err != nil {
+		return []repo.Repository{r}, err
+	}
+	o.state(key).units = units
+	o.state(key).unitsKnown = true
+	log.Logger().Infof("repository %s has %d folders to launch", name, len(units))
+	o.Status.Record(r, status.ResultUpToDate, text, "")
+	// lets launch each folder even if another folder fails
+	var failures []error
+	var failed []string
+	for _, u := range units {
+		unitKey := u.Namespace + "/" + u.Name
+		if o.state(unitKey).detected.sha != text {
+			o.state(unitKey).detected = o.state(key).detected
+		}
+		err = o.launchCommit(u, t, dir, filepath.Join(launchDir, filepath.FromSlash(u.Folder)), branch, text, relaunch, trigger, timings)
+		if err != nil {
+			log.Logger().Warnf("failed to launch folder %s of repository %s: %s", u.Folder, name, err.Error())
+			failures = append(failures, errors.Wrapf(err, "failed to launch folder %s", u.Folder))
+			failed = append(failed, u.Folder)
+		}
+	}
+	if len(failures) == 1 {
+		return units, failures[0]
+	}
+	if len(failures) > 1 {
+		return units, errors.Errorf("failed to launch %d of %d folders of repository %s: %s", len(failures), len(units), name, strings.Join(failed, ", "))
+	}
+	return units, nil
+}
+
+// launchCommit launches the given commit of the repository, or of the launch unit of a folder of a monorepo, from
+// its checkout in the launch dir unless none of its changed paths are relevant
+func (o *Options) launchCommit(r repo.Repository, t *tenant.Tenant, dir string, launchDir string, branch string, text string, relaunch bool, trigger string, timings *timing.Breakdown) error {
+	name := r.Name
+	key := r.Namespace + "/" + r.Name
+	priorityClassName := ""
+	if r.Priority > 0 {
+		priorityClassName = o.PriorityClassName
+	}
+	lo := launcher.LaunchOptions{
+		Repository:        r,
+		GitSHA:            text,
+		Commit:            o.commitMetadata(dir, branch, text),
+		Branch:            branch,
+		PreviousSHA:       o.state(key).lastLaunched,
+		Trigger:           trigger,
+		Parameters:        r.Parameters,
+		Changelog:         o.changelog(r, dir, text),
+		Dir:               launchDir,
+		NoResourceApply:   o.NoResourceApply,
+		DefaultJob:        o.DefaultJob,
+		Naming:            o.Naming,
+		Tenant:            t,
+		PriorityClassName: priorityClassName,
+		Inject:            o.Inject.ForRepository(r),
+		JobDefaults:       o.JobDefaults,
+		Owner:             o.OperatorID,
+		Relaunch:          relaunch,
+		RelaunchRequest:   relaunchRequest(r, relaunch),
+		PreflightScript:   o.PreflightScripts,
+		ValidateResources: o.ValidateResources,
+		Suspend:           o.RequireApproval || r.RequireApproval,
+		Timings:           timings,
+		Decision:          &launcher.Decision{},
+	}
+	if !lo.Relaunch {
+		start := time.Now()
+		lo.ChangedFiles = o.changedFiles(r, dir, text)
+		timings.Since(timing.PhaseDiff, start)
+	}
+	err := o.verifyManifests(r, launchDir, text)
+	if err != nil {
+		o.state(key).failedLaunch = text
+		return err
+	}
+	relevant, err := job.IsRelevant(launchDir, lo.ChangedFiles)
 	if err != nil {
+		return errors.Wrapf(err, "failed to check if the changes of repository %s are relevant", name)
+	}
+	if r.Folder != "" && lo.ChangedFiles != nil && len(lo.ChangedFiles) == 0 {
+		// changes outside of the folder are not relevant to its launch unit
+		relevant = false
+	}
+	if !relevant {
+		log.Logger().Infof("not launching repository %s sha %s as none of the changed paths are relevant", name, text)
+		o.Status.Record(r, status.ResultUpToDate, text, "")
+		o.deferLaunch(r, skipReasonNotRelevant)
+		o.dequeuePending(r, text)
+		return nil
+	}
+	if lo.Trigger == launcher.TriggerPoll && o.state(key).failedLaunch == text {
+		lo.Trigger = launcher.TriggerRetry
+	}
+	objects, err := o.launch(lo)
+	if preflightErr, ok := errors.Cause(err).(*launcher.PreflightError); ok {
+		o.Status.SetPreflight(r, status.Preflight{
+			Result:  launcher.ResultFailed,
+			GitSHA:  text,
+			Message: preflightErr.Output,
+			Time:    time.Now(),
+		})
+	}
+	if err != nil {
+		o.state(key).failedLaunch = text
 		return errors.Wrapf(err, "failed to launch job for %s", name)
 	}
+	if r.Triggered {
+		if triggerer, ok := o.RepoClient.(repo.Triggerer); ok {
+			err = triggerer.SetTriggered(parentRepository(r), false)
+			if err != nil {
+				return errors.Wrapf(err, "failed to acknowledge the trigger of repository %s", name)
+			}
+		}
+	}
+	if post := postJob(objects); post != nil {
+		// the post-success Job is part of the existing launch of the commit
+		log.Logger().Infof("launched post-success Job %s of repository %s sha %s", post.Name, name, text)
+		o.Status.SetActiveJob(r, post.Name)
+		o.Status.StartJob(r, status.JobWindow{Name: post.Name, Namespace: post.Namespace, GitSHA: text, Started: time.Now()})
+		return nil
+	}
+	if len(objects) > 0 {
+		o.state(key).failedLaunch = ""
+		countLaunch(lo)
+		metrics.LaunchLatency.WithLabelValues(r.Tenant, r.Namespace, r.Name).Observe(time.Since(o.state(key).detected.time).Seconds())
+		o.Status.Record(r, status.ResultLaunched, text, "")
+		o.unblockLaunch(r)
+		o.dequeuePending(r, text)
+		o.Events.Publish(stream.NewEvent(stream.EventLaunched, r, text, ""))
+		o.recordSkipped(r, dir, text)
+		o.state(key).lastLaunched = text
+		for _, object := range objects {
+			if j, ok := object.(*v1.Job); ok {
+				o.Status.SetActiveJob(r, j.Name)
+				o.Status.StartJob(r, status.JobWindow{Name: j.Name, Namespace: j.Namespace, GitSHA: text, Started: time.Now()})
+				if j.Annotations[launcher.PreflightAnnotation] != "" {
+					o.Status.SetPreflight(r, status.Preflight{
+						Result: j.Annotations[launcher.PreflightAnnotation],
+						GitSHA: text,
+						Time:   time.Now(),
+					})
+				}
+				break
+			}
+		}
+		return nil
+	}
+	if lo.Decision.BlockedBy != "" {
+		o.blockLaunch(r, dir, text, lo.Decision)
+	} else {
+		o.Status.Record(r, status.ResultUpToDate, text, "")
+		o.unblockLaunch(r)
+		o.dequeuePending(r, text)
+		if previous := o.state(key).lastLaunched; previous != "" && previous != text {
+			o.deferLaunch(r, skipReasonActiveJob)
+		} else if previous == text {
+			o.state(key).deferReason = ""
+		}
+	}
+	err = o.checkRollback(lo)
+	if err != nil {
+		return err
+	}
+	return o.checkDrift(lo)
+}
+
+// cooldownRemaining returns the remaining cool-down of the repository before the given commit sha can be launched
+// or zero if it can be launched now. A commit which has already been launched is never deferred
+func (o *Options) cooldownRemaining(r repo.Repository, sha string) time.Duration {
+	cooldown := o.LaunchCooldown
+	if r.Cooldown != 0 {
+		cooldown = r.Cooldown
+	}
+	if cooldown <= 0 {
+		return 0
+	}
+	s, found := o.Status.Get(r.Namespace, r.Name)
+	if !found || s.LastLaunched == nil || s.LaunchedSHA == sha {
+		return 0
+	}
+	return cooldown - time.Since(*s.LastLaunched)
+}
+
+// postJob returns the post-success Job if the launched objects are the post-success Jobs of a commit
+func postJob(objects []runtime.Object) *v1.Job {
+	for _, object := range objects {
+		if j, ok := object.(*v1.Job); ok && j.Labels[launcher.PostJobLabelKey] == "true" {
+			return j
+		}
+	}
+	return nil
+}
+
+// commitMetadata returns the metadata of the given commit of the branch in the git clone. The metadata is
+// informational only so any failures to find it are logged rather than failing the launch
+func (o *Options) commitMetadata(dir string, branch string, sha string) *launcher.Commit {
+	answer := &launcher.Commit{
+		Branch: branch,
+	}
+	text, err := o.GitClient.Command(dir, "log", "-1", "--format="+commitLogFormat, sha)
+	if err != nil {
+		log.Logger().Warnf("failed to find the metadata of commit %s in dir %s: %s", sha, dir, err.Error())
+		return answer
+	}
+	fields := strings.Split(strings.TrimSpace(text), commitLogSeparator)
+	if len(fields) == 4 {
+		answer.Author = fields[0]
+		answer.Committer = fields[1]
+		answer.Timestamp = fields[2]
+		answer.Subject = fields[3]
+	}
+
+	text, err = o.GitClient.Command(dir, "tag", "--points-at", sha)
+	if err != nil {
+		log.Logger().Warnf("failed to find the tags of commit %s in dir %s: %s", sha, dir, err.Error())
+		return answer
+	}
+	for _, tag := range strings.Split(text, "\n") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			answer.Tags = append(answer.Tags, tag)
+		}
+	}
+	return answer
+}
+
+// changelog returns the one line summaries of the commits since the commit last launched by this operator for the
+// repository up to and including the given commit, so that a launch which covers several commits records them all.
+// Returns an empty string if no commit has been launched yet or the latest commit has already been launched
+func (o *Options) changelog(r repo.Repository, dir string, sha string) string {
+	previous := o.state(r.Namespace + "/" + r.Name).lastLaunched
+	if previous == "" || previous == sha {
+		return ""
+	}
+	text, err := o.GitClient.Command(dir, "log", "--format=%h %s", previous+".."+sha)
+	if err != nil {
+		log.Logger().Warnf("failed to find the commits between %s and %s of repository %s: %s", previous, sha, r.Name, err.Error())
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxChangelogCommits {
+		lines = append(lines[0:maxChangelogCommits], fmt.Sprintf("... and %d more commits", len(lines)-maxChangelogCommits))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// changedFiles returns the paths changed since the commit last launched by this operator for the repository up to
+// the given commit, relative to the folder of a launch unit, so that the `triggers.yaml` file of the repository can select the job files to launch. Returns
+// nil if the changes are not known as no commit has been launched yet or the latest commit has already been launched
+func (o *Options) changedFiles(r repo.Repository, dir string, sha string) []string {
+	previous := o.state(r.Namespace + "/" + r.Name).lastLaunched
+	if previous == "" || previous == sha {
+		return nil
+	}
+	args := []string{"diff", "--name-only"}
+	if o.SparseCheckout {
+		// detecting renames would download the contents of the changed files of the partial clone
+		args = append(args, "--no-renames")
+	}
+	text, err := o.GitClient.Command(dir, append(args, previous, sha)...)
+	if err != nil {
+		log.Logger().Warnf("failed to find the paths changed between %s and %s of repository %s: %s", previous, sha, r.Name, err.Error())
+		return nil
+	}
+	answer := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			answer = append(answer, line)
+		}
+	}
+	if r.Folder != "" {
+		return folderChangedFiles(r.Folder, answer)
+	}
+	return answer
+}
+
+// inRepoGroups returns true if the repository is in one of the groups processed by this operator or no groups are
+// specified
+func (o *Options) inRepoGroups(r repo.Repository) bool {
+	if len(o.RepoGroups) == 0 {
+		return true
+	}
+	group := r.Group
+	if group == "" {
+		group = repo.NoneValue
+	}
+	return stringhelpers.StringArrayIndex(o.RepoGroups, group) >= 0
+}
+
+// claim records this operator instance as the owner of the repository if it has no owner. Returns false if the
+// repository is owned by another operator instance
+func (o *Options) claim(r repo.Repository) (bool, error) {
+	if r.Owner == o.OperatorID {
+		return true, nil
+	}
+	if r.Owner != "" {
+		o.reportConflict(r, fmt.Sprintf("repository %s is managed by another operator %s", r.Name, r.Owner))
+		return false, nil
+	}
+	claimer, ok := o.RepoClient.(repo.Claimer)
+	if !ok {
+		return true, nil
+	}
+	err := claimer.Claim(r, o.OperatorID)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to claim repository %s in namespace %s", r.Name, r.Namespace)
+	}
+	log.Logger().Infof("claimed repository %s in namespace %s for operator %s", r.Name, r.Namespace, o.OperatorID)
+	return true, nil
+}
+
+// reportConflict reports that the repository is managed by another operator instance. Each distinct conflict of a
+// repository is only reported once via an Event to avoid flooding the repository with Events on every poll
+func (o *Options) reportConflict(r repo.Repository, message string) {
+	metrics.OwnershipConflicts.WithLabelValues(r.Tenant, r.Namespace, r.Name).Inc()
+	log.Logger().Warnf("ignoring repository %s in namespace %s: %s", r.Name, r.Namespace, message)
+
+	key := r.Namespace + "/" + r.Name
+	if o.state(key).conflict == message {
+		return
+	}
+	o.state(key).conflict = message
+
+	claimer, ok := o.RepoClient.(repo.Claimer)
+	if !ok {
+		return
+	}
+	err := claimer.ReportConflict(r, o.OperatorID, message)
+	if err != nil {
+		log.Logger().Warnf("failed to report ownership conflict of repository %s: %s", r.Name, err.Error())
+	}
+}
+
+// reportInvalidJobFile records an `InvalidJobFile` warning event on the repository with the problems of its job file
+// if the repository client supports events. Each distinct set of problems is only recorded once
+func (o *Options) reportInvalidJobFile(r repo.Repository, schemaErr *job.SchemaError) {
+	key := r.Namespace + "/" + r.Name
+	message := schemaErr.Error()
+	if o.state(key).invalidJobFile == message {
+		return
+	}
+	o.state(key).invalidJobFile = message
+
+	recorder, ok := o.RepoClient.(repo.Recorder)
+	if !ok {
+		return
+	}
+	source := o.OperatorID
+	if source == "" {
+		source = "jx-git-operator"
+	}
+	err := recorder.RecordWarning(r, source, "InvalidJobFile", message)
+	if err != nil {
+		log.Logger().Warnf("failed to report the invalid job file of repository %s: %s", r.Name, err.Error())
+	}
+}
+
+// addFinalizer adds the cleanup finalizer to the repository if the repository client supports finalizers
+func (o *Options) addFinalizer(r repo.Repository) error {
+	finalizer, ok := o.RepoClient.(repo.Finalizer)
+	if !ok || stringhelpers.StringArrayIndex(r.Finalizers, repo.CleanupFinalizer) >= 0 {
+		return nil
+	}
+	err := finalizer.AddFinalizer(r, repo.CleanupFinalizer)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add finalizer to repository %s in namespace %s", r.Name, r.Namespace)
+	}
+	return nil
+}
+
+// cleanupRepository deletes the Jobs, applied resources and git clone of a repository which is being deleted and
+// then removes the cleanup finalizer so that the deletion can complete
+func (o *Options) cleanupRepository(r repo.Repository) error {
+	if stringhelpers.StringArrayIndex(r.Finalizers, repo.CleanupFinalizer) < 0 {
+		log.Logger().Infof("ignoring repository %s in namespace %s as it is being deleted", r.Name, r.Namespace)
+		return nil
+	}
+	log.Logger().Infof("cleaning up repository %s in namespace %s as it is being deleted", r.Name, r.Namespace)
+
+	unlock := o.locks.lockRepository(r)
+	defer unlock()
+
+	units, _ := o.knownUnits(r)
+	if cleaner, ok := o.Launcher.(launcher.Cleaner); ok {
+		for _, u := range append([]repo.Repository{r}, units...) {
+			err := cleaner.Cleanup(u)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete the resources of repository %s", u.Name)
+			}
+		}
+	}
+	for _, u := range append([]repo.Repository{r}, units...) {
+		err := o.releaseLease(u)
+		if err != nil {
+			return errors.Wrapf(err, "failed to release the boot lease of repository %s", u.Name)
+		}
+	}
+	for _, u := range units {
+		delete(o.states, u.Namespace+"/"+u.Name)
+		o.Status.Remove(u)
+	}
+
+	dir := o.repoDir(r)
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove git clone dir %s", dir)
+	}
+	delete(o.clones, o.cloneKey(r))
+	delete(o.states, r.Namespace+"/"+r.Name)
+	err = os.RemoveAll(o.internalDir(statusDirName, r))
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove the status branch clone of repository %s", r.Name)
+	}
+	o.Status.Remove(r)
+	o.Events.Publish(stream.NewEvent(stream.EventCleanedUp, r, "", ""))
+
+	finalizer, ok := o.RepoClient.(repo.Finalizer)
+	if !ok {
+		return nil
+	}
+	err = finalizer.RemoveFinalizer(r, repo.CleanupFinalizer)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove finalizer from repository %s in namespace %s", r.Name, r.Namespace)
+	}
+	return nil
+}
+
+// garbageCollect deletes the resources applied for any repositories which have been removed if garbage collection
+// is enabled and the launcher supports it
+func (o *Options) garbageCollect(repos []repo.Repository) error {
+	if !o.GarbageCollect {
+		return nil
+	}
+	gc, ok := o.Launcher.(launcher.GarbageCollector)
+	if !ok {
+		return nil
+	}
+	if len(repos) == 0 {
+		// lets never delete every applied resource because the repositories could not be discovered
+		log.Logger().Warnf("not garbage collecting as no repositories were found in namespace %s", o.Namespace)
+		return nil
+	}
+	all := repos
+	for _, r := range repos {
+		units, known := o.knownUnits(r)
+		if !known {
+			log.Logger().Infof("not garbage collecting until the folders of repository %s in namespace %s are known", r.Name, r.Namespace)
+			return nil
+		}
+		all = append(all, units...)
+	}
+	count, err := gc.GarbageCollect(all)
+	if err != nil {
+		return errors.Wrapf(err, "failed to garbage collect the resources of removed repositories")
+	}
+	if count > 0 {
+		log.Logger().Infof("garbage collected %d resources of removed repositories", count)
+	}
 	return nil
 }
 
+// checkDrift checks if the live state of the resources applied from the repository has drifted from the last
+// launched commit if drift detection is enabled and the launcher supports it
+func (o *Options) checkDrift(lo launcher.LaunchOptions) error {
+	if o.DriftInterval <= 0 || o.NoResourceApply {
+		return nil
+	}
+	detector, ok := o.Launcher.(launcher.DriftDetector)
+	if !ok {
+		return nil
+	}
+	r := lo.Repository
+	key := r.Namespace + "/" + r.Name
+	last := o.state(key).driftCheck
+	if !last.IsZero() && time.Since(last) < o.DriftInterval {
+		return nil
+	}
+	o.state(key).driftCheck = time.Now()
+
+	drift, err := detector.DetectDrift(lo)
+	if err != nil {
+		return errors.Wrapf(err, "failed to detect drift of repository %s", r.Name)
+	}
+	gauge := metrics.Drifted.WithLabelValues(r.Tenant, r.Namespace, r.Name)
+	if !drift.Drifted {
+		gauge.Set(0)
+		return nil
+	}
+	gauge.Set(1)
+	log.Logger().Warnf("repository %s in namespace %s has drifted from commit %s:\n%s", r.Name, r.Namespace, lo.GitSHA, drift.Diff)
+	o.Events.Publish(stream.NewEvent(stream.EventDrifted, r, lo.GitSHA, drift.Diff))
+
+	if !o.DriftRelaunch {
+		return nil
+	}
+	lo.Relaunch = true
+	lo.Trigger = launcher.TriggerDrift
+	objects, err := o.launch(lo)
+	if err != nil {
+		return errors.Wrapf(err, "failed to relaunch job for %s to correct drift", r.Name)
+	}
+	if len(objects) > 0 {
+		countLaunch(lo)
+	}
+	return nil
+}
+
+// launch launches the repository via the launcher once the launch namespace has been validated against the tenant of
+// the repository so that tenancy is enforced whichever launcher is used. Launchers also validate the namespaces and
+// service accounts of the resources they launch
+func (o *Options) launch(lo launcher.LaunchOptions) ([]runtime.Object, error) {
+	if lo.Tenant != nil {
+		r := lo.Repository
+		ns := r.Namespace
+		if ns == "" {
+			ns = o.Namespace
+		}
+		err := lo.Tenant.Validate(ns, nil)
+		if err != nil {
+			metrics.TenancyViolations.WithLabelValues(r.Tenant, r.Namespace, r.Name).Inc()
+			return nil, errors.Wrapf(err, "repository %s is not allowed to launch", r.Name)
+		}
+	}
+	return o.Launcher.Launch(lo)
+}
+
+// countLaunch counts a launch which created resources via the metrics of the launches of the repository and by
+// how they were triggered
+func countLaunch(lo launcher.LaunchOptions) {
+	r := lo.Repository
+	metrics.Launches.WithLabelValues(r.Tenant, r.Namespace, r.Name).Inc()
+	metrics.TriggeredLaunches.WithLabelValues(r.Tenant, r.Namespace, r.Name, lo.Trigger).Inc()
+}
+
 // ValidateOptions validates the options and lazily creates any resources required
 func (o *Options) ValidateOptions() error {
 	if o.PollDuration.Milliseconds() == int64(0) {
 		o.PollDuration = time.Second * 30
 	}
+	if o.watchdog == nil {
+		o.watchdog = newWatchdog()
+	}
+	if o.commandRunner == nil {
+		// lets kill the commands of a stalled cycle when it is reset unless a custom command runner is used
+		baseRunner := o.CommandRunner
+		if baseRunner == nil {
+			baseRunner = o.watchdog.exec
+		}
+		commandRunner, err := o.Commands.Wrap(o.credentialCacheRunner(baseRunner))
+		if err != nil {
+			return errors.Wrapf(err, "invalid command options")
+		}
+		o.commandRunner = o.watchdog.runner(commandRunner)
+	}
 	if o.GitClient == nil {
-		o.GitClient = cli.NewCLIClient(o.GitBinary, o.CommandRunner)
+		o.GitClient = cli.NewCLIClient(o.GitBinary, o.commandRunner)
+	}
+	if o.Status == nil {
+		o.Status = status.NewStore()
+	}
+	if o.triggered == nil {
+		o.triggered = newTriggers()
+	}
+	if o.locks == nil {
+		o.locks = &repoLocks{repos: map[string]*sync.Mutex{}}
+	}
+	if o.clones == nil {
+		o.clones = map[string]*cloneUsage{}
+	}
+	if o.Events == nil {
+		o.Events = stream.NewBroker()
+	}
+	if o.ProviderHeadLookup && o.ProviderClient == nil {
+		if o.ProviderRateLimitReserve <= 0 {
+			o.ProviderRateLimitReserve = provider.DefaultRateLimitReserve
+		}
+		o.ProviderClient = provider.NewClient(provider.NewRateLimiter(o.ProviderRateLimitReserve, provider.DefaultMaxWait))
+	}
+	if o.CommitStatus && o.statusClient == nil {
+		o.statusClient = o.ProviderClient
+		if o.statusClient == nil {
+			if o.ProviderRateLimitReserve <= 0 {
+				o.ProviderRateLimitReserve = provider.DefaultRateLimitReserve
+			}
+			o.statusClient = provider.NewClient(provider.NewRateLimiter(o.ProviderRateLimitReserve, provider.DefaultMaxWait))
+		}
+	}
+	if o.featureGates == nil {
+		gates, err := features.Parse(o.FeatureGates, nil)
+		if err != nil {
+			return errors.Wrapf(err, "invalid $FEATURE_GATES")
+		}
+		gates.Apply(features.Pruning, &o.GarbageCollect)
+		gates.Apply(features.DriftRelaunch, &o.DriftRelaunch)
+		gates.Apply(features.BootInventory, &o.BootInventory)
+		gates.Apply(features.Rollback, &o.Rollback)
+		gates.Apply(features.PreflightScripts, &o.PreflightScripts)
+		gates.Apply(features.RequireApproval, &o.RequireApproval)
+		gates.Apply(features.ValidateResources, &o.ValidateResources)
+		o.featureGates = gates
+	}
+	err := o.validateLayout()
+	if err != nil {
+		return err
+	}
+	err = o.validatePendingLaunches()
+	if err != nil {
+		return err
+	}
+	err = o.Naming.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "invalid naming strategy")
+	}
+	err = o.Inject.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "invalid injected environment variables")
+	}
+	err = o.JobDefaults.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "invalid Job defaults")
+	}
+	o.diskQuota, err = parseDiskQuota(o.DiskQuota)
+	if err != nil {
+		return err
+	}
+	o.urlRewrites, err = repo.ParseURLRewrites(o.GitURLRewrites)
+	if err != nil {
+		return errors.Wrapf(err, "invalid $GIT_URL_REWRITES")
+	}
+	if o.manifestKeys == nil && o.ManifestPublicKeyFile != "" {
+		o.manifestKeys, err = checksums.LoadPublicKeys(o.ManifestPublicKeyFile)
+		if err != nil {
+			return errors.Wrapf(err, "invalid $MANIFEST_PUBLIC_KEY_FILE")
+		}
+	}
+	if o.CredentialProvider == nil && o.CredentialHelper != "" {
+		o.CredentialProvider, err = credentials.NewExecProvider(o.CredentialHelper, o.CommandRunner)
+		if err != nil {
+			return errors.Wrapf(err, "invalid $CREDENTIAL_HELPER")
+		}
+	}
+	if o.tokenExchanger == nil && o.OIDCTokenExchangeURL != "" {
+		o.tokenExchanger = oidc.NewExchanger(o.OIDCTokenExchangeURL, o.OIDCTokenFile)
+	}
+	if o.artifactsBucket == nil && o.ArtifactsBucket != "" {
+		o.artifactsBucket, err = artifacts.NewBucket(o.ArtifactsBucket, o.commandRunner)
+		if err != nil {
+			return errors.Wrapf(err, "invalid $ARTIFACTS_BUCKET")
+		}
+	}
+	if o.KubeClient == nil && (o.RepoClient == nil || o.Launcher == nil) {
+		cfg, err := o.Kube.CreateConfig()
+		if err != nil {
+			return err
+		}
+		o.KubeClient, err = kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the kube client")
+		}
+		if o.DynamicClient == nil {
+			o.DynamicClient, err = dynamic.NewForConfig(cfg)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create the dynamic client")
+			}
+		}
+		if o.Namespace == "" {
+			o.Namespace, err = kubeclient.CurrentNamespace()
+			if err != nil {
+				return errors.Wrapf(err, "failed to find the current namespace")
+			}
+		}
+	}
+	if (o.Inject.Platform == "" || o.Inject.Platform == launcher.PlatformAuto) && o.KubeClient != nil {
+		platform, err := launcher.DetectPlatform(o.KubeClient)
+		if err != nil {
+			// lets detect the platform again next time
+			log.Logger().Warnf("failed to detect the platform so assuming %s: %s", launcher.PlatformKubernetes, err.Error())
+		} else {
+			log.Logger().Infof("detected the %s platform", platform)
+			o.Inject.Platform = platform
+		}
 	}
-	var err error
 	if o.RepoClient == nil {
 		o.RepoClient, err = secret.NewClient(o.KubeClient, o.Namespace, constants.DefaultSelector)
 		if err != nil {
@@ -162,16 +1359,48 @@ func (o *Options) ValidateOptions() error {
 		}
 	}
 	if o.Launcher == nil {
-		o.Launcher, err = job.NewLauncher(o.KubeClient, o.Namespace, constants.DefaultSelector, o.CommandRunner)
+		if o.LauncherName == "" {
+			o.LauncherName = job.LauncherName
+		}
+		fo := launcher.FactoryOptions{
+			KubeClient:      o.KubeClient,
+			DynamicClient:   o.DynamicClient,
+			Namespace:       o.Namespace,
+			Selector:        constants.DefaultSelector,
+			Owner:           o.OperatorID,
+			CommandRunner:   o.commandRunner,
+			NoResourceApply: o.NoResourceApply,
+		}
+		if o.Kube.QPS > 0 {
+			fo.RateLimiter = kube.NewAdaptiveLimiter(o.Kube.QPS, o.Kube.Burst)
+		}
+		o.Launcher, err = launcher.New(o.LauncherName, fo)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create launcher")
 		}
 	}
+	if o.tenants == nil && o.TenantsFile != "" {
+		o.tenants, err = tenant.LoadConfig(o.TenantsFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load tenants")
+		}
+	}
 	if o.Dir == "" {
 		o.Dir, err = ioutil.TempDir("", "jx-git-operator-")
 		if err != nil {
-			return errors.Wrapf(err, "failed to create temp dir")
+			return errors.Wrapf(err, "failed to create temp dir. You can specify a writable work dir via $WORK_DIR")
+		}
+	}
+	if !o.workDirReady {
+		err = os.MkdirAll(o.Dir, files.DefaultDirWritePermissions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create work dir %s", o.Dir)
+		}
+		err = o.setupWorkDir()
+		if err != nil {
+			return errors.Wrapf(err, "failed to setup work dir %s", o.Dir)
 		}
+		o.workDirReady = true
 	}
 	return nil
 }
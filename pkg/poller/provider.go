@@ -0,0 +1,46 @@
+package poller
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/provider"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+// unchangedOnProvider returns true if the latest commit sha of the branch of the repository, looked up via the REST
+// API of its git provider, is already checked out in the given git clone so that it does not need to be pulled.
+//
+// Returns false, so that the repository is pulled as usual, if the lookup is disabled, unsupported, deferred as the
+// provider is near its rate limit or fails
+func (o *Options) unchangedOnProvider(r repo.Repository, dir string, branch string) bool {
+	if o.ProviderClient == nil {
+		return false
+	}
+	var err error
+	if r.Branch == "" {
+		// lets compare with the default branch which was checked out by the clone
+		branch, err = o.ProviderClient.DefaultBranch(r.GitURL)
+	}
+	sha := ""
+	if err == nil {
+		sha, err = o.ProviderClient.HeadSHA(r.GitURL, branch)
+	}
+	if err == provider.ErrUnsupported {
+		return false
+	}
+	if provider.IsRateLimited(err) {
+		log.Logger().Infof("pulling repository %s: %s", r.Name, err.Error())
+		return false
+	}
+	if err != nil {
+		log.Logger().Warnf("failed to look up the latest commit sha of repository %s: %s", r.Name, err.Error())
+		return false
+	}
+	text, err := o.GitClient.Command(dir, "rev-parse", "HEAD")
+	if err != nil || sha == "" || strings.TrimSpace(text) != sha {
+		return false
+	}
+	log.Logger().Debugf("not pulling repository %s as its latest commit sha %s is already checked out", r.Name, sha)
+	return true
+}
@@ -0,0 +1,141 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// jobBackoffBase the delay before the Job controller recreates the first failed pod of a Job, which doubles for
+	// each further failure
+	jobBackoffBase = 10 * time.Second
+
+	// jobBackoffMax the maximum delay before the Job controller recreates a failed pod of a Job
+	jobBackoffMax = 6 * time.Minute
+
+	// defaultBackoffLimit the default number of retries of a Job before it fails
+	defaultBackoffLimit = 6
+)
+
+// checkRetries records the consecutive failed launches of the repository along with the retries, backoff and next
+// retry time of its active Job in the status of the repository and metrics
+func (o *Options) checkRetries(r repo.Repository) {
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return
+	}
+	records, err := historyProvider.History(r)
+	if err != nil {
+		log.Logger().Warnf("failed to find the launches of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	// the records are sorted with the most recent first
+	retry := status.Retry{
+		State: status.RetryHealthy,
+	}
+	latest := records[0]
+	for i, record := range records {
+		if i == 0 && record.Result == launcher.ResultActive {
+			continue
+		}
+		if record.Result != launcher.ResultFailed {
+			break
+		}
+		retry.ConsecutiveFailures++
+	}
+	if latest.Result == launcher.ResultFailed {
+		retry.State = status.RetryFailing
+	}
+
+	var backoff time.Duration
+	var nextRetry time.Time
+	if latest.Kind == "Job" && len(latest.Clusters) == 0 && latest.Result != launcher.ResultSucceeded && o.KubeClient != nil {
+		j, err := o.KubeClient.BatchV1().Jobs(latest.Namespace).Get(latest.JobName(), metav1.GetOptions{})
+		if err != nil {
+			log.Logger().Warnf("failed to get Job %s of repository %s: %s", latest.JobName(), r.Name, err.Error())
+		} else {
+			backoff, nextRetry = o.jobRetry(j, &retry)
+		}
+	}
+
+	labels := []string{r.Tenant, r.Namespace, r.Name}
+	metrics.ConsecutiveFailures.WithLabelValues(labels...).Set(float64(retry.ConsecutiveFailures))
+	metrics.RetryAttempts.WithLabelValues(labels...).Set(float64(retry.Attempts))
+	metrics.RetryBackoff.WithLabelValues(labels...).Set(backoff.Seconds())
+	if nextRetry.IsZero() {
+		metrics.NextRetryTimestamp.WithLabelValues(labels...).Set(0)
+	} else {
+		metrics.NextRetryTimestamp.WithLabelValues(labels...).Set(float64(nextRetry.Unix()))
+	}
+	o.Status.SetRetry(r, retry)
+}
+
+// jobRetry records the failed pods of the Job and the reason of the last failure in the retry status. If the Job is
+// still active the backoff and expected time of the next retry are returned using the exponential backoff of the
+// Job controller
+func (o *Options) jobRetry(j *v1.Job, retry *status.Retry) (time.Duration, time.Time) {
+	retry.Attempts = int(j.Status.Failed)
+	retry.BackoffLimit = defaultBackoffLimit
+	if j.Spec.BackoffLimit != nil {
+		retry.BackoffLimit = int(*j.Spec.BackoffLimit)
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Type == v1.JobFailed && c.Status == corev1.ConditionTrue {
+			retry.Reason = c.Reason
+			return 0, time.Time{}
+		}
+	}
+	if retry.Attempts == 0 {
+		return 0, time.Time{}
+	}
+	retry.State = status.RetryRetrying
+
+	// lets find when the last pod failed and why
+	var lastFailed time.Time
+	pods, err := o.KubeClient.CoreV1().Pods(j.Namespace).List(metav1.ListOptions{
+		LabelSelector: "job-name=" + j.Name,
+	})
+	if err != nil {
+		log.Logger().Warnf("failed to find the pods of Job %s in namespace %s: %s", j.Name, j.Namespace, err.Error())
+	} else {
+		for _, pod := range pods.Items {
+			for _, cs := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+				terminated := cs.State.Terminated
+				if terminated == nil || terminated.ExitCode == 0 {
+					continue
+				}
+				if terminated.FinishedAt.Time.After(lastFailed) {
+					lastFailed = terminated.FinishedAt.Time
+					retry.Reason = terminated.Reason
+				}
+			}
+		}
+	}
+
+	backoff := jobBackoffBase
+	for i := 1; i < retry.Attempts && backoff < jobBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > jobBackoffMax {
+		backoff = jobBackoffMax
+	}
+	retry.Backoff = backoff.String()
+	if lastFailed.IsZero() {
+		return backoff, time.Time{}
+	}
+	nextRetry := lastFailed.Add(backoff)
+	retry.NextRetry = &nextRetry
+	return backoff, nextRetry
+}
@@ -0,0 +1,52 @@
+package poller
+
+import (
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// rewriteGitURLs rewrites the git URLs of the repositories via the git URL rewrite rules. The original git URL of a
+// rewritten repository is its upstream URL unless it has one
+func (o *Options) rewriteGitURLs(repos []repo.Repository) {
+	for i := range repos {
+		r := &repos[i]
+		gitURL := repo.RewriteGitURL(r.GitURL, o.urlRewrites)
+		if gitURL != r.GitURL && r.UpstreamURL == "" {
+			r.UpstreamURL = r.GitURL
+		}
+		r.GitURL = gitURL
+	}
+}
+
+// syncOriginURL updates the `origin` remote of the existing git clone in the given dir if it does not match the
+// git URL of the repository so that changes to the git URL rewrite rules and refreshed credentials apply to
+// existing clones too. With the git credential cache the remote is always set so that its credentials are cached
+// before pulling
+func (o *Options) syncOriginURL(r repo.Repository, dir string) error {
+	if len(o.urlRewrites) == 0 && o.tokenExchanger == nil && o.CredentialProvider == nil && !o.GitCredentialCache {
+		return nil
+	}
+	text, err := o.GitClient.Command(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the origin URL of repository %s", r.Name)
+	}
+	expected := r.GitURL
+	if o.GitCredentialCache {
+		expected = repo.RedactGitURL(r.GitURL)
+	}
+	if strings.TrimSpace(text) == expected {
+		if !o.GitCredentialCache || expected == r.GitURL {
+			return nil
+		}
+	} else {
+		log.Logger().Infof("updating the origin of repository %s to %s", r.Name, repo.RedactGitURL(r.GitURL))
+	}
+	_, err = o.GitClient.Command(dir, "remote", "set-url", "origin", r.GitURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set the origin URL of repository %s", r.Name)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package poller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-helpers/pkg/kube/naming"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rollbackDirName the directory within the work directory containing the temporary checkouts of the commits
+// being rolled back to
+const rollbackDirName = ".rollback"
+
+// checkRollback launches the last successful commit of the repository again, if rollback is enabled, when the Job
+// of the latest commit has failed and exhausted its retries so that the environment is restored while the failure
+// is investigated. The rollback is reported on the failed Job, in the status of the repository and as an event
+func (o *Options) checkRollback(lo launcher.LaunchOptions) error {
+	if !o.Rollback || o.KubeClient == nil {
+		return nil
+	}
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return nil
+	}
+	r := lo.Repository
+	records, err := historyProvider.History(r)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the launches of repository %s", r.Name)
+	}
+
+	// the records are sorted with the most recent first
+	safeSha := naming.ToValidValue(lo.GitSHA)
+	var failed, good *launcher.LaunchRecord
+	for i := range records {
+		record := &records[i]
+		if record.Rollback || record.Kind != "Job" {
+			continue
+		}
+		if failed == nil {
+			if record.GitSHA != safeSha || record.Result != launcher.ResultFailed {
+				return nil
+			}
+			failed = record
+			continue
+		}
+		if record.GitSHA != safeSha && record.Result == launcher.ResultSucceeded {
+			good = record
+			break
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+	key := r.Namespace + "/" + r.Name
+	if o.state(key).rolledBack == failed.Name {
+		return nil
+	}
+	var j *v1.Job
+	if len(failed.Clusters) > 0 {
+		if failed.Completed == nil {
+			// lets wait for the Jobs of every remote cluster to complete
+			return nil
+		}
+	} else {
+		j, err = o.KubeClient.BatchV1().Jobs(failed.Namespace).Get(failed.JobName(), metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get Job %s of repository %s", failed.JobName(), r.Name)
+		}
+		if !isJobFailed(j) {
+			// lets wait for the Job controller to exhaust the retries
+			return nil
+		}
+	}
+	if good == nil {
+		log.Logger().Warnf("cannot roll back failed Job %s of repository %s as no previous commit succeeded", failed.JobName(), key)
+		o.state(key).rolledBack = failed.Name
+		return nil
+	}
+
+	relaunch := false
+	for _, record := range records {
+		if !record.Rollback || record.GitSHA != good.GitSHA {
+			continue
+		}
+		if !record.Created.Before(failed.Created) {
+			// the rollback was launched before the operator restarted
+			o.state(key).rolledBack = failed.Name
+			return nil
+		}
+		relaunch = true
+	}
+
+	dir := o.internalDir(rollbackDirName, r)
+	defer os.RemoveAll(dir)
+	checkoutDir := lo.Dir
+	if r.Folder != "" {
+		// the launch dir of a folder of a monorepo is within the checkout of the repository
+		checkoutDir = strings.TrimSuffix(lo.Dir, string(filepath.Separator)+filepath.FromSlash(r.Folder))
+	}
+	err = o.checkoutRollback(checkoutDir, dir, good.GitSHA)
+	if err != nil {
+		return errors.Wrapf(err, "failed to checkout sha %s of repository %s to roll back to", good.GitSHA, r.Name)
+	}
+	branch := ""
+	if lo.Commit != nil {
+		branch = lo.Commit.Branch
+	}
+	rollback := lo
+	rollback.GitSHA = good.GitSHA
+	rollback.Dir = filepath.Join(dir, filepath.FromSlash(r.Folder))
+	rollback.Commit = o.commitMetadata(dir, branch, good.GitSHA)
+	rollback.Changelog = ""
+	rollback.ChangedFiles = nil
+	rollback.RollbackOf = lo.GitSHA
+	rollback.Relaunch = relaunch
+	rollback.Trigger = launcher.TriggerRollback
+	err = o.verifyManifests(r, rollback.Dir, good.GitSHA)
+	if err != nil {
+		return err
+	}
+	objects, err := o.launch(rollback)
+	if err != nil {
+		return errors.Wrapf(err, "failed to roll back repository %s to sha %s", r.Name, good.GitSHA)
+	}
+	o.state(key).rolledBack = failed.Name
+	if len(objects) > 0 {
+		countLaunch(rollback)
+	}
+
+	log.Logger().Warnf("rolled back repository %s from failed sha %s of Job %s to the last successful sha %s", key, lo.GitSHA, failed.JobName(), good.GitSHA)
+	if j != nil {
+		if j.Annotations == nil {
+			j.Annotations = map[string]string{}
+		}
+		j.Annotations[launcher.RolledBackToAnnotation] = good.GitSHA
+		_, err = o.KubeClient.BatchV1().Jobs(j.Namespace).Update(j)
+		if err != nil {
+			log.Logger().Warnf("failed to annotate failed Job %s of repository %s with the rollback: %s", j.Name, key, err.Error())
+		}
+	}
+	o.Status.SetRollback(r, status.Rollback{
+		FailedSHA: lo.GitSHA,
+		FailedJob: failed.JobName(),
+		GitSHA:    good.GitSHA,
+		Time:      time.Now(),
+	})
+	event := stream.NewEvent(stream.EventRolledBack, r, lo.GitSHA, good.GitSHA)
+	event.Job = failed.JobName()
+	o.Events.Publish(event)
+	return nil
+}
+
+// checkoutRollback checks out the given commit sha of the git clone into a separate directory so that the clone of
+// the launched branch is never modified
+func (o *Options) checkoutRollback(cloneDir string, dir string, sha string) error {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove dir %s", dir)
+	}
+	parent := filepath.Dir(dir)
+	err = os.MkdirAll(parent, files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", parent)
+	}
+	_, err = o.GitClient.Command(parent, "clone", "--quiet", "--no-checkout", cloneDir, dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to clone %s", cloneDir)
+	}
+	_, err = o.GitClient.Command(dir, "checkout", "--detach", sha)
+	if err != nil {
+		return errors.Wrapf(err, "failed to checkout sha %s", sha)
+	}
+	return nil
+}
+
+// isJobFailed returns true if the Job has failed after exhausting its retries
+func isJobFailed(j *v1.Job) bool {
+	for _, c := range j.Status.Conditions {
+		if c.Type == v1.JobFailed && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package poller
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// credentialsDirName the directory within the work directory containing the socket of the git credential cache
+	credentialsDirName = ".credentials"
+
+	// credentialCacheTimeout the number of seconds the git credential cache keeps credentials in memory. The
+	// credentials of a repository are stored again before every pull
+	credentialCacheTimeout = 3600
+)
+
+// gitRemoteCommands the git commands which connect to remote repositories and so need the credential cache
+var gitRemoteCommands = map[string]bool{
+	"clone":     true,
+	"fetch":     true,
+	"ls-remote": true,
+	"pull":      true,
+	"push":      true,
+}
+
+// credentialCacheRunner wraps the command runner, if the git credential cache is enabled, so that the credentials of
+// the git URLs in the arguments of git commands are stored in the in memory git credential cache and removed from the
+// arguments. The git configuration of the clones then never contains any credentials
+func (o *Options) credentialCacheRunner(commandRunner cmdrunner.CommandRunner) cmdrunner.CommandRunner {
+	if !o.GitCredentialCache {
+		return commandRunner
+	}
+	if commandRunner == nil {
+		commandRunner = runner.Exec
+	}
+	return func(c *cmdrunner.Command) (string, error) {
+		if !o.isGitCommand(c) || len(c.Args) == 0 {
+			return commandRunner(c)
+		}
+		helperArgs := []string{
+			"-c", "credential.helper=",
+			"-c", fmt.Sprintf("credential.helper=cache --socket=%s --timeout=%d", filepath.Join(o.Dir, credentialsDirName, "socket"), credentialCacheTimeout),
+			"-c", "credential.useHttpPath=true",
+		}
+		var args []string
+		for _, arg := range c.Args {
+			u, err := url.Parse(arg)
+			if err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.User != nil {
+				if password, ok := u.User.Password(); ok {
+					input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\nusername=%s\npassword=%s\n\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"), u.User.Username(), password)
+					_, err = commandRunner(&cmdrunner.Command{
+						Dir:  c.Dir,
+						Name: c.Name,
+						Args: append(append([]string{}, helperArgs...), "credential", "approve"),
+						In:   strings.NewReader(input),
+					})
+					if err != nil {
+						return "", errors.Wrapf(err, "failed to store the credentials of %s in the git credential cache", repo.RedactGitURL(arg))
+					}
+					arg = repo.RedactGitURL(arg)
+				}
+			}
+			args = append(args, arg)
+		}
+		if gitRemoteCommands[args[0]] {
+			args = append(helperArgs, args...)
+		}
+		c.Args = args
+		return commandRunner(c)
+	}
+}
+
+// isGitCommand returns true if the command runs the git binary
+func (o *Options) isGitCommand(c *cmdrunner.Command) bool {
+	return c.Name == "git" || (o.GitBinary != "" && c.Name == o.GitBinary)
+}
+
+// shredWorkDir overwrites and removes the git clones and checkouts of the repositories, if enabled, so that the
+// sensitive content of the repositories does not remain on disk between polls
+func (o *Options) shredWorkDir(repos []repo.Repository) error {
+	if !o.ShredWorkDir {
+		return nil
+	}
+	for _, r := range repos {
+		err := o.shredRepository(r)
+		if err != nil {
+			return err
+		}
+	}
+	log.Logger().Debugf("shredded the git clones in the work dir %s", o.Dir)
+	return nil
+}
+
+// shredRepository overwrites and removes the git clone, checkouts and internal directories of the repository
+func (o *Options) shredRepository(r repo.Repository) error {
+	unlock := o.locks.lockRepository(r)
+	defer unlock()
+
+	key := o.cloneKey(r)
+	dirs := []string{filepath.Join(o.Dir, key)}
+	for _, name := range []string{statusDirName, rollbackDirName, pendingDirName, artifactsDirName} {
+		dirs = append(dirs, o.internalDir(name, r))
+	}
+	for _, dir := range dirs {
+		err := shred(dir)
+		if err != nil {
+			return err
+		}
+	}
+	delete(o.clones, key)
+	return nil
+}
+
+// shred overwrites every regular file in the given directory with zeros before removing the directory
+func shred(dir string) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		// git makes its objects read only
+		err = os.Chmod(path, 0600)
+		if err != nil {
+			return errors.Wrapf(err, "failed to make file %s writable", path)
+		}
+		return overwrite(path, info.Size())
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to overwrite the files in dir %s", dir)
+	}
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove dir %s", dir)
+	}
+	return nil
+}
+
+// overwrite overwrites the file in place with zeros
+func overwrite(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file %s", path)
+	}
+	defer f.Close()
+	zeros := make([]byte, 32*1024)
+	for size > 0 {
+		n := int64(len(zeros))
+		if size < n {
+			n = size
+		}
+		_, err = f.Write(zeros[:n])
+		if err != nil {
+			return errors.Wrapf(err, "failed to overwrite file %s", path)
+		}
+		size -= n
+	}
+	return f.Sync()
+}
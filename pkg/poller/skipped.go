@@ -0,0 +1,86 @@
+package poller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+const (
+	// skipReasonBatched the commit was pushed along with newer commits before the repository was next polled
+	skipReasonBatched = "batched"
+
+	// skipReasonPaused the commit was pushed while launching the repository was paused
+	skipReasonPaused = "paused"
+
+	// skipReasonCoolingDown the commit was deferred by the cool-down of the repository
+	skipReasonCoolingDown = "cooling-down"
+
+	// skipReasonActiveJob the commit was waiting for the active Job of a previous commit to complete
+	skipReasonActiveJob = "active-job"
+
+	// skipReasonNotRelevant none of the paths changed by the commit were relevant according to the triggers of the
+	// repository
+	skipReasonNotRelevant = "not-relevant"
+
+	// skipReasonQueueFull the commit was dropped from the pending queue of the repository as it exceeded its limit
+	skipReasonQueueFull = "queue-full"
+)
+
+// deferLaunch records why the latest commit of the repository could not be launched yet so that any commits which
+// are never launched individually can be reported with the reason they were skipped
+func (o *Options) deferLaunch(r repo.Repository, reason string) {
+	o.state(r.Namespace + "/" + r.Name).deferReason = reason
+}
+
+// recordSkipped records the commits since the previously launched commit which were not launched individually
+// before the given commit sha is launched, along with the reason, in the status of the repository and audit logs
+// so that it can be verified that no commit was silently dropped
+func (o *Options) recordSkipped(r repo.Repository, dir string, sha string) {
+	key := r.Namespace + "/" + r.Name
+	state := o.state(key)
+	reason := state.deferReason
+	state.deferReason = ""
+	dropped := state.droppedPending
+	state.droppedPending = nil
+	if reason == "" {
+		reason = skipReasonBatched
+	}
+	previous := state.lastLaunched
+	if previous == "" || previous == sha {
+		return
+	}
+	text, err := o.GitClient.Command(dir, "log", "--format=%H %s", previous+".."+sha)
+	if err != nil {
+		log.Logger().Warnf("failed to find the commits between %s and %s of repository %s: %s", previous, sha, r.Name, err.Error())
+		return
+	}
+	now := time.Now()
+	var skipped []status.SkippedCommit
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if fields[0] == "" || fields[0] == sha {
+			continue
+		}
+		c := status.SkippedCommit{
+			GitSHA:     fields[0],
+			Reason:     reason,
+			LaunchedBy: sha,
+			Time:       now,
+		}
+		if dropped[c.GitSHA] {
+			c.Reason = skipReasonQueueFull
+		}
+		if len(fields) > 1 {
+			c.Subject = fields[1]
+		}
+		log.Logger().Infof("audit: commit %s of repository %s was skipped (%s) and is covered by the launch of %s: %s", c.GitSHA, key, c.Reason, sha, c.Subject)
+		skipped = append(skipped, c)
+	}
+	if len(skipped) > 0 {
+		o.Status.AddSkipped(r, skipped...)
+	}
+}
@@ -0,0 +1,94 @@
+package poller
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// gitOperatorFolders the folders, relative to the root of a repository or a folder of a monorepo, which may contain
+// the git operator configuration
+var gitOperatorFolders = []string{".jx/git-operator", "versionStream/git-operator"}
+
+// cloneArgs returns the arguments of the `git clone` of the repository. With sparse checkouts the clone is a
+// blobless partial clone without a checkout so that only the files which are checked out are ever downloaded
+func (o *Options) cloneArgs(r repo.Repository) []string {
+	args := []string{"clone"}
+	if o.SparseCheckout {
+		args = append(args, "--filter=blob:none", "--no-checkout")
+	}
+	if r.Branch != "" {
+		args = append(args, "--branch", r.Branch)
+	}
+	return args
+}
+
+// sparsePatterns returns the sparse checkout patterns of the git operator folders of the repository and of the
+// folders of a monorepo
+func sparsePatterns(r repo.Repository) []string {
+	var answer []string
+	for _, folder := range gitOperatorFolders {
+		answer = append(answer, "/"+folder+"/")
+	}
+	for _, glob := range r.Folders {
+		glob = strings.Trim(glob, "/")
+		for _, folder := range gitOperatorFolders {
+			answer = append(answer, "/"+glob+"/"+folder+"/")
+		}
+	}
+	return answer
+}
+
+// sparseCheckout checks out only the git operator folders of the repository in the git clone or worktree in the
+// given dir if sparse checkouts are enabled. The patterns are rewritten each time so that changes to the folders of
+// a monorepo are picked up
+func (o *Options) sparseCheckout(r repo.Repository, dir string) error {
+	if !o.SparseCheckout {
+		return nil
+	}
+	text, err := o.GitClient.Command(dir, "rev-parse", "--git-path", "info/sparse-checkout")
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the sparse checkout file of repository %s", r.Name)
+	}
+	fileName := strings.TrimSpace(text)
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(dir, fileName)
+	}
+	data := []byte(strings.Join(sparsePatterns(r), "\n") + "\n")
+	existing, err := ioutil.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read file %s", fileName)
+	}
+	if bytes.Equal(existing, data) {
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(fileName), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", filepath.Dir(fileName))
+	}
+	err = ioutil.WriteFile(fileName, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", fileName)
+	}
+	_, err = o.GitClient.Command(dir, "config", "core.sparseCheckout", "true")
+	if err != nil {
+		return errors.Wrapf(err, "failed to enable the sparse checkout of repository %s", r.Name)
+	}
+	_, err = o.GitClient.Command(dir, "read-tree", "-mu", "HEAD")
+	if err != nil {
+		// lets remove the patterns so that the checkout is retried by the next poll
+		removeErr := os.Remove(fileName)
+		if removeErr != nil {
+			log.Logger().Warnf("failed to remove file %s: %s", fileName, removeErr.Error())
+		}
+		return errors.Wrapf(err, "failed to checkout the git operator folders of repository %s", r.Name)
+	}
+	return nil
+}
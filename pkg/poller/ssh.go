@@ -0,0 +1,208 @@
+package poller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// sshDirName the directory within the work directory containing the generated ssh configuration
+	sshDirName = ".ssh"
+
+	sshConfigFileName     = "config"
+	sshKnownHostsFileName = "known_hosts"
+)
+
+// sshHostSettings the ssh settings of the repositories of a git server
+type sshHostSettings struct {
+	proxyJump    string
+	proxyCommand string
+	repository   string
+}
+
+// configureSSH generates the ssh configuration of the jump hosts, proxy commands and known hosts of the operator and
+// repositories and points `$GIT_SSH_COMMAND` at it so that ssh git URLs can be cloned via a bastion. The ssh
+// configuration of the user is still included after the generated configuration
+func (o *Options) configureSSH(repos []repo.Repository) error {
+	config, knownHosts := o.sshConfiguration(repos)
+	if config == o.sshConfig && knownHosts == o.sshKnownHosts {
+		return nil
+	}
+	if config == "" {
+		// lets restore the original ssh command as the ssh settings have been removed
+		var err error
+		if o.sshCommand == "" {
+			err = os.Unsetenv("GIT_SSH_COMMAND")
+		} else {
+			err = os.Setenv("GIT_SSH_COMMAND", o.sshCommand)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to restore $GIT_SSH_COMMAND")
+		}
+		o.sshConfig = ""
+		o.sshKnownHosts = ""
+		return nil
+	}
+
+	dir := filepath.Join(o.Dir, sshDirName)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	if knownHosts != "" {
+		path := filepath.Join(dir, sshKnownHostsFileName)
+		err = ioutil.WriteFile(path, []byte(knownHosts), 0600)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save file %s", path)
+		}
+	}
+	path := filepath.Join(dir, sshConfigFileName)
+	err = ioutil.WriteFile(path, []byte(config), 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+
+	if o.sshConfig == "" {
+		o.sshCommand = os.Getenv("GIT_SSH_COMMAND")
+	}
+	command := o.sshCommand
+	if command == "" {
+		command = "ssh"
+	}
+	err = os.Setenv("GIT_SSH_COMMAND", command+" -F "+path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set $GIT_SSH_COMMAND")
+	}
+	o.sshConfig = config
+	o.sshKnownHosts = knownHosts
+	log.Logger().Infof("using the ssh configuration %s", path)
+	return nil
+}
+
+// sshConfiguration returns the ssh configuration and known hosts of the operator and the given repositories or empty
+// strings if no ssh settings are specified
+func (o *Options) sshConfiguration(repos []repo.Repository) (string, string) {
+	hosts := map[string]*sshHostSettings{}
+	proxyJumps := []string{o.SSHProxyJump}
+	knownHosts := []string{o.SSHKnownHosts}
+	for _, r := range repos {
+		host := repo.SSHHost(r.GitURL)
+		if host == "" {
+			continue
+		}
+		if r.SSHKnownHosts != "" {
+			knownHosts = append(knownHosts, r.SSHKnownHosts)
+		}
+		if r.SSHProxyJump == "" && r.SSHProxyCommand == "" {
+			continue
+		}
+		settings := &sshHostSettings{
+			proxyJump:    r.SSHProxyJump,
+			proxyCommand: r.SSHProxyCommand,
+			repository:   r.Namespace + "/" + r.Name,
+		}
+		existing := hosts[host]
+		if existing == nil {
+			hosts[host] = settings
+			proxyJumps = append(proxyJumps, r.SSHProxyJump)
+			continue
+		}
+		if existing.proxyJump != settings.proxyJump || existing.proxyCommand != settings.proxyCommand {
+			log.Logger().Warnf("ignoring the ssh settings of repository %s as they conflict with those of repository %s for the host %s", settings.repository, existing.repository, host)
+		}
+	}
+
+	knownHostsText := joinLines(knownHosts)
+	if o.SSHProxyJump == "" && o.SSHProxyCommand == "" && knownHostsText == "" && len(hosts) == 0 {
+		return "", ""
+	}
+
+	buf := &strings.Builder{}
+	buf.WriteString("# generated by the git operator\n")
+
+	// lets connect to the jump hosts directly so that a global ProxyJump does not loop
+	jumpHosts := sshJumpHosts(proxyJumps)
+	if len(jumpHosts) > 0 {
+		fmt.Fprintf(buf, "\nHost %s\n  ProxyJump none\n", strings.Join(jumpHosts, " "))
+	}
+
+	var names []string
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+	for _, host := range names {
+		settings := hosts[host]
+		fmt.Fprintf(buf, "\nHost %s\n", host)
+		if settings.proxyJump != "" {
+			fmt.Fprintf(buf, "  ProxyJump %s\n", settings.proxyJump)
+		}
+		if settings.proxyCommand != "" {
+			fmt.Fprintf(buf, "  ProxyCommand %s\n", settings.proxyCommand)
+		}
+	}
+
+	buf.WriteString("\nHost *\n")
+	if o.SSHProxyJump != "" {
+		fmt.Fprintf(buf, "  ProxyJump %s\n", o.SSHProxyJump)
+	}
+	if o.SSHProxyCommand != "" {
+		fmt.Fprintf(buf, "  ProxyCommand %s\n", o.SSHProxyCommand)
+	}
+	if knownHostsText != "" {
+		fmt.Fprintf(buf, "  GlobalKnownHostsFile %s\n", filepath.Join(o.Dir, sshDirName, sshKnownHostsFileName))
+	}
+
+	// ssh ignores the configuration of the user when a configuration file is specified so lets include it
+	if home, _ := os.UserHomeDir(); home != "" {
+		fmt.Fprintf(buf, "\nMatch all\nInclude %s\n", filepath.Join(home, ".ssh", "config"))
+	}
+	return buf.String(), knownHostsText
+}
+
+// sshJumpHosts returns the sorted host names of the given `ProxyJump` values such as
+// `git@bastion.example.com:2222,ssh://other.example.com`
+func sshJumpHosts(proxyJumps []string) []string {
+	set := map[string]bool{}
+	for _, proxyJump := range proxyJumps {
+		for _, jump := range strings.Split(proxyJump, ",") {
+			host := strings.TrimPrefix(strings.TrimSpace(jump), "ssh://")
+			if i := strings.LastIndex(host, "@"); i >= 0 {
+				host = host[i+1:]
+			}
+			if i := strings.Index(host, ":"); i >= 0 {
+				host = host[:i]
+			}
+			if host != "" && host != repo.NoneValue {
+				set[host] = true
+			}
+		}
+	}
+	var answer []string
+	for host := range set {
+		answer = append(answer, host)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// joinLines joins the non blank texts with new lines ending with a new line
+func joinLines(texts []string) string {
+	buf := &strings.Builder{}
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text != "" {
+			buf.WriteString(text)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,123 @@
+package poller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/status"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// webhookTimeout the timeout of sending a stale boot notification
+const webhookTimeout = 10 * time.Second
+
+// bootState the successful boots of a repository known to the poller
+type bootState struct {
+	// firstSeen when the repository was first polled which starts the staleness window until it boots successfully
+	firstSeen time.Time
+
+	// lastSucceeded when the most recent successful launch of the repository completed
+	lastSucceeded time.Time
+}
+
+// checkStale checks whether the repository has booted successfully within the staleness threshold, updating the
+// `StaleBoot` condition of its status and sending a notification when it becomes stale
+func (o *Options) checkStale(r repo.Repository) {
+	if o.StaleBootThreshold <= 0 {
+		return
+	}
+	key := r.Namespace + "/" + r.Name
+	state := o.state(key).boot
+	if state == nil {
+		state = &bootState{firstSeen: time.Now()}
+		o.state(key).boot = state
+	}
+
+	// the launched resources may have been removed since they succeeded so lets remember the latest success
+	if historyProvider, ok := o.Launcher.(launcher.HistoryProvider); ok {
+		records, err := historyProvider.History(r)
+		if err != nil {
+			log.Logger().Warnf("failed to find the launches of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+		}
+		for _, record := range records {
+			if record.Result != launcher.ResultSucceeded {
+				continue
+			}
+			t := record.Created
+			if record.Completed != nil {
+				t = *record.Completed
+			}
+			if t.After(state.lastSucceeded) {
+				state.lastSucceeded = t
+			}
+		}
+	}
+
+	since := state.firstSeen
+	if !state.lastSucceeded.IsZero() {
+		since = state.lastSucceeded
+		metrics.LastSuccessTimestamp.WithLabelValues(r.Tenant, r.Namespace, r.Name).Set(float64(since.Unix()))
+		o.Status.SetLastSucceeded(r, since)
+	}
+	gauge := metrics.StaleBoot.WithLabelValues(r.Tenant, r.Namespace, r.Name)
+	if time.Since(since) <= o.StaleBootThreshold {
+		gauge.Set(0)
+		o.Status.SetCondition(r, status.ConditionStaleBoot, false, "")
+		return
+	}
+	gauge.Set(1)
+	message := fmt.Sprintf("no successful boot since %s", since.UTC().Format(time.RFC3339))
+	if !o.Status.SetCondition(r, status.ConditionStaleBoot, true, message) {
+		return
+	}
+	log.Logger().Warnf("repository %s in namespace %s is stale: %s", r.Name, r.Namespace, message)
+	o.Events.Publish(stream.NewEvent(stream.EventStaleBoot, r, "", message))
+
+	err := o.notifyStale(r)
+	if err != nil {
+		log.Logger().Warnf("failed to notify that repository %s in namespace %s is stale: %s", r.Name, r.Namespace, err.Error())
+	}
+}
+
+// notifyStale posts the status of the stale repository as JSON to the optional webhook of its tenant or the operator
+func (o *Options) notifyStale(r repo.Repository) error {
+	webhook := o.notificationWebhook(r)
+	if webhook == "" {
+		return nil
+	}
+	s, _ := o.Status.Get(r.Namespace, r.Name)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal the status of repository %s", r.Name)
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to post to %s", webhook)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("failed to post to %s: status %s", webhook, resp.Status)
+	}
+	return nil
+}
+
+// notificationWebhook returns the webhook which is sent the notifications of the repository: the webhook of its tenant
+// if it has one, otherwise the webhook of the operator
+func (o *Options) notificationWebhook(r repo.Repository) string {
+	if o.tenants != nil {
+		t, err := o.tenants.FindTenant(r)
+		if err == nil && t.NotificationWebhook != "" {
+			return t.NotificationWebhook
+		}
+	}
+	return o.StaleBootWebhook
+}
@@ -0,0 +1,66 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+)
+
+// repoState the in memory state of a repository or launch unit, keyed by `<namespace>/<name>`, which is discarded
+// when the repository is cleaned up or is no longer listed
+type repoState struct {
+	lastLaunched    string
+	failedLaunch    string
+	detected        detectedCommit
+	boot            *bootState
+	reportedStatus  string
+	statusPushed    string
+	bootInventory   string
+	capturedFailure string
+	rolledBack      string
+	deferReason     string
+	pending         []string
+	droppedPending  map[string]bool
+	invalidJobFile  string
+	conflict        string
+	driftCheck      time.Time
+	units           []repo.Repository
+	unitsKnown      bool
+	lease           bool
+	leaseKnown      bool
+}
+
+// state returns the state of the repository or launch unit with the given `<namespace>/<name>` key lazily creating it
+func (o *Options) state(key string) *repoState {
+	if o.states == nil {
+		o.states = map[string]*repoState{}
+	}
+	s := o.states[key]
+	if s == nil {
+		s = &repoState{}
+		o.states[key] = s
+	}
+	return s
+}
+
+// forgetRemoved discards the state of any repositories, and their launch units, which are no longer listed such as
+// those deleted without the cleanup finalizer. Nothing is discarded if no repositories are listed in case the
+// listing failed transiently
+func (o *Options) forgetRemoved(repos []repo.Repository) {
+	if len(repos) == 0 {
+		return
+	}
+	listed := map[string]bool{}
+	for _, r := range repos {
+		key := r.Namespace + "/" + r.Name
+		listed[key] = true
+		for _, u := range o.state(key).units {
+			listed[u.Namespace+"/"+u.Name] = true
+		}
+	}
+	for key := range o.states {
+		if !listed[key] {
+			delete(o.states, key)
+		}
+	}
+}
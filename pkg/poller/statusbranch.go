@@ -0,0 +1,187 @@
+package poller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// statusDirName the directory within the work directory containing the git clones of the status branches
+	statusDirName = ".status"
+
+	// defaultStatusFile the default name of the status file in the status branch
+	defaultStatusFile = "status.json"
+
+	// statusCommitterName the name of the committer of the status file
+	statusCommitterName = "jx-git-operator"
+
+	// statusCommitterEmail the email of the committer of the status file
+	statusCommitterEmail = "jx-git-operator@jenkins-x.io"
+)
+
+// BootStatus the machine readable status of the latest completed boot of a repository which is pushed to its status
+// branch
+type BootStatus struct {
+	// Repository the name of the repository
+	Repository string `json:"repository"`
+
+	// Namespace the namespace of the repository
+	Namespace string `json:"namespace"`
+
+	// Operator the optional identifier of the operator instance which booted the repository
+	Operator string `json:"operator,omitempty"`
+
+	// GitSHA the commit sha which was booted
+	GitSHA string `json:"gitSha"`
+
+	// Result the result of the boot: `succeeded` or `failed`
+	Result string `json:"result"`
+
+	// Launched when the boot was launched
+	Launched time.Time `json:"launched"`
+
+	// Completed when the boot succeeded or failed
+	Completed *time.Time `json:"completed,omitempty"`
+}
+
+// pushStatus pushes the status of the latest completed boot of the repository to its status branch if enabled and
+// the launcher supports the launch history. The status is informational only so any failures are logged rather than
+// failing the poll
+func (o *Options) pushStatus(r repo.Repository) {
+	if o.StatusBranch == "" {
+		return
+	}
+	historyProvider, ok := o.Launcher.(launcher.HistoryProvider)
+	if !ok {
+		return
+	}
+	records, err := historyProvider.History(r)
+	if err != nil {
+		log.Logger().Warnf("failed to find the launches of repository %s in namespace %s: %s", r.Name, r.Namespace, err.Error())
+		return
+	}
+
+	// the records are sorted with the most recent first
+	var latest *launcher.LaunchRecord
+	for i := range records {
+		if records[i].Result != launcher.ResultActive {
+			latest = &records[i]
+			break
+		}
+	}
+	if latest == nil {
+		return
+	}
+	s := &BootStatus{
+		Repository: r.Name,
+		Namespace:  r.Namespace,
+		Operator:   o.OperatorID,
+		GitSHA:     latest.GitSHA,
+		Result:     latest.Result,
+		Launched:   latest.Created,
+		Completed:  latest.Completed,
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		log.Logger().Warnf("failed to marshal the boot status of repository %s: %s", r.Name, err.Error())
+		return
+	}
+	data = append(data, '\n')
+
+	key := r.Namespace + "/" + r.Name
+	if o.state(key).statusPushed == string(data) {
+		return
+	}
+	err = o.pushStatusFile(r, data, "boot of "+latest.GitSHA+" "+latest.Result)
+	if err != nil {
+		log.Logger().Warnf("failed to push the boot status of repository %s to branch %s: %s", r.Name, o.StatusBranch, err.Error())
+		return
+	}
+	o.state(key).statusPushed = string(data)
+}
+
+// pushStatusFile commits the status file to the status branch of the repository and pushes it if it has changed.
+// A separate git clone is used for the status branch so that the clone of the launched branch is never modified
+func (o *Options) pushStatusFile(r repo.Repository, data []byte, message string) error {
+	dir := o.internalDir(statusDirName, r)
+	exists, err := files.DirExists(filepath.Join(dir, ".git"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if dir exists %s", dir)
+	}
+	if !exists {
+		err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create dir %s", dir)
+		}
+		_, err = o.GitClient.Command(dir, "init")
+		if err != nil {
+			return errors.Wrapf(err, "failed to initialise git repository in %s", dir)
+		}
+		_, err = o.GitClient.Command(dir, "remote", "add", "origin", r.GitURL)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add remote to git repository in %s", dir)
+		}
+	} else {
+		err = o.syncOriginURL(r, dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	branch := o.StatusBranch
+	_, err = o.GitClient.Command(dir, "fetch", "origin", branch)
+	if err == nil {
+		_, err = o.GitClient.Command(dir, "checkout", "-f", "-B", branch, "FETCH_HEAD")
+		if err != nil {
+			return errors.Wrapf(err, "failed to checkout branch %s", branch)
+		}
+	} else {
+		// lets create the status branch on the first push
+		log.Logger().Infof("creating status branch %s of repository %s", branch, r.Name)
+		_, err = o.GitClient.Command(dir, "symbolic-ref", "HEAD", "refs/heads/"+branch)
+		if err != nil {
+			return errors.Wrapf(err, "failed to switch to new branch %s", branch)
+		}
+	}
+
+	fileName := o.StatusFile
+	if fileName == "" {
+		fileName = defaultStatusFile
+	}
+	path := filepath.Join(dir, fileName)
+	existing, err := ioutil.ReadFile(path)
+	if err == nil && string(existing) == string(data) {
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir for %s", path)
+	}
+	err = ioutil.WriteFile(path, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", path)
+	}
+	_, err = o.GitClient.Command(dir, "add", fileName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add %s", fileName)
+	}
+	_, err = o.GitClient.Command(dir, "-c", "user.name="+statusCommitterName, "-c", "user.email="+statusCommitterEmail, "commit", "-m", message)
+	if err != nil {
+		return errors.Wrapf(err, "failed to commit %s", fileName)
+	}
+	_, err = o.GitClient.Command(dir, "push", "origin", "HEAD:refs/heads/"+branch)
+	if err != nil {
+		return errors.Wrapf(err, "failed to push branch %s", branch)
+	}
+	log.Logger().Infof("pushed the boot status of repository %s to branch %s: %s", r.Name, branch, message)
+	return nil
+}
@@ -0,0 +1,14 @@
+package poller
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/timing"
+)
+
+// newTimings returns a new breakdown of the durations of the phases of a poll if the phase timings are enabled or
+// nil so that the phases are not timed
+func (o *Options) newTimings() *timing.Breakdown {
+	if !o.PhaseTimings {
+		return nil
+	}
+	return timing.NewBreakdown()
+}
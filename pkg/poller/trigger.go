@@ -0,0 +1,108 @@
+package poller
+
+import (
+	"sync"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+)
+
+// triggers the repositories which have been manually triggered to launch on the next poll or pushed to as notified
+// by a webhook. It is safe to use from the poller and HTTP handlers concurrently
+type triggers struct {
+	lock         sync.Mutex
+	repositories map[string]bool
+	pushed       map[string]bool
+	wake         chan struct{}
+}
+
+func newTriggers() *triggers {
+	return &triggers{
+		repositories: map[string]bool{},
+		pushed:       map[string]bool{},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// add triggers the repository and wakes up the poll loop
+func (t *triggers) add(key string) {
+	t.lock.Lock()
+	t.repositories[key] = true
+	t.lock.Unlock()
+
+	t.wakeUp()
+}
+
+// wakeUp wakes up the poll loop if it is waiting for the next poll
+func (t *triggers) wakeUp() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns true if the repository has been triggered, clearing the trigger
+func (t *triggers) take(key string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	triggered := t.repositories[key]
+	delete(t.repositories, key)
+	return triggered
+}
+
+// push records a push to the repository and wakes up the poll loop
+func (t *triggers) push(key string) {
+	t.lock.Lock()
+	t.pushed[key] = true
+	t.lock.Unlock()
+
+	t.wakeUp()
+}
+
+// takePushed returns true if a push to the repository has been received since it was last polled, clearing it
+func (t *triggers) takePushed(key string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	pushed := t.pushed[key]
+	delete(t.pushed, key)
+	return pushed
+}
+
+// relaunchRequest returns the identifier of the trigger annotation which requested the relaunch of the repository
+// so that a relaunch which was launched but not acknowledged before the operator restarted is not launched again.
+// Triggers which are only held in memory do not survive a restart so they have no identifier
+func relaunchRequest(r repo.Repository, relaunch bool) string {
+	if !relaunch || !r.Triggered {
+		return ""
+	}
+	return r.TriggerRequest
+}
+
+// Trigger requests that the latest commit of the given repository is launched on the next poll even if it has
+// already been launched, waking up the poll loop so that the launch happens straight away
+func (o *Options) Trigger(ns string, name string) {
+	if o.triggered == nil {
+		o.triggered = newTriggers()
+	}
+	o.triggered.add(ns + "/" + name)
+}
+
+// Push records that a push to the given repository was received via a webhook, waking up the poll loop so that the
+// pushed commit is launched straight away. The launch is recorded as triggered by the webhook
+func (o *Options) Push(ns string, name string) {
+	if o.triggered == nil {
+		o.triggered = newTriggers()
+	}
+	o.triggered.push(ns + "/" + name)
+}
+
+// PollNow wakes up the poll loop so that the repositories are polled straight away rather than waiting for the
+// next poll, such as when a push event is received. Unlike a trigger, commits which have already been launched are
+// not launched again
+func (o *Options) PollNow() {
+	if o.triggered == nil {
+		o.triggered = newTriggers()
+	}
+	o.triggered.wakeUp()
+}
@@ -0,0 +1,195 @@
+package poller
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/runner"
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// maxStackDump the maximum size of the goroutine stacks logged when the poll loop stalls
+const maxStackDump = 1 << 20
+
+// watchdog tracks the cycles of the poll loop so that a stalled loop, such as a hung git clone or a deadlock, can be
+// detected and reset. It is safe to use from the poll loop and HTTP handlers concurrently
+type watchdog struct {
+	lock      sync.Mutex
+	lastCycle time.Time
+	stalled   bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newWatchdog() *watchdog {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watchdog{
+		lastCycle: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// completed records that the poll loop completed a cycle with the given error.
+//
+// A cycle whose commands were abandoned by a reset does not count as recovered so the loop stays unhealthy until a
+// later cycle completes normally
+func (w *watchdog) completed(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lastCycle = time.Now()
+	if !w.stalled {
+		return
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	if !isAbandoned(err) {
+		log.Logger().Infof("the poll loop has recovered")
+		w.stalled = false
+	}
+}
+
+// check returns true if the poll loop has just been detected as stalled as no cycle has completed within the timeout.
+//
+// The loop is only reset once so if it stalls again before recovering it needs to be restarted
+func (w *watchdog) check(timeout time.Duration) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.stalled || time.Since(w.lastCycle) <= timeout {
+		return false
+	}
+	w.stalled = true
+	return true
+}
+
+// reset abandons the commands being run by the stalled cycle, killing those run via exec, so that the poll loop can
+// continue
+func (w *watchdog) reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.cancel()
+}
+
+// context returns the context of the current cycle which is cancelled when its commands should be abandoned
+func (w *watchdog) context() context.Context {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.ctx
+}
+
+// exec runs the command killing it if the current cycle is reset
+func (w *watchdog) exec(c *cmdrunner.Command) (string, error) {
+	return runner.ExecContext(w.context(), c)
+}
+
+// health returns an error if the poll loop is stalled
+func (w *watchdog) health() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.stalled {
+		return errors.Errorf("the poll loop has not completed a cycle since %s", w.lastCycle.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// runner wraps the command runner so that commands which hang when the poll loop stalls are abandoned on a reset.
+//
+// Commands run via exec are killed. A custom command runner cannot be cancelled so its abandoned command keeps
+// running in the background
+func (w *watchdog) runner(commandRunner cmdrunner.CommandRunner) cmdrunner.CommandRunner {
+	return func(c *cmdrunner.Command) (string, error) {
+		type result struct {
+			text string
+			err  error
+		}
+		ctx := w.context()
+		done := make(chan result, 1)
+		go func() {
+			text, err := commandRunner(c)
+			done <- result{text: text, err: err}
+		}()
+		select {
+		case r := <-done:
+			if r.err != nil && ctx.Err() != nil {
+				// the command was killed by the reset
+				return r.text, &abandonedError{command: c.CLI()}
+			}
+			return r.text, r.err
+		case <-ctx.Done():
+			return "", &abandonedError{command: c.CLI()}
+		}
+	}
+}
+
+// abandonedError the error returned for a command abandoned by resetting the stalled poll loop
+type abandonedError struct {
+	command string
+}
+
+func (e *abandonedError) Error() string {
+	return "abandoned command " + e.command + " as the poll loop stalled"
+}
+
+// isAbandoned returns true if the error was caused by abandoning a command of a stalled poll loop
+func isAbandoned(err error) bool {
+	_, ok := errors.Cause(err).(*abandonedError)
+	return ok
+}
+
+// discardAbandoned removes the git clone of the repository if its poll was abandoned by resetting the stalled poll
+// loop as an abandoned git command may have left the clone in an inconsistent state, so that the next cycle clones
+// the repository again
+func (o *Options) discardAbandoned(r repo.Repository, err error) {
+	if !isAbandoned(err) {
+		return
+	}
+	dir := o.cloneDir(r)
+	log.Logger().Warnf("removing the git clone %s of repository %s as its poll was abandoned", dir, r.Name)
+	removeErr := os.RemoveAll(dir)
+	if removeErr != nil {
+		log.Logger().Warnf("failed to remove the git clone %s: %s", dir, removeErr.Error())
+	}
+	delete(o.clones, o.cloneKey(r))
+}
+
+// watch checks the poll loop has completed a cycle within the configured number of poll intervals until the context
+// is done. If it has not the goroutine stacks are logged, the loop is reported as unhealthy and the commands of the
+// stalled cycle are abandoned
+func (o *Options) watch(ctx context.Context) {
+	timeout := time.Duration(o.WatchdogCycles) * o.PollDuration
+	ticker := time.NewTicker(o.PollDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !o.watchdog.check(timeout) {
+			continue
+		}
+		buf := make([]byte, maxStackDump)
+		n := runtime.Stack(buf, true)
+		log.Logger().Errorf("the poll loop has not completed a cycle in %s. Goroutine stacks:\n%s", timeout.String(), string(buf[:n]))
+		log.Logger().Warnf("resetting the poll loop by abandoning its running commands")
+		o.watchdog.reset()
+	}
+}
+
+// Health returns an error if the watchdog has detected that the poll loop is stalled so that a liveness probe can
+// restart the operator if resetting the loop does not recover it
+func (o *Options) Health() error {
+	if o.watchdog == nil {
+		return nil
+	}
+	return o.watchdog.health()
+}
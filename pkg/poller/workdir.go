@@ -0,0 +1,230 @@
+package poller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// tmpDirName the directory within the work directory used for temporary files
+	tmpDirName = ".tmp"
+
+	// homeDirName the directory within the work directory used as the home directory of the git and kubectl
+	// commands for their configuration, caches and known hosts
+	homeDirName = ".home"
+
+	// cloneDirName the directory within the directory of a repository containing its git clone when using the
+	// namespaced layout
+	cloneDirName = ".clone"
+
+	// LayoutFlat the default work directory layout where the git clone of each repository is `<dir>/<repository>`
+	// and is launched directly
+	LayoutFlat = "flat"
+
+	// LayoutNamespaced the work directory layout where the git clone of each repository is
+	// `<dir>/<namespace>/<repository>/.clone` and each commit is launched from its own checkout in
+	// `<dir>/<namespace>/<repository>/<sha>`
+	LayoutNamespaced = "namespaced"
+)
+
+// repoLocks the locks of each repository so that concurrent polls never modify the git clone or checkouts of the
+// same repository at the same time
+type repoLocks struct {
+	lock  sync.Mutex
+	repos map[string]*sync.Mutex
+}
+
+// lockRepository locks the repository returning the function to unlock it
+func (l *repoLocks) lockRepository(r repo.Repository) func() {
+	key := r.Namespace + "/" + r.Name
+	l.lock.Lock()
+	m := l.repos[key]
+	if m == nil {
+		m = &sync.Mutex{}
+		l.repos[key] = m
+	}
+	l.lock.Unlock()
+
+	m.Lock()
+	return m.Unlock
+}
+
+// validateLayout validates the work directory layout defaulting to LayoutFlat
+func (o *Options) validateLayout() error {
+	switch o.WorkDirLayout {
+	case "":
+		o.WorkDirLayout = LayoutFlat
+	case LayoutFlat, LayoutNamespaced:
+	default:
+		return errors.Errorf("invalid $WORK_DIR_LAYOUT %s. Supported layouts are %s and %s", o.WorkDirLayout, LayoutFlat, LayoutNamespaced)
+	}
+	return nil
+}
+
+// cloneKey returns the path of the directory of the repository relative to the work directory which is used to
+// record the disk usage of its clone
+func (o *Options) cloneKey(r repo.Repository) string {
+	if o.WorkDirLayout == LayoutNamespaced {
+		return filepath.Join(r.Namespace, r.Name)
+	}
+	return r.Name
+}
+
+// repoDir returns the directory of the repository in the work directory which is removed when the repository is
+// deleted or evicted
+func (o *Options) repoDir(r repo.Repository) string {
+	return filepath.Join(o.Dir, o.cloneKey(r))
+}
+
+// cloneDir returns the directory of the git clone of the repository
+func (o *Options) cloneDir(r repo.Repository) string {
+	if o.WorkDirLayout == LayoutNamespaced {
+		return filepath.Join(o.repoDir(r), cloneDirName)
+	}
+	return o.repoDir(r)
+}
+
+// internalDir returns the directory of the repository within the given internal directory of the work directory
+// such as the clone of its status branch
+func (o *Options) internalDir(name string, r repo.Repository) string {
+	if o.WorkDirLayout == LayoutNamespaced {
+		return filepath.Join(o.Dir, name, r.Namespace, r.Name)
+	}
+	return filepath.Join(o.Dir, name, r.Name)
+}
+
+// checkoutCommit returns the directory the given commit of the repository is launched from. With the namespaced
+// layout each commit gets its own git worktree so that a launch never sees the clone change underneath it and the
+// checkouts of previous commits are removed
+func (o *Options) checkoutCommit(r repo.Repository, cloneDir string, sha string) (string, error) {
+	if o.WorkDirLayout != LayoutNamespaced {
+		return cloneDir, nil
+	}
+	repoDir := o.repoDir(r)
+	dir := filepath.Join(repoDir, sha)
+	exists, err := files.DirExists(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check dir exists %s", dir)
+	}
+	if !exists {
+		args := []string{"worktree", "add", "--detach"}
+		if o.SparseCheckout {
+			args = append(args, "--no-checkout")
+		}
+		_, err = o.GitClient.Command(cloneDir, append(args, dir, sha)...)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to checkout sha %s of repository %s", sha, r.Name)
+		}
+		err = o.sparseCheckout(r, dir)
+		if err != nil {
+			// lets remove the empty checkout so that it is checked out again by the next poll
+			removeErr := os.RemoveAll(dir)
+			if removeErr != nil {
+				log.Logger().Warnf("failed to remove the checkout %s: %s", dir, removeErr.Error())
+			}
+			return "", err
+		}
+	}
+
+	infos, err := ioutil.ReadDir(repoDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read dir %s", repoDir)
+	}
+	removed := false
+	for _, info := range infos {
+		name := info.Name()
+		if !info.IsDir() || name == sha || isWorkDirInternal(name) {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(repoDir, name))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to remove the checkout of sha %s of repository %s", name, r.Name)
+		}
+		removed = true
+	}
+	if removed {
+		_, err = o.GitClient.Command(cloneDir, "worktree", "prune")
+		if err != nil {
+			log.Logger().Warnf("failed to prune the git worktrees of repository %s: %s", r.Name, err.Error())
+		}
+	}
+	return dir, nil
+}
+
+// setupWorkDir makes sure the operator and the git and kubectl commands it runs only need to write to the work
+// directory so that the operator can run as any user with a read only root filesystem.
+//
+// If the temporary or home directories are not writable the `TMPDIR` and `HOME` environment variables are changed to
+// directories within the work directory and ssh is configured to save known hosts there
+func (o *Options) setupWorkDir() error {
+	if !isWritable(os.TempDir()) {
+		dir := filepath.Join(o.Dir, tmpDirName)
+		err := setEnvDir("TMPDIR", dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if home == "" || !isWritable(home) {
+		home = filepath.Join(o.Dir, homeDirName)
+		err := setEnvDir("HOME", home)
+		if err != nil {
+			return err
+		}
+
+		// ssh looks up the home directory of the user rather than using $HOME so lets specify the known hosts file
+		if os.Getenv("GIT_SSH_COMMAND") == "" {
+			sshDir := filepath.Join(home, ".ssh")
+			err = os.MkdirAll(sshDir, 0700)
+			if err != nil {
+				return errors.Wrapf(err, "failed to create dir %s", sshDir)
+			}
+			knownHosts := filepath.Join(sshDir, "known_hosts")
+			err = os.Setenv("GIT_SSH_COMMAND", "ssh -o UserKnownHostsFile="+knownHosts)
+			if err != nil {
+				return errors.Wrapf(err, "failed to set $GIT_SSH_COMMAND")
+			}
+		}
+	}
+	return nil
+}
+
+// setEnvDir creates the directory and sets the environment variable to it
+func setEnvDir(name string, dir string) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir %s", dir)
+	}
+	err = os.Setenv(name, dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set $%s", name)
+	}
+	log.Logger().Infof("using $%s=%s as the default is not writable", name, dir)
+	return nil
+}
+
+// isWritable returns true if a file can be created in the given directory
+func isWritable(dir string) bool {
+	f, err := ioutil.TempFile(dir, ".jx-git-operator-")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name) == nil
+}
+
+// isWorkDirInternal returns true if the directory in the work directory is used by the operator rather than being
+// the git clone of a repository
+func isWorkDirInternal(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,245 @@
+package preflight
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/gitclient"
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Check the result of a single preflight check
+type Check struct {
+	// Name the name of the check such as `can list secrets in namespace jx`
+	Name string `json:"name"`
+
+	// Passed true if the check passed
+	Passed bool `json:"passed"`
+
+	// Message the details of the check. If the check failed it describes how to fix the problem
+	Message string `json:"message,omitempty"`
+}
+
+// requirement a binary or permission required by the operator along with a hint of how to fix it if it is missing
+type requirement struct {
+	name       string
+	attributes authorizationv1.ResourceAttributes
+	hint       string
+}
+
+// Options the options of the preflight checks which validate the operator can discover, poll and launch its
+// repositories before the first poll
+type Options struct {
+	// KubeClient the kubernetes client used to review the RBAC permissions of the operator
+	KubeClient kubernetes.Interface
+
+	// RepoClient the client used to discover the repositories
+	RepoClient repo.Interface
+
+	// GitClient the client used to check the git connectivity of each repository
+	GitClient gitclient.Interface
+
+	// Namespace the namespace of the repositories and launched Jobs
+	Namespace string
+
+	// Dir the directory the git commands are run in
+	Dir string
+
+	// URLRewrites the optional rules which rewrite the git URLs of the repositories before they are reached
+	URLRewrites []repo.URLRewrite
+
+	// GitBinary the name of the git binary; defaults to `git`
+	GitBinary string
+
+	// NoResourceApply if enabled the resources of repositories are not applied so `kubectl` and the permissions
+	// to apply resources are not required
+	NoResourceApply bool
+
+	// BootLeases if enabled the operator holds a boot Lease for each repository so needs permission to create Leases
+	BootLeases bool
+
+	// LookPath finds the path of a binary; defaults to `exec.LookPath`
+	LookPath func(file string) (string, error)
+}
+
+// Run runs all the preflight checks returning the result of each check rather than stopping at the first failure
+func (o *Options) Run() []Check {
+	var answer []Check
+	answer = append(answer, o.checkBinaries()...)
+	answer = append(answer, o.checkPermissions()...)
+	answer = append(answer, o.checkRepositories()...)
+	return answer
+}
+
+// checkBinaries checks the binaries the operator runs are on the `$PATH`
+func (o *Options) checkBinaries() []Check {
+	lookPath := o.LookPath
+	if lookPath == nil {
+		lookPath = exec.LookPath
+	}
+	gitBinary := o.GitBinary
+	if gitBinary == "" {
+		gitBinary = "git"
+	}
+	binaries := []requirement{
+		{name: gitBinary, hint: "install git in the image or specify the git binary via $GIT_BINARY"},
+	}
+	if !o.NoResourceApply {
+		binaries = append(binaries, requirement{name: "kubectl", hint: "install kubectl in the image or disable applying resources via $NO_RESOURCE_APPLY=true"})
+	}
+
+	var answer []Check
+	for _, b := range binaries {
+		c := Check{Name: "binary " + b.name}
+		path, err := lookPath(b.name)
+		if err != nil {
+			c.Message = fmt.Sprintf("%s was not found on the $PATH: %s", b.name, b.hint)
+		} else {
+			c.Passed = true
+			c.Message = path
+		}
+		answer = append(answer, c)
+	}
+	return answer
+}
+
+// checkPermissions reviews the RBAC permissions of the operator needed to discover repositories, launch Jobs and
+// apply resources
+func (o *Options) checkPermissions() []Check {
+	if o.KubeClient == nil {
+		return nil
+	}
+	reviews := []requirement{
+		{
+			attributes: authorizationv1.ResourceAttributes{Verb: "list", Resource: "secrets"},
+			hint:       "repositories cannot be discovered: grant the ServiceAccount of the operator permission to list Secrets",
+		},
+		{
+			attributes: authorizationv1.ResourceAttributes{Verb: "create", Group: "batch", Resource: "jobs"},
+			hint:       "commits cannot be launched: grant the ServiceAccount of the operator permission to create Jobs",
+		},
+	}
+	if o.BootLeases {
+		reviews = append(reviews, requirement{
+			attributes: authorizationv1.ResourceAttributes{Verb: "create", Group: "coordination.k8s.io", Resource: "leases"},
+			hint:       "boot leases cannot be held: grant the ServiceAccount of the operator permission to create Leases or disable them via $BOOT_LEASES=false",
+		})
+	}
+	if !o.NoResourceApply {
+		reviews = append(reviews, requirement{
+			attributes: authorizationv1.ResourceAttributes{Verb: "create", Group: "*", Resource: "*"},
+			hint:       "the resources of repositories cannot be applied: grant the ServiceAccount of the operator permission to create any resource, such as via `rbac.strict=false` in the chart, or disable applying resources via $NO_RESOURCE_APPLY=true",
+		})
+	}
+
+	var answer []Check
+	for _, r := range reviews {
+		attributes := r.attributes
+		attributes.Namespace = o.Namespace
+		c := Check{Name: "can " + describe(attributes)}
+		review, err := o.KubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &attributes,
+			},
+		})
+		switch {
+		case err != nil:
+			c.Message = fmt.Sprintf("failed to review permissions: %s", err.Error())
+		case !review.Status.Allowed:
+			c.Message = r.hint
+			if review.Status.Reason != "" {
+				c.Message += ": " + review.Status.Reason
+			}
+		default:
+			c.Passed = true
+		}
+		answer = append(answer, c)
+	}
+	return answer
+}
+
+// checkRepositories checks the repositories can be discovered and that each git repository can be reached with
+// its credentials
+func (o *Options) checkRepositories() []Check {
+	if o.RepoClient == nil {
+		return nil
+	}
+	c := Check{Name: "discover repositories"}
+	repos, err := o.RepoClient.List()
+	if err != nil {
+		c.Message = fmt.Sprintf("failed to list repositories in namespace %s: %s", o.Namespace, err.Error())
+		return []Check{c}
+	}
+	c.Passed = true
+	c.Message = fmt.Sprintf("found %d repositories", len(repos))
+	answer := []Check{c}
+	if o.GitClient == nil {
+		return answer
+	}
+	for _, r := range repos {
+		if r.Deleting {
+			continue
+		}
+		gitURL := repo.RewriteGitURL(r.GitURL, o.URLRewrites)
+		url := repo.RedactGitURL(gitURL)
+		c := Check{Name: "git connectivity " + r.Name}
+		_, err := o.GitClient.Command(o.Dir, "ls-remote", "--heads", gitURL)
+		if err != nil {
+			// lets make sure we don't display any credentials in the git URL
+			message := strings.ReplaceAll(err.Error(), gitURL, url)
+			c.Message = fmt.Sprintf("failed to reach %s: check the URL and credentials of the repository Secret %s: %s", url, r.Name, message)
+		} else {
+			c.Passed = true
+			c.Message = url
+		}
+		answer = append(answer, c)
+	}
+	return answer
+}
+
+// Summary returns the consolidated readiness summary of the checks as a table
+func Summary(checks []Check) string {
+	buf := &strings.Builder{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RESULT\tCHECK\tMESSAGE")
+	for _, c := range checks {
+		result := "FAILED"
+		if c.Passed {
+			result = "PASSED"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result, c.Name, c.Message)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// Error returns an error describing all the failed checks or nil if all the checks passed
+func Error(checks []Check) error {
+	var failed []string
+	for _, c := range checks {
+		if !c.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Message))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.Errorf("%d of %d preflight checks failed:\n  %s", len(failed), len(checks), strings.Join(failed, "\n  "))
+}
+
+// describe returns a description of the resource attributes such as `list secrets`
+func describe(a authorizationv1.ResourceAttributes) string {
+	resource := a.Resource
+	if resource == "*" {
+		resource = "any resource"
+	}
+	if a.Namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %s", a.Verb, resource, a.Namespace)
+	}
+	return fmt.Sprintf("%s %s", a.Verb, resource)
+}
@@ -0,0 +1,352 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KindGitHub the kind of a GitHub provider
+	KindGitHub = "github"
+
+	// KindGitLab the kind of a GitLab provider
+	KindGitLab = "gitlab"
+
+	// defaultTimeout the timeout of provider API requests
+	defaultTimeout = 30 * time.Second
+
+	// StatePending the state of the commit status of a commit whose launch is active
+	StatePending = "pending"
+
+	// StateSuccess the state of the commit status of a commit whose launch succeeded
+	StateSuccess = "success"
+
+	// StateFailure the state of the commit status of a commit whose launch failed
+	StateFailure = "failure"
+)
+
+// gitLabStates the GitLab commit status states of the states
+var gitLabStates = map[string]string{
+	StatePending: "running",
+	StateSuccess: "success",
+	StateFailure: "failed",
+}
+
+// CommitStatus the status of a commit reported to its git provider
+type CommitStatus struct {
+	// State the state of the commit: `pending`, `success` or `failure`
+	State string
+
+	// Context the name of the status such as `git-operator` which distinguishes it from the statuses of other tools
+	Context string
+
+	// Description the optional short description of the status
+	Description string
+
+	// TargetURL the optional URL of the details of the status
+	TargetURL string
+}
+
+// ErrUnsupported the error returned for git URLs whose host is not a known provider
+var ErrUnsupported = errors.New("the git provider is not supported")
+
+// Host the REST API of a git provider host
+type Host struct {
+	// Kind the kind of the provider: `github` or `gitlab`
+	Kind string
+
+	// APIURL the base URL of the REST API such as `https://api.github.com`
+	APIURL string
+}
+
+// DefaultHosts the known git provider hosts
+var DefaultHosts = map[string]Host{
+	"github.com": {Kind: KindGitHub, APIURL: "https://api.github.com"},
+	"gitlab.com": {Kind: KindGitLab, APIURL: "https://gitlab.com/api/v4"},
+}
+
+// Client looks up the latest commit sha of the branches of git repositories via the REST API of their git provider
+// so that unchanged repositories do not need to be pulled. Requests are rate limited via the RateLimiter and their
+// responses are cached by ETag
+type Client struct {
+	// Hosts the git provider hosts indexed by the host name of their git URLs. Defaults to DefaultHosts
+	Hosts map[string]Host
+
+	// HTTPClient the HTTP client used for requests
+	HTTPClient *http.Client
+
+	// RateLimiter the optional rate limiter of the requests
+	RateLimiter *RateLimiter
+
+	lock  sync.Mutex
+	cache map[string]cachedResponse
+}
+
+// cachedResponse the ETag and body of a previous response
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// NewClient creates a new provider client with the given rate limiter
+func NewClient(limiter *RateLimiter) *Client {
+	return &Client{
+		Hosts:       DefaultHosts,
+		HTTPClient:  &http.Client{Timeout: defaultTimeout},
+		RateLimiter: limiter,
+		cache:       map[string]cachedResponse{},
+	}
+}
+
+// HeadSHA returns the latest commit sha of the branch of the repository with the given git URL. Returns
+// ErrUnsupported if the host of the git URL is not a known provider or a RateLimitedError if the request was
+// deferred as the provider is near its rate limit
+func (c *Client) HeadSHA(gitURL string, branch string) (string, error) {
+	p, err := c.project(gitURL)
+	if err != nil {
+		return "", err
+	}
+	switch p.host.Kind {
+	case KindGitHub:
+		data, err := c.get(p, "/repos/"+p.path+"/commits/"+url.PathEscape(branch), "application/vnd.github.v3.sha")
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+
+	case KindGitLab:
+		data, err := c.get(p, "/projects/"+url.PathEscape(p.path)+"/repository/branches/"+url.PathEscape(branch), "")
+		if err != nil {
+			return "", err
+		}
+		result := struct {
+			Commit struct {
+				ID string `json:"id"`
+			} `json:"commit"`
+		}{}
+		err = json.Unmarshal(data, &result)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse the branch %s of %s", branch, p.key)
+		}
+		return result.Commit.ID, nil
+	}
+	return "", ErrUnsupported
+}
+
+// DefaultBranch returns the default branch of the repository with the given git URL. Returns ErrUnsupported if the
+// host of the git URL is not a known provider or a RateLimitedError if the request was deferred as the provider is
+// near its rate limit
+func (c *Client) DefaultBranch(gitURL string) (string, error) {
+	p, err := c.project(gitURL)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	switch p.host.Kind {
+	case KindGitHub:
+		data, err = c.get(p, "/repos/"+p.path, "")
+	case KindGitLab:
+		data, err = c.get(p, "/projects/"+url.PathEscape(p.path), "")
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+	result := struct {
+		DefaultBranch string `json:"default_branch"`
+	}{}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the repository %s", p.key)
+	}
+	return result.DefaultBranch, nil
+}
+
+// SetCommitStatus reports the status of the commit sha of the repository with the given git URL to its git provider.
+// Returns ErrUnsupported if the host of the git URL is not a known provider or a RateLimitedError if the request was
+// deferred as the provider is near its rate limit
+func (c *Client) SetCommitStatus(gitURL string, sha string, status CommitStatus) error {
+	p, err := c.project(gitURL)
+	if err != nil {
+		return err
+	}
+	var path string
+	var body map[string]string
+	switch p.host.Kind {
+	case KindGitHub:
+		path = "/repos/" + p.path + "/statuses/" + url.PathEscape(sha)
+		body = map[string]string{
+			"state":       status.State,
+			"context":     status.Context,
+			"description": status.Description,
+			"target_url":  status.TargetURL,
+		}
+	case KindGitLab:
+		path = "/projects/" + url.PathEscape(p.path) + "/statuses/" + url.PathEscape(sha)
+		body = map[string]string{
+			"state":       gitLabStates[status.State],
+			"name":        status.Context,
+			"description": status.Description,
+			"target_url":  status.TargetURL,
+		}
+	default:
+		return ErrUnsupported
+	}
+	for k, v := range body {
+		if v == "" {
+			delete(body, k)
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal the commit status")
+	}
+	req, err := http.NewRequest(http.MethodPost, p.host.APIURL+path, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(p, req)
+	data, resp, err := c.do(p.hostName, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("status %d from %s: %s", resp.StatusCode, req.URL.Path, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// project the repository of a git URL on a known provider host
+type project struct {
+	key      string
+	hostName string
+	path     string
+	host     Host
+	token    string
+}
+
+// project returns the repository of the git URL returning ErrUnsupported if its host is not a known provider
+func (c *Client) project(gitURL string) (*project, error) {
+	key := repo.GitURLKey(gitURL)
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return nil, ErrUnsupported
+	}
+	host, ok := c.Hosts[key[:i]]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return &project{
+		key:      key,
+		hostName: key[:i],
+		path:     key[i+1:],
+		host:     host,
+		token:    gitToken(gitURL),
+	}, nil
+}
+
+// get returns the body of the given API path of the provider of the repository. The response is cached by its ETag so
+// that if it has not changed the provider responds with a `304 Not Modified`, which does not cost any API quota, and
+// the cached body is returned
+func (c *Client) get(p *project, path string, accept string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, p.host.APIURL+path, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request")
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	c.authorize(p, req)
+	cacheKey := req.URL.String() + " " + accept
+	cached, ok := c.cached(cacheKey)
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	data, resp, err := c.do(p.hostName, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && ok {
+		metrics.ProviderCachedResponses.WithLabelValues(p.hostName).Inc()
+		return cached.body, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("status %d from %s: %s", resp.StatusCode, req.URL.Path, strings.TrimSpace(string(data)))
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		c.lock.Lock()
+		if c.cache == nil {
+			c.cache = map[string]cachedResponse{}
+		}
+		c.cache[cacheKey] = cachedResponse{etag: etag, body: data}
+		c.lock.Unlock()
+	}
+	return data, nil
+}
+
+// authorize adds the token of the repository, if it has one, to the request
+func (c *Client) authorize(p *project, req *http.Request) {
+	if p.token == "" {
+		return
+	}
+	switch p.host.Kind {
+	case KindGitHub:
+		req.Header.Set("Authorization", "token "+p.token)
+	case KindGitLab:
+		req.Header.Set("PRIVATE-TOKEN", p.token)
+	}
+}
+
+// cached returns the cached response of the given key
+func (c *Client) cached(key string) (cachedResponse, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	r, ok := c.cache[key]
+	return r, ok
+}
+
+// do performs the request once allowed by the rate limiter of the host returning the body of the response
+func (c *Client) do(hostName string, req *http.Request) ([]byte, *http.Response, error) {
+	err := c.RateLimiter.Wait(hostName)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to invoke %s", req.URL.Path)
+	}
+	defer resp.Body.Close()
+	c.RateLimiter.Observe(hostName, resp)
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read the response of %s", req.URL.Path)
+	}
+	return data, resp, nil
+}
+
+// gitToken returns the password or token in the git URL if it has one
+func gitToken(gitURL string) string {
+	u, err := url.Parse(gitURL)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	password, _ := u.User.Password()
+	return password
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+const (
+	// DefaultRateLimitReserve the default number of requests of the rate limit of a host which are left for other
+	// clients of the same account, such as the bot account of a fleet, before requests are deferred
+	DefaultRateLimitReserve = 100
+
+	// DefaultMaxWait the default maximum duration a request is queued waiting for a rate limit to reset rather than
+	// being deferred
+	DefaultMaxWait = 5 * time.Second
+
+	// defaultRetryAfter the duration requests to a host are deferred after a rate limited response which does not
+	// specify when to retry
+	defaultRetryAfter = time.Minute
+)
+
+// RateLimitedError the error returned when a request is deferred as the host is near or over its rate limit
+type RateLimitedError struct {
+	// Host the host of the provider API
+	Host string
+
+	// Until when requests to the host are allowed again
+	Until time.Time
+}
+
+// Error returns the error message
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("deferring requests to %s until %s as it is near its rate limit", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// IsRateLimited returns true if the error is a RateLimitedError
+func IsRateLimited(err error) bool {
+	_, ok := err.(*RateLimitedError)
+	return ok
+}
+
+// RateLimiter tracks the rate limit of each provider API host from the `X-RateLimit-Remaining`, `X-RateLimit-Reset`
+// and `Retry-After` headers of its responses so that requests are queued or deferred when a host is near its rate
+// limit rather than getting the account blocked.
+//
+// A nil limiter does not limit any requests
+type RateLimiter struct {
+	// Reserve the number of remaining requests of a host below which requests are deferred until the rate limit resets
+	Reserve int
+
+	// MaxWait the maximum duration a request is queued waiting for the rate limit of a host to reset. Requests which
+	// would wait longer are deferred by failing with a RateLimitedError
+	MaxWait time.Duration
+
+	lock  sync.Mutex
+	hosts map[string]*quota
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// quota the last known rate limit of a host
+type quota struct {
+	remaining int
+	reset     time.Time
+	blocked   time.Time
+}
+
+// NewRateLimiter creates a new rate limiter which keeps the given number of requests of each host in reserve
+func NewRateLimiter(reserve int, maxWait time.Duration) *RateLimiter {
+	return &RateLimiter{
+		Reserve: reserve,
+		MaxWait: maxWait,
+		hosts:   map[string]*quota{},
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Wait waits until a request to the host is allowed returning a RateLimitedError if the host is near or over its
+// rate limit for longer than the maximum wait
+func (l *RateLimiter) Wait(host string) error {
+	if l == nil {
+		return nil
+	}
+	until := l.allowedAt(host)
+	delay := until.Sub(l.now())
+	if delay <= 0 {
+		return nil
+	}
+	if delay > l.MaxWait {
+		metrics.ProviderDeferredRequests.WithLabelValues(host).Inc()
+		return &RateLimitedError{Host: host, Until: until}
+	}
+	log.Logger().Debugf("waiting %s for the rate limit of %s to reset", delay.String(), host)
+	l.sleep(delay)
+	return nil
+}
+
+// allowedAt returns when the next request to the host is allowed
+func (l *RateLimiter) allowedAt(host string) time.Time {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	q := l.hosts[host]
+	if q == nil {
+		return time.Time{}
+	}
+	if q.blocked.After(l.now()) {
+		return q.blocked
+	}
+	if q.remaining <= l.Reserve && q.reset.After(l.now()) {
+		return q.reset
+	}
+	return time.Time{}
+}
+
+// Observe records the rate limit of the host from the headers of the response
+func (l *RateLimiter) Observe(host string, resp *http.Response) {
+	if l == nil || resp == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	q := l.hosts[host]
+	if q == nil {
+		q = &quota{remaining: -1}
+		l.hosts[host] = q
+	}
+	if remaining, ok := headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining"); ok {
+		q.remaining = remaining
+		metrics.ProviderRateLimitRemaining.WithLabelValues(host).Set(float64(remaining))
+	}
+	if reset, ok := headerInt(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset"); ok {
+		q.reset = time.Unix(int64(reset), 0)
+		metrics.ProviderRateLimitReset.WithLabelValues(host).Set(float64(reset))
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && !(resp.StatusCode == http.StatusForbidden && q.remaining == 0) {
+		return
+	}
+	// lets back off until the host allows requests again
+	until := l.now().Add(defaultRetryAfter)
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			until = l.now().Add(time.Duration(seconds) * time.Second)
+		} else if t, err := http.ParseTime(retryAfter); err == nil {
+			until = t
+		}
+	} else if q.remaining == 0 && q.reset.After(l.now()) {
+		until = q.reset
+	}
+	q.blocked = until
+	log.Logger().Warnf("the rate limit of %s has been exceeded so deferring requests until %s", host, until.Format(time.RFC3339))
+}
+
+// headerInt returns the integer value of the first of the given headers which is present
+func headerInt(header http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		text := header.Get(name)
+		if text == "" {
+			continue
+		}
+		value, err := strconv.Atoi(text)
+		if err == nil {
+			return value, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package repo
+
+const (
+	// DefaultBranch the branch of a repository which is pulled if the repository does not specify one
+	DefaultBranch = "master"
+
+	// SecretKind the kind of the Secrets which declare repositories along with their credentials
+	SecretKind = "Secret"
+
+	// ConfigMapKind the kind of the ConfigMaps which declare repositories without any credentials
+	ConfigMapKind = "ConfigMap"
+
+	// CredentialsSecretAnnotation the annotation on a repository ConfigMap which references the Secret, in the same
+	// namespace, containing the `username` and `password` used to clone the repository
+	CredentialsSecretAnnotation = "git-operator.jenkins.io/credentials-secret"
+
+	// UpstreamURLAnnotation the annotation on a repository resource which specifies the canonical URL of the
+	// repository, such as the upstream of the internal mirror it is cloned from, to which commit statuses are reported
+	UpstreamURLAnnotation = "git-operator.jenkins.io/upstream-url"
+
+	// CredentialHelperAnnotation the annotation on a repository resource which disables the credential helper of the
+	// operator for the repository if set to `none`
+	CredentialHelperAnnotation = "git-operator.jenkins.io/credential-helper"
+
+	// OIDCAudienceAnnotation the annotation on a repository resource which specifies the audience of the short lived
+	// token the service account token of the operator is exchanged for to clone the repository, overriding that of the
+	// operator. Use `none` to clone without a federated token
+	OIDCAudienceAnnotation = "git-operator.jenkins.io/oidc-audience"
+
+	// OIDCScopeAnnotation the annotation on a repository resource which specifies the scope of the short lived token
+	// the service account token of the operator is exchanged for to clone the repository
+	OIDCScopeAnnotation = "git-operator.jenkins.io/oidc-scope"
+
+	// SSHProxyJumpAnnotation the annotation on a repository resource which specifies the ssh `ProxyJump` jump hosts,
+	// such as `git@bastion.example.com`, used to clone its ssh git URL overriding those of the operator. Use `none`
+	// to connect directly
+	SSHProxyJumpAnnotation = "git-operator.jenkins.io/ssh-proxy-jump"
+
+	// SSHProxyCommandAnnotation the annotation on a repository resource which specifies the ssh `ProxyCommand` used to
+	// clone its ssh git URL overriding that of the operator. Use `none` to connect directly
+	SSHProxyCommandAnnotation = "git-operator.jenkins.io/ssh-proxy-command"
+
+	// SSHKnownHostsAnnotation the annotation on a repository resource which specifies the additional known hosts
+	// lines, such as those of its jump hosts, trusted when cloning its ssh git URL
+	SSHKnownHostsAnnotation = "git-operator.jenkins.io/ssh-known-hosts"
+
+	// UpstreamTokenKey the optional key of the token used to report commit statuses to the upstream URL in the
+	// repository Secret or the credentials Secret of a repository ConfigMap
+	UpstreamTokenKey = "upstreamToken"
+
+	// BranchAnnotation the annotation on a repository Secret which specifies the branch which is pulled
+	BranchAnnotation = "git-operator.jenkins.io/branch"
+
+	// TenantLabel the label on a repository Secret which specifies the tenant the repository belongs to
+	TenantLabel = "git-operator.jenkins.io/tenant"
+
+	// GroupLabel the label on a repository Secret which specifies the group the repository belongs to such as `prod`
+	// so that separate operators can process different groups of repositories
+	GroupLabel = "git-operator.jenkins.io/group"
+
+	// KubeConfigSecretAnnotation the annotation on a repository Secret which references a comma separated list of
+	// Secrets containing the kubeconfig of the remote clusters to launch the Job in
+	KubeConfigSecretAnnotation = "git-operator.jenkins.io/kubeconfig-secret"
+
+	// ManagedClusterAnnotation the annotation on a repository Secret which specifies the Open Cluster Management
+	// `ManagedCluster` to launch in when using the `manifestwork` launcher
+	ManagedClusterAnnotation = "git-operator.jenkins.io/managed-cluster"
+
+	// PriorityAnnotation the annotation on a repository Secret which specifies the integer priority of the repository
+	PriorityAnnotation = "git-operator.jenkins.io/priority"
+
+	// ImagePullSecretsAnnotation the annotation on a repository Secret which overrides the comma separated image pull
+	// secrets injected into its Jobs by the operator. A value of `none` disables injecting image pull secrets
+	ImagePullSecretsAnnotation = "git-operator.jenkins.io/image-pull-secrets"
+
+	// RegistryMirrorsAnnotation the annotation on a repository Secret which overrides the comma separated
+	// `registry=mirror` registry mirrors of its Jobs. A value of `none` disables the registry mirrors
+	RegistryMirrorsAnnotation = "git-operator.jenkins.io/registry-mirrors"
+
+	// NoneValue the value of an annotation which disables a setting of the operator for the repository
+	NoneValue = "none"
+
+	// CleanupFinalizer the finalizer added to repositories so that the operator can clean up the resources of a
+	// repository before it is deleted
+	CleanupFinalizer = "git-operator.jenkins.io/cleanup"
+
+	// OwnerAnnotation the annotation on repositories and launched resources which identifies the operator instance
+	// which manages them
+	OwnerAnnotation = "git-operator.jenkins.io/owner"
+
+	// CascadeDeleteAnnotation the annotation on a repository Secret which adds an owner reference to the Secret on
+	// the resources launched in its namespace if set to `true` so that deleting the Secret deletes its Jobs too
+	CascadeDeleteAnnotation = "git-operator.jenkins.io/cascade-delete"
+
+	// PausedAnnotation the annotation on a repository Secret which pauses launching the repository if set to `true`
+	PausedAnnotation = "git-operator.jenkins.io/paused"
+
+	// TriggerAnnotation the annotation on a repository Secret which requests that the latest commit is launched
+	// again on the next poll. The operator removes the annotation once it has been handled
+	TriggerAnnotation = "git-operator.jenkins.io/trigger"
+
+	// CooldownAnnotation the annotation on a repository Secret which specifies the minimum duration between the
+	// launches of new commits of the repository such as `10m`, overriding the cool-down of the operator
+	CooldownAnnotation = "git-operator.jenkins.io/cooldown"
+
+	// PendingLaunchesAnnotation the annotation on a repository Secret which specifies which of its pending commits are
+	// launched: `newest` or `every`, overriding the pending launches mode of the operator
+	PendingLaunchesAnnotation = "git-operator.jenkins.io/pending-launches"
+
+	// PendingLimitAnnotation the annotation on a repository Secret which specifies the maximum number of its pending
+	// commits, overriding the limit of the operator. Use `none` for no limit
+	PendingLimitAnnotation = "git-operator.jenkins.io/pending-limit"
+
+	// RequireApprovalAnnotation the annotation on a repository Secret which creates its Jobs suspended until they
+	// are approved if set to `true`
+	RequireApprovalAnnotation = "git-operator.jenkins.io/require-approval"
+
+	// FoldersAnnotation the annotation on a repository Secret which specifies the comma separated globs of the
+	// folders of a monorepo, such as `envs/*`, which each contain a git operator folder and are launched independently
+	FoldersAnnotation = "git-operator.jenkins.io/folders"
+
+	// JobNamePrefixAnnotation the annotation on a repository Secret which specifies the prefix of the names of the
+	// resources launched for the repository such as `team-a`
+	JobNamePrefixAnnotation = "git-operator.jenkins.io/job-name-prefix"
+
+	// JobLabelsAnnotation the annotation on a repository Secret which specifies the comma separated `key=value`
+	// labels added to the resources launched for the repository such as `cost-center=1234,team=platform`
+	JobLabelsAnnotation = "git-operator.jenkins.io/job-labels"
+
+	// JobAnnotationsAnnotation the annotation on a repository Secret which specifies the comma separated `key=value`
+	// annotations added to the resources launched for the repository
+	JobAnnotationsAnnotation = "git-operator.jenkins.io/job-annotations"
+
+	// ParameterAnnotationPrefix the prefix of the annotations on a repository Secret which specify the parameters
+	// of the repository, such as `param.git-operator.jenkins.io/environment: production`, which are available to
+	// launchers
+	ParameterAnnotationPrefix = "param.git-operator.jenkins.io/"
+)
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"net/url"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -29,3 +30,134 @@ func AddGitURLUserPassword(rawurl string, username string, password string) (str
 	u.User = url.UserPassword(username, password)
 	return u.String(), nil
 }
+
+// RedactGitURL returns the git URL without any user name and password so that it can be displayed
+func RedactGitURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return rawurl
+	}
+	u.User = nil
+	return u.String()
+}
+
+// GitURLKey returns the host and path of the git URL without any credentials, scheme, port or `.git` suffix in
+// lower case such as `github.com/myorg/myrepo` so that the http and ssh URLs of the same repository can be matched.
+// An empty string is returned if the URL cannot be parsed
+func GitURLKey(rawurl string) string {
+	rawurl = strings.TrimSpace(rawurl)
+	if rawurl == "" {
+		return ""
+	}
+	host := ""
+	path := ""
+	if !strings.Contains(rawurl, "://") {
+		// lets handle scp style ssh URLs such as git@github.com:myorg/myrepo.git
+		parts := strings.SplitN(rawurl, ":", 2)
+		if len(parts) != 2 {
+			return ""
+		}
+		host = parts[0]
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		path = parts[1]
+	} else {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return ""
+		}
+		host = u.Hostname()
+		path = u.Path
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return ""
+	}
+	return strings.ToLower(host + "/" + path)
+}
+
+// URLRewrite a rule which rewrites the git URLs starting with a prefix, such as `https://github.com/`, to start with
+// a replacement instead, such as `https://git.example.com/mirrors/github/`, like the `url.<base>.insteadOf`
+// configuration of git
+type URLRewrite struct {
+	// Prefix the prefix of the git URLs without any credentials which are rewritten
+	Prefix string
+
+	// Replacement the replacement of the prefix
+	Replacement string
+}
+
+// ParseURLRewrites parses the `prefix=replacement` URL rewrite rules such as
+// `https://github.com/=git@github.com:`
+func ParseURLRewrites(texts []string) ([]URLRewrite, error) {
+	var answer []URLRewrite
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		parts := strings.SplitN(text, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid git URL rewrite %s. Expected prefix=replacement such as https://github.com/=https://git.example.com/mirrors/github/", text)
+		}
+		answer = append(answer, URLRewrite{Prefix: parts[0], Replacement: parts[1]})
+	}
+	return answer, nil
+}
+
+// RewriteGitURL returns the git URL rewritten by the rule with the longest matching prefix or the URL itself if no
+// rule matches. Rules match the URL without any credentials; the credentials are kept if the rewritten URL is an
+// http URL and dropped otherwise, such as when rewriting to an ssh URL which uses an ssh key instead
+func RewriteGitURL(rawurl string, rules []URLRewrite) string {
+	if len(rules) == 0 {
+		return rawurl
+	}
+	redacted := RedactGitURL(rawurl)
+	var match *URLRewrite
+	for i := range rules {
+		rule := &rules[i]
+		if strings.HasPrefix(redacted, rule.Prefix) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = rule
+		}
+	}
+	if match == nil {
+		return rawurl
+	}
+	answer := match.Replacement + strings.TrimPrefix(redacted, match.Prefix)
+	if redacted == rawurl || !(strings.HasPrefix(answer, "https://") || strings.HasPrefix(answer, "http://")) {
+		return answer
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return answer
+	}
+	password, _ := u.User.Password()
+	withUser, err := AddGitURLUserPassword(answer, u.User.Username(), password)
+	if err != nil {
+		return answer
+	}
+	return withUser
+}
+
+// SSHHost returns the host of an ssh git URL such as `git@github.com:myorg/myrepo.git` or
+// `ssh://git@github.com:2222/myorg/myrepo.git` or an empty string if the URL is not an ssh URL
+func SSHHost(rawurl string) string {
+	rawurl = strings.TrimSpace(rawurl)
+	if !strings.Contains(rawurl, "://") {
+		parts := strings.SplitN(rawurl, ":", 2)
+		if len(parts) != 2 || strings.Contains(parts[0], "/") {
+			return ""
+		}
+		host := parts[0]
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		return host
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "ssh" && u.Scheme != "git+ssh") {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -5,3 +5,41 @@ type Interface interface {
 	// List lists the repositories enabled for the git operator
 	List() ([]Repository, error)
 }
+
+// Finalizer is implemented by repository clients which can add and remove finalizers on the repository resources
+type Finalizer interface {
+	// AddFinalizer adds the finalizer to the repository resource if it is not already present
+	AddFinalizer(r Repository, finalizer string) error
+
+	// RemoveFinalizer removes the finalizer from the repository resource if it is present
+	RemoveFinalizer(r Repository, finalizer string) error
+}
+
+// Pauser is implemented by repository clients which can pause and resume launching a repository
+type Pauser interface {
+	// SetPaused pauses or resumes launching the repository
+	SetPaused(r Repository, paused bool) error
+}
+
+// Triggerer is implemented by repository clients which can request that the latest commit of a repository is
+// launched again
+type Triggerer interface {
+	// SetTriggered requests or acknowledges the launch of the latest commit of the repository
+	SetTriggered(r Repository, triggered bool) error
+}
+
+// Recorder is implemented by repository clients which can record warning events on the repository resources
+type Recorder interface {
+	// RecordWarning records a warning event with the given reason and message from the given source component on
+	// the repository resource
+	RecordWarning(r Repository, source string, reason string, message string) error
+}
+
+// Claimer is implemented by repository clients which can record the operator instance which manages a repository
+type Claimer interface {
+	// Claim records the given owner on the repository resource
+	Claim(r Repository, owner string) error
+
+	// ReportConflict reports that the repository is managed by a different operator instance than the given owner
+	ReportConflict(r Repository, owner string, message string) error
+}
@@ -0,0 +1,61 @@
+package secret
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// listConfigMaps lists the repositories declared by the ConfigMaps matching the selector. The credentials of a
+// repository are read from the optional Secret referenced by its credentials Secret annotation so that only the
+// credentials need to be kept in Secrets
+func (c *client) listConfigMaps() ([]repo.Repository, error) {
+	list, err := c.kubeClient.CoreV1().ConfigMaps(c.ns).List(metav1.ListOptions{
+		LabelSelector: c.selector,
+	})
+	if err != nil && apierrors.IsNotFound(err) {
+		err = nil
+	}
+	if err != nil && apierrors.IsForbidden(err) {
+		log.Logger().Warnf("ignoring repository ConfigMaps as the operator cannot list ConfigMaps in namespace %s: %s", c.ns, err.Error())
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find ConfigMaps in namespace %s with selector %s", c.ns, c.selector)
+	}
+
+	var answer []repo.Repository
+	for _, cm := range list.Items {
+		rawurl := cm.Data["url"]
+		if rawurl == "" {
+			continue
+		}
+		ns := cm.Namespace
+		if ns == "" {
+			ns = c.ns
+		}
+		username := ""
+		password := ""
+		upstreamToken := ""
+		if secretName := cm.Annotations[repo.CredentialsSecretAnnotation]; secretName != "" {
+			s, err := c.kubeClient.CoreV1().Secrets(ns).Get(secretName, metav1.GetOptions{})
+			if err != nil {
+				log.Logger().Warnf("ignoring repository ConfigMap %s in namespace %s as its credentials Secret %s could not be loaded: %s", cm.Name, ns, secretName, err.Error())
+				continue
+			}
+			username = string(s.Data["username"])
+			password = string(s.Data["password"])
+			upstreamToken = string(s.Data[repo.UpstreamTokenKey])
+		}
+		r, err := c.toRepository(repo.ConfigMapKind, cm.ObjectMeta, rawurl, username, password, upstreamToken)
+		if err != nil {
+			return answer, errors.Wrapf(err, "failed to create repo.Repository")
+		}
+		r.Kind = repo.ConfigMapKind
+		r.CredentialsSecret = cm.Annotations[repo.CredentialsSecretAnnotation]
+		answer = append(answer, r)
+	}
+	return answer, nil
+}
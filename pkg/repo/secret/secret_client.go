@@ -1,8 +1,15 @@
 package secret
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
 	"github.com/jenkins-x/jx-kube-client/pkg/kubeclient"
+	"github.com/jenkins-x/jx-logging/pkg/log"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -58,7 +65,10 @@ func (c *client) List() ([]repo.Repository, error) {
 
 	var answer []repo.Repository
 	for _, s := range list.Items {
-		r, err := c.toRepository(&s)
+		if s.Data == nil {
+			s.Data = map[string][]byte{}
+		}
+		r, err := c.toRepository(repo.SecretKind, s.ObjectMeta, string(s.Data["url"]), string(s.Data["username"]), string(s.Data["password"]), string(s.Data[repo.UpstreamTokenKey]))
 		if err != nil {
 			return answer, errors.Wrapf(err, "failed to create repo.Repository")
 		}
@@ -66,28 +76,363 @@ func (c *client) List() ([]repo.Repository, error) {
 			answer = append(answer, r)
 		}
 	}
-	return answer, nil
-}
 
-func (c *client) toRepository(s *v1.Secret) (repo.Repository, error) {
-	if s.Data == nil {
-		s.Data = map[string][]byte{}
+	configMapRepos, err := c.listConfigMaps()
+	if err != nil {
+		return answer, err
 	}
+	return append(answer, configMapRepos...), nil
+}
 
-	rawurl := string(s.Data["url"])
-	username := string(s.Data["username"])
-	password := string(s.Data["password"])
+// toRepository creates the repository of the Secret or ConfigMap with the given metadata, git URL and credentials
+func (c *client) toRepository(kind string, m metav1.ObjectMeta, rawurl string, username string, password string, upstreamToken string) (repo.Repository, error) {
 	gitURL, err := repo.AddGitURLUserPassword(rawurl, username, password)
 	if err != nil {
 		return repo.Repository{}, errors.Wrapf(err, "failed to create git URL from url %s username: %s password %s", rawurl, username, password)
 	}
-	ns := s.Namespace
+	upstreamURL := m.Annotations[repo.UpstreamURLAnnotation]
+	if upstreamURL != "" && upstreamToken != "" {
+		upstreamURL, err = repo.AddGitURLUserPassword(upstreamURL, "", upstreamToken)
+		if err != nil {
+			return repo.Repository{}, errors.Wrapf(err, "failed to add the upstream token to the upstream URL %s", upstreamURL)
+		}
+	}
+	ns := m.Namespace
 	if ns == "" {
 		ns = c.ns
 	}
+	priority := 0
+	if text := m.Annotations[repo.PriorityAnnotation]; text != "" {
+		priority, err = strconv.Atoi(text)
+		if err != nil {
+			log.Logger().Warnf("ignoring invalid %s annotation %s on %s %s in namespace %s", repo.PriorityAnnotation, text, kind, m.Name, ns)
+			priority = 0
+		}
+	}
+	var cooldown time.Duration
+	if text := m.Annotations[repo.CooldownAnnotation]; text != "" {
+		if text == repo.NoneValue {
+			cooldown = -1
+		} else {
+			cooldown, err = time.ParseDuration(text)
+			if err != nil {
+				log.Logger().Warnf("ignoring invalid %s annotation %s on %s %s in namespace %s", repo.CooldownAnnotation, text, kind, m.Name, ns)
+				cooldown = 0
+			}
+		}
+	}
+	pendingLimit := 0
+	if text := m.Annotations[repo.PendingLimitAnnotation]; text != "" {
+		if text == repo.NoneValue {
+			pendingLimit = -1
+		} else {
+			pendingLimit, err = strconv.Atoi(text)
+			if err != nil || pendingLimit <= 0 {
+				log.Logger().Warnf("ignoring invalid %s annotation %s on %s %s in namespace %s", repo.PendingLimitAnnotation, text, kind, m.Name, ns)
+				pendingLimit = 0
+			}
+		}
+	}
+	s := &m
 	return repo.Repository{
-		Name:      s.Name,
-		Namespace: ns,
-		GitURL:    gitURL,
+		Name:               s.Name,
+		Namespace:          ns,
+		GitURL:             gitURL,
+		UpstreamURL:        upstreamURL,
+		NoCredentialHelper: s.Annotations[repo.CredentialHelperAnnotation] == repo.NoneValue,
+		OIDCAudience:       s.Annotations[repo.OIDCAudienceAnnotation],
+		OIDCScope:          s.Annotations[repo.OIDCScopeAnnotation],
+		SSHProxyJump:       strings.TrimSpace(s.Annotations[repo.SSHProxyJumpAnnotation]),
+		SSHProxyCommand:    strings.TrimSpace(s.Annotations[repo.SSHProxyCommandAnnotation]),
+		SSHKnownHosts:      s.Annotations[repo.SSHKnownHostsAnnotation],
+		Branch:             s.Annotations[repo.BranchAnnotation],
+		KubeConfigSecrets:  splitList(s.Annotations[repo.KubeConfigSecretAnnotation]),
+		ManagedCluster:     s.Annotations[repo.ManagedClusterAnnotation],
+		Tenant:             s.Labels[repo.TenantLabel],
+		Group:              s.Labels[repo.GroupLabel],
+		Priority:           priority,
+		Cooldown:           cooldown,
+		PendingLaunches:    s.Annotations[repo.PendingLaunchesAnnotation],
+		PendingLimit:       pendingLimit,
+		RequireApproval:    s.Annotations[repo.RequireApprovalAnnotation] == "true",
+		Folders:            splitList(s.Annotations[repo.FoldersAnnotation]),
+		ImagePullSecrets:   overrideList(s.Annotations, repo.ImagePullSecretsAnnotation),
+		RegistryMirrors:    overrideList(s.Annotations, repo.RegistryMirrorsAnnotation),
+		Owner:              s.Annotations[repo.OwnerAnnotation],
+		Paused:             s.Annotations[repo.PausedAnnotation] == "true",
+		Triggered:          s.Annotations[repo.TriggerAnnotation] != "",
+		TriggerRequest:     s.Annotations[repo.TriggerAnnotation],
+		Parameters:         parameters(s.Annotations),
+		JobNamePrefix:      s.Annotations[repo.JobNamePrefixAnnotation],
+		JobLabels:          keyValues(kind, s, ns, repo.JobLabelsAnnotation),
+		JobAnnotations:     keyValues(kind, s, ns, repo.JobAnnotationsAnnotation),
+		OwnerReference:     ownerReference(kind, s),
+		CascadeDelete:      s.Annotations[repo.CascadeDeleteAnnotation] == "true",
+		Finalizers:         s.Finalizers,
+		Deleting:           s.DeletionTimestamp != nil,
+	}, nil
+}
+
+// ownerReference returns the owner reference of the repository Secret or ConfigMap or nil if it has not been
+// created yet
+func ownerReference(kind string, m *metav1.ObjectMeta) *metav1.OwnerReference {
+	if m.UID == "" {
+		return nil
+	}
+	return &metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       kind,
+		Name:       m.Name,
+		UID:        m.UID,
+	}
+}
+
+// parameters returns the parameters of the repository from the annotations with the parameter prefix or nil if
+// there are none
+func parameters(annotations map[string]string) map[string]string {
+	var answer map[string]string
+	for k, v := range annotations {
+		if strings.HasPrefix(k, repo.ParameterAnnotationPrefix) && len(k) > len(repo.ParameterAnnotationPrefix) {
+			if answer == nil {
+				answer = map[string]string{}
+			}
+			answer[strings.TrimPrefix(k, repo.ParameterAnnotationPrefix)] = v
+		}
+	}
+	return answer
+}
+
+// AddFinalizer adds the finalizer to the repository resource if it is not already present
+func (c *client) AddFinalizer(r repo.Repository, finalizer string) error {
+	return c.updateFinalizers(r, func(finalizers []string) []string {
+		if stringhelpers.StringArrayIndex(finalizers, finalizer) >= 0 {
+			return nil
+		}
+		return append(finalizers, finalizer)
+	})
+}
+
+// RemoveFinalizer removes the finalizer from the repository resource if it is present
+func (c *client) RemoveFinalizer(r repo.Repository, finalizer string) error {
+	return c.updateFinalizers(r, func(finalizers []string) []string {
+		if stringhelpers.StringArrayIndex(finalizers, finalizer) < 0 {
+			return nil
+		}
+		return stringhelpers.RemoveStringFromSlice(finalizers, finalizer)
+	})
+}
+
+// Claim records the owner in the annotations of the repository resource
+func (c *client) Claim(r repo.Repository, owner string) error {
+	res, err := c.getResource(r)
+	if err != nil {
+		return err
+	}
+	m := res.meta
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[repo.OwnerAnnotation] = owner
+	err = res.update()
+	if err != nil {
+		return errors.Wrapf(err, "failed to update the owner of %s %s in namespace %s", res.kind, m.Name, m.Namespace)
+	}
+	return nil
+}
+
+// SetPaused adds or removes the paused annotation on the repository resource
+func (c *client) SetPaused(r repo.Repository, paused bool) error {
+	value := ""
+	if paused {
+		value = "true"
+	}
+	return c.setAnnotation(r, repo.PausedAnnotation, value)
+}
+
+// SetTriggered adds the trigger annotation with the current time on the repository resource or removes it
+func (c *client) SetTriggered(r repo.Repository, triggered bool) error {
+	value := ""
+	if triggered {
+		value = time.Now().UTC().Format(time.RFC3339)
+	}
+	return c.setAnnotation(r, repo.TriggerAnnotation, value)
+}
+
+// setAnnotation sets the annotation on the repository resource or removes it if the value is blank
+func (c *client) setAnnotation(r repo.Repository, key string, value string) error {
+	res, err := c.getResource(r)
+	if err != nil {
+		return err
+	}
+	m := res.meta
+	if value != "" {
+		if m.Annotations == nil {
+			m.Annotations = map[string]string{}
+		}
+		m.Annotations[key] = value
+	} else {
+		if _, ok := m.Annotations[key]; !ok {
+			return nil
+		}
+		delete(m.Annotations, key)
+	}
+	err = res.update()
+	if err != nil {
+		return errors.Wrapf(err, "failed to update the %s annotation of %s %s in namespace %s", key, res.kind, m.Name, m.Namespace)
+	}
+	return nil
+}
+
+// ReportConflict creates a warning Event on the repository resource
+func (c *client) ReportConflict(r repo.Repository, owner string, message string) error {
+	return c.RecordWarning(r, owner, "OwnershipConflict", message)
+}
+
+// RecordWarning creates a warning Event with the given reason and message on the repository resource
+func (c *client) RecordWarning(r repo.Repository, source string, reason string, message string) error {
+	res, err := c.getResource(r)
+	if err != nil {
+		return err
+	}
+	s := res.meta
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", s.Name, now.UnixNano()),
+			Namespace: s.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion:      "v1",
+			Kind:            res.kind,
+			Name:            s.Name,
+			Namespace:       s.Namespace,
+			UID:             s.UID,
+			ResourceVersion: s.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: source},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err = c.kubeClient.CoreV1().Events(s.Namespace).Create(event)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create Event for %s %s in namespace %s", res.kind, s.Name, s.Namespace)
+	}
+	return nil
+}
+
+// resource the Secret or ConfigMap of a repository whose metadata can be modified and then updated
+type resource struct {
+	kind   string
+	meta   *metav1.ObjectMeta
+	update func() error
+}
+
+// getResource gets the Secret or ConfigMap of the repository
+func (c *client) getResource(r repo.Repository) (*resource, error) {
+	ns := r.Namespace
+	if ns == "" {
+		ns = c.ns
+	}
+	if r.Kind == repo.ConfigMapKind {
+		cm, err := c.kubeClient.CoreV1().ConfigMaps(ns).Get(r.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get ConfigMap %s in namespace %s", r.Name, ns)
+		}
+		if cm.Namespace == "" {
+			cm.Namespace = ns
+		}
+		return &resource{
+			kind: repo.ConfigMapKind,
+			meta: &cm.ObjectMeta,
+			update: func() error {
+				_, err := c.kubeClient.CoreV1().ConfigMaps(cm.Namespace).Update(cm)
+				return err
+			},
+		}, nil
+	}
+	s, err := c.kubeClient.CoreV1().Secrets(ns).Get(r.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get Secret %s in namespace %s", r.Name, ns)
+	}
+	if s.Namespace == "" {
+		s.Namespace = ns
+	}
+	return &resource{
+		kind: repo.SecretKind,
+		meta: &s.ObjectMeta,
+		update: func() error {
+			_, err := c.kubeClient.CoreV1().Secrets(s.Namespace).Update(s)
+			return err
+		},
 	}, nil
 }
+
+// updateFinalizers updates the finalizers of the repository resource if the function returns a non nil slice
+func (c *client) updateFinalizers(r repo.Repository, fn func([]string) []string) error {
+	res, err := c.getResource(r)
+	if err != nil {
+		return err
+	}
+	m := res.meta
+	finalizers := fn(m.Finalizers)
+	if finalizers == nil {
+		return nil
+	}
+	m.Finalizers = finalizers
+	err = res.update()
+	if err != nil {
+		return errors.Wrapf(err, "failed to update the finalizers of %s %s in namespace %s", res.kind, m.Name, m.Namespace)
+	}
+	return nil
+}
+
+// splitList splits the comma separated list ignoring any empty values
+func splitList(text string) []string {
+	var answer []string
+	for _, v := range strings.Split(text, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			answer = append(answer, v)
+		}
+	}
+	return answer
+}
+
+// keyValues returns the comma separated `key=value` pairs of the annotation of the repository resource or nil if it
+// is not specified. Invalid pairs are logged and ignored
+func keyValues(kind string, m *metav1.ObjectMeta, ns string, key string) map[string]string {
+	var answer map[string]string
+	for _, pair := range splitList(m.Annotations[key]) {
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			log.Logger().Warnf("ignoring invalid %s pair %s on %s %s in namespace %s", key, pair, kind, m.Name, ns)
+			continue
+		}
+		if answer == nil {
+			answer = map[string]string{}
+		}
+		answer[strings.TrimSpace(pair[0:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return answer
+}
+
+// overrideList returns the comma separated list of the annotation which overrides a setting of the operator.
+// Returns nil if the annotation is not specified or an empty list if it is `none`
+func overrideList(annotations map[string]string, key string) []string {
+	text, ok := annotations[key]
+	if !ok {
+		return nil
+	}
+	if strings.TrimSpace(text) == repo.NoneValue {
+		return []string{}
+	}
+	answer := splitList(text)
+	if answer == nil {
+		answer = []string{}
+	}
+	return answer
+}
@@ -1,7 +1,16 @@
 package repo
 
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // Repository represents a git repository to clone
 type Repository struct {
+	// Kind the kind of the resource declaring the repository: `Secret` or `ConfigMap`. Blank means `Secret`
+	Kind string
+
 	// Name name of the repository
 	Name string
 
@@ -10,4 +19,126 @@ type Repository struct {
 
 	// GitURL the URL to git clone the repository
 	GitURL string
+
+	// UpstreamURL the optional canonical URL of the repository, including any token, to which the commit statuses of
+	// its launches are reported such as the upstream of the internal mirror it is cloned from. Defaults to GitURL
+	UpstreamURL string
+
+	// NoCredentialHelper if true the credential helper of the operator does not supply the credentials of the
+	// repository
+	NoCredentialHelper bool
+
+	// OIDCAudience the optional audience of the federated token used to clone the repository which overrides that of
+	// the operator. The value `none` disables federated tokens for the repository
+	OIDCAudience string
+
+	// OIDCScope the optional scope of the federated token used to clone the repository
+	OIDCScope string
+
+	// SSHProxyJump the optional ssh `ProxyJump` jump hosts used to clone an ssh git URL which override those of the
+	// operator. The value `none` connects directly
+	SSHProxyJump string
+
+	// SSHProxyCommand the optional ssh `ProxyCommand` used to clone an ssh git URL which overrides that of the
+	// operator. The value `none` connects directly
+	SSHProxyCommand string
+
+	// SSHKnownHosts the optional additional known hosts lines, such as those of the jump hosts, trusted when cloning
+	// an ssh git URL
+	SSHKnownHosts string
+
+	// CredentialsSecret the optional name of the Secret containing the `username` and `password` of a repository
+	// declared by a ConfigMap. The credentials of a repository declared by a Secret are in the Secret itself
+	CredentialsSecret string
+
+	// Branch the optional branch which is pulled. If not specified the default branch is used
+	Branch string
+
+	// KubeConfigSecrets the optional names of Secrets in the namespace containing the kubeconfig of the
+	// remote clusters in which to launch the Job
+	KubeConfigSecrets []string
+
+	// ManagedCluster the optional name of the Open Cluster Management `ManagedCluster` to launch in
+	// when using the `manifestwork` launcher
+	ManagedCluster string
+
+	// Tenant the optional name of the tenant the repository belongs to
+	Tenant string
+
+	// Group the optional name of the group the repository belongs to such as `prod`
+	Group string
+
+	// Priority the priority of the repository. Repositories with a higher priority are polled and launched first
+	Priority int
+
+	// ImagePullSecrets the optional image pull secrets of the Jobs of the repository which override those of the
+	// operator. If nil the image pull secrets of the operator are used; if empty none are injected
+	ImagePullSecrets []string
+
+	// RegistryMirrors the optional registry mirrors of the Jobs of the repository which override those of the
+	// operator. If nil the registry mirrors of the operator are used; if empty none are used
+	RegistryMirrors []string
+
+	// Cooldown the optional minimum duration between the launches of new commits of the repository which overrides
+	// the cool-down of the operator. A negative duration disables the cool-down
+	Cooldown time.Duration
+
+	// PendingLaunches the optional mode of launching the pending commits of the repository, `newest` or `every`,
+	// which overrides the mode of the operator
+	PendingLaunches string
+
+	// PendingLimit the optional maximum number of pending commits of the repository which overrides the limit of the
+	// operator. A negative limit disables the limit
+	PendingLimit int
+
+	// RequireApproval true if the Jobs of the repository are created suspended until they are approved
+	RequireApproval bool
+
+	// Folders the optional globs of the folders of a monorepo, such as `envs/*`, which each contain a git operator
+	// folder and are launched independently of each other
+	Folders []string
+
+	// Folder the folder within the git repository of a launch unit discovered via the folder globs of its parent
+	Folder string
+
+	// Parent the name of the repository containing the folder of a launch unit
+	Parent string
+
+	// Parameters the optional parameters of the repository which are available to launchers
+	Parameters map[string]string
+
+	// JobNamePrefix the optional prefix of the names of the resources launched for the repository
+	JobNamePrefix string
+
+	// JobLabels the optional labels added to the resources launched for the repository
+	JobLabels map[string]string
+
+	// JobAnnotations the optional annotations added to the resources launched for the repository
+	JobAnnotations map[string]string
+
+	// OwnerReference the optional reference to the repository resource which owns the resources launched for the
+	// repository if CascadeDelete is enabled so that deleting the repository resource deletes them too
+	OwnerReference *metav1.OwnerReference
+
+	// CascadeDelete if enabled the resources launched for the repository are owned by the repository resource so that
+	// deleting the repository resource, even by accident, deletes its Jobs and the history they record
+	CascadeDelete bool
+
+	// Finalizers the finalizers of the repository resource
+	Finalizers []string
+
+	// Owner the optional identifier of the operator instance which manages the repository
+	Owner string
+
+	// Paused true if launching new commits of the repository has been paused
+	Paused bool
+
+	// Triggered true if the latest commit of the repository has been requested to be launched again
+	Triggered bool
+
+	// TriggerRequest the value of the trigger annotation identifying the request to launch the latest commit again
+	TriggerRequest string
+
+	// Deleting true if the repository resource is being deleted and is waiting for its finalizers to complete
+	Deleting bool
 }
@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// maxRecordLineSize the maximum size of a line of a recording which is loaded
+const maxRecordLineSize = 64 * 1024 * 1024
+
+// recordLock serialises the appending of records to record files
+var recordLock sync.Mutex
+
+// Record a command which was run, its redacted output and error
+type Record struct {
+	// Time when the command started
+	Time time.Time `json:"time"`
+
+	// Dir the directory the command was run in
+	Dir string `json:"dir,omitempty"`
+
+	// Name the name of the command such as `git`
+	Name string `json:"name"`
+
+	// Args the redacted arguments of the command
+	Args []string `json:"args,omitempty"`
+
+	// Env the redacted environment variables of the command
+	Env map[string]string `json:"env,omitempty"`
+
+	// Output the redacted output of the command
+	Output string `json:"output,omitempty"`
+
+	// Error the redacted error of the command if it failed
+	Error string `json:"error,omitempty"`
+
+	// ExitCode the exit code of the command if it failed
+	ExitCode int `json:"exitCode,omitempty"`
+
+	// DurationSeconds how long the command took
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// record appends the command to the record file if recording is enabled. Failures are logged rather than failing
+// the command
+func (o *Options) record(c *cmdrunner.Command, text string, err error, duration time.Duration) {
+	if o.RecordFile == "" {
+		return
+	}
+	r := Record{
+		Time:            time.Now().Add(-duration),
+		Dir:             c.Dir,
+		Name:            c.Name,
+		Output:          o.RedactText(text),
+		DurationSeconds: duration.Seconds(),
+	}
+	for _, arg := range c.Args {
+		r.Args = append(r.Args, o.RedactText(arg))
+	}
+	for k, v := range c.Env {
+		if r.Env == nil {
+			r.Env = map[string]string{}
+		}
+		r.Env[k] = o.RedactText(v)
+	}
+	if err != nil {
+		r.Error = o.RedactText(err.Error())
+		r.ExitCode = exitCode(err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		log.Logger().Warnf("failed to marshal the record of command %s: %s", c.Name, err.Error())
+		return
+	}
+
+	recordLock.Lock()
+	defer recordLock.Unlock()
+	f, err := os.OpenFile(o.RecordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, files.DefaultFileWritePermissions)
+	if err != nil {
+		log.Logger().Warnf("failed to open the command record file %s: %s", o.RecordFile, err.Error())
+		return
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		log.Logger().Warnf("failed to write to the command record file %s: %s", o.RecordFile, err.Error())
+	}
+}
+
+// Recording the recorded commands which can be replayed
+type Recording struct {
+	// Records the recorded commands in the order they were run
+	Records []Record
+
+	lock     sync.Mutex
+	replayed map[int]bool
+}
+
+// LoadRecording loads the recording of the commands in the given record file
+func LoadRecording(fileName string) (*Recording, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the command recording %s", fileName)
+	}
+	defer f.Close()
+
+	answer := &Recording{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		r := Record{}
+		err = json.Unmarshal([]byte(text), &r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse line %d of the command recording %s", line, fileName)
+		}
+		answer.Records = append(answer.Records, r)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the command recording %s", fileName)
+	}
+	return answer, nil
+}
+
+// Runner returns a runner which replays the output and error of the recorded commands rather than running them.
+//
+// Each command is matched by its name and arguments, ignoring the directory and environment, to the first recorded
+// command which has not been replayed yet or, once every match has been replayed, to the last match so that
+// commands run by every poll can be replayed any number of times
+func (r *Recording) Runner() cmdrunner.CommandRunner {
+	return func(c *cmdrunner.Command) (string, error) {
+		rec, found := r.take(c)
+		if !found {
+			return "", errors.Errorf("no recorded command matches '%s'", c.CLI())
+		}
+		if rec.Error != "" {
+			return rec.Output, &ReplayedError{Message: rec.Error, Code: rec.ExitCode}
+		}
+		return rec.Output, nil
+	}
+}
+
+// take returns the record to replay for the command
+func (r *Recording) take(c *cmdrunner.Command) (Record, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.replayed == nil {
+		r.replayed = map[int]bool{}
+	}
+	last := -1
+	for i, rec := range r.Records {
+		if rec.Name != c.Name || strings.Join(rec.Args, "\x00") != strings.Join(c.Args, "\x00") {
+			continue
+		}
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return rec, true
+		}
+		last = i
+	}
+	if last < 0 {
+		return Record{}, false
+	}
+	return r.Records[last], true
+}
+
+// ReplayedError the error of a replayed command
+type ReplayedError struct {
+	// Message the recorded error message
+	Message string
+
+	// Code the recorded exit code of the command
+	Code int
+}
+
+// Error returns the recorded error message
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+// ExitCode returns the recorded exit code of the command
+func (e *ReplayedError) ExitCode() int {
+	return e.Code
+}
+
+// exitCode returns the exit code of the failed command or zero if it is not known
+func exitCode(err error) int {
+	if coder, ok := errors.Cause(err).(interface{ ExitCode() int }); ok {
+		return coder.ExitCode()
+	}
+	return 0
+}
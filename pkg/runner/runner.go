@@ -0,0 +1,278 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// DefaultRetryBackoff the default backoff before the first retry of a failed command
+	DefaultRetryBackoff = time.Second
+
+	// redacted the replacement of redacted values
+	redacted = "*****"
+)
+
+// transientMessages the output of commands and messages of API errors which are likely to succeed if retried
+var transientMessages = []string{
+	"Could not resolve host",
+	"connection refused",
+	"connection reset by peer",
+	"Connection timed out",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"context deadline exceeded",
+	"Client.Timeout exceeded",
+	"the remote end hung up unexpectedly",
+	"early EOF",
+	"RPC failed",
+	"The requested URL returned error: 5",
+	"the object has been modified",
+	"etcdserver: request timed out",
+	"the server is currently unable to handle the request",
+	"Too many requests",
+	"failed calling webhook",
+}
+
+// urlCredentials matches the user info of URLs such as the credentials of git URLs
+var urlCredentials = regexp.MustCompile(`(://[^/@\s:]+:)[^/@\s]+@`)
+
+// Options the configuration of the runner of the git, kubectl and helm commands run by the operator
+type Options struct {
+	// Timeout the optional maximum duration of each command after which the command is killed
+	Timeout time.Duration `env:"COMMAND_TIMEOUT"`
+
+	// Timeouts the optional timeouts of commands by name such as `git:10m,kubectl:2m` which override Timeout
+	Timeouts map[string]time.Duration `env:"COMMAND_TIMEOUTS"`
+
+	// Retries the number of times a command which fails with a transient error, such as a connection failure or
+	// timeout, is retried. The launchers do not retry commands themselves so this is the only retry of commands
+	Retries int `env:"COMMAND_RETRIES,default=3"`
+
+	// RetryBackoff the backoff before the first retry which doubles for each retry. Defaults to 1s
+	RetryBackoff time.Duration `env:"COMMAND_RETRY_BACKOFF"`
+
+	// Retryable the optional function which returns true if a failed command should be retried. Defaults to
+	// IsTransient
+	Retryable func(c *cmdrunner.Command, output string, err error) bool
+
+	// Env the optional environment variables injected into every command such as `GIT_TRACE:1`. Variables set by
+	// the command take precedence
+	Env map[string]string `env:"COMMAND_ENV"`
+
+	// Redact the optional values, such as tokens, which are replaced by `*****` in the logs, errors and recordings
+	// of commands. The credentials of URLs are always redacted
+	Redact []string `env:"COMMAND_REDACT"`
+
+	// RecordFile the optional file which each command, its redacted output and error are appended to as JSON lines
+	// so that the commands can be inspected when debugging or replayed
+	RecordFile string `env:"COMMAND_RECORD_FILE"`
+
+	// ReplayFile the optional recording which the output and errors of commands are replayed from rather than
+	// running the commands
+	ReplayFile string `env:"COMMAND_REPLAY_FILE"`
+}
+
+// Wrap wraps the given runner, or Exec if nil, so that each command is run with the timeout, retries and
+// environment variables of the options and is logged at debug level along with its output at trace level,
+// redacted, which lets verbose command output be enabled at runtime by changing the log level
+func (o *Options) Wrap(runner cmdrunner.CommandRunner) (cmdrunner.CommandRunner, error) {
+	if o.ReplayFile != "" {
+		recording, err := LoadRecording(o.ReplayFile)
+		if err != nil {
+			return nil, err
+		}
+		runner = recording.Runner()
+	}
+	if runner == nil {
+		runner = Exec
+	}
+	return func(c *cmdrunner.Command) (string, error) {
+		for k, v := range o.Env {
+			if _, ok := c.Env[k]; !ok {
+				c.SetEnvVariable(k, v)
+			}
+		}
+		if timeout := o.timeout(c); timeout > 0 {
+			c.Timeout = timeout
+		}
+		cli := o.RedactText(c.CLI())
+		backoff := o.RetryBackoff
+		if backoff <= 0 {
+			backoff = DefaultRetryBackoff
+		}
+		for attempt := 0; ; attempt++ {
+			log.Logger().Debugf("running command: %s in dir %s", cli, c.Dir)
+			start := time.Now()
+			text, err := runner(c)
+			duration := time.Since(start)
+			log.Logger().Tracef("command %s returned:\n%s", cli, o.RedactText(text))
+			o.record(c, text, err, duration)
+			if err == nil {
+				return text, nil
+			}
+			if attempt >= o.Retries || !o.retryable(c, text, err) {
+				return text, &redactedError{err: err, message: o.RedactText(err.Error())}
+			}
+			log.Logger().Warnf("retrying command %s in %s after transient error: %s", cli, backoff.String(), o.RedactText(err.Error()))
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}, nil
+}
+
+// RedactText replaces the credentials of URLs and the values to redact in the given text
+func (o *Options) RedactText(text string) string {
+	text = urlCredentials.ReplaceAllString(text, "${1}"+redacted+"@")
+	for _, value := range o.Redact {
+		if value != "" {
+			text = strings.ReplaceAll(text, value, redacted)
+		}
+	}
+	return text
+}
+
+// timeout returns the timeout of the command or zero if it has no timeout
+func (o *Options) timeout(c *cmdrunner.Command) time.Duration {
+	if t, ok := o.Timeouts[c.Name]; ok {
+		return t
+	}
+	return o.Timeout
+}
+
+// retryable returns true if the failed command should be retried
+func (o *Options) retryable(c *cmdrunner.Command, text string, err error) bool {
+	if o.Retryable != nil {
+		return o.Retryable(c, text, err)
+	}
+	return IsTransient(text, err)
+}
+
+// IsTransient returns true if the command or API request failed with an error which is likely to succeed if retried
+// such as a connection failure, a timeout, a conflict or a webhook timeout. Other errors such as invalid resources or
+// forbidden requests are considered permanent. This is the only classifier of transient errors in the operator
+func IsTransient(text string, err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsTimeout(err) {
+		return true
+	}
+	cause := errors.Cause(err)
+	if _, ok := cause.(apierrors.APIStatus); ok {
+		if apierrors.IsConflict(cause) || apierrors.IsServerTimeout(cause) || apierrors.IsTimeout(cause) ||
+			apierrors.IsTooManyRequests(cause) || apierrors.IsServiceUnavailable(cause) || apierrors.IsInternalError(cause) {
+			return true
+		}
+		if apierrors.IsForbidden(cause) || apierrors.IsInvalid(cause) || apierrors.IsBadRequest(cause) ||
+			apierrors.IsUnauthorized(cause) || apierrors.IsNotFound(cause) || apierrors.IsAlreadyExists(cause) {
+			return false
+		}
+	}
+	message := text + "\n" + err.Error()
+	for _, m := range transientMessages {
+		if strings.Contains(message, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Exec runs the command killing it if it does not complete within its timeout. The output is returned in the same
+// way as cmdrunner.QuietCommandRunner
+func Exec(c *cmdrunner.Command) (string, error) {
+	return ExecContext(context.Background(), c)
+}
+
+// ExecContext runs the command in the same way as Exec but also kills it once the given context is done so that a
+// command which hangs can be cancelled
+func ExecContext(parent context.Context, c *cmdrunner.Command) (string, error) {
+	if c.Timeout <= 0 && parent.Done() == nil {
+		return c.RunWithoutRetry()
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, c.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
+
+	e := exec.CommandContext(ctx, c.Name, c.Args...) // #nosec
+	e.Dir = c.Dir
+	if len(c.Env) > 0 {
+		e.Env = os.Environ()
+		for k, v := range c.Env {
+			e.Env = append(e.Env, k+"="+v)
+		}
+	}
+	e.Stdin = c.In
+	var text string
+	var err error
+	if c.Out != nil {
+		e.Stdout = c.Out
+		e.Stderr = c.Err
+		err = e.Run()
+	} else {
+		var data []byte
+		data, err = e.CombinedOutput()
+		text = strings.TrimSpace(string(data))
+	}
+	if parent.Err() != nil {
+		return text, errors.Wrapf(parent.Err(), "cancelled command '%s' in directory '%s'", c.CLI(), c.Dir)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return text, &TimeoutError{Command: c.CLI(), Timeout: c.Timeout}
+	}
+	if err != nil {
+		return text, errors.Wrapf(err, "failed to run '%s' command in directory '%s', output: '%s'", c.CLI(), c.Dir, text)
+	}
+	return text, nil
+}
+
+// TimeoutError the error of a command which was killed as it did not complete within its timeout
+type TimeoutError struct {
+	// Command the command line
+	Command string
+
+	// Timeout the timeout of the command
+	Timeout time.Duration
+}
+
+// Error returns the error message
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command '%s' timed out after %s", e.Command, e.Timeout.String())
+}
+
+// IsTimeout returns true if the error is caused by a command timing out
+func IsTimeout(err error) bool {
+	_, ok := errors.Cause(err).(*TimeoutError)
+	return ok
+}
+
+// redactedError an error whose message is redacted which keeps the original error as its cause
+type redactedError struct {
+	err     error
+	message string
+}
+
+// Error returns the redacted error message
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Cause returns the original error
+func (e *redactedError) Cause() error {
+	return e.err
+}
@@ -0,0 +1,93 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TimestampHeader the header of a signed request containing the Unix time in seconds when it was signed
+	TimestampHeader = "X-Signature-Timestamp"
+
+	// RequestHeader the header of the HMAC signature of a signed request
+	RequestHeader = "X-Hub-Signature-256"
+)
+
+// headers the headers which may contain the HMAC signature of a payload in order of preference
+var headers = []struct {
+	name   string
+	prefix string
+	hash   func() hash.Hash
+}{
+	{name: "X-Hub-Signature-256", prefix: "sha256=", hash: sha256.New},
+	{name: "X-Hub-Signature", prefix: "sha1=", hash: sha1.New},
+}
+
+// Valid returns true if the `X-Hub-Signature-256` or `X-Hub-Signature` header is the HMAC of the payload signed
+// with the given token
+func Valid(token []byte, payload []byte, header http.Header) bool {
+	if len(token) == 0 {
+		return false
+	}
+	for _, h := range headers {
+		value := header.Get(h.name)
+		if value == "" {
+			continue
+		}
+		signature, err := hex.DecodeString(strings.TrimPrefix(value, h.prefix))
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(h.hash, token)
+		_, _ = mac.Write(payload)
+		return hmac.Equal(signature, mac.Sum(nil))
+	}
+	return false
+}
+
+// Sign returns the value of the `X-Hub-Signature-256` header of the payload signed with the given token
+func Sign(token []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, token)
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidRequest returns true if the `X-Hub-Signature-256` header is the HMAC, signed with the given token, of the
+// method, path, `X-Signature-Timestamp` header and payload of the request and the timestamp is within the given
+// maximum age of now. Unlike Valid the signature cannot be replayed against another path or after it has expired
+func ValidRequest(token []byte, r *http.Request, payload []byte, maxAge time.Duration) bool {
+	if len(token) == 0 {
+		return false
+	}
+	timestamp := r.Header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	if age > maxAge || age < -maxAge {
+		return false
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(RequestHeader), "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+	expected, _ := hex.DecodeString(strings.TrimPrefix(SignRequest(token, r.Method, r.URL.Path, timestamp, payload), "sha256="))
+	return hmac.Equal(signature, expected)
+}
+
+// SignRequest returns the value of the `X-Hub-Signature-256` header of a request with the given method, path,
+// `X-Signature-Timestamp` header and payload signed with the given token
+func SignRequest(token []byte, method string, path string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, token)
+	_, _ = mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n"))
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+const (
+	// StatusPath the path prefix of the JSON status endpoint
+	StatusPath = "/status/"
+
+	// BadgePath the path prefix of the SVG badge endpoint
+	BadgePath = "/badge/"
+
+	// badgeLabel the label on the left of the badges
+	badgeLabel = "boot"
+
+	// badgeCharWidth the approximate width in pixels of a character in the badge font
+	badgeCharWidth = 7
+)
+
+// badgeColors the colors of the badges of each result
+var badgeColors = map[string]string{
+	ResultLaunched:    "#007ec6",
+	ResultUpToDate:    "#4c1",
+	ResultFailed:      "#e05d44",
+	ResultPaused:      "#dfb317",
+	ResultCoolingDown: "#dfb317",
+	ResultBlocked:     "#dfb317",
+	ResultUnknown:     "#9f9f9f",
+}
+
+// StatusHandler returns the HTTP handler which serves the status of all the repositories as JSON at `/status/` and
+// the status of a single repository at `/status/<namespace>/<name>`. As the endpoint is not authenticated the
+// messages, such as the errors of failed polls, are passed through the optional redact function
+func (s *Store) StatusHandler(redact func(text string) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(StatusPath, "/")), "/")
+		if path == "" {
+			repositories := s.List()
+			for i := range repositories {
+				repositories[i].redact(redact)
+			}
+			writeJSON(w, repositories)
+			return
+		}
+		ns, name, ok := splitRepositoryPath(path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		status, found := s.Get(ns, name)
+		if !found {
+			http.Error(w, fmt.Sprintf("repository %s not found in namespace %s", name, ns), http.StatusNotFound)
+			return
+		}
+		status.redact(redact)
+		writeJSON(w, status)
+	})
+}
+
+// BadgeHandler returns the HTTP handler which serves an SVG badge of the result of the last poll of a repository
+// at `/badge/<namespace>/<name>.svg`. An `unknown` badge is returned for repositories which have not been polled
+// so that badges embedded in documentation always render
+func (s *Store) BadgeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, BadgePath), ".svg")
+		ns, name, ok := splitRepositoryPath(path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		result := ResultUnknown
+		status, found := s.Get(ns, name)
+		if found {
+			result = status.Result
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-cache, max-age=0")
+		_, err := w.Write([]byte(Badge(badgeLabel, result, badgeColors[result])))
+		if err != nil {
+			log.Logger().Warnf("failed to write badge of repository %s in namespace %s: %s", name, ns, err.Error())
+		}
+	})
+}
+
+// Badge returns the SVG of a badge with the given label, message and color
+func Badge(label string, message string, color string) string {
+	if color == "" {
+		color = badgeColors[ResultUnknown]
+	}
+	labelWidth := len(label)*badgeCharWidth + 10
+	messageWidth := len(message)*badgeCharWidth + 10
+	width := labelWidth + messageWidth
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+<title>%s: %s</title>
+<rect width="%d" height="20" fill="#555"/>
+<rect x="%d" width="%d" height="20" fill="%s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%d" y="14">%s</text>
+<text x="%d" y="14">%s</text>
+</g>
+</svg>
+`, width, label, message, label, message, labelWidth, labelWidth, messageWidth, color, labelWidth/2, label, labelWidth+messageWidth/2, message)
+}
+
+// splitRepositoryPath splits a `<namespace>/<name>` path
+func splitRepositoryPath(path string) (string, string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Logger().Warnf("failed to write JSON response: %s", err.Error())
+	}
+}
@@ -0,0 +1,600 @@
+package status
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+)
+
+const (
+	// ResultLaunched the latest commit of the repository was launched by the last poll
+	ResultLaunched = "launched"
+
+	// ResultUpToDate the latest commit of the repository has already been launched or is waiting for a previous
+	// launch to complete
+	ResultUpToDate = "up-to-date"
+
+	// ResultFailed the last poll of the repository failed
+	ResultFailed = "failed"
+
+	// ResultPaused launching the repository has been paused
+	ResultPaused = "paused"
+
+	// ResultCoolingDown the launch of the latest commit of the repository is deferred until the cool-down since the
+	// previous launch has expired
+	ResultCoolingDown = "cooling-down"
+
+	// ResultBlocked the launch of the latest commit of the repository is blocked until the active Job of a previous
+	// commit completes
+	ResultBlocked = "blocked"
+
+	// ResultUnknown the repository has not been polled yet
+	ResultUnknown = "unknown"
+
+	// MaxSkipped the maximum number of skipped commits recorded in the status of each repository
+	MaxSkipped = 50
+
+	// MaxJobs the maximum number of recent Jobs recorded in the status of each repository
+	MaxJobs = 10
+
+	// RetryHealthy the last launch of the repository did not fail
+	RetryHealthy = "healthy"
+
+	// RetryRetrying the active Job of the repository has failed pods and is being retried
+	RetryRetrying = "retrying"
+
+	// RetryFailing the last launch of the repository failed and will not be retried until a new commit or trigger
+	RetryFailing = "failing"
+
+	// ConditionStaleBoot the condition which is `True` if the repository has not booted successfully within the
+	// staleness threshold
+	ConditionStaleBoot = "StaleBoot"
+
+	// ConditionTrue the status of a condition which holds
+	ConditionTrue = "True"
+
+	// ConditionFalse the status of a condition which does not hold
+	ConditionFalse = "False"
+)
+
+// Repository the status of the last poll of a repository
+type Repository struct {
+	// Name the name of the repository
+	Name string `json:"name"`
+
+	// Namespace the namespace of the repository
+	Namespace string `json:"namespace"`
+
+	// Tenant the optional tenant of the repository
+	Tenant string `json:"tenant,omitempty"`
+
+	// Result the result of the last poll
+	Result string `json:"result"`
+
+	// Message the optional message such as the error of a failed poll
+	Message string `json:"message,omitempty"`
+
+	// GitSHA the latest commit sha found by the last successful poll
+	GitSHA string `json:"gitSha,omitempty"`
+
+	// LaunchedSHA the commit sha of the last launch
+	LaunchedSHA string `json:"launchedSha,omitempty"`
+
+	// LastPolled the time of the last poll
+	LastPolled time.Time `json:"lastPolled"`
+
+	// LastLaunched the optional time of the last launch
+	LastLaunched *time.Time `json:"lastLaunched,omitempty"`
+
+	// ActiveJob the optional name of the Job of the last launch while it is still running
+	ActiveJob string `json:"activeJob,omitempty"`
+
+	// LastSucceeded the optional time the last successful launch completed
+	LastSucceeded *time.Time `json:"lastSucceeded,omitempty"`
+
+	// Conditions the optional conditions of the repository such as `StaleBoot`
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// BootInventory the optional resources changed by the latest completed boot Job as reported by the Job
+	BootInventory *BootInventory `json:"bootInventory,omitempty"`
+
+	// FailureArtifacts the optional URL of the logs, events and manifest of the latest failed Job
+	FailureArtifacts string `json:"failureArtifacts,omitempty"`
+
+	// Retry the optional failures and retries of the recent launches of the repository
+	Retry *Retry `json:"retry,omitempty"`
+
+	// Rollback the optional latest rollback of the repository to its last successful commit
+	Rollback *Rollback `json:"rollback,omitempty"`
+
+	// Preflight the optional result of the latest pre-flight script of the repository
+	Preflight *Preflight `json:"preflight,omitempty"`
+
+	// Blocked the optional launch of the latest commit which is blocked by the active Job of a previous commit
+	Blocked *Blocked `json:"blocked,omitempty"`
+
+	// Pending the commit shas waiting to be launched once the active Job completes, with the oldest first
+	Pending []string `json:"pending,omitempty"`
+
+	// Skipped the most recent commits which were not launched individually as they were covered by the launch of a
+	// newer commit, with the oldest first
+	Skipped []SkippedCommit `json:"skipped,omitempty"`
+
+	// Jobs when the most recent Jobs of the repository started and completed, with the oldest first, so that cluster
+	// events and resource changes can be correlated with the boots
+	Jobs []JobWindow `json:"jobs,omitempty"`
+}
+
+// JobWindow the window of time during which a Job of a repository was running
+type JobWindow struct {
+	// Name the name of the Job
+	Name string `json:"name"`
+
+	// Namespace the namespace of the Job
+	Namespace string `json:"namespace"`
+
+	// GitSHA the commit sha launched by the Job
+	GitSHA string `json:"gitSha"`
+
+	// Started when the Job started
+	Started time.Time `json:"started"`
+
+	// Completed the optional time the Job completed
+	Completed *time.Time `json:"completed,omitempty"`
+
+	// Succeeded true if the Job completed successfully
+	Succeeded bool `json:"succeeded,omitempty"`
+}
+
+// SkippedCommit a commit of a repository which was not launched individually but was covered by the launch of a
+// newer commit
+type SkippedCommit struct {
+	// GitSHA the commit sha which was skipped
+	GitSHA string `json:"gitSha"`
+
+	// Subject the subject line of the commit message
+	Subject string `json:"subject,omitempty"`
+
+	// Reason why the commit was skipped: `batched`, `paused`, `cooling-down` or `active-job`
+	Reason string `json:"reason"`
+
+	// LaunchedBy the newer commit sha whose launch covered the skipped commit
+	LaunchedBy string `json:"launchedBy"`
+
+	// Time when the newer commit was launched
+	Time time.Time `json:"time"`
+}
+
+// Blocked a commit of a repository whose launch is blocked by the active Job of a previous commit. The commit is
+// launched as soon as the Job completes
+type Blocked struct {
+	// GitSHA the commit sha waiting to be launched
+	GitSHA string `json:"gitSha"`
+
+	// Job the name of the active Job blocking the launch
+	Job string `json:"job"`
+
+	// Namespace the namespace of the active Job
+	Namespace string `json:"namespace"`
+
+	// Since when the launch of the commit was first blocked
+	Since time.Time `json:"since"`
+}
+
+// Preflight the result of the pre-flight script which must succeed before a commit of a repository is launched
+type Preflight struct {
+	// Result the result of the check: `succeeded` or `failed`
+	Result string `json:"result"`
+
+	// GitSHA the commit sha which was checked
+	GitSHA string `json:"gitSha"`
+
+	// Message the optional output of a failed check
+	Message string `json:"message,omitempty"`
+
+	// Time when the check ran
+	Time time.Time `json:"time"`
+}
+
+// Rollback a launch of the last successful commit of a repository after the launch of a newer commit failed
+type Rollback struct {
+	// FailedSHA the commit sha which failed
+	FailedSHA string `json:"failedSha"`
+
+	// FailedJob the name of the failed Job
+	FailedJob string `json:"failedJob,omitempty"`
+
+	// GitSHA the last successful commit sha the repository was rolled back to
+	GitSHA string `json:"gitSha"`
+
+	// Time when the rollback was launched
+	Time time.Time `json:"time"`
+}
+
+// Retry the failures and retries of the recent launches of a repository so that a repository which is failing and
+// retrying can be distinguished from one which is healthy but idle
+type Retry struct {
+	// State the retry state: `healthy`, `retrying` or `failing`
+	State string `json:"state"`
+
+	// ConsecutiveFailures the number of consecutive failed launches
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+
+	// Attempts the number of failed pods of the active Job
+	Attempts int `json:"attempts,omitempty"`
+
+	// BackoffLimit the number of retries of the active Job before it fails
+	BackoffLimit int `json:"backoffLimit,omitempty"`
+
+	// Reason the reason of the last failure such as `OOMKilled` or `BackoffLimitExceeded`
+	Reason string `json:"reason,omitempty"`
+
+	// Backoff the current backoff before the active Job retries a failed pod
+	Backoff string `json:"backoff,omitempty"`
+
+	// NextRetry when the active Job is expected to retry a failed pod
+	NextRetry *time.Time `json:"nextRetry,omitempty"`
+}
+
+// BootInventory the resources a boot Job reported it changed so that cluster changes can be traced to a commit
+type BootInventory struct {
+	// Job the name of the boot Job
+	Job string `json:"job"`
+
+	// GitSHA the commit sha the Job booted
+	GitSHA string `json:"gitSha"`
+
+	// Resources the descriptions of the changed resources such as `Deployment foo in namespace jx`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// Condition a condition of a repository
+type Condition struct {
+	// Type the type of the condition such as `StaleBoot`
+	Type string `json:"type"`
+
+	// Status the status of the condition: `True` or `False`
+	Status string `json:"status"`
+
+	// Message the optional human readable details of the condition
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime when the status of the condition last changed
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// Condition returns the condition of the given type or nil if there is no such condition
+func (r *Repository) Condition(conditionType string) *Condition {
+	for i := range r.Conditions {
+		if r.Conditions[i].Type == conditionType {
+			return &r.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// Store the in memory status of the repositories which is safe to use from the poller and HTTP handlers concurrently
+type Store struct {
+	lock         sync.RWMutex
+	repositories map[string]*Repository
+}
+
+// NewStore creates a new empty status store
+func NewStore() *Store {
+	return &Store{
+		repositories: map[string]*Repository{},
+	}
+}
+
+// Record records the result of polling the repository at the given commit sha which may be empty if the poll failed
+// before the commit sha was found
+func (s *Store) Record(r repo.Repository, result string, gitSHA string, message string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.repository(r)
+	now := time.Now()
+	status.Name = r.Name
+	status.Namespace = r.Namespace
+	status.Tenant = r.Tenant
+	status.Result = result
+	status.Message = message
+	status.LastPolled = now
+	if gitSHA != "" {
+		status.GitSHA = gitSHA
+	}
+	if result == ResultLaunched {
+		status.LaunchedSHA = gitSHA
+		status.LastLaunched = &now
+	}
+}
+
+// Adopt records the last launch of the repository found when the operator starts so that the status reflects the
+// Jobs launched before the restart. The job is the name of the Job if it is still active
+func (s *Store) Adopt(r repo.Repository, gitSHA string, launched time.Time, job string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.repository(r)
+	status.LaunchedSHA = gitSHA
+	status.LastLaunched = &launched
+	status.ActiveJob = job
+}
+
+// SetActiveJob records the name of the running Job of the last launch of the repository
+func (s *Store) SetActiveJob(r repo.Repository, job string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).ActiveJob = job
+}
+
+// StartJob records the window of a Job of the repository, replacing any window of the same Job and keeping the most
+// recent MaxJobs windows
+func (s *Store) StartJob(r repo.Repository, window JobWindow) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.repository(r)
+	for i := range status.Jobs {
+		if status.Jobs[i].Name == window.Name && status.Jobs[i].Namespace == window.Namespace {
+			status.Jobs[i] = window
+			return
+		}
+	}
+	status.Jobs = append(status.Jobs, window)
+	if len(status.Jobs) > MaxJobs {
+		status.Jobs = append([]JobWindow(nil), status.Jobs[len(status.Jobs)-MaxJobs:]...)
+	}
+}
+
+// CompleteJob clears the active Job with the given namespace and name when it completes, recording when it
+// succeeded and the end of its window. Returns true if the Job was the active Job of a repository or was blocking
+// the launch of a commit
+func (s *Store) CompleteJob(ns string, job string, succeeded bool, completed time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	answer := false
+	for _, status := range s.repositories {
+		for i := range status.Jobs {
+			w := &status.Jobs[i]
+			if w.Name == job && w.Namespace == ns && w.Completed == nil {
+				t := completed
+				w.Completed = &t
+				w.Succeeded = succeeded
+			}
+		}
+		if status.Blocked != nil && status.Blocked.Job == job && status.Blocked.Namespace == ns {
+			answer = true
+		}
+		if status.ActiveJob != job || status.Namespace != ns {
+			continue
+		}
+		status.ActiveJob = ""
+		if succeeded {
+			status.LastSucceeded = &completed
+		}
+		answer = true
+	}
+	return answer
+}
+
+// SetLastSucceeded records when the last successful launch of the repository completed
+func (s *Store) SetLastSucceeded(r repo.Repository, t time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.repository(r)
+	status.LastSucceeded = &t
+}
+
+// SetBlocked records that the launch of a commit of the repository is blocked by an active Job, keeping when the
+// launch was first blocked if the same commit is still blocked. A nil value clears the blocked launch
+func (s *Store) SetBlocked(r repo.Repository, blocked *Blocked) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.repository(r)
+	if blocked != nil && status.Blocked != nil && status.Blocked.GitSHA == blocked.GitSHA {
+		blocked.Since = status.Blocked.Since
+	}
+	status.Blocked = blocked
+}
+
+// SetPending records the commit shas of the repository waiting to be launched, with the oldest first
+func (s *Store) SetPending(r repo.Repository, shas []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).Pending = append([]string(nil), shas...)
+}
+
+// SetBootInventory records the resources changed by the latest completed boot Job of the repository
+func (s *Store) SetBootInventory(r repo.Repository, inventory BootInventory) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).BootInventory = &inventory
+}
+
+// SetFailureArtifacts records the URL of the uploaded artifacts of the latest failed Job of the repository
+func (s *Store) SetFailureArtifacts(r repo.Repository, u string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).FailureArtifacts = u
+}
+
+// SetRetry records the failures and retries of the recent launches of the repository
+func (s *Store) SetRetry(r repo.Repository, retry Retry) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).Retry = &retry
+}
+
+// SetRollback records the latest rollback of the repository to its last successful commit
+func (s *Store) SetRollback(r repo.Repository, rollback Rollback) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).Rollback = &rollback
+}
+
+// SetPreflight records the result of the latest pre-flight script of the repository
+func (s *Store) SetPreflight(r repo.Repository, preflight Preflight) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.repository(r).Preflight = &preflight
+}
+
+// AddSkipped records the commits of the repository which were skipped, keeping the most recent MaxSkipped commits
+func (s *Store) AddSkipped(r repo.Repository, commits ...SkippedCommit) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := s.repository(r)
+	status.Skipped = append(status.Skipped, commits...)
+	if len(status.Skipped) > MaxSkipped {
+		status.Skipped = append([]SkippedCommit(nil), status.Skipped[len(status.Skipped)-MaxSkipped:]...)
+	}
+}
+
+// SetCondition sets the condition of the repository returning true if its status changed
+func (s *Store) SetCondition(r repo.Repository, conditionType string, value bool, message string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	conditionStatus := ConditionFalse
+	if value {
+		conditionStatus = ConditionTrue
+	}
+	status := s.repository(r)
+	c := status.Condition(conditionType)
+	if c == nil {
+		status.Conditions = append(status.Conditions, Condition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			Message:            message,
+			LastTransitionTime: time.Now(),
+		})
+		return true
+	}
+	c.Message = message
+	if c.Status == conditionStatus {
+		return false
+	}
+	c.Status = conditionStatus
+	c.LastTransitionTime = time.Now()
+	return true
+}
+
+// repository returns the status of the repository creating it if required. The lock must be held
+func (s *Store) repository(r repo.Repository) *Repository {
+	key := r.Namespace + "/" + r.Name
+	status := s.repositories[key]
+	if status == nil {
+		status = &Repository{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Tenant:    r.Tenant,
+			Result:    ResultUnknown,
+		}
+		s.repositories[key] = status
+	}
+	return status
+}
+
+// clone returns a copy of the status which does not share its conditions
+func (r *Repository) clone() Repository {
+	answer := *r
+	answer.Conditions = append([]Condition(nil), r.Conditions...)
+	answer.Skipped = append([]SkippedCommit(nil), r.Skipped...)
+	answer.Pending = append([]string(nil), r.Pending...)
+	answer.Jobs = nil
+	for _, w := range r.Jobs {
+		if w.Completed != nil {
+			completed := *w.Completed
+			w.Completed = &completed
+		}
+		answer.Jobs = append(answer.Jobs, w)
+	}
+	if r.BootInventory != nil {
+		inventory := *r.BootInventory
+		inventory.Resources = append([]string(nil), r.BootInventory.Resources...)
+		answer.BootInventory = &inventory
+	}
+	if r.Retry != nil {
+		retry := *r.Retry
+		answer.Retry = &retry
+	}
+	if r.Rollback != nil {
+		rollback := *r.Rollback
+		answer.Rollback = &rollback
+	}
+	if r.Preflight != nil {
+		preflight := *r.Preflight
+		answer.Preflight = &preflight
+	}
+	if r.Blocked != nil {
+		blocked := *r.Blocked
+		answer.Blocked = &blocked
+	}
+	return answer
+}
+
+// redact passes the messages of a copy of the status through the given redact function, if any
+func (r *Repository) redact(redact func(text string) string) {
+	if redact == nil {
+		return
+	}
+	r.Message = redact(r.Message)
+	for i := range r.Conditions {
+		r.Conditions[i].Message = redact(r.Conditions[i].Message)
+	}
+	if r.Preflight != nil {
+		r.Preflight.Message = redact(r.Preflight.Message)
+	}
+}
+
+// Remove removes the status of a repository which has been deleted
+func (s *Store) Remove(r repo.Repository) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.repositories, r.Namespace+"/"+r.Name)
+}
+
+// Get returns a copy of the status of the given repository or false if it has not been polled
+func (s *Store) Get(ns string, name string) (Repository, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	status := s.repositories[ns+"/"+name]
+	if status == nil {
+		return Repository{}, false
+	}
+	return status.clone(), true
+}
+
+// List returns a copy of the status of all the repositories sorted by namespace and name
+func (s *Store) List() []Repository {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	answer := make([]Repository, 0, len(s.repositories))
+	for _, status := range s.repositories {
+		answer = append(answer, status.clone())
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		if answer[i].Namespace != answer[j].Namespace {
+			return answer[i].Namespace < answer[j].Namespace
+		}
+		return answer[i].Name < answer[j].Name
+	})
+	return answer
+}
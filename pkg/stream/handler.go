@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/pkg/log"
+)
+
+const (
+	// Path the path of the Server-Sent Events endpoint
+	Path = "/events"
+
+	// heartbeatInterval how often a comment is sent to keep idle connections open
+	heartbeatInterval = 30 * time.Second
+)
+
+// Handler returns the HTTP handler which streams the events as Server-Sent Events.
+//
+// The events can be filtered to a single repository via the `namespace` and `repository` query parameters. As the
+// endpoint is not authenticated the messages, such as the errors of failed polls, are passed through the optional
+// redact function
+func (b *Broker) Handler(redact func(text string) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		namespace := r.URL.Query().Get("namespace")
+		repository := r.URL.Query().Get("repository")
+
+		ch := b.Subscribe()
+		defer b.Unsubscribe(ch)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				_, err := fmt.Fprint(w, ": heartbeat\n\n")
+				if err != nil {
+					return
+				}
+			case e := <-ch:
+				if (namespace != "" && e.Namespace != namespace) || (repository != "" && e.Repository != repository) {
+					continue
+				}
+				if redact != nil {
+					e.Message = redact(e.Message)
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					log.Logger().Warnf("failed to marshal event %s: %s", e.Type, err.Error())
+					continue
+				}
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, string(data))
+				if err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/constants"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher"
+	"github.com/jenkins-x/jx-git-operator/pkg/launcher/job"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	v1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rewatchDelay the delay before watching the Jobs again if the watch fails or is closed
+const rewatchDelay = 5 * time.Second
+
+// WatchJobs publishes the lifecycle events of the Jobs launched by the operator in the given namespace (or all
+// namespaces if blank) until the context is done
+func (b *Broker) WatchJobs(ctx context.Context, kubeClient kubernetes.Interface, ns string) {
+	results := map[string]string{}
+	for {
+		w, err := kubeClient.BatchV1().Jobs(ns).Watch(metav1.ListOptions{
+			LabelSelector: constants.DefaultSelector,
+		})
+		if err != nil {
+			log.Logger().Warnf("failed to watch Jobs in namespace %s: %s", ns, err.Error())
+		} else {
+			b.publishJobEvents(ctx, w, results)
+			w.Stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
+
+// publishJobEvents publishes the events of the watch until the watch is closed or the context is done
+func (b *Broker) publishJobEvents(ctx context.Context, w watch.Interface, results map[string]string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case we, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			j, ok := we.Object.(*v1.Job)
+			if !ok {
+				continue
+			}
+			key := j.Namespace + "/" + j.Name
+			eventType := ""
+			switch we.Type {
+			case watch.Deleted:
+				delete(results, key)
+				eventType = EventJobDeleted
+			case watch.Added, watch.Modified:
+				result := job.JobResult(*j)
+				previous, found := results[key]
+				results[key] = result
+				if found && previous == result {
+					continue
+				}
+				switch result {
+				case launcher.ResultSucceeded:
+					eventType = EventJobSucceeded
+				case launcher.ResultFailed:
+					eventType = EventJobFailed
+				default:
+					eventType = EventJobCreated
+				}
+			default:
+				continue
+			}
+			b.Publish(Event{
+				Type:       eventType,
+				Repository: j.Labels[launcher.RepositoryLabelKey],
+				Namespace:  j.Namespace,
+				GitSHA:     j.Labels[launcher.CommitShaLabelKey],
+				Job:        j.Name,
+				Time:       time.Now(),
+			})
+		}
+	}
+}
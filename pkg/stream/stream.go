@@ -0,0 +1,150 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+)
+
+const (
+	// EventLaunched resources were launched for a new commit of a repository
+	EventLaunched = "launched"
+
+	// EventPollFailed the poll of a repository failed
+	EventPollFailed = "poll-failed"
+
+	// EventDrifted the applied resources of a repository have drifted from the last launched commit
+	EventDrifted = "drifted"
+
+	// EventCleanedUp the resources of a deleted repository were cleaned up
+	EventCleanedUp = "cleaned-up"
+
+	// EventJobCreated a Job was created for a repository
+	EventJobCreated = "job-created"
+
+	// EventJobSucceeded a Job of a repository succeeded
+	EventJobSucceeded = "job-succeeded"
+
+	// EventJobFailed a Job of a repository failed
+	EventJobFailed = "job-failed"
+
+	// EventJobDeleted a Job of a repository was deleted
+	EventJobDeleted = "job-deleted"
+
+	// EventStaleBoot a repository has not booted successfully within the staleness threshold
+	EventStaleBoot = "stale-boot"
+
+	// EventTriggered a repository was triggered by an external system to launch its latest commit again
+	EventTriggered = "triggered"
+
+	// EventFailureArtifacts the logs, events and manifest of a failed Job were uploaded; the message is their URL
+	EventFailureArtifacts = "failure-artifacts"
+
+	// EventRolledBack a repository was rolled back to its last successful commit after the launch of the failed
+	// commit in the event exhausted its retries; the message is the commit sha rolled back to
+	EventRolledBack = "rolled-back"
+
+	// subscriberBuffer the number of events buffered for each subscriber before events are dropped
+	subscriberBuffer = 100
+)
+
+// Event a reconcile or Job lifecycle event of the operator
+type Event struct {
+	// Type the type of the event
+	Type string `json:"type"`
+
+	// Repository the name of the repository
+	Repository string `json:"repository"`
+
+	// Namespace the namespace of the repository
+	Namespace string `json:"namespace"`
+
+	// GitSHA the optional commit sha
+	GitSHA string `json:"gitSha,omitempty"`
+
+	// Job the optional name of the Job
+	Job string `json:"job,omitempty"`
+
+	// Message the optional message such as an error
+	Message string `json:"message,omitempty"`
+
+	// Time when the event occurred
+	Time time.Time `json:"time"`
+}
+
+// NewEvent creates a new event of the given type for the repository
+func NewEvent(eventType string, r repo.Repository, gitSHA string, message string) Event {
+	return Event{
+		Type:       eventType,
+		Repository: r.Name,
+		Namespace:  r.Namespace,
+		GitSHA:     gitSHA,
+		Message:    message,
+		Time:       time.Now(),
+	}
+}
+
+// Broker publishes events to any number of subscribers. Publishing never blocks: if a subscriber is too slow to
+// consume its events then new events are dropped for that subscriber
+type Broker struct {
+	lock        sync.Mutex
+	subscribers map[chan Event]int
+}
+
+// NewBroker creates a new broker
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[chan Event]int{},
+	}
+}
+
+// Publish publishes the event to all of the subscribers
+func (b *Broker) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			b.subscribers[ch]++
+		}
+	}
+}
+
+// Subscribe returns a new channel of the published events. The channel must be passed to Unsubscribe when the
+// events are no longer required
+func (b *Broker) Subscribe() chan Event {
+	return b.SubscribeWithBuffer(subscriberBuffer)
+}
+
+// SubscribeWithBuffer returns a new channel of the published events which buffers the given number of events before
+// events are dropped, for subscribers which must not miss events during bursts
+func (b *Broker) SubscribeWithBuffer(size int) chan Event {
+	if size < subscriberBuffer {
+		size = subscriberBuffer
+	}
+	ch := make(chan Event, size)
+	b.lock.Lock()
+	b.subscribers[ch] = 0
+	b.lock.Unlock()
+	return ch
+}
+
+// Dropped returns the number of events dropped for the given subscriber as its buffer was full
+func (b *Broker) Dropped(ch chan Event) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.subscribers[ch]
+}
+
+// Unsubscribe stops publishing events to the given channel
+func (b *Broker) Unsubscribe(ch chan Event) {
+	b.lock.Lock()
+	delete(b.subscribers, ch)
+	b.lock.Unlock()
+}
@@ -0,0 +1,120 @@
+package tenant
+
+import (
+	"github.com/jenkins-x/jx-git-operator/pkg/podspecs"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-helpers/pkg/files"
+	"github.com/jenkins-x/jx-helpers/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/pkg/yamls"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Config the tenancy configuration of the operator
+type Config struct {
+	// Tenants the tenants which repositories can be grouped into
+	Tenants []Tenant `json:"tenants,omitempty"`
+}
+
+// Tenant a group of repositories which are restricted to specific namespaces, service accounts and notification
+// channels
+type Tenant struct {
+	// Name the name of the tenant which repositories reference via the `git-operator.jenkins.io/tenant` label
+	Name string `json:"name"`
+
+	// Namespaces the namespaces the repositories of the tenant can launch Jobs and apply resources in.
+	// If empty any namespace is allowed
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ServiceAccounts the service accounts the Jobs of the tenant can run as. If empty any service account is allowed
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+
+	// NotificationWebhook the optional URL which is sent the notifications of the repositories of the tenant, such as
+	// when a repository becomes stale, instead of the webhook of the operator so that each team only receives the
+	// notifications of its own repositories
+	NotificationWebhook string `json:"notificationWebhook,omitempty"`
+}
+
+// LoadConfig loads the tenancy configuration from the given YAML file
+func LoadConfig(fileName string) (*Config, error) {
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", fileName)
+	}
+	if !exists {
+		return nil, errors.Errorf("tenants file %s does not exist", fileName)
+	}
+	config := &Config{}
+	err = yamls.LoadFile(fileName, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load tenants file %s", fileName)
+	}
+	for i, t := range config.Tenants {
+		if t.Name == "" {
+			return nil, errors.Errorf("tenant %d in file %s has no name", i+1, fileName)
+		}
+	}
+	return config, nil
+}
+
+// FindTenant finds the tenant of the given repository or returns an error if the repository does not
+// reference a known tenant
+func (c *Config) FindTenant(r repo.Repository) (*Tenant, error) {
+	if r.Tenant == "" {
+		return nil, errors.Errorf("repository %s in namespace %s does not have a %s label", r.Name, r.Namespace, repo.TenantLabel)
+	}
+	for i := range c.Tenants {
+		t := &c.Tenants[i]
+		if t.Name == r.Tenant {
+			return t, nil
+		}
+	}
+	return nil, errors.Errorf("repository %s in namespace %s uses unknown tenant %s", r.Name, r.Namespace, r.Tenant)
+}
+
+// Validate validates that the resources to be launched in the given namespace are allowed by the tenant
+func (t *Tenant) Validate(ns string, resources []*unstructured.Unstructured) error {
+	if !t.namespaceAllowed(ns) {
+		return errors.Errorf("tenant %s is not allowed to launch in namespace %s", t.Name, ns)
+	}
+	for _, r := range resources {
+		resourceNs := r.GetNamespace()
+		if resourceNs != "" && !t.namespaceAllowed(resourceNs) {
+			return errors.Errorf("tenant %s is not allowed to use namespace %s for %s %s", t.Name, resourceNs, r.GetKind(), r.GetName())
+		}
+		if len(t.ServiceAccounts) == 0 {
+			continue
+		}
+		for _, sa := range serviceAccountNames(r) {
+			if stringhelpers.StringArrayIndex(t.ServiceAccounts, sa) < 0 {
+				return errors.Errorf("tenant %s is not allowed to use service account %s for %s %s", t.Name, sa, r.GetKind(), r.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Tenant) namespaceAllowed(ns string) bool {
+	return len(t.Namespaces) == 0 || stringhelpers.StringArrayIndex(t.Namespaces, ns) >= 0
+}
+
+// serviceAccountNames returns the service account names used by the resource
+func serviceAccountNames(r *unstructured.Unstructured) []string {
+	var answer []string
+	podspecs.Visit(r, func(podSpec map[string]interface{}) {
+		sa, _ := podSpec["serviceAccountName"].(string)
+		if sa == "" {
+			sa = "default"
+		}
+		answer = append(answer, sa)
+	})
+
+	// lets handle resources such as a TaskRun which specify the service account in their spec
+	if r.GetKind() != "Pod" {
+		sa, _, _ := unstructured.NestedString(r.Object, "spec", "serviceAccountName")
+		if sa != "" {
+			answer = append(answer, sa)
+		}
+	}
+	return answer
+}
@@ -0,0 +1,108 @@
+package tenant_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTenants(t *testing.T) {
+	config, err := tenant.LoadConfig(filepath.Join("test_data", "tenants.yaml"))
+	require.NoError(t, err, "failed to load tenants")
+	require.Len(t, config.Tenants, 2, "tenants")
+
+	_, err = config.FindTenant(repo.Repository{Name: "no-tenant"})
+	require.Error(t, err, "should not find a tenant for a repository without a tenant label")
+
+	_, err = config.FindTenant(repo.Repository{Name: "unknown", Tenant: "team-c"})
+	require.Error(t, err, "should not find an unknown tenant")
+
+	teamA, err := config.FindTenant(repo.Repository{Name: "app", Tenant: "team-a"})
+	require.NoError(t, err, "failed to find tenant")
+	assert.Equal(t, "team-a", teamA.Name, "tenant name")
+
+	teamB, err := config.FindTenant(repo.Repository{Name: "app", Tenant: "team-b"})
+	require.NoError(t, err, "failed to find tenant")
+
+	testCases := []struct {
+		name      string
+		tenant    *tenant.Tenant
+		ns        string
+		resources []*unstructured.Unstructured
+		valid     bool
+	}{
+		{
+			name:      "allowed",
+			tenant:    teamA,
+			ns:        "team-a",
+			resources: []*unstructured.Unstructured{newJob("", "team-a-boot")},
+			valid:     true,
+		},
+		{
+			name:      "wrong-namespace",
+			tenant:    teamA,
+			ns:        "jx",
+			resources: []*unstructured.Unstructured{newJob("", "team-a-boot")},
+		},
+		{
+			name:      "wrong-resource-namespace",
+			tenant:    teamA,
+			ns:        "team-a",
+			resources: []*unstructured.Unstructured{newJob("kube-system", "team-a-boot")},
+		},
+		{
+			name:      "wrong-service-account",
+			tenant:    teamA,
+			ns:        "team-a",
+			resources: []*unstructured.Unstructured{newJob("", "cluster-admin")},
+		},
+		{
+			name:      "default-service-account",
+			tenant:    teamA,
+			ns:        "team-a",
+			resources: []*unstructured.Unstructured{newJob("", "")},
+		},
+		{
+			name:      "unrestricted",
+			tenant:    teamB,
+			ns:        "jx",
+			resources: []*unstructured.Unstructured{newJob("kube-system", "cluster-admin")},
+			valid:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		err = tc.tenant.Validate(tc.ns, tc.resources)
+		if tc.valid {
+			assert.NoError(t, err, "for test %s", tc.name)
+		} else {
+			assert.Error(t, err, "for test %s", tc.name)
+		}
+	}
+}
+
+func newJob(ns string, serviceAccount string) *unstructured.Unstructured {
+	podSpec := map[string]interface{}{}
+	if serviceAccount != "" {
+		podSpec["serviceAccountName"] = serviceAccount
+	}
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": podSpec,
+				},
+			},
+		},
+	}
+	u.SetName("myjob")
+	u.SetNamespace(ns)
+	return u
+}
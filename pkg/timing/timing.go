@@ -0,0 +1,83 @@
+package timing
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/metrics"
+)
+
+const (
+	// PhaseList listing the repositories
+	PhaseList = "list"
+
+	// PhaseClone cloning or pulling the git clone of a repository
+	PhaseClone = "clone"
+
+	// PhaseDiff finding the paths changed by a commit and previewing the changes to the applied resources
+	PhaseDiff = "diff"
+
+	// PhaseApply applying the resources of a repository
+	PhaseApply = "apply"
+
+	// PhaseCreate creating the Job or other launched resources of a commit
+	PhaseCreate = "create"
+)
+
+// Breakdown the durations of the phases of polling and launching a repository.
+//
+// A nil breakdown records nothing so that the timing breakdown is optional
+type Breakdown struct {
+	lock      sync.Mutex
+	phases    []string
+	durations map[string]time.Duration
+}
+
+// NewBreakdown creates a new empty breakdown
+func NewBreakdown() *Breakdown {
+	return &Breakdown{
+		durations: map[string]time.Duration{},
+	}
+}
+
+// Since adds the duration since the given start time to the phase and observes it via the phase duration metric
+func (b *Breakdown) Since(phase string, start time.Time) {
+	if b == nil {
+		return
+	}
+	d := time.Since(start)
+	metrics.PhaseDuration.WithLabelValues(phase).Observe(d.Seconds())
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.durations[phase]; !ok {
+		b.phases = append(b.phases, phase)
+	}
+	b.durations[phase] += d
+}
+
+// Get returns the total duration of the phase or zero if it has not been recorded
+func (b *Breakdown) Get(phase string) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.durations[phase]
+}
+
+// String returns the duration of each phase in the order they were first recorded such as `clone=1.2s diff=3ms`
+func (b *Breakdown) String() string {
+	if b == nil {
+		return ""
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var fields []string
+	for _, phase := range b.phases {
+		fields = append(fields, fmt.Sprintf("%s=%s", phase, b.durations[phase].Round(time.Microsecond).String()))
+	}
+	return strings.Join(fields, " ")
+}
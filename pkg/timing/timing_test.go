@@ -0,0 +1,27 @@
+package timing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/timing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakdown(t *testing.T) {
+	b := timing.NewBreakdown()
+	b.Since(timing.PhaseClone, time.Now().Add(-2*time.Second))
+	b.Since(timing.PhaseDiff, time.Now().Add(-time.Second))
+	b.Since(timing.PhaseClone, time.Now().Add(-time.Second))
+
+	assert.True(t, b.Get(timing.PhaseClone) >= 3*time.Second, "should add the durations of a phase but got %s", b.Get(timing.PhaseClone).String())
+	assert.Equal(t, time.Duration(0), b.Get(timing.PhaseApply), "should not have timed the apply phase")
+	assert.Regexp(t, `^clone=3[.\d]*s diff=1[.\d]*s$`, b.String(), "should display the phases in the order they were timed")
+}
+
+func TestNilBreakdown(t *testing.T) {
+	var b *timing.Breakdown
+	b.Since(timing.PhaseClone, time.Now())
+	assert.Equal(t, time.Duration(0), b.Get(timing.PhaseClone), "a nil breakdown should not record anything")
+	assert.Empty(t, b.String(), "a nil breakdown should be empty")
+}
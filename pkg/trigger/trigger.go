@@ -0,0 +1,302 @@
+package trigger
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/poller"
+	"github.com/jenkins-x/jx-git-operator/pkg/repo"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/jenkins-x/jx-git-operator/pkg/stream"
+	"github.com/jenkins-x/jx-logging/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Path the path prefix of the trigger endpoint which is followed by `<name>` or `<namespace>/<name>`
+	Path = "/trigger/"
+
+	// DefaultMinInterval the default minimum interval between triggers of the same repository
+	DefaultMinInterval = time.Minute
+
+	// MaxSignatureAge the maximum age of the timestamp of a signed request
+	MaxSignatureAge = 5 * time.Minute
+
+	// maxPayloadSize the maximum size of a trigger request body
+	maxPayloadSize = 64 * 1024
+)
+
+// Request the optional JSON body of a trigger request which is recorded in the audit log
+type Request struct {
+	// Source the system which requested the trigger such as `release-pipeline`
+	Source string `json:"source,omitempty"`
+
+	// Reason why the repository is triggered
+	Reason string `json:"reason,omitempty"`
+}
+
+// Handler the endpoint which lets external automation such as release pipelines or ChatOps force the latest commit
+// of a repository to be launched again.
+//
+// Each request must either pass the token via an `Authorization: Bearer <token>` header or sign its method, path,
+// `X-Signature-Timestamp` header and body with the token via the `X-Hub-Signature-256` header. Signed requests are
+// rejected if their timestamp is older than MaxSignatureAge or their signature has already been used. Each repository
+// can be triggered at most once per minimum interval and every request is audit logged along with the client address,
+// which is only taken from the `X-Forwarded-For` header of requests sent by the trusted proxies
+type Handler struct {
+	token          []byte
+	minInterval    time.Duration
+	trustedProxies []*net.IPNet
+	poller         *poller.Options
+
+	lock sync.Mutex
+	last map[string]time.Time
+	used map[string]time.Time
+}
+
+// NewHandler creates a new trigger endpoint for the given token, minimum interval between triggers of a repository
+// and optional trusted proxies
+func NewHandler(token string, minInterval time.Duration, trustedProxies []*net.IPNet, p *poller.Options) *Handler {
+	if minInterval <= 0 {
+		minInterval = DefaultMinInterval
+	}
+	return &Handler{
+		token:          []byte(token),
+		minInterval:    minInterval,
+		trustedProxies: trustedProxies,
+		poller:         p,
+		last:           map[string]time.Time{},
+		used:           map[string]time.Time{},
+	}
+}
+
+// ParseTrustedProxies parses the comma separated IP addresses or CIDR ranges of the trusted proxies
+func ParseTrustedProxies(text string) ([]*net.IPNet, error) {
+	var answer []*net.IPNet
+	for _, value := range strings.Split(text, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy address %s", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			answer = append(answer, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted proxy range %s", value)
+		}
+		answer = append(answer, ipNet)
+	}
+	return answer, nil
+}
+
+// ServeHTTP handles a trigger request
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := h.clientAddress(r)
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read the request body", http.StatusBadRequest)
+		return
+	}
+	auth := h.authenticate(r, payload)
+	if auth == "" {
+		log.Logger().Warnf("audit: rejected unauthenticated trigger of %s from %s", r.URL.Path, client)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="jx-git-operator"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	req := &Request{}
+	if len(strings.TrimSpace(string(payload))) > 0 {
+		err = json.Unmarshal(payload, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse the request body: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Source == "" {
+		req.Source = r.UserAgent()
+	}
+
+	rp, status, err := h.findRepository(strings.Trim(strings.TrimPrefix(r.URL.Path, Path), "/"))
+	if err != nil {
+		log.Logger().Warnf("audit: failed trigger of %s by %s from %s: %s", r.URL.Path, req.Source, client, err.Error())
+		http.Error(w, err.Error(), status)
+		return
+	}
+	key := rp.Namespace + "/" + rp.Name
+	if rp.Paused {
+		log.Logger().Warnf("audit: rejected trigger of paused repository %s by %s from %s", key, req.Source, client)
+		http.Error(w, fmt.Sprintf("repository %s in namespace %s is paused", rp.Name, rp.Namespace), http.StatusConflict)
+		return
+	}
+	wait := h.reserve(key)
+	if wait > 0 {
+		log.Logger().Warnf("audit: rate limited trigger of repository %s by %s from %s", key, req.Source, client)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, fmt.Sprintf("repository %s was triggered recently, retry in %s", key, wait.Round(time.Second).String()), http.StatusTooManyRequests)
+		return
+	}
+
+	message := fmt.Sprintf("triggered by %s via %s from %s", req.Source, auth, client)
+	if req.Reason != "" {
+		message += ": " + req.Reason
+	}
+	log.Logger().Infof("audit: repository %s %s", key, message)
+	h.poller.Trigger(rp.Namespace, rp.Name)
+	h.poller.Events.Publish(stream.NewEvent(stream.EventTriggered, rp, "", message))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	_, err = fmt.Fprintf(w, "triggered repository %s\n", key)
+	if err != nil {
+		log.Logger().Warnf("failed to write response: %s", err.Error())
+	}
+}
+
+// authenticate returns how the request was authenticated or an empty string if it was not
+func (h *Handler) authenticate(r *http.Request, payload []byte) string {
+	if len(h.token) == 0 {
+		return ""
+	}
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), h.token) == 1 {
+			return "bearer token"
+		}
+		return ""
+	}
+	if signature.ValidRequest(h.token, r, payload, MaxSignatureAge) && h.useSignature(r.Header.Get(signature.RequestHeader)) {
+		return "HMAC signature"
+	}
+	return ""
+}
+
+// useSignature records the signature of a request returning false if it has already been used so that a signed
+// request cannot be replayed before its timestamp expires
+func (h *Handler) useSignature(value string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	for k, expires := range h.used {
+		if now.After(expires) {
+			delete(h.used, k)
+		}
+	}
+	if _, ok := h.used[value]; ok {
+		return false
+	}
+	// the timestamp may be up to the maximum age in the future
+	h.used[value] = now.Add(2 * MaxSignatureAge)
+	return true
+}
+
+// findRepository finds the repository for the given `<name>` or `<namespace>/<name>` returning the HTTP status of
+// any error
+func (h *Handler) findRepository(path string) (repo.Repository, int, error) {
+	ns := ""
+	name := path
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 2:
+		ns = parts[0]
+		name = parts[1]
+	case len(parts) > 2 || path == "":
+		return repo.Repository{}, http.StatusNotFound, fmt.Errorf("expected a path of %s<name> or %s<namespace>/<name>", Path, Path)
+	}
+
+	repos, err := h.poller.RepoClient.List()
+	if err != nil {
+		return repo.Repository{}, http.StatusInternalServerError, err
+	}
+	var found []repo.Repository
+	for _, r := range repos {
+		if r.Name == name && (ns == "" || r.Namespace == ns) {
+			found = append(found, r)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return repo.Repository{}, http.StatusNotFound, fmt.Errorf("repository %s not found", path)
+	case 1:
+		return found[0], 0, nil
+	default:
+		return repo.Repository{}, http.StatusConflict, fmt.Errorf("there are %d repositories called %s. Please specify the namespace via %s<namespace>/<name>", len(found), name, Path)
+	}
+}
+
+// reserve records the trigger of the repository returning how long to wait if it was triggered too recently
+func (h *Handler) reserve(key string) time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+	if last, ok := h.last[key]; ok {
+		wait := h.minInterval - now.Sub(last)
+		if wait > 0 {
+			return wait
+		}
+	}
+	h.last[key] = now
+	return 0
+}
+
+// clientAddress returns the address of the client. If the request was sent by a trusted proxy the last address of
+// the `X-Forwarded-For` header which is not a trusted proxy is used, as the addresses before it could be forged by
+// the client
+func (h *Handler) clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !h.trusted(host) {
+		return r.RemoteAddr
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwarded[i])
+		if address != "" && !h.trusted(address) {
+			return address
+		}
+	}
+	return r.RemoteAddr
+}
+
+// trusted returns true if the address is one of the trusted proxies
+func (h *Handler) trusted(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range h.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package trigger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-git-operator/pkg/harness"
+	"github.com/jenkins-x/jx-git-operator/pkg/signature"
+	"github.com/jenkins-x/jx-git-operator/pkg/trigger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const token = "mytoken"
+
+func TestTriggerHandler(t *testing.T) {
+	h := harness.NewHarness(t, "jx", nil)
+	h.AddRepository(t, "myrepo", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.Poll(t)
+	h.SetJobSucceeded(t, "myrepo", "sha1")
+
+	handler := trigger.NewHandler(token, time.Hour, nil, h.Poller)
+
+	w := post(handler, "myrepo", `{"source": "release-pipeline", "reason": "promote 1.2.3"}`, "Bearer wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject an invalid token")
+
+	w = post(handler, "unknown", "", "Bearer "+token)
+	assert.Equal(t, http.StatusNotFound, w.Code, "should not find an unknown repository")
+
+	w = post(handler, "jx/myrepo", `{"source": "release-pipeline", "reason": "promote 1.2.3"}`, "Bearer "+token)
+	require.Equal(t, http.StatusAccepted, w.Code, "trigger: %s", w.Body.String())
+	h.Poll(t)
+	jobs := h.JobsForRepositoryAndSha(t, "myrepo", "sha1")
+	require.Len(t, jobs, 1)
+	assert.Equal(t, int32(0), jobs[0].Status.Succeeded, "should have relaunched the job")
+
+	w = postSigned(handler, "myrepo", `{"source": "chatops"}`, "myrepo", time.Now())
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "should rate limit a signed trigger of the same repository")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"), "Retry-After header")
+
+	req := httptest.NewRequest(http.MethodGet, trigger.Path+"myrepo", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code, "trigger requires a POST")
+}
+
+func TestTriggerHandlerSignedRequests(t *testing.T) {
+	h := harness.NewHarness(t, "jx", nil)
+	h.AddRepository(t, "myrepo", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.AddRepository(t, "other", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.Poll(t)
+
+	handler := trigger.NewHandler(token, time.Nanosecond, nil, h.Poller)
+
+	payload := `{"source": "chatops"}`
+	w := postSigned(handler, "myrepo", payload, "myrepo", time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject a signature with an expired timestamp")
+
+	w = postSigned(handler, "other", payload, "myrepo", time.Now())
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject a signature of another path")
+
+	req := httptest.NewRequest(http.MethodPost, trigger.Path+"myrepo", strings.NewReader(payload))
+	req.Header.Set("X-Hub-Signature-256", signature.Sign([]byte(token), []byte(payload)))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject a signature of only the body")
+
+	timestamp := time.Now()
+	w = postSigned(handler, "myrepo", payload, "myrepo", timestamp)
+	require.Equal(t, http.StatusAccepted, w.Code, "trigger: %s", w.Body.String())
+
+	time.Sleep(time.Millisecond)
+	w = postSigned(handler, "myrepo", payload, "myrepo", timestamp)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "should reject a replayed signature")
+}
+
+func TestTriggerHandlerClientAddress(t *testing.T) {
+	h := harness.NewHarness(t, "jx", nil)
+	h.AddRepository(t, "myrepo", "https://github.com/jenkins-x/fake-repository.git", filepath.Join("..", "poller", "test_data", "fake-repository"), "sha1")
+	h.Poll(t)
+
+	proxies, err := trigger.ParseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	require.NoError(t, err, "failed to parse trusted proxies")
+	_, err = trigger.ParseTrustedProxies("not-an-address")
+	assert.Error(t, err, "should fail to parse an invalid proxy")
+
+	handler := trigger.NewHandler(token, time.Nanosecond, proxies, h.Poller)
+	events := h.Poller.Events.Subscribe()
+	defer h.Poller.Events.Unsubscribe(events)
+
+	testCases := []struct {
+		remoteAddress string
+		forwardedFor  string
+		expected      string
+	}{
+		{
+			remoteAddress: "203.0.113.7:1234",
+			forwardedFor:  "198.51.100.1",
+			expected:      "from 203.0.113.7:1234",
+		},
+		{
+			remoteAddress: "10.1.2.3:1234",
+			forwardedFor:  "198.51.100.1",
+			expected:      "from 198.51.100.1",
+		},
+		{
+			remoteAddress: "192.168.1.1:1234",
+			forwardedFor:  "198.51.100.1, 203.0.113.9, 10.4.5.6",
+			expected:      "from 203.0.113.9",
+		},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, trigger.Path+"myrepo", nil)
+		req.RemoteAddr = tc.remoteAddress
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		w := httptest.NewRecorder()
+		time.Sleep(time.Millisecond)
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, "trigger: %s", w.Body.String())
+
+		select {
+		case e := <-events:
+			assert.Contains(t, e.Message, tc.expected, "client address for %s forwarded for %s", tc.remoteAddress, tc.forwardedFor)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "no triggered event for %s", tc.remoteAddress)
+		}
+	}
+}
+
+func post(handler http.Handler, name string, payload string, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, trigger.Path+name, strings.NewReader(payload))
+	req.Header.Set("Authorization", authorization)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func postSigned(handler http.Handler, name string, payload string, signedName string, timestamp time.Time) *httptest.ResponseRecorder {
+	value := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, trigger.Path+name, strings.NewReader(payload))
+	req.Header.Set(signature.TimestampHeader, value)
+	req.Header.Set(signature.RequestHeader, signature.SignRequest([]byte(token), http.MethodPost, trigger.Path+signedName, value, []byte(payload)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}